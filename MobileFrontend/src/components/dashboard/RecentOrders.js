@@ -25,7 +25,7 @@ const RecentOrders = ({ onOrderComplete, isMobile = false }) => {
   const fetchOrders = async () => {
     try {
       const token = await AsyncStorage.getItem('authToken');
-      const response = await fetch(`${API_BASE_URL}/api/v1/trading/orders`, {
+      const response = await fetch(`${API_BASE_URL}/api/v1/trading/orders?status=all&limit=10`, {
         headers: {
           'Authorization': `Bearer ${token}`,
           'Content-Type': 'application/json',
@@ -20,7 +20,7 @@ const RecentOrders = ({ onOrderComplete }) => {
       setLoading(true);
       setError(null);
 
-      const response = await fetch(`${API_BASE_URL}/api/v1/trading/orders`, {
+      const response = await fetch(`${API_BASE_URL}/api/v1/trading/orders?status=all&limit=3`, {
         method: 'GET',
         credentials: 'include',
         headers: {
@@ -33,8 +33,8 @@ const RecentOrders = ({ onOrderComplete }) => {
       }
 
       const data = await response.json();
-      // Take only the 3 most recent orders
-      const recentOrders = Array.isArray(data) ? data.slice(0, 3) : [];
+      // The 3 most recent orders, newest first
+      const recentOrders = Array.isArray(data.orders) ? data.orders : [];
       setOrders(recentOrders);
     } catch (err) {
       console.error('Error fetching orders:', err);
@@ -2,17 +2,23 @@ import React, { useState, useEffect } from 'react';
 
 const ViewAllOrdersPopup = ({ isOpen, onClose }) => {
   const [orders, setOrders] = useState([]);
+  const [nextCursor, setNextCursor] = useState(null);
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState(null);
 
   const API_BASE_URL = process.env.REACT_APP_API_URL || 'http://localhost:3000';
 
-  const fetchAllOrders = async () => {
+  const fetchAllOrders = async (cursor = null) => {
     try {
       setLoading(true);
       setError(null);
 
-      const response = await fetch(`${API_BASE_URL}/api/v1/trading/orders`, {
+      const params = new URLSearchParams({ status: 'all', limit: '100' });
+      if (cursor) {
+        params.set('cursor', cursor);
+      }
+
+      const response = await fetch(`${API_BASE_URL}/api/v1/trading/orders?${params}`, {
         method: 'GET',
         credentials: 'include',
         headers: {
@@ -25,7 +31,9 @@ const ViewAllOrdersPopup = ({ isOpen, onClose }) => {
       }
 
       const data = await response.json();
-      setOrders(Array.isArray(data) ? data : []);
+      const page = Array.isArray(data.orders) ? data.orders : [];
+      setOrders(prev => (cursor ? [...prev, ...page] : page));
+      setNextCursor(data.next_cursor || null);
     } catch (err) {
       console.error('Error fetching orders:', err);
       setError(err.message || 'Failed to fetch orders');
@@ -95,7 +103,7 @@ const ViewAllOrdersPopup = ({ isOpen, onClose }) => {
 
         {/* Content */}
         <div className="p-6 overflow-auto max-h-[70vh]">
-          {loading ? (
+          {loading && orders.length === 0 ? (
             <div className="flex items-center justify-center py-12">
               <div className="text-gray-500">Loading orders...</div>
             </div>
@@ -105,7 +113,7 @@ const ViewAllOrdersPopup = ({ isOpen, onClose }) => {
                 <p className="text-red-500 mb-2">Error loading orders</p>
                 <p className="text-gray-500 text-sm">{error}</p>
                 <button 
-                  onClick={fetchAllOrders}
+                  onClick={() => fetchAllOrders()}
                   className="mt-3 px-4 py-2 bg-blue-500 text-white rounded hover:bg-blue-600"
                 >
                   Retry
@@ -166,6 +174,17 @@ const ViewAllOrdersPopup = ({ isOpen, onClose }) => {
                   ))}
                 </tbody>
               </table>
+              {nextCursor && (
+                <div className="flex justify-center mt-4">
+                  <button
+                    onClick={() => fetchAllOrders(nextCursor)}
+                    disabled={loading}
+                    className="px-4 py-2 bg-blue-500 text-white rounded hover:bg-blue-600 disabled:opacity-50"
+                  >
+                    {loading ? 'Loading...' : 'Load more'}
+                  </button>
+                </div>
+              )}
             </div>
           )}
         </div>
@@ -0,0 +1,330 @@
+package broker
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AlpacaBroker implements Broker against the Alpaca Broker API
+type AlpacaBroker struct {
+	baseURL   string
+	basicAuth string
+	client    *http.Client
+}
+
+var _ Broker = (*AlpacaBroker)(nil)
+
+// NewAlpaca creates an Alpaca broker client
+func NewAlpaca(config Config) *AlpacaBroker {
+	baseURL := strings.TrimRight(config.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		timeout := config.Timeout
+		if timeout <= 0 {
+			timeout = DefaultTimeout
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+
+	auth := config.APIKey + ":" + config.APISecret
+	return &AlpacaBroker{
+		baseURL:   baseURL,
+		basicAuth: "Basic " + base64.StdEncoding.EncodeToString([]byte(auth)),
+		client:    client,
+	}
+}
+
+// BaseURL returns the broker API root requests are sent to
+func (a *AlpacaBroker) BaseURL() string {
+	return a.baseURL
+}
+
+// Do sends a request to path (relative to the base URL) and decodes a successful
+// JSON response into out. Non-2xx responses are returned as *APIError.
+func (a *AlpacaBroker) Do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	endpoint := a.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		payload = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", a.basicAuth)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return decodeAPIError(res.StatusCode, data)
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func tradingPath(accountID string, parts ...string) string {
+	path := "/v1/trading/accounts/" + url.PathEscape(accountID)
+	for _, part := range parts {
+		path += "/" + part
+	}
+	return path
+}
+
+func accountsPath(accountID string, parts ...string) string {
+	path := "/v1/accounts/" + url.PathEscape(accountID)
+	for _, part := range parts {
+		path += "/" + part
+	}
+	return path
+}
+
+func (a *AlpacaBroker) SubmitOrder(ctx context.Context, accountID string, req OrderRequest) (*Order, error) {
+	var order Order
+	if err := a.Do(ctx, http.MethodPost, tradingPath(accountID, "orders"), nil, req, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (a *AlpacaBroker) GetOrder(ctx context.Context, accountID, orderID string) (*Order, error) {
+	var order Order
+	if err := a.Do(ctx, http.MethodGet, tradingPath(accountID, "orders", url.PathEscape(orderID)), nil, nil, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (a *AlpacaBroker) ListOrders(ctx context.Context, accountID string, params ListOrdersParams) ([]Order, error) {
+	query := url.Values{}
+	if params.Status != "" {
+		query.Set("status", params.Status)
+	}
+	if params.Limit > 0 {
+		query.Set("limit", strconv.Itoa(params.Limit))
+	}
+	if !params.After.IsZero() {
+		query.Set("after", params.After.UTC().Format(time.RFC3339Nano))
+	}
+	if !params.Until.IsZero() {
+		query.Set("until", params.Until.UTC().Format(time.RFC3339Nano))
+	}
+	if params.Direction != "" {
+		query.Set("direction", params.Direction)
+	}
+	if params.Nested {
+		query.Set("nested", "true")
+	}
+	if len(params.Symbols) > 0 {
+		query.Set("symbols", strings.Join(params.Symbols, ","))
+	}
+	if params.Side != "" {
+		query.Set("side", params.Side)
+	}
+
+	var orders []Order
+	if err := a.Do(ctx, http.MethodGet, tradingPath(accountID, "orders"), query, nil, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// ReplaceOrder amends a working order; the broker cancels it and returns the new order that replaces it
+func (a *AlpacaBroker) ReplaceOrder(ctx context.Context, accountID, orderID string, req ReplaceOrderRequest) (*Order, error) {
+	var order Order
+	if err := a.Do(ctx, http.MethodPatch, tradingPath(accountID, "orders", url.PathEscape(orderID)), nil, req, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (a *AlpacaBroker) CancelOrder(ctx context.Context, accountID, orderID string) error {
+	return a.Do(ctx, http.MethodDelete, tradingPath(accountID, "orders", url.PathEscape(orderID)), nil, nil, nil)
+}
+
+func (a *AlpacaBroker) CancelAllOrders(ctx context.Context, accountID string) ([]CancelStatus, error) {
+	var statuses []CancelStatus
+	if err := a.Do(ctx, http.MethodDelete, tradingPath(accountID, "orders"), nil, nil, &statuses); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+func (a *AlpacaBroker) ListPositions(ctx context.Context, accountID string) ([]Position, error) {
+	var positions []Position
+	if err := a.Do(ctx, http.MethodGet, tradingPath(accountID, "positions"), nil, nil, &positions); err != nil {
+		return nil, err
+	}
+	return positions, nil
+}
+
+func (a *AlpacaBroker) GetPosition(ctx context.Context, accountID, symbol string) (*Position, error) {
+	var position Position
+	if err := a.Do(ctx, http.MethodGet, tradingPath(accountID, "positions", url.PathEscape(symbol)), nil, nil, &position); err != nil {
+		return nil, err
+	}
+	return &position, nil
+}
+
+// ClosePosition sells (or covers) all or part of a position with a market order
+func (a *AlpacaBroker) ClosePosition(ctx context.Context, accountID, symbol string, params ClosePositionParams) (*Order, error) {
+	query := url.Values{}
+	if params.Qty != "" {
+		query.Set("qty", params.Qty)
+	}
+	if params.Percentage != "" {
+		query.Set("percentage", params.Percentage)
+	}
+
+	var order Order
+	if err := a.Do(ctx, http.MethodDelete, tradingPath(accountID, "positions", url.PathEscape(symbol)), query, nil, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// CloseAllPositions liquidates every position, optionally cancelling open orders first
+func (a *AlpacaBroker) CloseAllPositions(ctx context.Context, accountID string, cancelOrders bool) ([]ClosePositionStatus, error) {
+	query := url.Values{}
+	if cancelOrders {
+		query.Set("cancel_orders", "true")
+	}
+
+	var statuses []ClosePositionStatus
+	if err := a.Do(ctx, http.MethodDelete, tradingPath(accountID, "positions"), query, nil, &statuses); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+func (a *AlpacaBroker) GetAccount(ctx context.Context, accountID string) (*Account, error) {
+	var account Account
+	if err := a.Do(ctx, http.MethodGet, tradingPath(accountID, "account"), nil, nil, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (a *AlpacaBroker) GetPortfolioHistory(ctx context.Context, accountID string, params PortfolioHistoryParams) (*PortfolioHistory, error) {
+	query := url.Values{}
+	if params.Period != "" {
+		query.Set("period", params.Period)
+	}
+	if params.Timeframe != "" {
+		query.Set("timeframe", params.Timeframe)
+	}
+	if params.IntradayReporting != "" {
+		query.Set("intraday_reporting", params.IntradayReporting)
+	}
+	if params.PnlReset != "" {
+		query.Set("pnl_reset", params.PnlReset)
+	}
+	if params.CashflowTypes != "" {
+		query.Set("cashflow_types", params.CashflowTypes)
+	}
+	if !params.Start.IsZero() {
+		query.Set("start", params.Start.UTC().Format(time.RFC3339))
+	}
+	if !params.End.IsZero() {
+		query.Set("end", params.End.UTC().Format(time.RFC3339))
+	}
+
+	var history PortfolioHistory
+	if err := a.Do(ctx, http.MethodGet, tradingPath(accountID, "account", "portfolio", "history"), query, nil, &history); err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// ListActivities returns one page of the account's activity log
+func (a *AlpacaBroker) ListActivities(ctx context.Context, accountID string, params ListActivitiesParams) ([]Activity, error) {
+	query := url.Values{"account_id": {accountID}}
+	if len(params.ActivityTypes) > 0 {
+		query.Set("activity_types", strings.Join(params.ActivityTypes, ","))
+	}
+	if !params.After.IsZero() {
+		query.Set("after", params.After.UTC().Format(time.RFC3339Nano))
+	}
+	if !params.Until.IsZero() {
+		query.Set("until", params.Until.UTC().Format(time.RFC3339Nano))
+	}
+	if params.Direction != "" {
+		query.Set("direction", params.Direction)
+	}
+	if params.PageSize > 0 {
+		query.Set("page_size", strconv.Itoa(params.PageSize))
+	}
+	if params.PageToken != "" {
+		query.Set("page_token", params.PageToken)
+	}
+
+	var activities []Activity
+	if err := a.Do(ctx, http.MethodGet, "/v1/accounts/activities", query, nil, &activities); err != nil {
+		return nil, err
+	}
+	return activities, nil
+}
+
+func (a *AlpacaBroker) CreateTransfer(ctx context.Context, accountID string, req TransferRequest) (*Transfer, error) {
+	var transfer Transfer
+	if err := a.Do(ctx, http.MethodPost, accountsPath(accountID, "transfers"), nil, req, &transfer); err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func (a *AlpacaBroker) ListACHRelationships(ctx context.Context, accountID string) ([]ACHRelationship, error) {
+	var relationships []ACHRelationship
+	if err := a.Do(ctx, http.MethodGet, accountsPath(accountID, "ach_relationships"), nil, nil, &relationships); err != nil {
+		return nil, err
+	}
+	return relationships, nil
+}
+
+func (a *AlpacaBroker) CreateACHRelationship(ctx context.Context, accountID string, req ACHRelationshipRequest) (*ACHRelationship, error) {
+	var relationship ACHRelationship
+	if err := a.Do(ctx, http.MethodPost, accountsPath(accountID, "ach_relationships"), nil, req, &relationship); err != nil {
+		return nil, err
+	}
+	return &relationship, nil
+}
@@ -0,0 +1,201 @@
+package broker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestBroker(t *testing.T, handler http.HandlerFunc) *AlpacaBroker {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewAlpaca(Config{BaseURL: server.URL + "/", APIKey: "key", APISecret: "secret"})
+}
+
+func TestSubmitOrderSendsAuthAndBody(t *testing.T) {
+	b := newTestBroker(t, func(w http.ResponseWriter, r *http.Request) {
+		wantAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("key:secret"))
+		if got := r.Header.Get("Authorization"); got != wantAuth {
+			t.Errorf("expected Authorization %q, got %q", wantAuth, got)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("expected JSON content type, got %q", got)
+		}
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/trading/accounts/acct-1/orders" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		var req OrderRequest
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("failed to decode body: %v", err)
+		}
+		if req.Symbol != "AAPL" || req.Type != "limit" || req.LimitPrice != "190" {
+			t.Errorf("unexpected order body: %s", string(body))
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"order-1","symbol":"AAPL","status":"accepted","qty":"1","limit_price":"190","created_at":"2024-01-02T15:04:05.123Z"}`))
+	})
+
+	order, err := b.SubmitOrder(context.Background(), "acct-1", OrderRequest{
+		Symbol: "AAPL", Side: "buy", Type: "limit", TimeInForce: "day", Qty: "1", LimitPrice: "190",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.ID != "order-1" || order.Status != "accepted" || *order.LimitPrice != "190" {
+		t.Errorf("unexpected order: %+v", order)
+	}
+}
+
+func TestListOrdersEncodesParams(t *testing.T) {
+	b := newTestBroker(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("status") != "closed" || q.Get("limit") != "50" || q.Get("symbols") != "AAPL,MSFT" ||
+			q.Get("direction") != "asc" || q.Get("nested") != "true" || q.Get("after") != "2024-01-02T00:00:00Z" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`[{"id":"a"},{"id":"b"}]`))
+	})
+
+	orders, err := b.ListOrders(context.Background(), "acct-1", ListOrdersParams{
+		Status:    "closed",
+		Limit:     50,
+		After:     time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		Direction: "asc",
+		Nested:    true,
+		Symbols:   []string{"AAPL", "MSFT"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(orders) != 2 {
+		t.Errorf("expected 2 orders, got %d", len(orders))
+	}
+}
+
+func TestListActivitiesEncodesParams(t *testing.T) {
+	b := newTestBroker(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/v1/accounts/activities" || q.Get("account_id") != "acct-1" ||
+			q.Get("activity_types") != "DIV,CSD" || q.Get("page_size") != "100" || q.Get("page_token") != "act-9" {
+			t.Errorf("unexpected request %s?%s", r.URL.Path, r.URL.RawQuery)
+		}
+		w.Write([]byte(`[{"id":"act-10","activity_type":"DIV","date":"2024-05-16","symbol":"AAPL","qty":"10","net_amount":"2.4","per_share_amount":"0.24"}]`))
+	})
+
+	activities, err := b.ListActivities(context.Background(), "acct-1", ListActivitiesParams{
+		ActivityTypes: []string{ActivityDividend, ActivityDeposit},
+		PageSize:      100,
+		PageToken:     "act-9",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(activities) != 1 || activities[0].NetAmount != "2.4" || activities[0].Symbol != "AAPL" {
+		t.Errorf("unexpected activities: %+v", activities)
+	}
+}
+
+func TestReplaceOrderPatchesOnlyChangedFields(t *testing.T) {
+	b := newTestBroker(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/v1/trading/accounts/acct-1/orders/order-1" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"limit_price":"191"}` {
+			t.Errorf("unexpected body: %s", body)
+		}
+		w.Write([]byte(`{"id":"order-2","replaces":"order-1","limit_price":"191","status":"accepted"}`))
+	})
+
+	order, err := b.ReplaceOrder(context.Background(), "acct-1", "order-1", ReplaceOrderRequest{LimitPrice: "191"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.ID != "order-2" || *order.Replaces != "order-1" {
+		t.Errorf("unexpected order: %+v", order)
+	}
+}
+
+func TestCloseAllPositionsMultiStatus(t *testing.T) {
+	b := newTestBroker(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Path != "/v1/trading/accounts/acct-1/positions" || r.URL.Query().Get("cancel_orders") != "true" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		w.Write([]byte(`[{"symbol":"AAPL","status":200,"body":{"id":"order-1"}},{"symbol":"MSFT","status":403,"body":{"code":40310000,"message":"insufficient qty"}}]`))
+	})
+
+	statuses, err := b.CloseAllPositions(context.Background(), "acct-1", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].Symbol != "AAPL" || statuses[1].Status != http.StatusForbidden {
+		t.Errorf("unexpected statuses: %+v", statuses)
+	}
+}
+
+func TestAPIErrorDecoding(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantCode    int
+		wantMessage string
+	}{
+		{"alpaca json error", http.StatusForbidden, `{"code":40310000,"message":"insufficient buying power"}`, 40310000, "insufficient buying power"},
+		{"plain text error", http.StatusBadGateway, "upstream unavailable\n", 0, "upstream unavailable"},
+		{"empty body", http.StatusNotFound, "", 0, "Not Found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBroker(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			_, err := b.GetAccount(context.Background(), "acct-1")
+			apiErr, ok := AsAPIError(err)
+			if !ok {
+				t.Fatalf("expected APIError, got %v", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Code != tt.wantCode || apiErr.Message != tt.wantMessage {
+				t.Errorf("unexpected error: %+v", apiErr)
+			}
+		})
+	}
+}
+
+func TestCancelOrderNoContent(t *testing.T) {
+	b := newTestBroker(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Path != "/v1/trading/accounts/acct-1/orders/order-1" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := b.CancelOrder(context.Background(), "acct-1", "order-1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	b := NewAlpaca(Config{BaseURL: server.URL, Timeout: 20 * time.Millisecond})
+	if _, err := b.ListPositions(context.Background(), "acct-1"); err == nil {
+		t.Errorf("expected timeout error")
+	}
+}
@@ -0,0 +1,28 @@
+package broker
+
+import (
+	"time"
+	_ "time/tzdata"
+)
+
+// marketTZ is the exchange's time zone, which the broker's order windows follow
+var marketTZ, _ = time.LoadLocation("America/New_York")
+
+// Orders for the opening auction (time_in_force=opg) are accepted from 19:00 ET until
+// 09:28 ET the next morning; in between the broker rejects them
+const (
+	opgAcceptFrom  = 19 * time.Hour
+	opgAcceptUntil = 9*time.Hour + 28*time.Minute
+)
+
+// AcceptsOpeningAuctionOrders reports whether an OPG order placed at t would be
+// accepted and, when it would not, the time the broker starts taking them again
+func AcceptsOpeningAuctionOrders(t time.Time) (bool, time.Time) {
+	local := t.In(marketTZ)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, marketTZ)
+	sinceMidnight := local.Sub(midnight)
+	if sinceMidnight < opgAcceptUntil || sinceMidnight >= opgAcceptFrom {
+		return true, time.Time{}
+	}
+	return false, time.Date(local.Year(), local.Month(), local.Day(), 19, 0, 0, 0, marketTZ)
+}
@@ -0,0 +1,29 @@
+package broker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAcceptsOpeningAuctionOrders(t *testing.T) {
+	tests := []struct {
+		at       time.Time
+		accepted bool
+	}{
+		{time.Date(2024, 3, 28, 23, 30, 0, 0, time.UTC), true},  // 19:30 ET
+		{time.Date(2024, 3, 29, 13, 0, 0, 0, time.UTC), true},   // 09:00 ET
+		{time.Date(2024, 3, 29, 13, 28, 0, 0, time.UTC), false}, // 09:28 ET
+		{time.Date(2024, 3, 28, 20, 30, 0, 0, time.UTC), false}, // 16:30 ET
+		{time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), true},     // 19:00 EST
+		{time.Date(2024, 1, 4, 23, 59, 0, 0, time.UTC), false},  // 18:59 EST
+	}
+	for _, tt := range tests {
+		accepted, from := AcceptsOpeningAuctionOrders(tt.at)
+		if accepted != tt.accepted {
+			t.Errorf("%v: expected accepted=%v", tt.at, tt.accepted)
+		}
+		if !accepted && (from.Hour() != 19 || from.Before(tt.at)) {
+			t.Errorf("%v: expected orders accepted again at 19:00 ET, got %v", tt.at, from)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Package broker is the typed client the Go services use to talk to the brokerage.
+// Handlers depend on the Broker interface; AlpacaBroker implements it against the
+// Alpaca Broker API (or anything speaking the same protocol, such as a local simulator).
+package broker
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"time"
+)
+
+// DefaultBaseURL is the Alpaca Broker API sandbox
+const DefaultBaseURL = "https://broker-api.sandbox.alpaca.markets"
+
+// DefaultTimeout bounds every broker request unless overridden
+const DefaultTimeout = 15 * time.Second
+
+// Broker is the set of brokerage operations used across the platform
+type Broker interface {
+	SubmitOrder(ctx context.Context, accountID string, req OrderRequest) (*Order, error)
+	GetOrder(ctx context.Context, accountID, orderID string) (*Order, error)
+	ListOrders(ctx context.Context, accountID string, params ListOrdersParams) ([]Order, error)
+	ReplaceOrder(ctx context.Context, accountID, orderID string, req ReplaceOrderRequest) (*Order, error)
+	CancelOrder(ctx context.Context, accountID, orderID string) error
+	CancelAllOrders(ctx context.Context, accountID string) ([]CancelStatus, error)
+
+	ListPositions(ctx context.Context, accountID string) ([]Position, error)
+	GetPosition(ctx context.Context, accountID, symbol string) (*Position, error)
+	ClosePosition(ctx context.Context, accountID, symbol string, params ClosePositionParams) (*Order, error)
+	CloseAllPositions(ctx context.Context, accountID string, cancelOrders bool) ([]ClosePositionStatus, error)
+
+	GetAccount(ctx context.Context, accountID string) (*Account, error)
+	GetPortfolioHistory(ctx context.Context, accountID string, params PortfolioHistoryParams) (*PortfolioHistory, error)
+	ListActivities(ctx context.Context, accountID string, params ListActivitiesParams) ([]Activity, error)
+
+	CreateTransfer(ctx context.Context, accountID string, req TransferRequest) (*Transfer, error)
+	ListACHRelationships(ctx context.Context, accountID string) ([]ACHRelationship, error)
+	CreateACHRelationship(ctx context.Context, accountID string, req ACHRelationshipRequest) (*ACHRelationship, error)
+}
+
+// Config configures an AlpacaBroker
+type Config struct {
+	BaseURL   string
+	APIKey    string
+	APISecret string
+	// Timeout applies to each request; zero means DefaultTimeout
+	Timeout time.Duration
+	// HTTPClient overrides the client used for requests, mainly for tests
+	HTTPClient *http.Client
+}
+
+// ConfigFromEnv reads ALPACA_BROKER_URL, ALPACA_API_KEY, ALPACA_SECRET_KEY and ALPACA_TIMEOUT
+func ConfigFromEnv() Config {
+	config := Config{
+		BaseURL:   os.Getenv("ALPACA_BROKER_URL"),
+		APIKey:    os.Getenv("ALPACA_API_KEY"),
+		APISecret: os.Getenv("ALPACA_SECRET_KEY"),
+	}
+	if timeout, err := time.ParseDuration(os.Getenv("ALPACA_TIMEOUT")); err == nil {
+		config.Timeout = timeout
+	}
+	return config
+}
+
+// NewFromEnv creates an Alpaca broker configured from the environment
+func NewFromEnv() *AlpacaBroker {
+	return NewAlpaca(ConfigFromEnv())
+}
@@ -0,0 +1,54 @@
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIError is a non-2xx response from the broker
+type APIError struct {
+	// StatusCode is the HTTP status returned by the broker
+	StatusCode int `json:"-"`
+	// Code is the broker's own error code, when it sent one
+	Code int `json:"code"`
+	// Message is the broker's error message
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("broker error %d (code %d): %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("broker error %d: %s", e.StatusCode, e.Message)
+}
+
+// decodeAPIError builds an APIError from an error response body. Alpaca normally sends
+// {"code": 40010001, "message": "..."}, but proxies and gateways may send plain text.
+func decodeAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode}
+	if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(statusCode)
+	}
+	return apiErr
+}
+
+// AsAPIError unwraps err into an APIError if it is one
+func AsAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	return nil, false
+}
+
+// IsNotFound reports whether the broker answered 404
+func IsNotFound(err error) bool {
+	apiErr, ok := AsAPIError(err)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
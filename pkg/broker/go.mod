@@ -0,0 +1,3 @@
+module github.com/seunghoon34/trading-app/pkg/broker
+
+go 1.23.2
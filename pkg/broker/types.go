@@ -0,0 +1,285 @@
+package broker
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Decimal values are kept as strings, exactly as the broker sends them.
+
+// OrderRequest is the body of a new order
+type OrderRequest struct {
+	Symbol        string      `json:"symbol"`
+	Side          string      `json:"side"`
+	Type          string      `json:"type"`
+	TimeInForce   string      `json:"time_in_force"`
+	Qty           string      `json:"qty,omitempty"`
+	Notional      string      `json:"notional,omitempty"`
+	LimitPrice    string      `json:"limit_price,omitempty"`
+	StopPrice     string      `json:"stop_price,omitempty"`
+	TrailPrice    string      `json:"trail_price,omitempty"`
+	TrailPercent  string      `json:"trail_percent,omitempty"`
+	ExtendedHours bool        `json:"extended_hours,omitempty"`
+	ClientOrderID string      `json:"client_order_id,omitempty"`
+	OrderClass    string      `json:"order_class,omitempty"`
+	TakeProfit    *TakeProfit `json:"take_profit,omitempty"`
+	StopLoss      *StopLoss   `json:"stop_loss,omitempty"`
+}
+
+// ReplaceOrderRequest amends a working order. Empty fields keep the original value;
+// Trail is the new trail_price or trail_percent, whichever the order was placed with.
+type ReplaceOrderRequest struct {
+	Qty           string `json:"qty,omitempty"`
+	TimeInForce   string `json:"time_in_force,omitempty"`
+	LimitPrice    string `json:"limit_price,omitempty"`
+	StopPrice     string `json:"stop_price,omitempty"`
+	Trail         string `json:"trail,omitempty"`
+	ClientOrderID string `json:"client_order_id,omitempty"`
+}
+
+// TakeProfit is the limit leg of an advanced order
+type TakeProfit struct {
+	LimitPrice string `json:"limit_price"`
+}
+
+// StopLoss is the stop leg of an advanced order
+type StopLoss struct {
+	StopPrice  string `json:"stop_price"`
+	LimitPrice string `json:"limit_price,omitempty"`
+}
+
+// Order is an order as reported by the broker
+type Order struct {
+	ID             string     `json:"id"`
+	ClientOrderID  string     `json:"client_order_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      *time.Time `json:"updated_at"`
+	SubmittedAt    *time.Time `json:"submitted_at"`
+	FilledAt       *time.Time `json:"filled_at"`
+	ExpiredAt      *time.Time `json:"expired_at"`
+	CanceledAt     *time.Time `json:"canceled_at"`
+	FailedAt       *time.Time `json:"failed_at"`
+	ReplacedAt     *time.Time `json:"replaced_at"`
+	ReplacedBy     *string    `json:"replaced_by"`
+	Replaces       *string    `json:"replaces"`
+	AssetID        string     `json:"asset_id"`
+	Symbol         string     `json:"symbol"`
+	AssetClass     string     `json:"asset_class"`
+	Notional       *string    `json:"notional"`
+	Qty            *string    `json:"qty"`
+	FilledQty      string     `json:"filled_qty"`
+	FilledAvgPrice *string    `json:"filled_avg_price"`
+	OrderClass     string     `json:"order_class"`
+	Type           string     `json:"type"`
+	OrderType      string     `json:"order_type"`
+	Side           string     `json:"side"`
+	TimeInForce    string     `json:"time_in_force"`
+	LimitPrice     *string    `json:"limit_price"`
+	StopPrice      *string    `json:"stop_price"`
+	TrailPrice     *string    `json:"trail_price"`
+	TrailPercent   *string    `json:"trail_percent"`
+	HWM            *string    `json:"hwm"`
+	Status         string     `json:"status"`
+	ExtendedHours  bool       `json:"extended_hours"`
+	Legs           []Order    `json:"legs"`
+}
+
+// TradeEvent is a single order update from the trade events stream
+type TradeEvent struct {
+	AccountID   string    `json:"account_id"`
+	Event       string    `json:"event"` // new, fill, partial_fill, canceled, replaced, ...
+	EventID     string    `json:"event_id"`
+	Timestamp   time.Time `json:"timestamp"`
+	Order       Order     `json:"order"`
+	Price       string    `json:"price,omitempty"`
+	Qty         string    `json:"qty,omitempty"`
+	PositionQty string    `json:"position_qty,omitempty"`
+}
+
+// ListOrdersParams filters ListOrders. Zero values are left to the broker's defaults.
+type ListOrdersParams struct {
+	Status    string // open, closed or all
+	Limit     int
+	After     time.Time
+	Until     time.Time
+	Direction string // asc or desc
+	Nested    bool
+	Symbols   []string
+	Side      string
+}
+
+// CancelStatus is the per-order result of cancelling all orders
+type CancelStatus struct {
+	ID     string          `json:"id"`
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// ClosePositionParams limits how much of a position ClosePosition sells. Set at most
+// one of Qty or Percentage; leaving both empty closes the whole position.
+type ClosePositionParams struct {
+	Qty        string
+	Percentage string
+}
+
+// ClosePositionStatus is the per-symbol result of closing all positions. Body holds the
+// closing order on success and the broker's error otherwise.
+type ClosePositionStatus struct {
+	Symbol string          `json:"symbol"`
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// Position is an open position in a single asset
+type Position struct {
+	AssetID                string `json:"asset_id"`
+	Symbol                 string `json:"symbol"`
+	Exchange               string `json:"exchange"`
+	AssetClass             string `json:"asset_class"`
+	Quantity               string `json:"qty"`
+	QtyAvailable           string `json:"qty_available"`
+	AvgEntryPrice          string `json:"avg_entry_price"`
+	CurrentPrice           string `json:"current_price"`
+	LastdayPrice           string `json:"lastday_price"`
+	ChangeToday            string `json:"change_today"`
+	MarketValue            string `json:"market_value"`
+	CostBasis              string `json:"cost_basis"`
+	UnrealizedPL           string `json:"unrealized_pl"`
+	UnrealizedPLPC         string `json:"unrealized_plpc"`
+	UnrealizedIntradayPL   string `json:"unrealized_intraday_pl"`
+	UnrealizedIntradayPLPC string `json:"unrealized_intraday_plpc"`
+	Side                   string `json:"side"`
+}
+
+// Account is the trading account summary
+type Account struct {
+	ID                       string `json:"id"`
+	AccountNumber            string `json:"account_number"`
+	Status                   string `json:"status"`
+	Currency                 string `json:"currency"`
+	Cash                     string `json:"cash"`
+	BuyingPower              string `json:"buying_power"`
+	Equity                   string `json:"equity"`
+	LastEquity               string `json:"last_equity"`
+	PortfolioValue           string `json:"portfolio_value"`
+	LongMarketValue          string `json:"long_market_value"`
+	ShortMarketValue         string `json:"short_market_value"`
+	DaytradeCount            int    `json:"daytrade_count"`
+	PatternDayTrader         bool   `json:"pattern_day_trader"`
+	TradingBlocked           bool   `json:"trading_blocked"`
+	TransfersBlocked         bool   `json:"transfers_blocked"`
+	AccountBlocked           bool   `json:"account_blocked"`
+	ShortingEnabled          bool   `json:"shorting_enabled"`
+	Multiplier               string `json:"multiplier"`
+	InitialMargin            string `json:"initial_margin"`
+	MaintenanceMargin        string `json:"maintenance_margin"`
+	NonMarginableBuyingPower string `json:"non_marginable_buying_power"`
+	RegTBuyingPower          string `json:"regt_buying_power"`
+	DaytradingBuyingPower    string `json:"daytrading_buying_power"`
+}
+
+// PortfolioHistoryParams selects the equity history window
+type PortfolioHistoryParams struct {
+	Period            string // e.g. 1D, 1W, 1M, 1A
+	Timeframe         string // 1Min, 5Min, 15Min, 1H, 1D
+	IntradayReporting string // market_hours, extended_hours, continuous
+	PnlReset          string // per_day or no_reset
+	CashflowTypes     string // e.g. NONE, ALL or a comma list of activity types
+	Start             time.Time
+	End               time.Time
+}
+
+// PortfolioHistory is the account equity time series
+type PortfolioHistory struct {
+	Timestamp     []int64              `json:"timestamp"`
+	Equity        []float64            `json:"equity"`
+	ProfitLoss    []float64            `json:"profit_loss"`
+	ProfitLossPct []float64            `json:"profit_loss_pct"`
+	BaseValue     float64              `json:"base_value"`
+	Timeframe     string               `json:"timeframe"`
+	Cashflow      map[string][]float64 `json:"cashflow,omitempty"`
+}
+
+// Account activity types
+const (
+	ActivityFill     = "FILL"
+	ActivityDividend = "DIV"
+	// ActivityDeposit and ActivityWithdrawal are cash transfers in and out
+	ActivityDeposit    = "CSD"
+	ActivityWithdrawal = "CSW"
+)
+
+// ListActivitiesParams filters ListActivities. Zero values are left to the broker's
+// defaults; PageToken is the ID of the last activity of the previous page.
+type ListActivitiesParams struct {
+	ActivityTypes []string
+	After         time.Time
+	Until         time.Time
+	Direction     string // asc or desc
+	PageSize      int
+	PageToken     string
+}
+
+// Activity is an entry in the account's activity log: a fill, or a non-trade
+// activity such as a dividend or a cash transfer. Fills carry TransactionTime, Price
+// and Side; non-trade activities carry Date and NetAmount.
+type Activity struct {
+	ID              string     `json:"id"`
+	AccountID       string     `json:"account_id"`
+	ActivityType    string     `json:"activity_type"`
+	Date            string     `json:"date,omitempty"`
+	TransactionTime *time.Time `json:"transaction_time,omitempty"`
+	Symbol          string     `json:"symbol,omitempty"`
+	OrderID         string     `json:"order_id,omitempty"`
+	Qty             string     `json:"qty,omitempty"`
+	Price           string     `json:"price,omitempty"`
+	Side            string     `json:"side,omitempty"`
+	NetAmount       string     `json:"net_amount,omitempty"`
+	PerShareAmount  string     `json:"per_share_amount,omitempty"`
+	Description     string     `json:"description,omitempty"`
+	Status          string     `json:"status,omitempty"`
+}
+
+// TransferRequest is the body of a new funding transfer
+type TransferRequest struct {
+	TransferType   string `json:"transfer_type"`
+	RelationshipID string `json:"relationship_id,omitempty"`
+	Amount         string `json:"amount"`
+	Direction      string `json:"direction"`
+	Timing         string `json:"timing,omitempty"`
+}
+
+// Transfer is a funding transfer in or out of an account
+type Transfer struct {
+	ID             string    `json:"id"`
+	RelationshipID string    `json:"relationship_id"`
+	AccountID      string    `json:"account_id"`
+	Type           string    `json:"type"`
+	Status         string    `json:"status"`
+	Reason         *string   `json:"reason"`
+	Amount         string    `json:"amount"`
+	Direction      string    `json:"direction"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ACHRelationshipRequest links a bank account to a brokerage account
+type ACHRelationshipRequest struct {
+	AccountOwnerName  string `json:"account_owner_name"`
+	BankAccountType   string `json:"bank_account_type"`
+	BankAccountNumber string `json:"bank_account_number"`
+	BankRoutingNumber string `json:"bank_routing_number"`
+	Nickname          string `json:"nickname,omitempty"`
+}
+
+// ACHRelationship is a linked bank account
+type ACHRelationship struct {
+	ID                string    `json:"id"`
+	AccountID         string    `json:"account_id"`
+	Status            string    `json:"status"`
+	AccountOwnerName  string    `json:"account_owner_name"`
+	BankAccountType   string    `json:"bank_account_type"`
+	BankAccountNumber string    `json:"bank_account_number"`
+	BankRoutingNumber string    `json:"bank_routing_number"`
+	Nickname          string    `json:"nickname"`
+	CreatedAt         time.Time `json:"created_at"`
+}
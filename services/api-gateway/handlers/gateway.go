@@ -4,6 +4,9 @@ package handlers
 import (
 	"io"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -46,46 +49,62 @@ func ForwardToAuthService(c *gin.Context) {
 	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), body)
 }
 
-func ForwardToMarketDataService(c *gin.Context) {
-	path := c.Param("path")
-	targetUrl := "http://market-data:8082" + path
-	if c.Request.URL.RawQuery != "" {
-		targetUrl += "?" + c.Request.URL.RawQuery
-	}
+// marketDataURL is where market-data requests are proxied, replaced in tests
+var marketDataURL = &url.URL{Scheme: "http", Host: "market-data:8082"}
+
+// marketDataProxy passes market-data responses through unbuffered. Streams never end
+// and exports can be large, so neither can be read fully and replayed; proxying also
+// keeps the export's Content-Disposition header and its X-Next-Page-Token and
+// X-Export-Error trailers. The standard library proxy handles the WebSocket upgrade itself.
+var marketDataProxy = &httputil.ReverseProxy{
+	Rewrite: func(r *httputil.ProxyRequest) {
+		r.SetURL(marketDataURL)
+	},
+	FlushInterval: -1,
+	ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, `{"error":"Failed to forward request"}`)
+	},
+}
 
-	// Use the same method as original request
-	req, err := http.NewRequest(c.Request.Method, targetUrl, c.Request.Body)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request"})
-		return
-	}
+func ForwardToMarketDataService(c *gin.Context) {
+	c.Request.URL.Path = c.Param("path")
+	c.Request.URL.RawPath = ""
+	marketDataProxy.ServeHTTP(c.Writer, c.Request)
+}
 
-	// Copy headers from original request
-	req.Header = c.Request.Header
+// Upstreams of the versioned services, replaced in tests
+var (
+	tradingEngineURL = "http://trading-engine:8083"
+	portfolioURL     = "http://portfolio:8084"
+)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to forward request"})
-		return
+// tradingRoutes and portfolioRoutes are the first path segments of each service's /v1
+// routes. Any other single segment is a legacy bare order ID or symbol, which only the
+// deprecated unversioned routes accepted.
+var (
+	tradingRoutes   = map[string]bool{"orders": true}
+	portfolioRoutes = map[string]bool{
+		"positions": true, "value": true, "performance": true, "dividends": true,
+		"analytics": true, "exposure": true, "tax": true,
 	}
-	defer resp.Body.Close()
+)
 
-	// Read response and send back to client
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read response"})
-		return
+// versionedPath maps a gateway path onto the service's /v1 routes, rewriting a legacy
+// bare /<item> to /v1/<collection>/<item>
+func versionedPath(path string, routes map[string]bool, collection string) string {
+	segment := strings.TrimPrefix(path, "/")
+	if segment != "" && !strings.Contains(segment, "/") && !routes[segment] {
+		return "/v1/" + collection + "/" + segment
 	}
-
-	// Forward the response back to client
-	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	return "/v1" + path
 }
 
 func ForwardToTradingService(c *gin.Context) {
 	path := c.Param("path")
 	accountID, _ := c.Get("account_id")
-	targetUrl := "http://trading-engine:8083" + path
+	targetUrl := tradingEngineURL + versionedPath(path, tradingRoutes, "orders")
 	if c.Request.URL.RawQuery != "" {
 		targetUrl += "?" + c.Request.URL.RawQuery
 	}
@@ -118,7 +137,7 @@ func ForwardToTradingService(c *gin.Context) {
 func ForwardToPortfolioService(c *gin.Context) {
 	path := c.Param("path")
 	accountID, _ := c.Get("account_id")
-	targetUrl := "http://portfolio:8084" + path
+	targetUrl := portfolioURL + versionedPath(path, portfolioRoutes, "positions")
 	if c.Request.URL.RawQuery != "" {
 		targetUrl += "?" + c.Request.URL.RawQuery
 	}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// forwardTo serves target through the gateway route and returns the path and account
+// header the upstream saw
+func forwardTo(t *testing.T, upstream *string, route string, handler gin.HandlerFunc, method, target string) (string, string) {
+	t.Helper()
+	var path, account string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, account = r.URL.RequestURI(), r.Header.Get("X-Account-ID")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	previous := *upstream
+	*upstream = server.URL
+	defer func() { *upstream = previous }()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Any(route, func(c *gin.Context) { c.Set("account_id", "acct-1") }, handler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s %s: expected 200, got %d", method, target, w.Code)
+	}
+	return path, account
+}
+
+func TestForwardToTradingServiceVersionsPaths(t *testing.T) {
+	cases := []struct {
+		method, target, want string
+	}{
+		{http.MethodGet, "/api/v1/trading/orders?status=open", "/v1/orders?status=open"},
+		{http.MethodGet, "/api/v1/trading/orders/abc-123", "/v1/orders/abc-123"},
+		{http.MethodGet, "/api/v1/trading/abc-123", "/v1/orders/abc-123"},
+		{http.MethodDelete, "/api/v1/trading/abc-123", "/v1/orders/abc-123"},
+	}
+	for _, tc := range cases {
+		path, account := forwardTo(t, &tradingEngineURL, "/api/v1/trading/*path", ForwardToTradingService, tc.method, tc.target)
+		if path != tc.want {
+			t.Errorf("%s %s: forwarded to %q, want %q", tc.method, tc.target, path, tc.want)
+		}
+		if account != "acct-1" {
+			t.Errorf("%s %s: expected X-Account-ID acct-1, got %q", tc.method, tc.target, account)
+		}
+	}
+}
+
+func TestForwardToPortfolioServiceVersionsPaths(t *testing.T) {
+	cases := []struct {
+		target, want string
+	}{
+		{"/api/v1/portfolio/positions", "/v1/positions"},
+		{"/api/v1/portfolio/value", "/v1/value"},
+		{"/api/v1/portfolio/performance/all", "/v1/performance/all"},
+		{"/api/v1/portfolio/tax/lots", "/v1/tax/lots"},
+		{"/api/v1/portfolio/AAPL", "/v1/positions/AAPL"},
+	}
+	for _, tc := range cases {
+		path, _ := forwardTo(t, &portfolioURL, "/api/v1/portfolio/*path", ForwardToPortfolioService, http.MethodGet, tc.target)
+		if path != tc.want {
+			t.Errorf("GET %s: forwarded to %q, want %q", tc.target, path, tc.want)
+		}
+	}
+}
+
+func TestForwardToMarketDataServiceStreamsExports(t *testing.T) {
+	var path string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.RequestURI()
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="AAPL-trades.csv"`)
+		w.Header().Set("Trailer", "X-Next-Page-Token, X-Export-Error")
+		io.WriteString(w, "t,p,s\n")
+		w.(http.Flusher).Flush()
+		io.WriteString(w, "2024-01-02T14:30:00Z,185.5,100\n")
+		w.Header().Set("X-Export-Error", "upstream closed the page")
+	}))
+	defer upstream.Close()
+	previous := marketDataURL
+	marketDataURL, _ = url.Parse(upstream.URL)
+	defer func() { marketDataURL = previous }()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/v1/market/*path", ForwardToMarketDataService)
+	gateway := httptest.NewServer(r)
+	defer gateway.Close()
+
+	resp, err := http.Get(gateway.URL + "/api/v1/market/trades/AAPL?format=csv&limit=2")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if path != "/trades/AAPL?format=csv&limit=2" {
+		t.Errorf("forwarded to %q", path)
+	}
+	if resp.Header.Get("Content-Disposition") != `attachment; filename="AAPL-trades.csv"` {
+		t.Errorf("expected the Content-Disposition header, got %v", resp.Header)
+	}
+	if string(body) != "t,p,s\n2024-01-02T14:30:00Z,185.5,100\n" {
+		t.Errorf("unexpected body %q", body)
+	}
+	if got := resp.Trailer.Get("X-Export-Error"); got != "upstream closed the page" {
+		t.Errorf("expected the X-Export-Error trailer, got %v", resp.Trailer)
+	}
+}
@@ -33,12 +33,18 @@ type SSEClient struct {
 func NewSSEClient() *SSEClient {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// ALPACA_BROKER_URL can point at the local paper-exchange instead of the sandbox
+	baseURL := strings.TrimRight(os.Getenv("ALPACA_BROKER_URL"), "/")
+	if baseURL == "" {
+		baseURL = "https://broker-api.sandbox.alpaca.markets"
+	}
+
 	return &SSEClient{
 		apiKey:       os.Getenv("ALPACA_API_KEY"),
 		secretKey:    os.Getenv("ALPACA_SECRET_KEY"),
-		baseURL:      "https://broker-api.sandbox.alpaca.markets", // Use sandbox for testing
-		client:       &http.Client{Timeout: 0},                    // No timeout for SSE connections
-		EventChannel: make(chan AlpacaTradeEvent, 100),            // Buffered channel
+		baseURL:      baseURL,
+		client:       &http.Client{Timeout: 0},         // No timeout for SSE connections
+		EventChannel: make(chan AlpacaTradeEvent, 100), // Buffered channel
 		ErrorChannel: make(chan error, 10),
 		ctx:          ctx,
 		cancel:       cancel,
@@ -48,7 +54,7 @@ func NewSSEClient() *SSEClient {
 // Connect establishes connection to Alpaca trade events SSE endpoint
 func (s *SSEClient) Connect(accountID string) error {
 	// Build the SSE URL for trade events
-	url := "https://broker-api.sandbox.alpaca.markets/v2/events/trades"
+	url := s.baseURL + "/v2/events/trades"
 
 	log.Printf("🔌 Connecting to Alpaca Trade Events SSE: %s", url)
 
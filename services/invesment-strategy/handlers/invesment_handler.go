@@ -3,11 +3,16 @@ package handlers
 // services/invesment-strategy/handlers/invesment_handler.go
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
 	"math"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/investment-strategy/internal/marketdata"
 	"github.com/seunghoon34/trading-app/services/investment-strategy/internal/mongo"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -34,6 +39,31 @@ type PortfolioRequest struct {
 	Positions []Position `json:"positions" binding:"required,dive"`
 }
 
+// validateSymbols upper-cases each position's symbol and checks it against the asset
+// master. It returns the status and error to respond with when a symbol is rejected.
+// Like the trading service's pre-trade checks it fails open: when the asset master
+// cannot be reached the symbol is accepted with a warning, and the order path checks
+// it again before anything is bought.
+func validateSymbols(positions []Position) (int, error) {
+	for i := range positions {
+		symbol := strings.ToUpper(strings.TrimSpace(positions[i].Symbol))
+		positions[i].Symbol = symbol
+
+		asset, err := marketdata.GetAsset(symbol)
+		if errors.Is(err, marketdata.ErrNotFound) {
+			return http.StatusBadRequest, fmt.Errorf("unknown symbol %s", symbol)
+		}
+		if err != nil {
+			log.Printf("Warning: could not validate symbol %s, accepting it: %v", symbol, err)
+			continue
+		}
+		if !asset.Tradable || asset.Status != "active" {
+			return http.StatusBadRequest, fmt.Errorf("%s is not tradable", symbol)
+		}
+	}
+	return http.StatusOK, nil
+}
+
 // createPortfolio creates a new portfolio (prevents duplicates)
 func CreatePortfolio(c *gin.Context) {
 	// Get account_id from header
@@ -60,6 +90,11 @@ func CreatePortfolio(c *gin.Context) {
 		return
 	}
 
+	if status, err := validateSymbols(req.Positions); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -127,6 +162,11 @@ func UpdatePortfolio(c *gin.Context) {
 		return
 	}
 
+	if status, err := validateSymbols(req.Positions); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
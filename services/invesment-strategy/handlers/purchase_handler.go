@@ -3,7 +3,6 @@ package handlers
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -15,30 +14,33 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
+	"github.com/seunghoon34/trading-app/services/investment-strategy/internal/marketdata"
 	"github.com/seunghoon34/trading-app/services/investment-strategy/internal/mongo"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	mongodriver "go.mongodb.org/mongo-driver/mongo"
 )
 
-// AccountDetails represents the account information from Alpaca
-type AccountDetails struct {
-	BuyingPower string `json:"buying_power"`
-	AccountID   string `json:"id"`
-	Status      string `json:"status"`
-}
-
 // OrderRequest represents the order request to trading service
 type OrderRequest struct {
-	AccountID string `json:"account_id"`
+	AccountID string `json:"-"`
 	Side      string `json:"side"`
 	Symbol    string `json:"symbol"`
-	Notional  string `json:"notional"`
+	Notional  string `json:"notional,omitempty"`
+	Qty       string `json:"qty,omitempty"`
+	// TimeInForce is "opg" for orders queued for the opening auction, otherwise the default day
+	TimeInForce       string `json:"time_in_force,omitempty"`
+	MarketHoursPolicy string `json:"market_hours_policy,omitempty"`
+	// IdempotencyKey lets the trading service replay the first response if this order is retried
+	IdempotencyKey string `json:"-"`
 }
 
 // OrderResult represents the result of an individual order
 type OrderResult struct {
 	Symbol   string `json:"symbol"`
 	Notional string `json:"notional"`
+	Qty      string `json:"qty,omitempty"`
 	Success  bool   `json:"success"`
 	Error    string `json:"error,omitempty"`
 	OrderID  string `json:"order_id,omitempty"`
@@ -50,25 +52,8 @@ type PurchaseResult struct {
 	OrderResults     []OrderResult `json:"order_results"`
 	SuccessCount     int           `json:"success_count"`
 	FailureCount     int           `json:"failure_count"`
-}
-
-func makeAlpacaRequest(method, url string, payload io.Reader) (*http.Response, error) {
-	req, err := http.NewRequest(method, url, payload)
-	if err != nil {
-		return nil, err
-	}
-
-	// Add authentication headers
-	auth := os.Getenv("ALPACA_API_KEY") + ":" + os.Getenv("ALPACA_SECRET_KEY")
-	basicAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte(auth))
-	req.Header.Add("Authorization", basicAuth)
-	req.Header.Add("Accept", "application/json")
-
-	if method == "POST" {
-		req.Header.Add("Content-Type", "application/json")
-	}
-
-	return http.DefaultClient.Do(req)
+	// QueuedForOpen is set when the market was closed and the orders wait for the next open
+	QueuedForOpen bool `json:"queued_for_open,omitempty"`
 }
 
 func callTradingService(orderReq OrderRequest) (map[string]interface{}, error) {
@@ -78,26 +63,23 @@ func callTradingService(orderReq OrderRequest) (map[string]interface{}, error) {
 		tradingServiceURL = "http://trading-engine:8083" // Default for local development
 	}
 
-	// Remove AccountID from the request body as it should be in header
-	orderReqBody := map[string]string{
-		"side":     orderReq.Side,
-		"symbol":   orderReq.Symbol,
-		"notional": orderReq.Notional,
-	}
-
-	jsonData, err := json.Marshal(orderReqBody)
+	// AccountID is not part of the body; it goes in the header
+	jsonData, err := json.Marshal(orderReq)
 	if err != nil {
 		return nil, err
 	}
 
 	// Create request with proper headers
-	req, err := http.NewRequest("POST", tradingServiceURL+"/orders", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest("POST", tradingServiceURL+"/v1/orders", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Account-ID", orderReq.AccountID) // Set account ID in header
+	if orderReq.IdempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", orderReq.IdempotencyKey)
+	}
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -123,6 +105,9 @@ func callTradingService(orderReq OrderRequest) (map[string]interface{}, error) {
 	return result, nil
 }
 
+// PurchasePortfolio spends the account's buying power on the portfolio's weights.
+// While the market is closed, ?market_hours_policy=reject (the default) refuses the
+// purchase and queue sizes whole-share orders to trade at the next open.
 func PurchasePortfolio(c *gin.Context) {
 	// Get account_id from header
 	accountID := c.GetHeader("X-Account-ID")
@@ -131,44 +116,21 @@ func PurchasePortfolio(c *gin.Context) {
 		return
 	}
 
+	policy := strings.ToLower(c.DefaultQuery("market_hours_policy", "reject"))
+	if policy != "reject" && policy != "queue" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "market_hours_policy must be 'reject' or 'queue'"})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	// Step 1: Get buying power from Alpaca
-	accountURL := fmt.Sprintf("https://broker-api.sandbox.alpaca.markets/v1/trading/accounts/%s/account", accountID)
-	res, err := makeAlpacaRequest("GET", accountURL, nil)
+	accountDetails, err := brokerClient.GetAccount(ctx, accountID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account details"})
-		return
-	}
-	defer res.Body.Close()
-
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read account response"})
-		return
-	}
-
-	// Debug: Log the raw response
-	fmt.Printf("Raw Alpaca response status: %d\n", res.StatusCode)
-	fmt.Printf("Raw Alpaca response body: %s\n", string(body))
-
-	// Check if the API call was successful
-	if res.StatusCode != http.StatusOK {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":       "Failed to fetch account details from Alpaca",
-			"status_code": res.StatusCode,
-			"response":    string(body),
-		})
-		return
-	}
-
-	var accountDetails AccountDetails
-	if err := json.Unmarshal(body, &accountDetails); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":        "Failed to parse account details",
-			"raw_response": string(body),
-			"parse_error":  err.Error(),
+			"error":   "Failed to fetch account details",
+			"details": err.Error(),
 		})
 		return
 	}
@@ -212,7 +174,63 @@ func PurchasePortfolio(c *gin.Context) {
 		return
 	}
 
-	// Step 3: Calculate dollar amounts and execute orders
+	// Retrying a purchase with the same Idempotency-Key must not buy twice, so each
+	// order gets a key derived from it. Without one, every purchase is a new request.
+	purchaseKey := c.GetHeader("Idempotency-Key")
+	if purchaseKey == "" {
+		purchaseKey = primitive.NewObjectID().Hex()
+	}
+
+	// Step 3: Check the market is open. When it is closed, either stop here or size
+	// whole-share orders for the opening auction, which does not take notional orders.
+	queue := false
+	clock, err := marketdata.MarketClock()
+	if err != nil {
+		// The trading service checks again for each order
+		fmt.Printf("Market clock unavailable: %v\n", err)
+	} else if !clock.IsOpen {
+		if policy == "reject" {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":       "Market is closed; retry with market_hours_policy=queue to buy at the open",
+				"reason_code": "MARKET_CLOSED",
+				"next_open":   clock.NextOpen,
+			})
+			return
+		}
+		// The broker only takes orders for the open from 19:00 ET
+		at := clock.Timestamp
+		if at.IsZero() {
+			at = time.Now()
+		}
+		if accepted, from := broker.AcceptsOpeningAuctionOrders(at); !accepted {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":       fmt.Sprintf("Market is closed and orders can be queued for the open from %s", from.Format(time.RFC3339)),
+				"reason_code": "MARKET_CLOSED",
+				"next_open":   clock.NextOpen,
+				"queue_from":  from,
+			})
+			return
+		}
+		queue = true
+	}
+
+	var prices map[string]float64
+	if queue {
+		symbols := make([]string, 0, len(portfolio.Positions))
+		for _, position := range portfolio.Positions {
+			symbols = append(symbols, strings.ToUpper(position.Symbol))
+		}
+		prices, err = marketdata.AskPrices(symbols)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error":   "Failed to fetch prices to size orders for the open",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	// Step 4: Calculate dollar amounts and execute orders
 	var orderResults []OrderResult
 	successCount := 0
 	failureCount := 0
@@ -234,10 +252,34 @@ func PurchasePortfolio(c *gin.Context) {
 
 		// Prepare order request
 		orderReq := OrderRequest{
-			AccountID: accountID,
-			Side:      "buy",
-			Symbol:    strings.ToUpper(position.Symbol),
-			Notional:  fmt.Sprintf("%.0f", dollarAmount), // No decimal places
+			AccountID:         accountID,
+			Side:              "buy",
+			Symbol:            strings.ToUpper(position.Symbol),
+			Notional:          fmt.Sprintf("%.0f", dollarAmount), // No decimal places
+			MarketHoursPolicy: policy,
+		}
+		orderReq.IdempotencyKey = fmt.Sprintf("%s-%s", purchaseKey, orderReq.Symbol)
+		allocation := orderReq.Notional
+
+		if queue {
+			price := prices[orderReq.Symbol]
+			shares := 0.0
+			if price > 0 {
+				shares = math.Floor(dollarAmount / price)
+			}
+			if shares < 1 {
+				orderResults = append(orderResults, OrderResult{
+					Symbol:   position.Symbol,
+					Notional: allocation,
+					Success:  false,
+					Error:    "Amount does not buy a whole share, which orders queued for the open require",
+				})
+				failureCount++
+				continue
+			}
+			orderReq.Notional = ""
+			orderReq.Qty = fmt.Sprintf("%.0f", shares)
+			orderReq.TimeInForce = "opg"
 		}
 
 		// Call trading service
@@ -245,7 +287,7 @@ func PurchasePortfolio(c *gin.Context) {
 		if err != nil {
 			orderResults = append(orderResults, OrderResult{
 				Symbol:   position.Symbol,
-				Notional: orderReq.Notional,
+				Notional: allocation,
 				Success:  false,
 				Error:    err.Error(),
 			})
@@ -263,7 +305,8 @@ func PurchasePortfolio(c *gin.Context) {
 
 		orderResults = append(orderResults, OrderResult{
 			Symbol:   position.Symbol,
-			Notional: orderReq.Notional,
+			Notional: allocation,
+			Qty:      orderReq.Qty,
 			Success:  true,
 			OrderID:  orderID,
 		})
@@ -276,6 +319,7 @@ func PurchasePortfolio(c *gin.Context) {
 		OrderResults:     orderResults,
 		SuccessCount:     successCount,
 		FailureCount:     failureCount,
+		QueuedForOpen:    queue,
 	}
 
 	// Return appropriate status code
@@ -0,0 +1,110 @@
+package marketdata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Clock reports whether the regular session is open and when it next opens and closes
+type Clock struct {
+	Timestamp time.Time `json:"timestamp"`
+	IsOpen    bool      `json:"is_open"`
+	NextOpen  time.Time `json:"next_open"`
+	NextClose time.Time `json:"next_close"`
+}
+
+// Asset is a symbol's entry in the asset master
+type Asset struct {
+	Symbol   string `json:"symbol"`
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Tradable bool   `json:"tradable"`
+}
+
+type quote struct {
+	AskPrice float64 `json:"ap"`
+	BidPrice float64 `json:"bp"`
+}
+
+// ErrNotFound is returned when market-data answers 404, e.g. for an unknown symbol
+var ErrNotFound = errors.New("not found")
+
+var httpClient = &http.Client{Timeout: 5 * time.Second}
+
+func baseURL() string {
+	if u := os.Getenv("MARKET_DATA_SERVICE_URL"); u != "" {
+		return u
+	}
+	return "http://market-data:8082" // Default for local development
+}
+
+// get fetches a market-data endpoint and decodes the JSON body into out
+func get(path string, out interface{}) error {
+	res, err := httpClient.Get(baseURL() + path)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrNotFound, string(body))
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("market-data returned status %d: %s", res.StatusCode, string(body))
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+// MarketClock fetches the market clock
+func MarketClock() (*Clock, error) {
+	var clock Clock
+	if err := get("/clock", &clock); err != nil {
+		return nil, err
+	}
+	return &clock, nil
+}
+
+// AskPrices returns the latest ask per symbol, falling back to the bid when there is
+// no ask. Symbols without a quote are left out.
+func AskPrices(symbols []string) (map[string]float64, error) {
+	var res struct {
+		Quotes map[string]quote `json:"quotes"`
+	}
+	if err := get("/quotes?symbols="+url.QueryEscape(strings.Join(symbols, ",")), &res); err != nil {
+		return nil, err
+	}
+
+	prices := make(map[string]float64, len(res.Quotes))
+	for symbol, q := range res.Quotes {
+		price := q.AskPrice
+		if price == 0 {
+			price = q.BidPrice
+		}
+		if price > 0 {
+			prices[symbol] = price
+		}
+	}
+	return prices, nil
+}
+
+// GetAsset looks up a symbol in the asset master; unknown symbols return ErrNotFound
+func GetAsset(symbol string) (*Asset, error) {
+	var asset Asset
+	if err := get("/assets/"+url.PathEscape(symbol), &asset); err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
@@ -5,33 +5,76 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
+	"time"
 )
 
-func MakeMarketDataRequest(endpoint string) ([]byte, error) {
-	// Build full URL
-	baseURL := os.Getenv("ALPACA_MARKET_DATA_URL")
-	url := fmt.Sprintf("%s%s", baseURL, endpoint)
-	fmt.Printf("Making request to Alpaca Market Data API: %s", url)
-	// Create request
-	req, err := http.NewRequest("GET", url, nil)
+// HTTPClient makes the Alpaca REST calls. Its timeout keeps a stalled upstream from
+// hanging the handler and surfaces as a timeout error instead; replaced in tests.
+var HTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// UpstreamError is returned when Alpaca answers with a non-2xx status
+type UpstreamError struct {
+	StatusCode int
+	Body       []byte
+	// RetryAfter is Alpaca's Retry-After header, set when it rate limits us
+	RetryAfter string
+}
+
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("alpaca returned status %d: %s", e.StatusCode, string(e.Body))
+}
+
+// GetMarketData performs a GET against the market data API with the given query and
+// returns the body, or an *UpstreamError when the status is not 2xx
+func GetMarketData(endpoint string, query url.Values) ([]byte, error) {
+	return get(os.Getenv("ALPACA_MARKET_DATA_URL"), endpoint, query)
+}
+
+// GetBrokerData performs a GET against the broker API, which serves the market
+// calendar, clock and asset list, with the same error handling as GetMarketData.
+// MARKET_DATA_BROKER_URL takes precedence over ALPACA_BROKER_URL, so the other
+// services can trade against the paper exchange, which serves none of these, while
+// market-data keeps reading them from Alpaca.
+func GetBrokerData(endpoint string, query url.Values) ([]byte, error) {
+	baseURL := os.Getenv("MARKET_DATA_BROKER_URL")
+	if baseURL == "" {
+		baseURL = os.Getenv("ALPACA_BROKER_URL")
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+	if baseURL == "" {
+		baseURL = "https://broker-api.sandbox.alpaca.markets"
+	}
+	return get(baseURL, endpoint, query)
+}
+
+func get(baseURL, endpoint string, query url.Values) ([]byte, error) {
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(http.MethodGet, baseURL+endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	// Add Basic Auth (same as your working curl command)
 	auth := os.Getenv("ALPACA_API_KEY") + ":" + os.Getenv("ALPACA_SECRET_KEY")
-	basicAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte(auth))
-	req.Header.Add("Authorization", basicAuth)
+	req.Header.Add("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
 	req.Header.Add("Accept", "application/json")
 
-	// Make request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := HTTPClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	return io.ReadAll(resp.Body)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &UpstreamError{StatusCode: resp.StatusCode, Body: body, RetryAfter: resp.Header.Get("Retry-After")}
+	}
+	return body, nil
 }
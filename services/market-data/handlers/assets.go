@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/market-data/config"
+	"github.com/seunghoon34/trading-app/services/market-data/internal/assets"
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+const (
+	defaultAssetSearchLimit = 20
+	maxAssetSearchLimit     = 100
+)
+
+var assetCatalog = assets.NewCatalog()
+
+// InitAssets starts syncing the broker's asset list into the catalog on a schedule
+func InitAssets() {
+	if os.Getenv("ASSET_CLASSIFICATIONS_FILE") == "" {
+		log.Printf("ASSET_CLASSIFICATIONS_FILE is not set; assets will have no sector or industry")
+	}
+	syncer := assets.NewSyncer(assets.SyncConfigFromEnv(), assetCatalog, fetchAssets)
+	go syncer.Run(context.Background())
+}
+
+// fetchAssets downloads every active US equity from the broker and classifies them
+// with ASSET_CLASSIFICATIONS_FILE, which is re-read on every sync. A classifications
+// file that fails to load leaves the assets unclassified rather than failing the sync.
+func fetchAssets() ([]models.Asset, error) {
+	data, err := config.GetBrokerData("/v1/assets", url.Values{"status": {"active"}, "asset_class": {"us_equity"}})
+	if err != nil {
+		return nil, err
+	}
+	var list []models.Asset
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("invalid assets response: %w", err)
+	}
+	if path := os.Getenv("ASSET_CLASSIFICATIONS_FILE"); path != "" {
+		classifications, err := assets.LoadClassifications(path)
+		if err != nil {
+			log.Printf("loading asset classifications from %s: %v", path, err)
+		} else {
+			assets.Classify(list, classifications)
+		}
+	}
+	return list, nil
+}
+
+// catalogReady responds with 503 until the first sync has loaded the asset list
+func catalogReady(c *gin.Context) bool {
+	if assetCatalog.Len() == 0 {
+		respondError(c, http.StatusServiceUnavailable, CodeUnavailable, "Asset list has not been loaded yet")
+		return false
+	}
+	return true
+}
+
+// GetAsset returns a symbol's entry in the asset master, or 404 if the broker does not list it
+func GetAsset(c *gin.Context) {
+	if !catalogReady(c) {
+		return
+	}
+	symbol, err := parseSymbol(c.Param("symbol"))
+	if err != nil {
+		respondInvalid(c, err)
+		return
+	}
+	asset, ok := assetCatalog.Get(symbol)
+	if !ok {
+		respondError(c, http.StatusNotFound, CodeNotFound, fmt.Sprintf("Unknown symbol %s", symbol))
+		return
+	}
+	c.JSON(http.StatusOK, asset)
+}
+
+// GetAssets returns the asset master entries of ?symbols, listing the symbols the
+// broker does not know under unknown
+func GetAssets(c *gin.Context) {
+	symbols, err := parseSymbolList(c.QueryArray("symbols")...)
+	if err != nil {
+		respondInvalid(c, err)
+		return
+	}
+	if !catalogReady(c) {
+		return
+	}
+
+	found := []models.Asset{}
+	unknown := []string{}
+	for _, symbol := range symbols {
+		if asset, ok := assetCatalog.Get(symbol); ok {
+			found = append(found, asset)
+		} else {
+			unknown = append(unknown, symbol)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"assets": found, "unknown": unknown})
+}
+
+// SearchAssets finds assets whose symbol or name matches ?q, best matches first
+func SearchAssets(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "q is required")
+		return
+	}
+	limit := defaultAssetSearchLimit
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxAssetSearchLimit {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("limit must be between 1 and %d", maxAssetSearchLimit))
+			return
+		}
+		limit = n
+	}
+	if !catalogReady(c) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"assets": assetCatalog.Search(query, limit)})
+}
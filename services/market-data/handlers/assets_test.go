@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/market-data/internal/assets"
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+func TestAssetEndpoints(t *testing.T) {
+	previous := assetCatalog
+	assetCatalog = assets.NewCatalog()
+	t.Cleanup(func() { assetCatalog = previous })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/assets", GetAssets)
+	r.GET("/assets/search", SearchAssets)
+	r.GET("/assets/:symbol", GetAsset)
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	if w := get("/assets/AAPL"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 before the first sync, got %d", w.Code)
+	}
+
+	assetCatalog.Replace([]models.Asset{
+		{Symbol: "AAPL", Name: "Apple Inc.", Status: "active", Tradable: true, Fractionable: true},
+		{Symbol: "AAP", Name: "Advance Auto Parts Inc.", Status: "active", Tradable: true},
+	})
+
+	w := get("/assets/aapl")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var asset models.Asset
+	json.Unmarshal(w.Body.Bytes(), &asset)
+	if asset.Symbol != "AAPL" || !asset.Fractionable {
+		t.Errorf("unexpected asset %s", w.Body)
+	}
+
+	if w := get("/assets/NOPE"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown symbol, got %d", w.Code)
+	}
+
+	w = get("/assets/search?q=apple")
+	var body struct {
+		Assets []models.Asset `json:"assets"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusOK || len(body.Assets) != 1 || body.Assets[0].Symbol != "AAPL" {
+		t.Errorf("unexpected search result %d: %s", w.Code, w.Body)
+	}
+
+	w = get("/assets?symbols=aapl,NOPE")
+	var batch struct {
+		Assets  []models.Asset `json:"assets"`
+		Unknown []string       `json:"unknown"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &batch)
+	if w.Code != http.StatusOK || len(batch.Assets) != 1 || batch.Assets[0].Symbol != "AAPL" || len(batch.Unknown) != 1 || batch.Unknown[0] != "NOPE" {
+		t.Errorf("unexpected batch result %d: %s", w.Code, w.Body)
+	}
+
+	for _, target := range []string{"/assets/search", "/assets/search?q=a&limit=0", "/assets", "/assets?symbols=AA$"} {
+		if w := get(target); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, w.Code)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/seunghoon34/trading-app/services/market-data/config"
+	"github.com/seunghoon34/trading-app/services/market-data/internal/cache"
+)
+
+var (
+	cacheConfig = cache.LoadConfig()
+	dataCache   = cache.New(cache.NewMemoryStore(cacheConfig.Size))
+)
+
+// InitCache selects the cache backend and TTLs used by the quote and bar handlers
+func InitCache() {
+	cacheConfig = cache.LoadConfig()
+	dataCache = cache.New(cache.NewStore(cacheConfig))
+}
+
+// latestKind describes one of Alpaca's multi-symbol latest-data endpoints, e.g. quotes or bars
+type latestKind struct {
+	// name is the field holding the data in a single-symbol response ("quote")
+	name string
+	// plural is the response field of the multi-symbol endpoint ("quotes"); empty
+	// when the response is keyed by symbol at the top level, as snapshots are
+	plural string
+	path   string
+	ttl    func() time.Duration
+}
+
+var (
+	latestQuotes = latestKind{name: "quote", plural: "quotes", path: "/v2/stocks/quotes/latest", ttl: func() time.Duration { return cacheConfig.QuoteTTL }}
+	latestBars   = latestKind{name: "bar", plural: "bars", path: "/v2/stocks/bars/latest", ttl: func() time.Duration { return cacheConfig.BarTTL }}
+	snapshots    = latestKind{name: "snapshot", path: "/v2/stocks/snapshots", ttl: func() time.Duration { return cacheConfig.SnapshotTTL }}
+)
+
+// fetchLatest returns the latest data of one kind per symbol. Symbols found in the cache
+// are served from it and only the misses are requested upstream, in one call that
+// concurrent requests for the same misses share.
+func fetchLatest(ctx context.Context, kind latestKind, symbols []string) (map[string]json.RawMessage, error) {
+	results := make(map[string]json.RawMessage, len(symbols))
+	var misses []string
+	for _, symbol := range symbols {
+		if value, found := dataCache.Get(ctx, kind.name+":"+symbol); found {
+			results[symbol] = value
+		} else {
+			misses = append(misses, symbol)
+		}
+	}
+	if len(misses) == 0 {
+		return results, nil
+	}
+
+	sort.Strings(misses)
+	joined := strings.Join(misses, ",")
+	fetched, err := dataCache.Do(kind.path+":"+joined, func() (interface{}, error) {
+		data, err := config.GetMarketData(kind.path, url.Values{"symbols": {joined}})
+		if err != nil {
+			return nil, err
+		}
+		if kind.plural != "" {
+			var res map[string]json.RawMessage
+			if err := json.Unmarshal(data, &res); err != nil {
+				return nil, fmt.Errorf("invalid %s response: %w", kind.path, err)
+			}
+			data = res[kind.plural]
+		}
+		var bySymbol map[string]json.RawMessage
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &bySymbol); err != nil {
+				return nil, fmt.Errorf("invalid %s response: %w", kind.path, err)
+			}
+		}
+		for symbol, value := range bySymbol {
+			dataCache.Set(ctx, kind.name+":"+symbol, value, kind.ttl())
+		}
+		return bySymbol, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for symbol, value := range fetched.(map[string]json.RawMessage) {
+		results[symbol] = value
+	}
+	return results, nil
+}
+
+// cacheKey identifies a bar history query; equal queries share one cache entry
+func (q BarHistoryQuery) cacheKey() string {
+	return fmt.Sprintf("history:%s:%s:%s:%s:%s:%s:%d:%s",
+		strings.Join(q.Symbols, ","), q.Timeframe,
+		q.Start.UTC().Format(time.RFC3339), q.End.UTC().Format(time.RFC3339),
+		q.Adjustment, q.Feed, q.Limit, q.Sort)
+}
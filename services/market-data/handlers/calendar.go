@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	// The service image has no zoneinfo, so embed it for America/New_York
+	_ "time/tzdata"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/market-data/config"
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+const (
+	// defaultCalendarDays is how far ahead /calendar looks when no end is given
+	defaultCalendarDays = 30
+	// clockLookahead covers the longest run of closed days, so /clock always finds the next session
+	clockLookahead = 14
+)
+
+// marketTZ is the exchange's time zone, in which the calendar is published
+var marketTZ, _ = time.LoadLocation("America/New_York")
+
+// now is replaced in tests to pin the clock
+var now = time.Now
+
+// alpacaCalendarDay is one entry of Alpaca's /v1/calendar response; times are
+// exchange-local wall clock, "09:30" for the session and "0400" for extended hours
+type alpacaCalendarDay struct {
+	Date         string `json:"date"`
+	Open         string `json:"open"`
+	Close        string `json:"close"`
+	SessionOpen  string `json:"session_open"`
+	SessionClose string `json:"session_close"`
+}
+
+func (d alpacaCalendarDay) normalize() (models.CalendarDay, error) {
+	date, err := time.ParseInLocation("2006-01-02", d.Date, marketTZ)
+	if err != nil {
+		return models.CalendarDay{}, fmt.Errorf("invalid calendar date %q", d.Date)
+	}
+	at := func(clock string) (time.Time, error) {
+		t, err := time.Parse("1504", strings.ReplaceAll(clock, ":", ""))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid calendar time %q on %s", clock, d.Date)
+		}
+		return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, marketTZ), nil
+	}
+
+	day := models.CalendarDay{Date: d.Date}
+	if day.Open, err = at(d.Open); err != nil {
+		return day, err
+	}
+	if day.Close, err = at(d.Close); err != nil {
+		return day, err
+	}
+	// Extended hours are missing on some feeds; fall back to the regular session
+	day.SessionOpen, day.SessionClose = day.Open, day.Close
+	if d.SessionOpen != "" {
+		if day.SessionOpen, err = at(d.SessionOpen); err != nil {
+			return day, err
+		}
+	}
+	if d.SessionClose != "" {
+		if day.SessionClose, err = at(d.SessionClose); err != nil {
+			return day, err
+		}
+	}
+	return day, nil
+}
+
+// marketDate formats t as a date on the exchange's calendar
+func marketDate(t time.Time) string {
+	return t.In(marketTZ).Format("2006-01-02")
+}
+
+// untilMidnight is how long is left of the exchange's current day
+func untilMidnight(t time.Time) time.Duration {
+	local := t.In(marketTZ)
+	midnight := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, marketTZ)
+	return midnight.Sub(t)
+}
+
+// loadCalendar returns the trading days between start and end inclusive. Responses
+// are cached until the end of the exchange's day, since the calendar rarely changes
+// and every order checks the clock.
+func loadCalendar(ctx context.Context, start, end string) ([]models.CalendarDay, error) {
+	t := now()
+	key := fmt.Sprintf("calendar:%s:%s:%s", marketDate(t), start, end)
+	data, err := dataCache.Fetch(ctx, key, untilMidnight(t), func() ([]byte, error) {
+		data, err := config.GetBrokerData("/v1/calendar", url.Values{"start": {start}, "end": {end}})
+		if err != nil {
+			return nil, err
+		}
+		var raw []alpacaCalendarDay
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("invalid calendar response: %w", err)
+		}
+		days := make([]models.CalendarDay, 0, len(raw))
+		for _, d := range raw {
+			day, err := d.normalize()
+			if err != nil {
+				return nil, err
+			}
+			days = append(days, day)
+		}
+		return json.Marshal(days)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var days []models.CalendarDay
+	if err := json.Unmarshal(data, &days); err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+// clockAt works out the market clock at t from trading days in date order
+func clockAt(t time.Time, days []models.CalendarDay) (models.Clock, error) {
+	for i, day := range days {
+		if !t.Before(day.Close) {
+			continue
+		}
+		clock := models.Clock{Timestamp: t.In(marketTZ), NextOpen: day.Open, NextClose: day.Close}
+		if !t.Before(day.Open) {
+			clock.IsOpen = true
+			if i+1 >= len(days) {
+				return clock, errors.New("calendar does not cover the next session")
+			}
+			clock.NextOpen = days[i+1].Open
+		}
+		return clock, nil
+	}
+	return models.Clock{}, errors.New("calendar does not cover the next session")
+}
+
+// GetCalendar returns the trading days between ?start and ?end (YYYY-MM-DD).
+// start defaults to today and end to 30 days after start.
+func GetCalendar(c *gin.Context) {
+	start, err := parseDate(c.Query("start"), now().In(marketTZ))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "start must be a YYYY-MM-DD date")
+		return
+	}
+	end, err := parseDate(c.Query("end"), start.AddDate(0, 0, defaultCalendarDays))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "end must be a YYYY-MM-DD date")
+		return
+	}
+	if end.Before(start) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "end must not be before start")
+		return
+	}
+
+	days, err := loadCalendar(c.Request.Context(), start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		respondUpstreamError(c, err, "Failed to fetch market calendar")
+		return
+	}
+	c.JSON(http.StatusOK, days)
+}
+
+// GetClock reports whether the market is open now and when it next opens and closes
+func GetClock(c *gin.Context) {
+	t := now()
+	today := t.In(marketTZ)
+	days, err := loadCalendar(c.Request.Context(), today.Format("2006-01-02"), today.AddDate(0, 0, clockLookahead).Format("2006-01-02"))
+	if err != nil {
+		respondUpstreamError(c, err, "Failed to fetch market calendar")
+		return
+	}
+
+	clock, err := clockAt(t, days)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, CodeUpstreamError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, clock)
+}
+
+// parseDate parses a YYYY-MM-DD date on the exchange's calendar; empty means fallback
+func parseDate(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		y, m, d := fallback.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, marketTZ), nil
+	}
+	return time.ParseInLocation("2006-01-02", value, marketTZ)
+}
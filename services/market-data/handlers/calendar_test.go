@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+// Good Friday, 2024-03-29, is a market holiday
+const easterCalendar = `[
+	{"date": "2024-03-28", "open": "09:30", "close": "16:00", "session_open": "0400", "session_close": "2000"},
+	{"date": "2024-04-01", "open": "09:30", "close": "16:00", "session_open": "0400", "session_close": "2000"},
+	{"date": "2024-04-02", "open": "09:30", "close": "13:00", "session_open": "0400", "session_close": "1700"}
+]`
+
+func marketTime(value string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", value, marketTZ)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestClockAt(t *testing.T) {
+	var raw []alpacaCalendarDay
+	json.Unmarshal([]byte(easterCalendar), &raw)
+	var days []models.CalendarDay
+	for _, d := range raw {
+		day, err := d.normalize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		days = append(days, day)
+	}
+
+	tests := []struct {
+		name      string
+		at        string
+		isOpen    bool
+		nextOpen  string
+		nextClose string
+	}{
+		{"before the open", "2024-03-28 08:00", false, "2024-03-28 09:30", "2024-03-28 16:00"},
+		{"during the session", "2024-03-28 10:00", true, "2024-04-01 09:30", "2024-03-28 16:00"},
+		{"at the close", "2024-03-28 16:00", false, "2024-04-01 09:30", "2024-04-01 16:00"},
+		{"on a holiday", "2024-03-29 12:00", false, "2024-04-01 09:30", "2024-04-01 16:00"},
+		{"early close", "2024-04-02 12:59", true, "", "2024-04-02 13:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock, err := clockAt(marketTime(tt.at), days)
+			if tt.nextOpen == "" {
+				// The calendar ends with this session, so the next open is unknown
+				if err == nil {
+					t.Fatal("expected an error when the calendar runs out")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if clock.IsOpen != tt.isOpen || !clock.NextOpen.Equal(marketTime(tt.nextOpen)) || !clock.NextClose.Equal(marketTime(tt.nextClose)) {
+				t.Errorf("unexpected clock %+v", clock)
+			}
+		})
+	}
+}
+
+func TestGetClockCachesCalendarForTheDay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/v1/calendar" || r.URL.Query().Get("start") != "2024-03-29" || r.URL.Query().Get("end") != "2024-04-12" {
+			t.Errorf("unexpected upstream request %s", r.URL)
+		}
+		w.Write([]byte(easterCalendar))
+	}))
+	defer server.Close()
+	t.Setenv("ALPACA_BROKER_URL", "http://paper-exchange.invalid")
+	t.Setenv("MARKET_DATA_BROKER_URL", server.URL)
+	useEmptyCache(t)
+
+	current := marketTime("2024-03-29 12:00")
+	now = func() time.Time { return current }
+	t.Cleanup(func() { now = time.Now })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/clock", GetClock)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/clock", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+		}
+		var clock models.Clock
+		json.Unmarshal(w.Body.Bytes(), &clock)
+		if clock.IsOpen || !clock.NextOpen.Equal(marketTime("2024-04-01 09:30")) {
+			t.Errorf("expected the market closed until Monday, got %s", w.Body)
+		}
+		current = current.Add(time.Hour)
+	}
+	if calls != 1 {
+		t.Errorf("expected one calendar request for the day, got %d", calls)
+	}
+}
+
+func TestGetCalendarValidatesDates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/calendar", GetCalendar)
+
+	for _, target := range []string{"/calendar?start=yesterday", "/calendar?start=2024-04-02&end=2024-04-01"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, w.Code)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/market-data/config"
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+const (
+	// defaultActionsLookbackYears and defaultActionsLookaheadDays bound /corporate-actions
+	// when no range is given, so announced actions are included
+	defaultActionsLookbackYears = 1
+	defaultActionsLookaheadDays = 90
+	// maxActionsPage is the most actions Alpaca returns per page
+	maxActionsPage = 1000
+)
+
+// actionTypes maps our corporate action types to Alpaca's
+var actionTypes = map[string]string{
+	models.ActionSplit:         "forward_split",
+	models.ActionReverseSplit:  "reverse_split",
+	models.ActionCashDividend:  "cash_dividend",
+	models.ActionStockDividend: "stock_dividend",
+	models.ActionSymbolChange:  "name_change",
+}
+
+// alpacaCorporateActions is one page of Alpaca's /v1/corporate-actions response
+type alpacaCorporateActions struct {
+	CorporateActions struct {
+		CashDividends []struct {
+			Symbol      string  `json:"symbol"`
+			Rate        float64 `json:"rate"`
+			Special     bool    `json:"special"`
+			Foreign     bool    `json:"foreign"`
+			ProcessDate string  `json:"process_date"`
+			ExDate      string  `json:"ex_date"`
+			RecordDate  string  `json:"record_date"`
+			PayableDate string  `json:"payable_date"`
+		} `json:"cash_dividends"`
+		StockDividends []struct {
+			Symbol      string  `json:"symbol"`
+			Rate        float64 `json:"rate"`
+			ProcessDate string  `json:"process_date"`
+			ExDate      string  `json:"ex_date"`
+			RecordDate  string  `json:"record_date"`
+			PayableDate string  `json:"payable_date"`
+		} `json:"stock_dividends"`
+		ForwardSplits []alpacaSplit `json:"forward_splits"`
+		ReverseSplits []alpacaSplit `json:"reverse_splits"`
+		NameChanges   []struct {
+			OldSymbol   string `json:"old_symbol"`
+			NewSymbol   string `json:"new_symbol"`
+			ProcessDate string `json:"process_date"`
+		} `json:"name_changes"`
+	} `json:"corporate_actions"`
+	NextPageToken *string `json:"next_page_token"`
+}
+
+type alpacaSplit struct {
+	Symbol      string  `json:"symbol"`
+	OldRate     float64 `json:"old_rate"`
+	NewRate     float64 `json:"new_rate"`
+	ProcessDate string  `json:"process_date"`
+	ExDate      string  `json:"ex_date"`
+	RecordDate  string  `json:"record_date"`
+	PayableDate string  `json:"payable_date"`
+}
+
+func (s alpacaSplit) normalize(actionType string) models.CorporateAction {
+	return models.CorporateAction{
+		Type:        actionType,
+		Symbol:      s.Symbol,
+		ExDate:      s.ExDate,
+		RecordDate:  s.RecordDate,
+		PayableDate: s.PayableDate,
+		ProcessDate: s.ProcessDate,
+		OldRate:     s.OldRate,
+		NewRate:     s.NewRate,
+	}
+}
+
+// normalize flattens the page into one list of actions
+func (p alpacaCorporateActions) normalize() []models.CorporateAction {
+	ca := p.CorporateActions
+	var actions []models.CorporateAction
+	for _, d := range ca.CashDividends {
+		actions = append(actions, models.CorporateAction{
+			Type:        models.ActionCashDividend,
+			Symbol:      d.Symbol,
+			ExDate:      d.ExDate,
+			RecordDate:  d.RecordDate,
+			PayableDate: d.PayableDate,
+			ProcessDate: d.ProcessDate,
+			Rate:        d.Rate,
+			Special:     d.Special,
+			Foreign:     d.Foreign,
+		})
+	}
+	for _, d := range ca.StockDividends {
+		actions = append(actions, models.CorporateAction{
+			Type:        models.ActionStockDividend,
+			Symbol:      d.Symbol,
+			ExDate:      d.ExDate,
+			RecordDate:  d.RecordDate,
+			PayableDate: d.PayableDate,
+			ProcessDate: d.ProcessDate,
+			Rate:        d.Rate,
+		})
+	}
+	for _, s := range ca.ForwardSplits {
+		actions = append(actions, s.normalize(models.ActionSplit))
+	}
+	for _, s := range ca.ReverseSplits {
+		actions = append(actions, s.normalize(models.ActionReverseSplit))
+	}
+	for _, n := range ca.NameChanges {
+		actions = append(actions, models.CorporateAction{
+			Type:        models.ActionSymbolChange,
+			Symbol:      n.OldSymbol,
+			NewSymbol:   n.NewSymbol,
+			ProcessDate: n.ProcessDate,
+		})
+	}
+	return actions
+}
+
+// actionDate is the date an action takes effect: its ex-date, or the process date
+// for actions without one such as symbol changes
+func actionDate(a models.CorporateAction) string {
+	if a.ExDate != "" {
+		return a.ExDate
+	}
+	return a.ProcessDate
+}
+
+// parseActionTypes reads a comma separated list of corporate action types; empty
+// means all of them
+func parseActionTypes(value string) ([]string, error) {
+	var types []string
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(strings.ToLower(part))
+		if part == "" {
+			continue
+		}
+		if _, ok := actionTypes[part]; !ok {
+			return nil, invalid(CodeInvalidRequest, "unknown corporate action type %q", part)
+		}
+		types = append(types, part)
+	}
+	if len(types) == 0 {
+		for t := range actionTypes {
+			types = append(types, t)
+		}
+	}
+	sort.Strings(types)
+	return types, nil
+}
+
+// loadCorporateActions returns the actions of the given types for symbols between
+// start and end inclusive, following every page, sorted by effective date
+func loadCorporateActions(ctx context.Context, symbols, types []string, start, end string) ([]models.CorporateAction, error) {
+	sorted := append([]string(nil), symbols...)
+	sort.Strings(sorted)
+	key := fmt.Sprintf("corporate-actions:%s:%s:%s:%s", strings.Join(sorted, ","), strings.Join(types, ","), start, end)
+
+	data, err := dataCache.Fetch(ctx, key, cacheConfig.CorporateActionsTTL, func() ([]byte, error) {
+		alpacaTypes := make([]string, len(types))
+		for i, t := range types {
+			alpacaTypes[i] = actionTypes[t]
+		}
+		params := url.Values{
+			"symbols": {strings.Join(sorted, ",")},
+			"types":   {strings.Join(alpacaTypes, ",")},
+			"start":   {start},
+			"end":     {end},
+			"limit":   {fmt.Sprint(maxActionsPage)},
+		}
+
+		actions := []models.CorporateAction{}
+		for {
+			data, err := config.GetMarketData("/v1/corporate-actions", params)
+			if err != nil {
+				return nil, err
+			}
+			var page alpacaCorporateActions
+			if err := json.Unmarshal(data, &page); err != nil {
+				return nil, fmt.Errorf("invalid corporate actions response: %w", err)
+			}
+			actions = append(actions, page.normalize()...)
+			if page.NextPageToken == nil || *page.NextPageToken == "" {
+				break
+			}
+			params.Set("page_token", *page.NextPageToken)
+		}
+
+		sort.SliceStable(actions, func(i, j int) bool {
+			if di, dj := actionDate(actions[i]), actionDate(actions[j]); di != dj {
+				return di < dj
+			}
+			return actions[i].Symbol < actions[j].Symbol
+		})
+		return json.Marshal(actions)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var actions []models.CorporateAction
+	if err := json.Unmarshal(data, &actions); err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
+
+// GetCorporateActions returns splits, dividends and symbol changes for ?symbols between
+// ?start and ?end (YYYY-MM-DD), optionally filtered by ?types. The range defaults to
+// the past year plus the next 90 days, so announced actions are included.
+func GetCorporateActions(c *gin.Context) {
+	symbols, err := parseSymbolList(append(c.QueryArray("symbols"), c.Param("symbol"))...)
+	if err != nil {
+		respondInvalid(c, err)
+		return
+	}
+	types, err := parseActionTypes(c.Query("types"))
+	if err != nil {
+		respondInvalid(c, err)
+		return
+	}
+
+	today := now().In(marketTZ)
+	start, err := parseDate(c.Query("start"), today.AddDate(-defaultActionsLookbackYears, 0, 0))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "start must be a YYYY-MM-DD date")
+		return
+	}
+	end, err := parseDate(c.Query("end"), today.AddDate(0, 0, defaultActionsLookaheadDays))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "end must be a YYYY-MM-DD date")
+		return
+	}
+	if end.Before(start) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "end must not be before start")
+		return
+	}
+
+	actions, err := loadCorporateActions(c.Request.Context(), symbols, types, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		respondUpstreamError(c, err, fmt.Sprintf("Failed to fetch corporate actions for %v", symbols))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"corporate_actions": actions})
+}
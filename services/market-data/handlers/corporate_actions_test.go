@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+func TestGetCorporateActions(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/corporate-actions" {
+			t.Errorf("unexpected upstream path %s", r.URL.Path)
+		}
+		requests = append(requests, r.URL.RawQuery)
+		switch r.URL.Query().Get("page_token") {
+		case "":
+			w.Write([]byte(`{"corporate_actions": {
+				"cash_dividends": [{"symbol": "AAPL", "rate": 0.24, "special": false, "foreign": false,
+					"process_date": "2024-05-16", "ex_date": "2024-05-10", "record_date": "2024-05-13", "payable_date": "2024-05-16"}],
+				"forward_splits": [{"symbol": "NVDA", "old_rate": 1, "new_rate": 10,
+					"process_date": "2024-06-10", "ex_date": "2024-06-10", "record_date": "2024-06-06", "payable_date": "2024-06-07"}]
+			}, "next_page_token": "p2"}`))
+		case "p2":
+			w.Write([]byte(`{"corporate_actions": {
+				"name_changes": [{"old_symbol": "FB", "new_symbol": "META", "process_date": "2024-01-02"}]
+			}, "next_page_token": null}`))
+		default:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("ALPACA_MARKET_DATA_URL", server.URL)
+	useEmptyCache(t)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/corporate-actions", GetCorporateActions)
+	r.GET("/corporate-actions/:symbol", GetCorporateActions)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/corporate-actions?symbols=nvda,aapl,fb&start=2024-01-01&end=2024-12-31", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		CorporateActions []models.CorporateAction `json:"corporate_actions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	actions := body.CorporateActions
+	if len(actions) != 3 {
+		t.Fatalf("expected 3 actions across both pages, got %s", w.Body)
+	}
+	if actions[0].Type != models.ActionSymbolChange || actions[0].Symbol != "FB" || actions[0].NewSymbol != "META" {
+		t.Errorf("expected the symbol change first, got %+v", actions[0])
+	}
+	if actions[1].Type != models.ActionCashDividend || actions[1].Rate != 0.24 || actions[1].PayableDate != "2024-05-16" {
+		t.Errorf("unexpected dividend %+v", actions[1])
+	}
+	if actions[2].Type != models.ActionSplit || actions[2].OldRate != 1 || actions[2].NewRate != 10 {
+		t.Errorf("unexpected split %+v", actions[2])
+	}
+	if len(requests) != 2 {
+		t.Errorf("expected two upstream pages, got %v", requests)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/corporate-actions/AAPL?types=cash_dividend&start=2024-01-01&end=2024-12-31", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if got := requests[len(requests)-2]; got != "end=2024-12-31&limit=1000&start=2024-01-01&symbols=AAPL&types=cash_dividend" {
+		t.Errorf("unexpected upstream query %s", got)
+	}
+}
+
+func TestGetCorporateActionsValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/corporate-actions", GetCorporateActions)
+
+	for _, query := range []string{
+		"/corporate-actions",
+		"/corporate-actions?symbols=AAPL&types=merger",
+		"/corporate-actions?symbols=AAPL&start=2024-02-01&end=2024-01-01",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", query, w.Code, w.Body)
+		}
+	}
+}
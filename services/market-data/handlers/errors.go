@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/market-data/config"
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+// Error codes returned in models.ErrorResponse
+const (
+	CodeInvalidRequest  = "invalid_request"
+	CodeInvalidSymbol   = "invalid_symbol"
+	CodeTooManySymbols  = "too_many_symbols"
+	CodeNotFound        = "not_found"
+	CodeRateLimited     = "rate_limited"
+	CodeUpstreamError   = "upstream_error"
+	CodeUpstreamTimeout = "upstream_timeout"
+	CodeUnavailable     = "unavailable"
+	CodeInternal        = "internal_error"
+)
+
+// requestError is a validation failure with the code to report it under
+type requestError struct {
+	code    string
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+func invalid(code, format string, args ...interface{}) error {
+	return &requestError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// respondError writes a typed error body
+func respondError(c *gin.Context, status int, code, message string) {
+	c.JSON(status, models.ErrorResponse{Error: message, Code: code})
+}
+
+// respondInvalid answers 400 for a request that failed validation
+func respondInvalid(c *gin.Context, err error) {
+	code := CodeInvalidRequest
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		code = reqErr.code
+	}
+	respondError(c, http.StatusBadRequest, code, err.Error())
+}
+
+// respondUpstreamError maps a failed upstream call onto our own status. Alpaca
+// rejecting our credentials or failing is a bad gateway rather than the client's
+// fault; not found and rate limiting are passed through.
+func respondUpstreamError(c *gin.Context, err error, message string) {
+	var upstream *config.UpstreamError
+	if errors.As(err, &upstream) {
+		body := models.ErrorResponse{
+			Error:          fmt.Sprintf("%s: %s", message, upstreamMessage(upstream.Body)),
+			UpstreamStatus: upstream.StatusCode,
+		}
+		status := http.StatusBadGateway
+		switch code := upstream.StatusCode; {
+		case code == http.StatusBadRequest || code == http.StatusUnprocessableEntity:
+			status, body.Code = http.StatusBadRequest, CodeInvalidRequest
+		case code == http.StatusNotFound:
+			status, body.Code = http.StatusNotFound, CodeNotFound
+		case code == http.StatusTooManyRequests:
+			status, body.Code = http.StatusTooManyRequests, CodeRateLimited
+			if upstream.RetryAfter != "" {
+				c.Header("Retry-After", upstream.RetryAfter)
+			}
+		default:
+			body.Code = CodeUpstreamError
+		}
+		c.JSON(status, body)
+		return
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			respondError(c, http.StatusGatewayTimeout, CodeUpstreamTimeout, fmt.Sprintf("%s: upstream timed out", message))
+			return
+		}
+		respondError(c, http.StatusBadGateway, CodeUpstreamError, fmt.Sprintf("%s: %v", message, err))
+		return
+	}
+
+	respondError(c, http.StatusInternalServerError, CodeInternal, fmt.Sprintf("%s: %v", message, err))
+}
+
+// upstreamMessage pulls the message out of an Alpaca error body
+func upstreamMessage(body []byte) string {
+	var res struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &res) == nil && res.Message != "" {
+		return res.Message
+	}
+	if len(body) > 200 {
+		body = body[:200]
+	}
+	return string(body)
+}
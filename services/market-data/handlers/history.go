@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/market-data/config"
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+const (
+	// defaultBarLimit is how many bars per symbol are returned when no limit is given
+	defaultBarLimit = 1000
+	// maxBarLimit caps the bars per symbol a single request may ask for
+	maxBarLimit = 10000
+	// maxBarPages bounds how many next_page_token hops one request follows
+	maxBarPages = 50
+)
+
+var validTimeframes = map[string]bool{"1Min": true, "5Min": true, "15Min": true, "1Hour": true, "1Day": true}
+var validAdjustments = map[string]bool{"raw": true, "split": true, "dividend": true, "all": true}
+var validFeeds = map[string]bool{"iex": true, "sip": true, "otc": true}
+
+// BarHistoryQuery holds the parameters accepted by GetBarHistory
+type BarHistoryQuery struct {
+	Symbols    []string
+	Timeframe  string
+	Start      time.Time
+	End        time.Time
+	Adjustment string
+	Feed       string
+	Limit      int
+	Sort       string
+}
+
+// alpacaBarsPage is one page of Alpaca's multi-symbol bars response
+type alpacaBarsPage struct {
+	Bars          map[string][]alpacaBar `json:"bars"`
+	NextPageToken *string                `json:"next_page_token"`
+}
+
+type alpacaBar struct {
+	Timestamp  time.Time `json:"t"`
+	Open       float64   `json:"o"`
+	High       float64   `json:"h"`
+	Low        float64   `json:"l"`
+	Close      float64   `json:"c"`
+	Volume     float64   `json:"v"`
+	TradeCount int64     `json:"n"`
+	VWAP       float64   `json:"vw"`
+}
+
+func (b alpacaBar) normalize() models.Bar {
+	return models.Bar{
+		Timestamp:  b.Timestamp,
+		Open:       b.Open,
+		High:       b.High,
+		Low:        b.Low,
+		Close:      b.Close,
+		Volume:     b.Volume,
+		TradeCount: b.TradeCount,
+		VWAP:       b.VWAP,
+	}
+}
+
+// parseTimestamp accepts RFC3339 timestamps or plain dates; empty means unset
+func parseTimestamp(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// parseBarHistoryQuery reads and validates the GetBarHistory parameters. The symbol
+// path parameter may list several symbols separated by commas.
+func parseBarHistoryQuery(c *gin.Context) (BarHistoryQuery, error) {
+	q := BarHistoryQuery{
+		Timeframe:  c.DefaultQuery("timeframe", "1Day"),
+		Adjustment: c.Query("adjustment"),
+		Feed:       c.Query("feed"),
+		Limit:      defaultBarLimit,
+		Sort:       c.DefaultQuery("sort", "asc"),
+	}
+
+	var err error
+	if q.Symbols, err = parseSymbolList(c.Param("symbol")); err != nil {
+		return q, err
+	}
+	if !validTimeframes[q.Timeframe] {
+		return q, errors.New("timeframe must be one of 1Min, 5Min, 15Min, 1Hour or 1Day")
+	}
+	if q.Adjustment != "" && !validAdjustments[q.Adjustment] {
+		return q, errors.New("adjustment must be one of raw, split, dividend or all")
+	}
+	if q.Feed != "" && !validFeeds[q.Feed] {
+		return q, errors.New("feed must be one of iex, sip or otc")
+	}
+	if q.Sort != "asc" && q.Sort != "desc" {
+		return q, errors.New("sort must be 'asc' or 'desc'")
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxBarLimit {
+			return q, fmt.Errorf("limit must be between 1 and %d", maxBarLimit)
+		}
+		q.Limit = n
+	}
+
+	if q.Start, err = parseTimestamp(c.Query("start")); err != nil {
+		return q, errors.New("start must be an RFC3339 timestamp or a YYYY-MM-DD date")
+	}
+	if q.End, err = parseTimestamp(c.Query("end")); err != nil {
+		return q, errors.New("end must be an RFC3339 timestamp or a YYYY-MM-DD date")
+	}
+	if !q.Start.IsZero() && !q.End.IsZero() && !q.Start.Before(q.End) {
+		return q, errors.New("start must be before end")
+	}
+
+	return q, nil
+}
+
+// fetchBarHistory collects up to q.Limit bars per symbol, following Alpaca's
+// next_page_token until every symbol is full or the data runs out
+func fetchBarHistory(q BarHistoryQuery) (map[string][]models.Bar, error) {
+	params := url.Values{}
+	params.Set("symbols", strings.Join(q.Symbols, ","))
+	params.Set("timeframe", q.Timeframe)
+	if !q.Start.IsZero() {
+		params.Set("start", q.Start.UTC().Format(time.RFC3339))
+	}
+	if !q.End.IsZero() {
+		params.Set("end", q.End.UTC().Format(time.RFC3339))
+	}
+	if q.Adjustment != "" {
+		params.Set("adjustment", q.Adjustment)
+	}
+	if q.Feed != "" {
+		params.Set("feed", q.Feed)
+	}
+	if q.Sort != "" {
+		params.Set("sort", q.Sort)
+	}
+	// Alpaca's limit is per page across all symbols
+	pageSize := q.Limit * len(q.Symbols)
+	if pageSize > maxBarLimit {
+		pageSize = maxBarLimit
+	}
+	params.Set("limit", strconv.Itoa(pageSize))
+
+	bars := make(map[string][]models.Bar, len(q.Symbols))
+	for _, symbol := range q.Symbols {
+		bars[symbol] = []models.Bar{}
+	}
+
+	for page := 0; page < maxBarPages; page++ {
+		data, err := config.GetMarketData("/v2/stocks/bars", params)
+		if err != nil {
+			return nil, err
+		}
+		var res alpacaBarsPage
+		if err := json.Unmarshal(data, &res); err != nil {
+			return nil, fmt.Errorf("invalid bars response: %w", err)
+		}
+
+		for symbol, symbolBars := range res.Bars {
+			if _, requested := bars[symbol]; !requested {
+				continue
+			}
+			for _, bar := range symbolBars {
+				if len(bars[symbol]) < q.Limit {
+					bars[symbol] = append(bars[symbol], bar.normalize())
+				}
+			}
+		}
+
+		if res.NextPageToken == nil || *res.NextPageToken == "" || allFull(bars, q.Limit) {
+			return bars, nil
+		}
+		params.Set("page_token", *res.NextPageToken)
+	}
+	return nil, fmt.Errorf("bar history exceeded %d pages; narrow the range or lower the limit", maxBarPages)
+}
+
+func allFull(bars map[string][]models.Bar, limit int) bool {
+	for _, symbolBars := range bars {
+		if len(symbolBars) < limit {
+			return false
+		}
+	}
+	return true
+}
+
+// loadBarHistory returns the bar history for q as a JSON models.BarHistory, from the
+// cache when an identical query was answered recently
+func loadBarHistory(ctx context.Context, q BarHistoryQuery) ([]byte, error) {
+	return dataCache.Fetch(ctx, q.cacheKey(), cacheConfig.HistoryTTL, func() ([]byte, error) {
+		bars, err := fetchBarHistory(q)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(models.BarHistory{Timeframe: q.Timeframe, Bars: bars})
+	})
+}
+
+// GetBarHistory returns historical OHLCV bars for one or more symbols
+func GetBarHistory(c *gin.Context) {
+	q, err := parseBarHistoryQuery(c)
+	if err != nil {
+		respondInvalid(c, err)
+		return
+	}
+
+	data, err := loadBarHistory(c.Request.Context(), q)
+	if err != nil {
+		respondUpstreamError(c, err, fmt.Sprintf("Failed to fetch bar history for %v", q.Symbols))
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json", data)
+}
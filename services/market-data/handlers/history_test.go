@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/market-data/internal/cache"
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+// useEmptyCache gives the test its own cache so responses from other tests are not replayed
+func useEmptyCache(t *testing.T) {
+	t.Helper()
+	previous := dataCache
+	dataCache = cache.New(cache.NewMemoryStore(100))
+	t.Cleanup(func() { dataCache = previous })
+}
+
+// servePages answers /v2/stocks/bars with the given pages, chained by page_token
+func servePages(t *testing.T, pages map[string]string) *[]string {
+	t.Helper()
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/stocks/bars" {
+			http.NotFound(w, r)
+			return
+		}
+		requests = append(requests, r.URL.RawQuery)
+		body, ok := pages[r.URL.Query().Get("page_token")]
+		if !ok {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"message":"invalid page_token"}`))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("ALPACA_MARKET_DATA_URL", server.URL)
+	useEmptyCache(t)
+	return &requests
+}
+
+func getHistory(t *testing.T, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/bars/:symbol/history", GetBarHistory)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func TestGetBarHistoryFollowsPageToken(t *testing.T) {
+	requests := servePages(t, map[string]string{
+		"": `{"bars":{"AAPL":[{"t":"2024-01-02T05:00:00Z","o":187.15,"h":188.44,"l":183.89,"c":185.64,"v":82488674,"n":1009074,"vw":185.9}]},"next_page_token":"p2"}`,
+		"p2": `{"bars":{"AAPL":[{"t":"2024-01-03T05:00:00Z","o":184.22,"h":185.88,"l":183.43,"c":184.25,"v":58414460,"n":656853,"vw":184.32}],
+		        "MSFT":[{"t":"2024-01-02T05:00:00Z","o":373.86,"h":375.9,"l":366.77,"c":370.87,"v":25258600,"n":331161,"vw":370.5}]},"next_page_token":null}`,
+	})
+
+	w := getHistory(t, "/bars/aapl,MSFT/history?timeframe=1Day&start=2024-01-01&end=2024-01-05&adjustment=split&feed=sip")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	var history models.BarHistory
+	if err := json.Unmarshal(w.Body.Bytes(), &history); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if len(history.Bars["AAPL"]) != 2 || len(history.Bars["MSFT"]) != 1 {
+		t.Fatalf("expected 2 AAPL and 1 MSFT bars, got %+v", history.Bars)
+	}
+	if bar := history.Bars["AAPL"][1]; bar.Close != 184.25 || bar.Volume != 58414460 || bar.TradeCount != 656853 {
+		t.Errorf("unexpected normalized bar: %+v", bar)
+	}
+
+	if len(*requests) != 2 {
+		t.Fatalf("expected 2 upstream requests, got %d", len(*requests))
+	}
+	first := (*requests)[0]
+	for _, want := range []string{"symbols=AAPL%2CMSFT", "timeframe=1Day", "start=2024-01-01T00%3A00%3A00Z", "adjustment=split", "feed=sip"} {
+		if !strings.Contains(first, want) {
+			t.Errorf("expected %q in upstream query %q", want, first)
+		}
+	}
+}
+
+func TestGetBarHistoryStopsAtLimit(t *testing.T) {
+	requests := servePages(t, map[string]string{
+		"": `{"bars":{"AAPL":[{"t":"2024-01-02T14:30:00Z","c":1},{"t":"2024-01-02T14:31:00Z","c":2}]},"next_page_token":"p2"}`,
+	})
+
+	w := getHistory(t, "/bars/AAPL/history?timeframe=1Min&limit=2")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if len(*requests) != 1 {
+		t.Errorf("expected the page token to be ignored once the limit is met, got %d requests", len(*requests))
+	}
+}
+
+func TestGetBarHistoryIsCached(t *testing.T) {
+	requests := servePages(t, map[string]string{
+		"": `{"bars":{"AAPL":[{"t":"2024-01-02T05:00:00Z","c":185.64}]},"next_page_token":null}`,
+	})
+
+	for i := 0; i < 2; i++ {
+		if w := getHistory(t, "/bars/AAPL/history?start=2024-01-01"); w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+		}
+	}
+	if len(*requests) != 1 {
+		t.Errorf("expected the second request to be served from cache, got %d upstream requests", len(*requests))
+	}
+}
+
+func TestGetBarHistoryValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+	}{
+		{"bad timeframe", "/bars/AAPL/history?timeframe=2Day"},
+		{"bad adjustment", "/bars/AAPL/history?adjustment=none"},
+		{"bad feed", "/bars/AAPL/history?feed=nasdaq"},
+		{"limit too large", "/bars/AAPL/history?limit=20000"},
+		{"bad start", "/bars/AAPL/history?start=yesterday"},
+		{"inverted range", "/bars/AAPL/history?start=2024-02-01&end=2024-01-01"},
+		{"no symbols", "/bars/,/history"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := getHistory(t, tt.target); w.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d: %s", w.Code, w.Body)
+			}
+		})
+	}
+}
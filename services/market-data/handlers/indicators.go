@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/market-data/indicators"
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+const (
+	// maxIndicatorPeriod caps any period parameter
+	maxIndicatorPeriod = 500
+	// maxIndicatorsPerRequest caps how many indicators one request computes
+	maxIndicatorsPerRequest = 10
+)
+
+// indicatorDefaults are the parameters used when a request names an indicator without
+// any; the first parameter of the single-period indicators can be set with ?period=
+var indicatorDefaults = map[string][]float64{
+	"sma":       {20},
+	"ema":       {20},
+	"rsi":       {14},
+	"atr":       {14},
+	"bollinger": {20, 2},
+	"macd":      {12, 26, 9},
+	"vwap":      {},
+}
+
+// indicatorSpec is one requested indicator, e.g. "macd:12:26:9"
+type indicatorSpec struct {
+	name   string
+	params []float64
+}
+
+// key names the indicator in the response, e.g. "macd_12_26_9"
+func (s indicatorSpec) key() string {
+	parts := []string{s.name}
+	for _, p := range s.params {
+		parts = append(parts, strconv.FormatFloat(p, 'f', -1, 64))
+	}
+	return strings.Join(parts, "_")
+}
+
+// warmup is how many bars before the first returned one the indicator needs so its
+// values have settled; exponential smoothing needs several periods to converge
+func (s indicatorSpec) warmup() int {
+	switch s.name {
+	case "sma", "bollinger":
+		return int(s.params[0])
+	case "ema", "rsi", "atr":
+		return 3 * int(s.params[0])
+	case "macd":
+		return 3 * int(s.params[1]+s.params[2])
+	}
+	return 0
+}
+
+// compute runs the indicator over bars and returns its named outputs
+func (s indicatorSpec) compute(bars []models.Bar) map[string][]float64 {
+	closes := indicators.Closes(bars)
+	switch s.name {
+	case "sma":
+		return map[string][]float64{"value": indicators.SMA(closes, int(s.params[0]))}
+	case "ema":
+		return map[string][]float64{"value": indicators.EMA(closes, int(s.params[0]))}
+	case "rsi":
+		return map[string][]float64{"value": indicators.RSI(closes, int(s.params[0]))}
+	case "atr":
+		return map[string][]float64{"value": indicators.ATR(bars, int(s.params[0]))}
+	case "vwap":
+		return map[string][]float64{"value": indicators.VWAP(bars)}
+	case "bollinger":
+		bands := indicators.Bollinger(closes, int(s.params[0]), s.params[1])
+		return map[string][]float64{"upper": bands.Upper, "middle": bands.Middle, "lower": bands.Lower}
+	case "macd":
+		macd := indicators.MACD(closes, int(s.params[0]), int(s.params[1]), int(s.params[2]))
+		return map[string][]float64{"macd": macd.MACD, "signal": macd.Signal, "histogram": macd.Histogram}
+	}
+	return nil
+}
+
+// parseIndicatorSpecs reads ?name=, which may repeat or list several indicators
+// separated by commas, each optionally followed by colon-separated parameters
+func parseIndicatorSpecs(c *gin.Context) ([]indicatorSpec, error) {
+	var period float64
+	if value := c.Query("period"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxIndicatorPeriod {
+			return nil, fmt.Errorf("period must be between 1 and %d", maxIndicatorPeriod)
+		}
+		period = float64(n)
+	}
+
+	var specs []indicatorSpec
+	seen := map[string]bool{}
+	for _, item := range strings.Split(strings.Join(c.QueryArray("name"), ","), ",") {
+		parts := strings.Split(strings.ToLower(strings.TrimSpace(item)), ":")
+		if parts[0] == "" {
+			continue
+		}
+		defaults, ok := indicatorDefaults[parts[0]]
+		if !ok {
+			return nil, fmt.Errorf("unknown indicator %q; supported: sma, ema, rsi, macd, bollinger, atr, vwap", parts[0])
+		}
+
+		spec := indicatorSpec{name: parts[0], params: append([]float64{}, defaults...)}
+		if len(parts) > 1 {
+			if len(parts)-1 > len(defaults) {
+				return nil, fmt.Errorf("%s takes at most %d parameters", spec.name, len(defaults))
+			}
+			for i, part := range parts[1:] {
+				p, err := strconv.ParseFloat(part, 64)
+				if err != nil || p <= 0 {
+					return nil, fmt.Errorf("invalid parameter %q for %s", part, spec.name)
+				}
+				spec.params[i] = p
+			}
+		} else if period > 0 && len(defaults) > 0 && spec.name != "macd" {
+			spec.params[0] = period
+		}
+		if err := spec.validate(); err != nil {
+			return nil, err
+		}
+
+		if !seen[spec.key()] {
+			seen[spec.key()] = true
+			specs = append(specs, spec)
+		}
+	}
+
+	if len(specs) == 0 {
+		return nil, errors.New("at least one indicator name is required, e.g. ?name=rsi")
+	}
+	if len(specs) > maxIndicatorsPerRequest {
+		return nil, fmt.Errorf("at most %d indicators may be requested at once", maxIndicatorsPerRequest)
+	}
+	return specs, nil
+}
+
+// validate checks that periods are whole numbers in range; the Bollinger width may be fractional
+func (s indicatorSpec) validate() error {
+	for i, p := range s.params {
+		if s.name == "bollinger" && i == 1 {
+			continue
+		}
+		if p != math.Trunc(p) || p > maxIndicatorPeriod {
+			return fmt.Errorf("%s periods must be whole numbers up to %d", s.name, maxIndicatorPeriod)
+		}
+	}
+	if s.name == "macd" && s.params[0] >= s.params[1] {
+		return errors.New("macd fast period must be shorter than the slow period")
+	}
+	return nil
+}
+
+// GetIndicators computes technical indicators over a symbol's most recent bars.
+// It accepts the bar history parameters; limit is the number of values returned
+// per indicator, and the extra bars needed to warm the indicators up are fetched too.
+func GetIndicators(c *gin.Context) {
+	q, err := parseBarHistoryQuery(c)
+	if err != nil {
+		respondInvalid(c, err)
+		return
+	}
+	if len(q.Symbols) != 1 {
+		respondError(c, http.StatusBadRequest, CodeInvalidSymbol, "Indicators are computed for one symbol at a time")
+		return
+	}
+	specs, err := parseIndicatorSpecs(c)
+	if err != nil {
+		respondInvalid(c, err)
+		return
+	}
+
+	limit := q.Limit
+	for _, spec := range specs {
+		if warmup := spec.warmup(); q.Limit < limit+warmup {
+			q.Limit = limit + warmup
+		}
+	}
+	// Newest first so the limit keeps the latest bars; without a start, look back far
+	// enough for any limit (truncated to the day so the query stays cacheable)
+	q.Sort = "desc"
+	if q.Start.IsZero() {
+		q.Start = time.Now().UTC().Truncate(24*time.Hour).AddDate(-10, 0, 0)
+	}
+
+	data, err := loadBarHistory(c.Request.Context(), q)
+	if err != nil {
+		respondUpstreamError(c, err, fmt.Sprintf("Failed to fetch bars for %s", q.Symbols[0]))
+		return
+	}
+	var history models.BarHistory
+	if err := json.Unmarshal(data, &history); err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to decode bars")
+		return
+	}
+
+	bars := history.Bars[q.Symbols[0]]
+	for i, j := 0, len(bars)-1; i < j; i, j = i+1, j-1 {
+		bars[i], bars[j] = bars[j], bars[i]
+	}
+	first := len(bars) - limit
+	if first < 0 {
+		first = 0
+	}
+
+	response := models.IndicatorSeries{
+		Symbol:     q.Symbols[0],
+		Timeframe:  q.Timeframe,
+		Timestamps: make([]time.Time, 0, len(bars)-first),
+		Indicators: make(map[string]map[string][]*float64, len(specs)),
+	}
+	for _, bar := range bars[first:] {
+		response.Timestamps = append(response.Timestamps, bar.Timestamp)
+	}
+	for _, spec := range specs {
+		outputs := make(map[string][]*float64)
+		for name, values := range spec.compute(bars) {
+			outputs[name] = nullable(values[first:])
+		}
+		response.Indicators[spec.key()] = outputs
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// nullable converts warm-up NaNs to nil so they encode as JSON null
+func nullable(values []float64) []*float64 {
+	out := make([]*float64, len(values))
+	for i := range values {
+		if !math.IsNaN(values[i]) && !math.IsInf(values[i], 0) {
+			out[i] = &values[i]
+		}
+	}
+	return out
+}
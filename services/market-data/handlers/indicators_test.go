@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+func getIndicators(t *testing.T, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/indicators/:symbol", GetIndicators)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func TestGetIndicatorsUsesLatestBarsWithWarmup(t *testing.T) {
+	// 100 daily bars, served newest first as Alpaca does for sort=desc
+	start := time.Date(2024, 1, 1, 5, 0, 0, 0, time.UTC)
+	var bars []string
+	for i := 99; i >= 0; i-- {
+		bars = append(bars, fmt.Sprintf(`{"t":%q,"o":%d,"h":%d,"l":%d,"c":%d,"v":1000}`,
+			start.AddDate(0, 0, i).Format(time.RFC3339), 100+i, 101+i, 99+i, 100+i))
+	}
+	requests := servePages(t, map[string]string{
+		"": `{"bars":{"AAPL":[` + strings.Join(bars, ",") + `]},"next_page_token":null}`,
+	})
+
+	w := getIndicators(t, "/indicators/aapl?name=sma,rsi:5&period=3&limit=10")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if query := (*requests)[0]; !strings.Contains(query, "sort=desc") || !strings.Contains(query, "limit=25") {
+		t.Errorf("expected the latest 10 bars plus 15 warm-up bars, upstream saw %q", query)
+	}
+
+	var series models.IndicatorSeries
+	if err := json.Unmarshal(w.Body.Bytes(), &series); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if len(series.Timestamps) != 10 || !series.Timestamps[9].Equal(start.AddDate(0, 0, 99)) {
+		t.Fatalf("expected the 10 latest bars in ascending order, got %v", series.Timestamps)
+	}
+	sma := series.Indicators["sma_3"]["value"]
+	if len(sma) != 10 || sma[9] == nil || *sma[9] != 198 {
+		t.Errorf("expected sma_3 of the last three closes to be 198, got %v", sma)
+	}
+	// Closes only rise, so RSI is pinned at 100
+	if rsi := series.Indicators["rsi_5"]["value"]; rsi[0] == nil || *rsi[0] != 100 {
+		t.Errorf("expected rsi_5 to be warmed up at the first returned bar, got %v", rsi)
+	}
+}
+
+func TestParseIndicatorSpecs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		query    string
+		wantKeys []string
+		wantErr  string
+	}{
+		{query: "name=rsi&period=7", wantKeys: []string{"rsi_7"}},
+		{query: "name=macd,bollinger&name=vwap", wantKeys: []string{"macd_12_26_9", "bollinger_20_2", "vwap"}},
+		{query: "name=bollinger:10:2.5,sma:50,sma:50", wantKeys: []string{"bollinger_10_2.5", "sma_50"}},
+		{query: "", wantErr: "at least one indicator"},
+		{query: "name=stoch", wantErr: "unknown indicator"},
+		{query: "name=rsi:1.5", wantErr: "whole numbers"},
+		{query: "name=macd:26:12:9", wantErr: "fast period"},
+		{query: "name=sma:1:2", wantErr: "at most 1 parameters"},
+		{query: "name=rsi&period=0", wantErr: "period must be between"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/indicators/AAPL?"+tt.query, nil)
+
+			specs, err := parseIndicatorSpecs(c)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var keys []string
+			for _, spec := range specs {
+				keys = append(keys, spec.key())
+			}
+			if strings.Join(keys, ",") != strings.Join(tt.wantKeys, ",") {
+				t.Errorf("expected %v, got %v", tt.wantKeys, keys)
+			}
+		})
+	}
+}
@@ -5,97 +5,64 @@ package handlers
 import (
 	"fmt"
 	"net/http"
-	"net/url"
-	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/seunghoon34/trading-app/services/market-data/config"
 )
 
 func GetLatestQuote(c *gin.Context) {
-	symbol := c.Param("symbol")
-
-	// Use the same endpoint that worked in curl
-	endpoint := fmt.Sprintf("/v2/stocks/%s/quotes/latest", symbol)
-	data, err := config.MakeMarketDataRequest(endpoint)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Failed to fetch quote for %s: %v", symbol, err),
-		})
-		return
-	}
-
-	// Return the raw JSON data (you can parse it later)
-	c.Header("Content-Type", "application/json")
-	c.String(http.StatusOK, string(data))
+	getLatest(c, latestQuotes)
 }
 
 func GetLatestBar(c *gin.Context) {
-	symbol := c.Param("symbol")
-
-	// Use the same endpoint that worked in curl
-	endpoint := fmt.Sprintf("/v2/stocks/%s/bars/latest", symbol)
-	data, err := config.MakeMarketDataRequest(endpoint)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Failed to fetch bar for %s: %v", symbol, err),
-		})
-		return
-	}
-
-	// Return the raw JSON data (you can parse it later)
-	c.Header("Content-Type", "application/json")
-	c.String(http.StatusOK, string(data))
+	getLatest(c, latestBars)
 }
 
 func GetLatestQuoteMulti(c *gin.Context) {
-	symbols := c.QueryArray("symbols")
+	getLatestMulti(c, latestQuotes)
+}
 
-	if len(symbols) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "No symbols provided. Use ?symbols=AAPL&symbols=TSLA or ?symbols=AAPL,TSLA",
-		})
+func GetLatestBarMulti(c *gin.Context) {
+	getLatestMulti(c, latestBars)
+}
+
+// getLatest responds with Alpaca's single-symbol shape, e.g. {"symbol": ..., "quote": {...}}
+func getLatest(c *gin.Context, kind latestKind) {
+	symbol, err := parseSymbol(c.Param("symbol"))
+	if err != nil {
+		respondInvalid(c, err)
 		return
 	}
 
-	// Join symbols with comma and URL encode
-	symbolsParam := url.QueryEscape(strings.Join(symbols, ","))
-
-	endpoint := fmt.Sprintf("/v2/stocks/quotes/latest?symbols=%s", symbolsParam)
-	data, err := config.MakeMarketDataRequest(endpoint)
+	results, err := fetchLatest(c.Request.Context(), kind, []string{symbol})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Failed to fetch quotes for %v: %v", symbols, err),
-		})
+		respondUpstreamError(c, err, fmt.Sprintf("Failed to fetch %s for %s", kind.name, symbol))
+		return
+	}
+	value, ok := results[symbol]
+	if !ok {
+		respondError(c, http.StatusNotFound, CodeNotFound, fmt.Sprintf("No %s found for %s", kind.name, symbol))
 		return
 	}
 
-	c.Header("Content-Type", "application/json")
-	c.String(http.StatusOK, string(data))
+	c.JSON(http.StatusOK, gin.H{
+		"symbol":  symbol,
+		kind.name: value,
+	})
 }
 
-func GetLatestBarMulti(c *gin.Context) {
-	symbols := c.QueryArray("symbols")
-
-	if len(symbols) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "No symbols provided. Use ?symbols=AAPL&symbols=TSLA or ?symbols=AAPL,TSLA",
-		})
+// getLatestMulti responds with Alpaca's multi-symbol shape, e.g. {"quotes": {"AAPL": {...}}}
+func getLatestMulti(c *gin.Context, kind latestKind) {
+	symbols, err := parseSymbolList(c.QueryArray("symbols")...)
+	if err != nil {
+		respondInvalid(c, err)
 		return
 	}
 
-	// Join symbols with comma and URL encode
-	symbolsParam := url.QueryEscape(strings.Join(symbols, ","))
-
-	endpoint := fmt.Sprintf("/v2/stocks/bars/latest?symbols=%s", symbolsParam)
-	data, err := config.MakeMarketDataRequest(endpoint)
+	results, err := fetchLatest(c.Request.Context(), kind, symbols)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("Failed to fetch bars for %v: %v", symbols, err),
-		})
+		respondUpstreamError(c, err, fmt.Sprintf("Failed to fetch %s for %v", kind.plural, symbols))
 		return
 	}
 
-	c.Header("Content-Type", "application/json")
-	c.String(http.StatusOK, string(data))
+	c.JSON(http.StatusOK, gin.H{kind.plural: results})
 }
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/market-data/config"
+)
+
+func TestLatestQuoteReportsUpstreamTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+	t.Setenv("ALPACA_MARKET_DATA_URL", server.URL)
+	useEmptyCache(t)
+	previous := config.HTTPClient
+	config.HTTPClient = &http.Client{Timeout: 20 * time.Millisecond}
+	defer func() { config.HTTPClient = previous }()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/quotes/:symbol", GetLatestQuote)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/quotes/AAPL", nil))
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504, got %d: %s", w.Code, w.Body)
+	}
+	if !strings.Contains(w.Body.String(), CodeUpstreamTimeout) {
+		t.Errorf("expected the %s code, got %s", CodeUpstreamTimeout, w.Body)
+	}
+}
+
+func TestLatestQuotesOnlyRequestMisses(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Query().Get("symbols"))
+		quotes := map[string]json.RawMessage{}
+		for _, symbol := range strings.Split(r.URL.Query().Get("symbols"), ",") {
+			if symbol != "NOPE" {
+				quotes[symbol] = json.RawMessage(`{"ap":101,"bp":99}`)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"quotes": quotes})
+	}))
+	defer server.Close()
+	t.Setenv("ALPACA_MARKET_DATA_URL", server.URL)
+	useEmptyCache(t)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/quotes", GetLatestQuoteMulti)
+	r.GET("/quotes/:symbol", GetLatestQuote)
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	if w := get("/quotes/aapl"); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	} else {
+		var body struct {
+			Symbol string `json:"symbol"`
+			Quote  struct {
+				AskPrice float64 `json:"ap"`
+			} `json:"quote"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if body.Symbol != "AAPL" || body.Quote.AskPrice != 101 {
+			t.Errorf("unexpected single quote body: %s", w.Body)
+		}
+	}
+
+	w := get("/quotes?symbols=AAPL,MSFT&symbols=TSLA")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Quotes map[string]json.RawMessage `json:"quotes"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if len(body.Quotes) != 3 {
+		t.Errorf("expected 3 quotes, got %s", w.Body)
+	}
+
+	if len(requested) != 2 || requested[0] != "AAPL" || requested[1] != "MSFT,TSLA" {
+		t.Errorf("expected AAPL to be served from cache, upstream saw %v", requested)
+	}
+
+	if w := get("/quotes/nope"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a symbol without a quote, got %d", w.Code)
+	}
+}
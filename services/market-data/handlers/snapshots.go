@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+// alpacaSnapshot is one symbol of Alpaca's /v2/stocks/snapshots response
+type alpacaSnapshot struct {
+	LatestTrade  *alpacaTrade `json:"latestTrade"`
+	LatestQuote  *alpacaQuote `json:"latestQuote"`
+	MinuteBar    *alpacaBar   `json:"minuteBar"`
+	DailyBar     *alpacaBar   `json:"dailyBar"`
+	PrevDailyBar *alpacaBar   `json:"prevDailyBar"`
+}
+
+type alpacaTrade struct {
+	Timestamp  time.Time `json:"t"`
+	Exchange   string    `json:"x"`
+	Price      float64   `json:"p"`
+	Size       float64   `json:"s"`
+	Conditions []string  `json:"c"`
+	ID         int64     `json:"i"`
+	Tape       string    `json:"z"`
+}
+
+type alpacaQuote struct {
+	Timestamp   time.Time `json:"t"`
+	AskExchange string    `json:"ax"`
+	AskPrice    float64   `json:"ap"`
+	AskSize     float64   `json:"as"`
+	BidExchange string    `json:"bx"`
+	BidPrice    float64   `json:"bp"`
+	BidSize     float64   `json:"bs"`
+	Conditions  []string  `json:"c"`
+	Tape        string    `json:"z"`
+}
+
+// normalize converts the snapshot and works out the change since the previous
+// close, using the daily bar's close when there is no trade yet
+func (s alpacaSnapshot) normalize(symbol string) models.Snapshot {
+	snapshot := models.Snapshot{Symbol: symbol}
+	var price float64
+	if t := s.LatestTrade; t != nil {
+		snapshot.LatestTrade = &models.Trade{
+			Timestamp:  t.Timestamp,
+			Price:      t.Price,
+			Size:       t.Size,
+			Exchange:   t.Exchange,
+			ID:         t.ID,
+			Conditions: t.Conditions,
+			Tape:       t.Tape,
+		}
+		price = t.Price
+	}
+	if q := s.LatestQuote; q != nil {
+		snapshot.LatestQuote = &models.Quote{
+			Timestamp:   q.Timestamp,
+			AskPrice:    q.AskPrice,
+			AskSize:     q.AskSize,
+			AskExchange: q.AskExchange,
+			BidPrice:    q.BidPrice,
+			BidSize:     q.BidSize,
+			BidExchange: q.BidExchange,
+			Conditions:  q.Conditions,
+			Tape:        q.Tape,
+		}
+	}
+	bar := func(b *alpacaBar) *models.Bar {
+		if b == nil {
+			return nil
+		}
+		normalized := b.normalize()
+		return &normalized
+	}
+	snapshot.MinuteBar = bar(s.MinuteBar)
+	snapshot.DailyBar = bar(s.DailyBar)
+	snapshot.PrevDailyBar = bar(s.PrevDailyBar)
+
+	if price == 0 && snapshot.DailyBar != nil {
+		price = snapshot.DailyBar.Close
+	}
+	if price != 0 && snapshot.PrevDailyBar != nil && snapshot.PrevDailyBar.Close != 0 {
+		prevClose := snapshot.PrevDailyBar.Close
+		change := price - prevClose
+		changePct := change / prevClose * 100
+		snapshot.Change = &change
+		snapshot.ChangePct = &changePct
+	}
+	return snapshot
+}
+
+// GetSnapshots returns the latest trade, quote and bars of each requested symbol
+// together with its change since the previous close
+func GetSnapshots(c *gin.Context) {
+	symbols, err := parseSymbolList(c.QueryArray("symbols")...)
+	if err != nil {
+		respondInvalid(c, err)
+		return
+	}
+
+	results, err := fetchLatest(c.Request.Context(), snapshots, symbols)
+	if err != nil {
+		respondUpstreamError(c, err, fmt.Sprintf("Failed to fetch snapshots for %v", symbols))
+		return
+	}
+
+	out := make(map[string]models.Snapshot, len(results))
+	for symbol, raw := range results {
+		var s alpacaSnapshot
+		if err := json.Unmarshal(raw, &s); err != nil {
+			respondError(c, http.StatusBadGateway, CodeUpstreamError, fmt.Sprintf("Invalid snapshot for %s: %v", symbol, err))
+			return
+		}
+		out[symbol] = s.normalize(symbol)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"snapshots": out})
+}
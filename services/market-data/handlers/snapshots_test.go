@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+func TestGetSnapshots(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/stocks/snapshots" {
+			t.Errorf("unexpected upstream path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{
+			"AAPL": {
+				"latestTrade": {"t": "2024-03-01T15:59:59Z", "x": "V", "p": 110, "s": 100, "c": ["@"], "i": 42, "z": "C"},
+				"latestQuote": {"t": "2024-03-01T15:59:59Z", "ax": "V", "ap": 110.05, "as": 2, "bx": "V", "bp": 109.95, "bs": 3, "c": ["R"], "z": "C"},
+				"minuteBar": {"t": "2024-03-01T15:59:00Z", "o": 109, "h": 110, "l": 109, "c": 110, "v": 500, "n": 10, "vw": 109.5},
+				"dailyBar": {"t": "2024-03-01T05:00:00Z", "o": 101, "h": 111, "l": 100, "c": 109, "v": 90000, "n": 900, "vw": 105},
+				"prevDailyBar": {"t": "2024-02-29T05:00:00Z", "o": 98, "h": 101, "l": 97, "c": 100, "v": 80000, "n": 800, "vw": 99}
+			},
+			"MSFT": {
+				"dailyBar": {"t": "2024-03-01T05:00:00Z", "o": 400, "h": 410, "l": 395, "c": 396, "v": 1000, "n": 10, "vw": 400}
+			}
+		}`))
+	}))
+	defer server.Close()
+	t.Setenv("ALPACA_MARKET_DATA_URL", server.URL)
+	useEmptyCache(t)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/snapshots", GetSnapshots)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/snapshots?symbols=aapl,msft", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Snapshots map[string]models.Snapshot `json:"snapshots"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	aapl := body.Snapshots["AAPL"]
+	if aapl.LatestTrade == nil || aapl.LatestTrade.Price != 110 || aapl.LatestQuote == nil || aapl.LatestQuote.BidSize != 3 {
+		t.Errorf("unexpected trade or quote: %s", w.Body)
+	}
+	if aapl.MinuteBar == nil || aapl.DailyBar == nil || aapl.PrevDailyBar == nil || aapl.PrevDailyBar.Close != 100 {
+		t.Errorf("unexpected bars: %s", w.Body)
+	}
+	if aapl.Change == nil || *aapl.Change != 10 || aapl.ChangePct == nil || math.Abs(*aapl.ChangePct-10) > 1e-9 {
+		t.Errorf("expected +10 (10%%) from the latest trade, got %s", w.Body)
+	}
+
+	// Without a previous close there is nothing to compare against
+	msft := body.Snapshots["MSFT"]
+	if msft.LatestTrade != nil || msft.DailyBar == nil || msft.Change != nil || msft.ChangePct != nil {
+		t.Errorf("unexpected MSFT snapshot: %s", w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/snapshots", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without symbols, got %d", w.Code)
+	}
+}
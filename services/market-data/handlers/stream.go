@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/seunghoon34/trading-app/services/market-data/internal/stream"
+)
+
+// streamHeartbeat is how often idle streams get a keep-alive so proxies do not close them
+const streamHeartbeat = 15 * time.Second
+
+var streamHub = stream.NewHub(stream.DefaultLimits)
+
+// upgrader accepts any origin; the api-gateway authenticates clients before proxying them here
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// InitStream applies the per-client limits and starts the upstream connection that
+// feeds every /stream client
+func InitStream() {
+	streamHub = stream.NewHub(stream.LimitsFromEnv())
+	upstream := stream.NewAlpacaStream(stream.ConfigFromEnv(), streamHub)
+	go upstream.Run(context.Background())
+}
+
+// subscriptionQuery reads an initial subscription from ?trades=&quotes=&bars=
+func subscriptionQuery(c *gin.Context) stream.Subscription {
+	return stream.Subscription{
+		Trades: c.QueryArray("trades"),
+		Quotes: c.QueryArray("quotes"),
+		Bars:   c.QueryArray("bars"),
+	}
+}
+
+// validateSubscription checks every symbol is a valid ticker or the "*" wildcard; the
+// hub decides whether the wildcard is allowed
+func validateSubscription(sub stream.Subscription) error {
+	for _, symbols := range [][]string{sub.Trades, sub.Quotes, sub.Bars} {
+		for _, value := range symbols {
+			for _, part := range strings.Split(value, ",") {
+				if part = strings.TrimSpace(part); part == "" || part == "*" {
+					continue
+				}
+				if _, err := parseSymbol(part); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// applyClientMessage subscribes or unsubscribes the client and returns its subscription
+func applyClientMessage(client *stream.Client, msg stream.ClientMessage) (stream.Subscription, error) {
+	if err := validateSubscription(msg.Subscription); err != nil {
+		return stream.Subscription{}, err
+	}
+	switch msg.Action {
+	case "subscribe":
+		return subscribe(client, msg.Subscription)
+	case "unsubscribe":
+		return streamHub.Unsubscribe(client, msg.Subscription), nil
+	default:
+		return stream.Subscription{}, fmt.Errorf("action must be 'subscribe' or 'unsubscribe'")
+	}
+}
+
+// subscribe adds to the client's subscription, reporting the hub's limits as
+// validation errors
+func subscribe(client *stream.Client, sub stream.Subscription) (stream.Subscription, error) {
+	result, err := streamHub.Subscribe(client, sub)
+	switch {
+	case errors.Is(err, stream.ErrWildcardNotAllowed):
+		return result, invalid(CodeInvalidSymbol, "%v", err)
+	case errors.Is(err, stream.ErrTooManySymbols):
+		return result, invalid(CodeTooManySymbols, "%v", err)
+	}
+	return result, err
+}
+
+// subscriptionMessage is sent to clients whenever their subscription changes
+func subscriptionMessage(sub stream.Subscription) gin.H {
+	return gin.H{"type": "subscription", "trades": sub.Trades, "quotes": sub.Quotes, "bars": sub.Bars}
+}
+
+// StreamSSE serves market data as server-sent events. The first event carries the
+// client ID that POST /stream/:client_id/subscription uses to change the symbols.
+func StreamSSE(c *gin.Context) {
+	initial := subscriptionQuery(c)
+	if err := validateSubscription(initial); err != nil {
+		respondInvalid(c, err)
+		return
+	}
+	client := streamHub.Register()
+	defer streamHub.Unregister(client)
+	sub, err := subscribe(client, initial)
+	if err != nil {
+		respondInvalid(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+
+	connected, _ := json.Marshal(gin.H{"id": client.ID, "trades": sub.Trades, "quotes": sub.Quotes, "bars": sub.Bars})
+	fmt.Fprintf(c.Writer, "event: connected\ndata: %s\n\n", connected)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+		case event, ok := <-client.Events():
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event.Type, data)
+		}
+		c.Writer.Flush()
+	}
+}
+
+// UpdateStreamSubscription subscribes or unsubscribes symbols for an SSE client
+func UpdateStreamSubscription(c *gin.Context) {
+	client, ok := streamHub.Client(c.Param("client_id"))
+	if !ok {
+		respondError(c, http.StatusNotFound, CodeNotFound, "Stream client not found")
+		return
+	}
+
+	var msg stream.ClientMessage
+	if err := c.ShouldBindJSON(&msg); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid subscription message")
+		return
+	}
+	sub, err := applyClientMessage(client, msg)
+	if err != nil {
+		respondInvalid(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, subscriptionMessage(sub))
+}
+
+// StreamWebSocket serves market data over a WebSocket. Clients send
+// {"action": "subscribe"|"unsubscribe", "trades": [...], "quotes": [...], "bars": [...]}
+// and receive a subscription message after each change.
+func StreamWebSocket(c *gin.Context) {
+	initial := subscriptionQuery(c)
+	if err := validateSubscription(initial); err != nil {
+		respondInvalid(c, err)
+		return
+	}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade has already written the error response
+		return
+	}
+	defer conn.Close()
+
+	client := streamHub.Register()
+	defer streamHub.Unregister(client)
+
+	// Only this goroutine writes; the reader hands replies over on a channel
+	replies := make(chan interface{}, 8)
+	done := make(chan struct{})
+	stop := make(chan struct{})
+	defer close(stop)
+	reply := func(message interface{}) {
+		select {
+		case replies <- message:
+		case <-stop:
+		}
+	}
+	go func() {
+		defer close(done)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg stream.ClientMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				reply(gin.H{"type": "error", "message": "Invalid subscription message"})
+				continue
+			}
+			sub, err := applyClientMessage(client, msg)
+			if err != nil {
+				reply(gin.H{"type": "error", "message": err.Error()})
+				continue
+			}
+			reply(subscriptionMessage(sub))
+		}
+	}()
+
+	if !initial.Empty() {
+		if sub, err := subscribe(client, initial); err != nil {
+			reply(gin.H{"type": "error", "message": err.Error()})
+		} else {
+			reply(subscriptionMessage(sub))
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case <-heartbeat.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second))
+		case message := <-replies:
+			err = conn.WriteJSON(message)
+		case event, ok := <-client.Events():
+			if !ok {
+				return
+			}
+			err = conn.WriteJSON(event)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
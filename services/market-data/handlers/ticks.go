@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/market-data/config"
+	"github.com/seunghoon34/trading-app/services/market-data/internal/export"
+)
+
+// maxTickPage is the most ticks Alpaca returns per page
+const maxTickPage = 10000
+
+// TickQuery holds the parameters accepted by the tick export endpoints
+type TickQuery struct {
+	Symbol    string
+	Start     time.Time
+	End       time.Time
+	Feed      string
+	Sort      string
+	PageToken string
+	// Limit caps the rows returned; zero streams the whole range
+	Limit  int
+	Format export.Format
+}
+
+// alpacaTicksPage is one page of Alpaca's single-symbol trades or quotes response
+type alpacaTicksPage struct {
+	Trades        []alpacaTrade `json:"trades"`
+	Quotes        []alpacaQuote `json:"quotes"`
+	NextPageToken *string       `json:"next_page_token"`
+}
+
+// tickKind describes one exportable tick dataset
+type tickKind struct {
+	name    string
+	columns []export.Column
+	rows    func(symbol string, page alpacaTicksPage) [][]interface{}
+}
+
+var tradeTicks = tickKind{
+	name: "trades",
+	columns: []export.Column{
+		{Name: "symbol", Type: export.String},
+		{Name: "timestamp", Type: export.Timestamp},
+		{Name: "price", Type: export.Float64},
+		{Name: "size", Type: export.Float64},
+		{Name: "exchange", Type: export.String},
+		{Name: "id", Type: export.Int64},
+		{Name: "conditions", Type: export.String},
+		{Name: "tape", Type: export.String},
+	},
+	rows: func(symbol string, page alpacaTicksPage) [][]interface{} {
+		rows := make([][]interface{}, len(page.Trades))
+		for i, t := range page.Trades {
+			rows[i] = []interface{}{symbol, t.Timestamp, t.Price, t.Size, t.Exchange, t.ID, strings.Join(t.Conditions, " "), t.Tape}
+		}
+		return rows
+	},
+}
+
+var quoteTicks = tickKind{
+	name: "quotes",
+	columns: []export.Column{
+		{Name: "symbol", Type: export.String},
+		{Name: "timestamp", Type: export.Timestamp},
+		{Name: "bid_price", Type: export.Float64},
+		{Name: "bid_size", Type: export.Float64},
+		{Name: "bid_exchange", Type: export.String},
+		{Name: "ask_price", Type: export.Float64},
+		{Name: "ask_size", Type: export.Float64},
+		{Name: "ask_exchange", Type: export.String},
+		{Name: "conditions", Type: export.String},
+		{Name: "tape", Type: export.String},
+	},
+	rows: func(symbol string, page alpacaTicksPage) [][]interface{} {
+		rows := make([][]interface{}, len(page.Quotes))
+		for i, q := range page.Quotes {
+			rows[i] = []interface{}{symbol, q.Timestamp, q.BidPrice, q.BidSize, q.BidExchange,
+				q.AskPrice, q.AskSize, q.AskExchange, strings.Join(q.Conditions, " "), q.Tape}
+		}
+		return rows
+	},
+}
+
+// exportFormat picks the format from ?format, falling back to the Accept header and
+// then to JSON lines
+func exportFormat(c *gin.Context) (export.Format, error) {
+	switch format := strings.ToLower(c.Query("format")); format {
+	case "jsonl", "ndjson":
+		return export.FormatJSONL, nil
+	case "csv":
+		return export.FormatCSV, nil
+	case "parquet":
+		return export.FormatParquet, nil
+	case "":
+	default:
+		return "", fmt.Errorf("format must be one of jsonl, csv or parquet")
+	}
+
+	accept := strings.ToLower(c.GetHeader("Accept"))
+	switch {
+	case strings.Contains(accept, "text/csv"):
+		return export.FormatCSV, nil
+	case strings.Contains(accept, "parquet"):
+		return export.FormatParquet, nil
+	default:
+		return export.FormatJSONL, nil
+	}
+}
+
+// parseTickQuery reads and validates the tick export parameters. start defaults to
+// the beginning of the exchange's current day.
+func parseTickQuery(c *gin.Context) (TickQuery, error) {
+	q := TickQuery{
+		Feed:      c.Query("feed"),
+		Sort:      c.DefaultQuery("sort", "asc"),
+		PageToken: c.Query("page_token"),
+	}
+
+	var err error
+	if q.Symbol, err = parseSymbol(c.Param("symbol")); err != nil {
+		return q, err
+	}
+	if q.Format, err = exportFormat(c); err != nil {
+		return q, err
+	}
+	if q.Feed != "" && !validFeeds[q.Feed] {
+		return q, errors.New("feed must be one of iex, sip or otc")
+	}
+	if q.Sort != "asc" && q.Sort != "desc" {
+		return q, errors.New("sort must be 'asc' or 'desc'")
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil || q.Limit < 1 {
+			return q, errors.New("limit must be a positive number")
+		}
+	}
+
+	if q.Start, err = parseTimestamp(c.Query("start")); err != nil {
+		return q, errors.New("start must be an RFC3339 timestamp or a YYYY-MM-DD date")
+	}
+	if q.Start.IsZero() {
+		y, m, d := now().In(marketTZ).Date()
+		q.Start = time.Date(y, m, d, 0, 0, 0, 0, marketTZ)
+	}
+	if q.End, err = parseTimestamp(c.Query("end")); err != nil {
+		return q, errors.New("end must be an RFC3339 timestamp or a YYYY-MM-DD date")
+	}
+	if !q.End.IsZero() && !q.Start.Before(q.End) {
+		return q, errors.New("start must be before end")
+	}
+	return q, nil
+}
+
+// fetchTicksPage fetches one page of ticks starting at pageToken
+func fetchTicksPage(kind tickKind, q TickQuery, pageToken string, size int) (alpacaTicksPage, error) {
+	params := url.Values{}
+	params.Set("start", q.Start.UTC().Format(time.RFC3339Nano))
+	if !q.End.IsZero() {
+		params.Set("end", q.End.UTC().Format(time.RFC3339Nano))
+	}
+	if q.Feed != "" {
+		params.Set("feed", q.Feed)
+	}
+	params.Set("sort", q.Sort)
+	params.Set("limit", strconv.Itoa(size))
+	if pageToken != "" {
+		params.Set("page_token", pageToken)
+	}
+
+	var page alpacaTicksPage
+	data, err := config.GetMarketData(fmt.Sprintf("/v2/stocks/%s/%s", url.PathEscape(q.Symbol), kind.name), params)
+	if err != nil {
+		return page, err
+	}
+	if err := json.Unmarshal(data, &page); err != nil {
+		return page, fmt.Errorf("invalid %s response: %w", kind.name, err)
+	}
+	return page, nil
+}
+
+// streamTicks writes the range page by page, flushing each page to the client so only
+// one page is held in memory. Errors before the first byte get a normal error
+// response; later ones can only be reported in the X-Export-Error trailer. When limit
+// stops the export early, the X-Next-Page-Token trailer continues it.
+func streamTicks(c *gin.Context, kind tickKind) {
+	q, err := parseTickQuery(c)
+	if err != nil {
+		respondInvalid(c, err)
+		return
+	}
+
+	pageSize := func(written int) int {
+		if q.Limit > 0 && q.Limit-written < maxTickPage {
+			return q.Limit - written
+		}
+		return maxTickPage
+	}
+	page, err := fetchTicksPage(kind, q, q.PageToken, pageSize(0))
+	if err != nil {
+		respondUpstreamError(c, err, fmt.Sprintf("Failed to fetch %s for %s", kind.name, q.Symbol))
+		return
+	}
+
+	c.Header("Content-Type", q.Format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.%s"`, q.Symbol, kind.name, q.Format))
+	c.Header("Trailer", "X-Next-Page-Token, X-Export-Error")
+	c.Status(http.StatusOK)
+
+	writer, err := export.NewWriter(q.Format, c.Writer, kind.columns)
+	if err != nil {
+		c.Writer.Header().Set("X-Export-Error", err.Error())
+		return
+	}
+
+	written := 0
+	for {
+		for _, row := range kind.rows(q.Symbol, page) {
+			if err := writer.Write(row); err != nil {
+				c.Writer.Header().Set("X-Export-Error", err.Error())
+				return
+			}
+			written++
+		}
+		if err := writer.Flush(); err != nil {
+			// The client has gone away
+			return
+		}
+		c.Writer.Flush()
+
+		if page.NextPageToken == nil || *page.NextPageToken == "" {
+			break
+		}
+		if q.Limit > 0 && written >= q.Limit {
+			c.Writer.Header().Set("X-Next-Page-Token", *page.NextPageToken)
+			break
+		}
+		if c.Request.Context().Err() != nil {
+			return
+		}
+
+		if page, err = fetchTicksPage(kind, q, *page.NextPageToken, pageSize(written)); err != nil {
+			// The file is left unfinished so a truncated export is not mistaken for a complete one
+			log.Printf("%s export for %s failed after %d rows: %v", kind.name, q.Symbol, written, err)
+			c.Writer.Header().Set("X-Export-Error", err.Error())
+			return
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return
+	}
+}
+
+// GetTrades exports a symbol's trades over a time range as JSON lines, CSV or Parquet
+func GetTrades(c *gin.Context) {
+	streamTicks(c, tradeTicks)
+}
+
+// GetQuoteHistory exports a symbol's quotes over a time range as JSON lines, CSV or Parquet
+func GetQuoteHistory(c *gin.Context) {
+	streamTicks(c, quoteTicks)
+}
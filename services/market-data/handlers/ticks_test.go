@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveTrades answers /v2/stocks/AAPL/trades with pages chained by page_token
+func serveTrades(t *testing.T, pages map[string]string) *[]string {
+	t.Helper()
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/stocks/AAPL/trades" {
+			http.NotFound(w, r)
+			return
+		}
+		requests = append(requests, r.URL.RawQuery)
+		body, ok := pages[r.URL.Query().Get("page_token")]
+		if !ok {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"message":"invalid page_token"}`))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("ALPACA_MARKET_DATA_URL", server.URL)
+	return &requests
+}
+
+var tradePages = map[string]string{
+	"": `{"symbol":"AAPL","trades":[
+		{"t":"2024-03-01T14:30:00Z","x":"V","p":180.5,"s":100,"c":["@","I"],"i":1,"z":"C"},
+		{"t":"2024-03-01T14:30:01Z","x":"P","p":180.75,"s":5,"c":["@"],"i":2,"z":"C"}
+	],"next_page_token":"p2"}`,
+	"p2": `{"symbol":"AAPL","trades":[
+		{"t":"2024-03-01T14:30:02Z","x":"V","p":181,"s":10,"c":[],"i":3,"z":"C"}
+	],"next_page_token":null}`,
+}
+
+func tickRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/trades/:symbol", GetTrades)
+	return r
+}
+
+func TestGetTradesStreamsAllPages(t *testing.T) {
+	requests := serveTrades(t, tradePages)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/trades/aapl?start=2024-03-01", nil)
+	req.Header.Set("Accept", "text/csv")
+	tickRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("expected text/csv from the Accept header, got %s", ct)
+	}
+	want := "symbol,timestamp,price,size,exchange,id,conditions,tape\n" +
+		"AAPL,2024-03-01T14:30:00Z,180.5,100,V,1,@ I,C\n" +
+		"AAPL,2024-03-01T14:30:01Z,180.75,5,P,2,@,C\n" +
+		"AAPL,2024-03-01T14:30:02Z,181,10,V,3,,C\n"
+	if w.Body.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, w.Body)
+	}
+	if len(*requests) != 2 || !strings.Contains((*requests)[1], "page_token=p2") {
+		t.Errorf("expected two upstream pages, got %v", *requests)
+	}
+}
+
+func TestGetTradesLimitReturnsNextPageToken(t *testing.T) {
+	requests := serveTrades(t, tradePages)
+
+	w := httptest.NewRecorder()
+	tickRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/trades/AAPL?start=2024-03-01&limit=2&format=jsonl", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], `{"symbol":"AAPL","timestamp":"2024-03-01T14:30:00Z","price":180.5`) {
+		t.Errorf("expected two JSON lines, got %s", w.Body)
+	}
+	if token := w.Header().Get("X-Next-Page-Token"); token != "p2" {
+		t.Errorf("expected next page token p2, got %q", token)
+	}
+	if len(*requests) != 1 || !strings.Contains((*requests)[0], "limit=2") {
+		t.Errorf("expected one upstream page of 2, got %v", *requests)
+	}
+}
+
+func TestGetTradesErrors(t *testing.T) {
+	serveTrades(t, tradePages)
+
+	for _, tc := range []struct {
+		query string
+		code  int
+	}{
+		{"/trades/AAPL?format=xml", http.StatusBadRequest},
+		{"/trades/AAPL?limit=0", http.StatusBadRequest},
+		{"/trades/AAPL?start=2024-03-02&end=2024-03-01", http.StatusBadRequest},
+		{"/trades/AAPL?start=2024-03-01&page_token=bad", http.StatusBadRequest},
+	} {
+		w := httptest.NewRecorder()
+		tickRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.query, nil))
+		if w.Code != tc.code {
+			t.Errorf("%s: expected %d, got %d: %s", tc.query, tc.code, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"regexp"
+	"strings"
+)
+
+// maxSymbols caps how many symbols one request may ask for
+const maxSymbols = 100
+
+// symbolPattern matches exchange tickers such as AAPL, BRK.B or BF-B
+var symbolPattern = regexp.MustCompile(`^[A-Z][A-Z0-9.\-]{0,10}$`)
+
+// parseSymbol normalizes and validates a single symbol
+func parseSymbol(value string) (string, error) {
+	symbol := strings.ToUpper(strings.TrimSpace(value))
+	if symbol == "" {
+		return "", invalid(CodeInvalidSymbol, "symbol is required")
+	}
+	if !symbolPattern.MatchString(symbol) {
+		return "", invalid(CodeInvalidSymbol, "invalid symbol %q", value)
+	}
+	return symbol, nil
+}
+
+// parseSymbolList splits comma-separated symbol lists, normalizing, validating and
+// de-duplicating them in order. At least one and at most maxSymbols are accepted.
+func parseSymbolList(values ...string) ([]string, error) {
+	var symbols []string
+	seen := make(map[string]bool)
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+			symbol, err := parseSymbol(part)
+			if err != nil {
+				return nil, err
+			}
+			if seen[symbol] {
+				continue
+			}
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	if len(symbols) == 0 {
+		return nil, invalid(CodeInvalidSymbol, "at least one symbol is required")
+	}
+	if len(symbols) > maxSymbols {
+		return nil, invalid(CodeTooManySymbols, "at most %d symbols may be requested at once", maxSymbols)
+	}
+	return symbols, nil
+}
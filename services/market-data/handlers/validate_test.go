@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+func TestParseSymbolList(t *testing.T) {
+	var many []string
+	for i := 0; i <= maxSymbols; i++ {
+		many = append(many, fmt.Sprintf("S%d", i))
+	}
+	tooMany := strings.Join(many, ",")
+	tests := []struct {
+		name     string
+		values   []string
+		want     []string
+		wantCode string
+	}{
+		{"comma lists and repeats", []string{"aapl, msft", "TSLA,aapl"}, []string{"AAPL", "MSFT", "TSLA"}, ""},
+		{"share classes", []string{"brk.b,BF-B"}, []string{"BRK.B", "BF-B"}, ""},
+		{"blanks only", []string{" , ,"}, nil, CodeInvalidSymbol},
+		{"path injection", []string{"AAPL/../../v2/account"}, nil, CodeInvalidSymbol},
+		{"query injection", []string{"AAPL?feed=sip"}, nil, CodeInvalidSymbol},
+		{"too long", []string{"ABCDEFGHIJKLM"}, nil, CodeInvalidSymbol},
+		{"too many", []string{tooMany}, nil, CodeTooManySymbols},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSymbolList(tt.values...)
+			if tt.wantCode != "" {
+				reqErr, ok := err.(*requestError)
+				if !ok || reqErr.code != tt.wantCode {
+					t.Fatalf("expected %s, got %v", tt.wantCode, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestUpstreamErrorsMapToTypedResponses(t *testing.T) {
+	tests := []struct {
+		upstream   int
+		wantStatus int
+		wantCode   string
+	}{
+		{http.StatusBadRequest, http.StatusBadRequest, CodeInvalidRequest},
+		{http.StatusForbidden, http.StatusBadGateway, CodeUpstreamError},
+		{http.StatusNotFound, http.StatusNotFound, CodeNotFound},
+		{http.StatusTooManyRequests, http.StatusTooManyRequests, CodeRateLimited},
+		{http.StatusInternalServerError, http.StatusBadGateway, CodeUpstreamError},
+	}
+	gin.SetMode(gin.TestMode)
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.upstream), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "3")
+				w.WriteHeader(tt.upstream)
+				w.Write([]byte(`{"code": 40010001, "message": "upstream says no"}`))
+			}))
+			defer server.Close()
+			t.Setenv("ALPACA_MARKET_DATA_URL", server.URL)
+			useEmptyCache(t)
+
+			r := gin.New()
+			r.GET("/quotes/:symbol", GetLatestQuote)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/quotes/AAPL", nil))
+
+			var body models.ErrorResponse
+			json.Unmarshal(w.Body.Bytes(), &body)
+			if w.Code != tt.wantStatus || body.Code != tt.wantCode || body.UpstreamStatus != tt.upstream {
+				t.Errorf("expected %d %s, got %d: %s", tt.wantStatus, tt.wantCode, w.Code, w.Body)
+			}
+			if !strings.Contains(body.Error, "upstream says no") {
+				t.Errorf("expected the upstream message in %q", body.Error)
+			}
+			if tt.upstream == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "3" {
+				t.Errorf("expected Retry-After to be passed through")
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+// Package indicators computes technical indicators over OHLCV bars. Every function
+// returns one value per input bar, aligned with the input, and NaN for the bars
+// before the indicator has enough history.
+package indicators
+
+import (
+	"math"
+	"time"
+
+	// The Docker image has no zoneinfo; VWAP needs New York's calendar
+	_ "time/tzdata"
+
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+// Closes returns the close price of each bar
+func Closes(bars []models.Bar) []float64 {
+	closes := make([]float64, len(bars))
+	for i, bar := range bars {
+		closes[i] = bar.Close
+	}
+	return closes
+}
+
+func nans(n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = math.NaN()
+	}
+	return values
+}
+
+// SMA is the simple moving average of values over period
+func SMA(values []float64, period int) []float64 {
+	out := nans(len(values))
+	if period < 1 {
+		return out
+	}
+	var sum float64
+	for i, v := range values {
+		sum += v
+		if i >= period {
+			sum -= values[i-period]
+		}
+		if i >= period-1 {
+			out[i] = sum / float64(period)
+		}
+	}
+	return out
+}
+
+// EMA is the exponential moving average of values over period, seeded with the SMA
+// of the first period values. Leading NaNs in values are skipped, so an EMA can be
+// taken of another indicator's output.
+func EMA(values []float64, period int) []float64 {
+	out := nans(len(values))
+	start := 0
+	for start < len(values) && math.IsNaN(values[start]) {
+		start++
+	}
+	if period < 1 || len(values)-start < period {
+		return out
+	}
+
+	var sum float64
+	for _, v := range values[start : start+period] {
+		sum += v
+	}
+	seed := start + period - 1
+	out[seed] = sum / float64(period)
+
+	k := 2 / float64(period+1)
+	for i := seed + 1; i < len(values); i++ {
+		out[i] = (values[i]-out[i-1])*k + out[i-1]
+	}
+	return out
+}
+
+// RSI is Wilder's relative strength index over period
+func RSI(values []float64, period int) []float64 {
+	out := nans(len(values))
+	if period < 1 || len(values) <= period {
+		return out
+	}
+
+	var avgGain, avgLoss float64
+	for i := 1; i <= period; i++ {
+		gain, loss := change(values[i] - values[i-1])
+		avgGain += gain
+		avgLoss += loss
+	}
+	avgGain /= float64(period)
+	avgLoss /= float64(period)
+	out[period] = rsi(avgGain, avgLoss)
+
+	for i := period + 1; i < len(values); i++ {
+		gain, loss := change(values[i] - values[i-1])
+		avgGain = (avgGain*float64(period-1) + gain) / float64(period)
+		avgLoss = (avgLoss*float64(period-1) + loss) / float64(period)
+		out[i] = rsi(avgGain, avgLoss)
+	}
+	return out
+}
+
+func change(delta float64) (gain, loss float64) {
+	if delta > 0 {
+		return delta, 0
+	}
+	return 0, -delta
+}
+
+func rsi(avgGain, avgLoss float64) float64 {
+	if avgLoss == 0 {
+		return 100
+	}
+	return 100 - 100/(1+avgGain/avgLoss)
+}
+
+// MACDResult holds the MACD line, its signal line and their difference
+type MACDResult struct {
+	MACD      []float64
+	Signal    []float64
+	Histogram []float64
+}
+
+// MACD is the difference of the fast and slow EMAs, with an EMA of that
+// difference over signal periods as the signal line
+func MACD(values []float64, fast, slow, signal int) MACDResult {
+	fastEMA, slowEMA := EMA(values, fast), EMA(values, slow)
+	line := make([]float64, len(values))
+	for i := range values {
+		line[i] = fastEMA[i] - slowEMA[i]
+	}
+	signalLine := EMA(line, signal)
+	histogram := make([]float64, len(values))
+	for i := range values {
+		histogram[i] = line[i] - signalLine[i]
+	}
+	return MACDResult{MACD: line, Signal: signalLine, Histogram: histogram}
+}
+
+// BandsResult holds Bollinger Bands
+type BandsResult struct {
+	Upper  []float64
+	Middle []float64
+	Lower  []float64
+}
+
+// Bollinger is the SMA over period with bands k population standard deviations away
+func Bollinger(values []float64, period int, k float64) BandsResult {
+	middle := SMA(values, period)
+	upper, lower := nans(len(values)), nans(len(values))
+	if period < 1 {
+		return BandsResult{Upper: upper, Middle: middle, Lower: lower}
+	}
+	for i := period - 1; i < len(values); i++ {
+		var variance float64
+		for _, v := range values[i-period+1 : i+1] {
+			variance += (v - middle[i]) * (v - middle[i])
+		}
+		sd := math.Sqrt(variance / float64(period))
+		upper[i] = middle[i] + k*sd
+		lower[i] = middle[i] - k*sd
+	}
+	return BandsResult{Upper: upper, Middle: middle, Lower: lower}
+}
+
+// ATR is Wilder's average true range over period. The first bar's true range is
+// its high-low range, as there is no previous close.
+func ATR(bars []models.Bar, period int) []float64 {
+	out := nans(len(bars))
+	if period < 1 || len(bars) < period {
+		return out
+	}
+
+	tr := make([]float64, len(bars))
+	for i, bar := range bars {
+		tr[i] = bar.High - bar.Low
+		if i > 0 {
+			prevClose := bars[i-1].Close
+			tr[i] = math.Max(tr[i], math.Max(math.Abs(bar.High-prevClose), math.Abs(bar.Low-prevClose)))
+		}
+	}
+
+	var sum float64
+	for _, v := range tr[:period] {
+		sum += v
+	}
+	out[period-1] = sum / float64(period)
+	for i := period; i < len(bars); i++ {
+		out[i] = (out[i-1]*float64(period-1) + tr[i]) / float64(period)
+	}
+	return out
+}
+
+// marketTZ is the exchange time zone; a VWAP session is one New York calendar day
+var marketTZ, _ = time.LoadLocation("America/New_York")
+
+// VWAP is the volume-weighted average of each bar's typical price (high+low+close)/3,
+// accumulated from the first bar of each trading day
+func VWAP(bars []models.Bar) []float64 {
+	out := nans(len(bars))
+	var pv, volume float64
+	var session string
+	for i, bar := range bars {
+		if day := bar.Timestamp.In(marketTZ).Format("2006-01-02"); day != session {
+			session, pv, volume = day, 0, 0
+		}
+		pv += (bar.High + bar.Low + bar.Close) / 3 * bar.Volume
+		volume += bar.Volume
+		if volume > 0 {
+			out[i] = pv / volume
+		}
+	}
+	return out
+}
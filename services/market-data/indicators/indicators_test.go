@@ -0,0 +1,163 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+// nan marks a warm-up position in expected series
+var nan = math.NaN()
+
+func assertSeries(t *testing.T, name string, got, want []float64, tolerance float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: expected %d values, got %d", name, len(want), len(got))
+	}
+	for i := range want {
+		if math.IsNaN(want[i]) {
+			if !math.IsNaN(got[i]) {
+				t.Errorf("%s[%d]: expected warm-up NaN, got %v", name, i, got[i])
+			}
+			continue
+		}
+		if math.Abs(got[i]-want[i]) > tolerance {
+			t.Errorf("%s[%d]: expected %v, got %v", name, i, want[i], got[i])
+		}
+	}
+}
+
+func TestMovingAverages(t *testing.T) {
+	// EMA reference values are StockCharts' 10-day EMA worked example
+	stockcharts := []float64{22.27, 22.19, 22.08, 22.17, 22.18, 22.13, 22.23, 22.43, 22.24, 22.29, 22.15, 22.39, 22.38, 22.61, 23.36}
+
+	tests := []struct {
+		name      string
+		fn        func([]float64, int) []float64
+		values    []float64
+		period    int
+		want      []float64
+		tolerance float64
+	}{
+		{"sma", SMA, []float64{1, 2, 3, 4, 5}, 3, []float64{nan, nan, 2, 3, 4}, 1e-9},
+		{"sma period 1", SMA, []float64{4, 5}, 1, []float64{4, 5}, 1e-9},
+		{"sma too short", SMA, []float64{1, 2}, 3, []float64{nan, nan}, 1e-9},
+		{"ema stockcharts", EMA, stockcharts, 10,
+			[]float64{nan, nan, nan, nan, nan, nan, nan, nan, nan, 22.22, 22.21, 22.24, 22.27, 22.33, 22.52}, 0.005},
+		{"ema skips leading nan", EMA, []float64{nan, 2, 4, 6}, 2, []float64{nan, nan, 3, 5}, 1e-9},
+		{"ema too short", EMA, []float64{1, 2}, 3, []float64{nan, nan}, 1e-9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSeries(t, tt.name, tt.fn(tt.values, tt.period), tt.want, tt.tolerance)
+		})
+	}
+}
+
+func TestRSI(t *testing.T) {
+	// StockCharts' 14-day RSI worked example. Its table rounds the running averages,
+	// so the expected values are the unrounded ones TA-Lib produces.
+	closes := []float64{44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08, 45.89, 46.03, 45.61, 46.28, 46.28, 46.00, 46.03, 46.41, 46.22, 45.64}
+
+	tests := []struct {
+		name   string
+		values []float64
+		period int
+		want   []float64
+	}{
+		{"stockcharts", closes, 14, append(append([]float64{}, nans(14)...), 70.46, 66.25, 66.48, 69.35, 66.29, 57.92)},
+		{"only gains", []float64{1, 2, 3, 4}, 2, []float64{nan, nan, 100, 100}},
+		{"only losses", []float64{4, 3, 2, 1}, 2, []float64{nan, nan, 0, 0}},
+		{"too short", []float64{1, 2}, 2, []float64{nan, nan}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSeries(t, "rsi", RSI(tt.values, tt.period), tt.want, 0.01)
+		})
+	}
+}
+
+func TestMACD(t *testing.T) {
+	closes := []float64{10, 11, 12, 11, 13, 15, 14, 16}
+	got := MACD(closes, 3, 5, 3)
+
+	assertSeries(t, "macd", got.MACD, []float64{nan, nan, nan, nan, 0.6, 0.9, 0.683333, 0.830556}, 1e-6)
+	assertSeries(t, "signal", got.Signal, []float64{nan, nan, nan, nan, nan, nan, 0.727778, 0.779167}, 1e-6)
+	assertSeries(t, "histogram", got.Histogram, []float64{nan, nan, nan, nan, nan, nan, -0.044444, 0.051389}, 1e-6)
+}
+
+func TestBollinger(t *testing.T) {
+	tests := []struct {
+		name                 string
+		values               []float64
+		period               int
+		k                    float64
+		upper, middle, lower []float64
+	}{
+		{
+			name: "rising", values: []float64{1, 2, 3, 4, 5}, period: 3, k: 2,
+			upper:  []float64{nan, nan, 3.632993, 4.632993, 5.632993},
+			middle: []float64{nan, nan, 2, 3, 4},
+			lower:  []float64{nan, nan, 0.367007, 1.367007, 2.367007},
+		},
+		{
+			name: "flat", values: []float64{5, 5, 5}, period: 2, k: 2,
+			upper:  []float64{nan, 5, 5},
+			middle: []float64{nan, 5, 5},
+			lower:  []float64{nan, 5, 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Bollinger(tt.values, tt.period, tt.k)
+			assertSeries(t, "upper", got.Upper, tt.upper, 1e-6)
+			assertSeries(t, "middle", got.Middle, tt.middle, 1e-6)
+			assertSeries(t, "lower", got.Lower, tt.lower, 1e-6)
+		})
+	}
+}
+
+func TestATR(t *testing.T) {
+	bars := []models.Bar{
+		{High: 12, Low: 10, Close: 11},
+		{High: 13, Low: 11, Close: 12.5},
+		{High: 12.8, Low: 11.5, Close: 12},
+		{High: 14, Low: 12.2, Close: 13.8}, // gaps above the previous close
+		{High: 13.5, Low: 12, Close: 12.4},
+	}
+
+	tests := []struct {
+		name   string
+		period int
+		want   []float64
+	}{
+		{"period 3", 3, []float64{nan, nan, 1.766667, 1.844444, 1.829630}},
+		{"period 1 is the true range", 1, []float64{2, 2, 1.3, 2, 1.8}},
+		{"too short", 6, []float64{nan, nan, nan, nan, nan}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSeries(t, "atr", ATR(bars, tt.period), tt.want, 1e-6)
+		})
+	}
+}
+
+func TestVWAPResetsEachSession(t *testing.T) {
+	day1 := time.Date(2024, 1, 2, 14, 30, 0, 0, time.UTC)
+	day2 := time.Date(2024, 1, 3, 14, 30, 0, 0, time.UTC)
+	bars := []models.Bar{
+		{Timestamp: day1, High: 11, Low: 9, Close: 10, Volume: 100},                       // typical 10
+		{Timestamp: day1.Add(time.Minute), High: 13, Low: 11, Close: 12, Volume: 300},     // typical 12
+		{Timestamp: day1.Add(6 * time.Hour), High: 12, Low: 12, Close: 12, Volume: 0},     // no volume
+		{Timestamp: day2, High: 21, Low: 19, Close: 20, Volume: 50},                       // new session
+		{Timestamp: day2.Add(time.Minute), High: 22.5, Low: 21.5, Close: 22, Volume: 150}, // typical 22
+	}
+
+	assertSeries(t, "vwap", VWAP(bars), []float64{10, 11.5, 11.5, 20, 21.5}, 1e-9)
+}
@@ -0,0 +1,227 @@
+// file: market-data/internal/assets/catalog.go
+package assets
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+// Match tiers, best first
+const (
+	matchExactSymbol = iota
+	matchSymbolPrefix
+	matchNamePrefix
+	matchNameWordPrefix
+	matchFuzzySymbol
+	matchFuzzyName
+)
+
+// entry is an asset with the lower-cased fields search compares against
+type entry struct {
+	asset  models.Asset
+	symbol string
+	name   string
+	words  []string
+}
+
+// Catalog is the in-memory asset master. A sync replaces it wholesale, so readers
+// always see one consistent snapshot of the broker's list.
+type Catalog struct {
+	mu       sync.RWMutex
+	entries  []entry
+	bySymbol map[string]int
+	syncedAt time.Time
+}
+
+// NewCatalog creates an empty catalog
+func NewCatalog() *Catalog {
+	return &Catalog{bySymbol: make(map[string]int)}
+}
+
+// Replace swaps the catalog's contents for assets
+func (c *Catalog) Replace(assets []models.Asset) {
+	entries := make([]entry, 0, len(assets))
+	bySymbol := make(map[string]int, len(assets))
+	for _, asset := range assets {
+		symbol := strings.ToUpper(asset.Symbol)
+		if symbol == "" {
+			continue
+		}
+		if _, dup := bySymbol[symbol]; dup {
+			continue
+		}
+		name := strings.ToLower(asset.Name)
+		bySymbol[symbol] = len(entries)
+		entries = append(entries, entry{
+			asset:  asset,
+			symbol: strings.ToLower(symbol),
+			name:   name,
+			words:  strings.FieldsFunc(name, func(r rune) bool { return !isAlphanumeric(r) }),
+		})
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = entries
+	c.bySymbol = bySymbol
+	c.syncedAt = time.Now()
+}
+
+// Len returns how many assets the catalog holds
+func (c *Catalog) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// SyncedAt returns when the catalog was last replaced, zero if never
+func (c *Catalog) SyncedAt() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.syncedAt
+}
+
+// Get looks up an asset by symbol, ignoring case
+func (c *Catalog) Get(symbol string) (models.Asset, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	i, ok := c.bySymbol[strings.ToUpper(strings.TrimSpace(symbol))]
+	if !ok {
+		return models.Asset{}, false
+	}
+	return c.entries[i].asset, true
+}
+
+// Search returns up to limit assets matching query on symbol or name. Exact and
+// prefix matches rank ahead of fuzzy ones, which tolerate a typo or two.
+func (c *Catalog) Search(query string, limit int) []models.Asset {
+	q := strings.ToLower(strings.TrimSpace(query))
+	if q == "" || limit <= 0 {
+		return nil
+	}
+
+	type hit struct {
+		index    int
+		tier     int
+		distance int
+	}
+	var hits []hit
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for i, e := range c.entries {
+		if tier, distance, ok := match(q, e); ok {
+			hits = append(hits, hit{index: i, tier: tier, distance: distance})
+		}
+	}
+
+	sort.Slice(hits, func(a, b int) bool {
+		ha, hb := hits[a], hits[b]
+		if ha.tier != hb.tier {
+			return ha.tier < hb.tier
+		}
+		if ha.distance != hb.distance {
+			return ha.distance < hb.distance
+		}
+		ea, eb := c.entries[ha.index], c.entries[hb.index]
+		// Tradable listings before the rest, then shorter symbols, e.g. AAPL before AAPLW
+		if ea.asset.Tradable != eb.asset.Tradable {
+			return ea.asset.Tradable
+		}
+		if len(ea.symbol) != len(eb.symbol) {
+			return len(ea.symbol) < len(eb.symbol)
+		}
+		return ea.symbol < eb.symbol
+	})
+
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	results := make([]models.Asset, len(hits))
+	for i, h := range hits {
+		results[i] = c.entries[h.index].asset
+	}
+	return results
+}
+
+// match ranks how well the lower-cased query q matches e
+func match(q string, e entry) (tier, distance int, ok bool) {
+	switch {
+	case e.symbol == q:
+		return matchExactSymbol, 0, true
+	case strings.HasPrefix(e.symbol, q):
+		return matchSymbolPrefix, len(e.symbol) - len(q), true
+	case strings.HasPrefix(e.name, q):
+		return matchNamePrefix, 0, true
+	}
+	for _, word := range e.words {
+		if strings.HasPrefix(word, q) {
+			return matchNameWordPrefix, 0, true
+		}
+	}
+
+	allowed := maxTypos(q)
+	if allowed == 0 {
+		return 0, 0, false
+	}
+	if d := levenshtein(q, e.symbol); d <= allowed {
+		return matchFuzzySymbol, d, true
+	}
+	best := allowed + 1
+	for _, word := range e.words {
+		// Compare against the start of longer words so "appl" still finds "apple"
+		if len(word) > len(q)+allowed {
+			word = word[:len(q)+allowed]
+		}
+		if d := levenshtein(q, word); d < best {
+			best = d
+		}
+	}
+	if best <= allowed {
+		return matchFuzzyName, best, true
+	}
+	return 0, 0, false
+}
+
+// maxTypos is how many edits a query of this length may be from a match; very
+// short queries must match exactly or they would match almost everything
+func maxTypos(q string) int {
+	switch n := len(q); {
+	case n < 3:
+		return 0
+	case n < 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func isAlphanumeric(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9')
+}
@@ -0,0 +1,98 @@
+package assets
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+func testCatalog() *Catalog {
+	catalog := NewCatalog()
+	catalog.Replace([]models.Asset{
+		{Symbol: "AAPL", Name: "Apple Inc. Common Stock", Tradable: true},
+		{Symbol: "AAP", Name: "Advance Auto Parts Inc.", Tradable: true},
+		{Symbol: "AAPLW", Name: "Apple Warrant", Tradable: false},
+		{Symbol: "MSFT", Name: "Microsoft Corporation Common Stock", Tradable: true},
+		{Symbol: "BRK.B", Name: "Berkshire Hathaway Inc. Class B", Tradable: true},
+		{Symbol: "PINE", Name: "Alpine Income Property Trust", Tradable: true},
+	})
+	return catalog
+}
+
+func symbols(assets []models.Asset) []string {
+	out := make([]string, len(assets))
+	for i, a := range assets {
+		out[i] = a.Symbol
+	}
+	return out
+}
+
+func TestCatalogGet(t *testing.T) {
+	catalog := testCatalog()
+	if asset, ok := catalog.Get(" brk.b "); !ok || asset.Name != "Berkshire Hathaway Inc. Class B" {
+		t.Errorf("expected BRK.B, got %+v %v", asset, ok)
+	}
+	if _, ok := catalog.Get("NOPE"); ok {
+		t.Error("expected NOPE to be unknown")
+	}
+}
+
+func TestCatalogSearch(t *testing.T) {
+	catalog := testCatalog()
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// Exact symbol first, then prefixes with tradable and shorter symbols ahead
+		{"aap", []string{"AAP", "AAPL", "AAPLW"}},
+		{"micro", []string{"MSFT"}},
+		// Word prefix in the middle of a name
+		{"hathaway", []string{"BRK.B"}},
+		// One typo in a symbol or a name
+		{"msfy", []string{"MSFT"}},
+		{"mircosoft", []string{"MSFT"}},
+		// Too short to be fuzzy
+		{"zz", nil},
+	}
+	for _, tt := range tests {
+		got := symbols(catalog.Search(tt.query, 10))
+		if len(got) != len(tt.want) {
+			t.Errorf("search %q: expected %v, got %v", tt.query, tt.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("search %q: expected %v, got %v", tt.query, tt.want, got)
+				break
+			}
+		}
+	}
+
+	if got := catalog.Search("a", 2); len(got) != 2 {
+		t.Errorf("expected the limit to apply, got %v", symbols(got))
+	}
+}
+
+func TestSyncKeepsCatalogOnFailure(t *testing.T) {
+	catalog := testCatalog()
+	syncer := NewSyncer(SyncConfig{}, catalog, func() ([]models.Asset, error) {
+		return nil, errors.New("broker unavailable")
+	})
+	if err := syncer.Sync(); err == nil {
+		t.Fatal("expected the sync to fail")
+	}
+	if catalog.Len() != 6 {
+		t.Errorf("expected the previous assets to remain, got %d", catalog.Len())
+	}
+
+	syncer = NewSyncer(SyncConfig{}, catalog, func() ([]models.Asset, error) {
+		return []models.Asset{{Symbol: "TSLA", Name: "Tesla, Inc."}}, nil
+	})
+	if err := syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := catalog.Get("AAPL"); ok || catalog.Len() != 1 {
+		t.Error("expected the sync to replace the catalog")
+	}
+}
@@ -0,0 +1,95 @@
+// file: market-data/internal/assets/classifications.go
+package assets
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+// Classification is the reference data the broker's asset list lacks. AssetClass
+// refines the broker's class, e.g. etf for an exchange-traded fund.
+type Classification struct {
+	Sector     string
+	Industry   string
+	AssetClass string
+}
+
+// classificationColumns are the columns a classifications file may have besides symbol
+var classificationColumns = map[string]bool{"sector": true, "industry": true, "asset_class": true}
+
+// LoadClassifications reads a CSV file with a header row naming a symbol column and
+// any of sector, industry and asset_class, in any order
+func LoadClassifications(path string) (map[string]Classification, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadClassifications(f)
+}
+
+// ReadClassifications parses a classifications CSV, keyed by upper-cased symbol
+func ReadClassifications(r io.Reader) (map[string]Classification, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading classifications header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "symbol" && !classificationColumns[name] {
+			return nil, fmt.Errorf("unknown classifications column %q", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["symbol"]; !ok {
+		return nil, errors.New("classifications file has no symbol column")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	classifications := make(map[string]Classification)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return classifications, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		symbol := strings.ToUpper(field(record, "symbol"))
+		if symbol == "" {
+			continue
+		}
+		classifications[symbol] = Classification{
+			Sector:     field(record, "sector"),
+			Industry:   field(record, "industry"),
+			AssetClass: strings.ToLower(field(record, "asset_class")),
+		}
+	}
+}
+
+// Classify fills in the classification of every asset the classifications list
+func Classify(assets []models.Asset, classifications map[string]Classification) {
+	for i := range assets {
+		c, ok := classifications[strings.ToUpper(assets[i].Symbol)]
+		if !ok {
+			continue
+		}
+		assets[i].Sector = c.Sector
+		assets[i].Industry = c.Industry
+		assets[i].AssetClass = c.AssetClass
+	}
+}
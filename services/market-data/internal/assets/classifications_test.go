@@ -0,0 +1,43 @@
+package assets
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+func TestClassify(t *testing.T) {
+	classifications, err := ReadClassifications(strings.NewReader(
+		"Symbol,sector,industry,asset_class\n" +
+			"aapl,Information Technology,Technology Hardware,\n" +
+			"SPY,,,ETF\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assets := []models.Asset{{Symbol: "AAPL", Class: "us_equity"}, {Symbol: "SPY", Class: "us_equity"}, {Symbol: "MSFT"}}
+	Classify(assets, classifications)
+	if a := assets[0]; a.Sector != "Information Technology" || a.Industry != "Technology Hardware" || a.AssetClass != "" {
+		t.Errorf("unexpected AAPL classification %+v", a)
+	}
+	if a := assets[1]; a.AssetClass != "etf" || a.Sector != "" {
+		t.Errorf("unexpected SPY classification %+v", a)
+	}
+	if a := assets[2]; a.Sector != "" {
+		t.Errorf("expected MSFT to stay unclassified, got %+v", a)
+	}
+}
+
+func TestReadClassificationsErrors(t *testing.T) {
+	for _, data := range []string{
+		"",
+		"sector,industry\nTech,Software\n",
+		"symbol,rating\nAAPL,buy\n",
+		"symbol,market_cap\nAAPL,3400000000000\n",
+	} {
+		if _, err := ReadClassifications(strings.NewReader(data)); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// file: market-data/internal/assets/sync.go
+package assets
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/seunghoon34/trading-app/services/market-data/models"
+)
+
+// SyncConfig sets how often the asset master is refreshed
+type SyncConfig struct {
+	Interval time.Duration
+	// RetryInterval is used instead of Interval after a failed sync
+	RetryInterval time.Duration
+}
+
+// SyncConfigFromEnv reads ASSET_SYNC_INTERVAL, defaulting to 12h. Failed syncs are
+// retried after a minute.
+func SyncConfigFromEnv() SyncConfig {
+	config := SyncConfig{Interval: 12 * time.Hour, RetryInterval: time.Minute}
+	if d, err := time.ParseDuration(os.Getenv("ASSET_SYNC_INTERVAL")); err == nil && d > 0 {
+		config.Interval = d
+	}
+	return config
+}
+
+// Syncer keeps a catalog filled with the broker's asset list
+type Syncer struct {
+	config  SyncConfig
+	catalog *Catalog
+	fetch   func() ([]models.Asset, error)
+}
+
+// NewSyncer creates a syncer that loads assets with fetch into catalog
+func NewSyncer(config SyncConfig, catalog *Catalog, fetch func() ([]models.Asset, error)) *Syncer {
+	return &Syncer{config: config, catalog: catalog, fetch: fetch}
+}
+
+// Sync replaces the catalog with a fresh asset list. A failed fetch leaves the
+// previous list in place.
+func (s *Syncer) Sync() error {
+	assets, err := s.fetch()
+	if err != nil {
+		return err
+	}
+	s.catalog.Replace(assets)
+	return nil
+}
+
+// Run syncs immediately and then on every interval until ctx is done
+func (s *Syncer) Run(ctx context.Context) {
+	for {
+		wait := s.config.Interval
+		if err := s.Sync(); err != nil {
+			log.Printf("asset sync failed: %v; retrying in %s", err, s.config.RetryInterval)
+			wait = s.config.RetryInterval
+		} else {
+			log.Printf("asset sync loaded %d assets", s.catalog.Len())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
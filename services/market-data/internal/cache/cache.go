@@ -0,0 +1,69 @@
+// file: market-data/internal/cache/cache.go
+package cache
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache fronts a Store and coalesces concurrent loads of the same key into a
+// single upstream request. Store failures are logged and treated as misses so
+// an unavailable cache never fails a request.
+type Cache struct {
+	store Store
+	group singleflight.Group
+}
+
+// New creates a cache backed by store
+func New(store Store) *Cache {
+	return &Cache{store: store}
+}
+
+// Get returns the cached value for key, if any
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, bool) {
+	value, found, err := c.store.Get(ctx, key)
+	if err != nil {
+		log.Printf("cache get %s failed: %v", key, err)
+		return nil, false
+	}
+	return value, found
+}
+
+// Set caches value under key for ttl; a non-positive ttl is a no-op
+func (c *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	if err := c.store.Set(ctx, key, value, ttl); err != nil {
+		log.Printf("cache set %s failed: %v", key, err)
+	}
+}
+
+// Fetch returns the cached value for key, or calls load once for all concurrent
+// callers asking for the same key and caches its result for ttl
+func (c *Cache) Fetch(ctx context.Context, key string, ttl time.Duration, load func() ([]byte, error)) ([]byte, error) {
+	if value, found := c.Get(ctx, key); found {
+		return value, nil
+	}
+	value, err := c.Do(key, func() (interface{}, error) {
+		value, err := load()
+		if err != nil {
+			return nil, err
+		}
+		c.Set(ctx, key, value, ttl)
+		return value, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]byte), nil
+}
+
+// Do runs fn once for all concurrent callers sharing key and hands each the result
+func (c *Cache) Do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	value, err, _ := c.group.Do(key, fn)
+	return value, err
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreExpiresEntries(t *testing.T) {
+	store := NewMemoryStore(10)
+	now := time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	store.Set(ctx, "quote:AAPL", []byte(`{"ap":100}`), time.Second)
+	if value, found, _ := store.Get(ctx, "quote:AAPL"); !found || string(value) != `{"ap":100}` {
+		t.Fatalf("expected a hit, got %q (found %v)", value, found)
+	}
+
+	now = now.Add(time.Second)
+	if _, found, _ := store.Get(ctx, "quote:AAPL"); found {
+		t.Errorf("expected the entry to expire")
+	}
+}
+
+func TestMemoryStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	store := NewMemoryStore(2)
+	ctx := context.Background()
+
+	store.Set(ctx, "a", []byte("1"), time.Minute)
+	store.Set(ctx, "b", []byte("2"), time.Minute)
+	store.Get(ctx, "a") // a is now more recent than b
+	store.Set(ctx, "c", []byte("3"), time.Minute)
+
+	if _, found, _ := store.Get(ctx, "b"); found {
+		t.Errorf("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, found, _ := store.Get(ctx, key); !found {
+			t.Errorf("expected %s to be kept", key)
+		}
+	}
+}
+
+func TestFetchCoalescesConcurrentLoads(t *testing.T) {
+	c := New(NewMemoryStore(10))
+	ctx := context.Background()
+
+	var loads int32
+	release := make(chan struct{})
+	load := func() ([]byte, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return []byte("value"), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if value, err := c.Fetch(ctx, "key", time.Minute, load); err != nil || string(value) != "value" {
+				t.Errorf("unexpected result %q (%v)", value, err)
+			}
+		}()
+	}
+	// Give the goroutines time to pile up behind the first load
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if loads != 1 {
+		t.Errorf("expected a single load, got %d", loads)
+	}
+	if _, err := c.Fetch(ctx, "key", time.Minute, func() ([]byte, error) { t.Fatal("expected a cache hit"); return nil, nil }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+// file: market-data/internal/cache/memory.go
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// MemoryStore is a size-bounded LRU kept in process memory. Each market-data
+// replica has its own copy; use RedisStore to share one cache between replicas.
+type MemoryStore struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // front is most recently used
+	entries map[string]*list.Element
+	now     func() time.Time
+}
+
+// NewMemoryStore creates an empty store holding at most size entries
+func NewMemoryStore(size int) *MemoryStore {
+	if size < 1 {
+		size = 1
+	}
+	return &MemoryStore{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		now:     time.Now,
+	}
+}
+
+func (m *MemoryStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*memoryEntry)
+	if !m.now().Before(entry.expiresAt) {
+		m.remove(element)
+		return nil, false, nil
+	}
+	m.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (m *MemoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expiresAt := m.now().Add(ttl)
+	if element, ok := m.entries[key]; ok {
+		entry := element.Value.(*memoryEntry)
+		entry.value, entry.expiresAt = value, expiresAt
+		m.order.MoveToFront(element)
+		return nil
+	}
+
+	m.entries[key] = m.order.PushFront(&memoryEntry{key: key, value: value, expiresAt: expiresAt})
+	for m.order.Len() > m.size {
+		m.remove(m.order.Back())
+	}
+	return nil
+}
+
+// remove drops an entry; callers must hold the lock
+func (m *MemoryStore) remove(element *list.Element) {
+	m.order.Remove(element)
+	delete(m.entries, element.Value.(*memoryEntry).key)
+}
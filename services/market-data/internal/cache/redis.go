@@ -0,0 +1,38 @@
+// file: market-data/internal/cache/redis.go
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const redisKeyPrefix = "market-data:"
+
+// RedisStore keeps cached responses in Redis so every market-data replica shares them
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore connects to the Redis server at addr
+func NewRedisStore(addr string) *RedisStore {
+	return &RedisStore{
+		client: redis.NewClient(&redis.Options{Addr: addr}),
+	}
+}
+
+func (r *RedisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, redisKeyPrefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (r *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, redisKeyPrefix+key, value, ttl).Err()
+}
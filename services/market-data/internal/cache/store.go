@@ -0,0 +1,70 @@
+// file: market-data/internal/cache/store.go
+package cache
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Store holds cached upstream responses with a per-entry expiry
+type Store interface {
+	// Get returns the value for key; found is false when it is missing or expired
+	Get(ctx context.Context, key string) (value []byte, found bool, err error)
+	// Set stores value under key for ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// Config holds the cache size and the TTL of each cached endpoint
+type Config struct {
+	// Size is how many entries the in-memory store keeps before evicting
+	Size int
+	// QuoteTTL applies to latest quotes
+	QuoteTTL time.Duration
+	// BarTTL applies to latest bars
+	BarTTL time.Duration
+	// SnapshotTTL applies to snapshots
+	SnapshotTTL time.Duration
+	// HistoryTTL applies to historical bar queries
+	HistoryTTL time.Duration
+	// CorporateActionsTTL applies to corporate action queries
+	CorporateActionsTTL time.Duration
+}
+
+// LoadConfig reads CACHE_SIZE, CACHE_QUOTE_TTL, CACHE_BAR_TTL, CACHE_SNAPSHOT_TTL,
+// CACHE_HISTORY_TTL and CACHE_CORPORATE_ACTIONS_TTL, defaulting to 10000 entries, 2s,
+// 15s, 2s, 5m and 1h. A TTL of 0 disables caching for that endpoint.
+func LoadConfig() Config {
+	config := Config{
+		Size:                10000,
+		QuoteTTL:            2 * time.Second,
+		BarTTL:              15 * time.Second,
+		SnapshotTTL:         2 * time.Second,
+		HistoryTTL:          5 * time.Minute,
+		CorporateActionsTTL: time.Hour,
+	}
+	if n, err := strconv.Atoi(os.Getenv("CACHE_SIZE")); err == nil && n > 0 {
+		config.Size = n
+	}
+	for env, ttl := range map[string]*time.Duration{
+		"CACHE_QUOTE_TTL":             &config.QuoteTTL,
+		"CACHE_BAR_TTL":               &config.BarTTL,
+		"CACHE_SNAPSHOT_TTL":          &config.SnapshotTTL,
+		"CACHE_HISTORY_TTL":           &config.HistoryTTL,
+		"CACHE_CORPORATE_ACTIONS_TTL": &config.CorporateActionsTTL,
+	} {
+		if d, err := time.ParseDuration(os.Getenv(env)); err == nil && d >= 0 {
+			*ttl = d
+		}
+	}
+	return config
+}
+
+// NewStore returns a Redis backed store when REDIS_ADDR is set, otherwise an in-memory LRU
+func NewStore(config Config) Store {
+	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
+		return NewRedisStore(addr)
+	}
+	return NewMemoryStore(config.Size)
+}
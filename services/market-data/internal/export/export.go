@@ -0,0 +1,146 @@
+// file: market-data/internal/export/export.go
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Format is an export file format
+type Format string
+
+const (
+	FormatJSONL   Format = "jsonl"
+	FormatCSV     Format = "csv"
+	FormatParquet Format = "parquet"
+)
+
+// ContentType returns the MIME type to serve the format with
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+// ColumnType is the type of every value in a column
+type ColumnType int
+
+const (
+	// String columns hold string values
+	String ColumnType = iota
+	// Int64 columns hold int64 values
+	Int64
+	// Float64 columns hold float64 values
+	Float64
+	// Timestamp columns hold time.Time values
+	Timestamp
+)
+
+// Column describes one field of the exported rows
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Writer streams rows in one format. Rows are handed over in chunks: Flush ends a
+// chunk and pushes it to the underlying writer, so memory use is bounded by the
+// largest chunk rather than the whole export.
+type Writer interface {
+	// Write adds a row whose values match the columns in order and type
+	Write(values []interface{}) error
+	// Flush writes out the rows added since the last flush
+	Flush() error
+	// Close flushes and finishes the file; the underlying writer is left open
+	Close() error
+}
+
+// NewWriter creates a writer of the given format for rows with the given columns
+func NewWriter(format Format, w io.Writer, columns []Column) (Writer, error) {
+	switch format {
+	case FormatJSONL:
+		return &jsonlWriter{w: bufio.NewWriter(w), columns: columns}, nil
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatParquet:
+		return newParquetWriter(w, columns)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// jsonlWriter writes one JSON object per line with keys in column order
+type jsonlWriter struct {
+	w       *bufio.Writer
+	columns []Column
+}
+
+func (j *jsonlWriter) Write(values []interface{}) error {
+	j.w.WriteByte('{')
+	for i, column := range j.columns {
+		if i > 0 {
+			j.w.WriteByte(',')
+		}
+		name, _ := json.Marshal(column.Name)
+		j.w.Write(name)
+		j.w.WriteByte(':')
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return err
+		}
+		j.w.Write(value)
+	}
+	_, err := j.w.WriteString("}\n")
+	return err
+}
+
+func (j *jsonlWriter) Flush() error { return j.w.Flush() }
+func (j *jsonlWriter) Close() error { return j.w.Flush() }
+
+// csvWriter writes a header row followed by one record per row
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer, columns []Column) (*csvWriter, error) {
+	c := &csvWriter{w: csv.NewWriter(w), record: make([]string, len(columns))}
+	for i, column := range columns {
+		c.record[i] = column.Name
+	}
+	return c, c.w.Write(c.record)
+}
+
+func (c *csvWriter) Write(values []interface{}) error {
+	for i, value := range values {
+		switch v := value.(type) {
+		case string:
+			c.record[i] = v
+		case int64:
+			c.record[i] = strconv.FormatInt(v, 10)
+		case float64:
+			c.record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case time.Time:
+			c.record[i] = v.UTC().Format(time.RFC3339Nano)
+		default:
+			return fmt.Errorf("unsupported value %T in column %d", value, i)
+		}
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error { return c.Flush() }
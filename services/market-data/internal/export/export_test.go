@@ -0,0 +1,120 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+var testColumns = []Column{
+	{Name: "symbol", Type: String},
+	{Name: "timestamp", Type: Timestamp},
+	{Name: "price", Type: Float64},
+	{Name: "id", Type: Int64},
+}
+
+var testTime = time.Date(2024, 3, 1, 14, 30, 0, 123456789, time.UTC)
+
+func writeRows(t *testing.T, format Format, chunks ...[][]interface{}) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf, testColumns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, chunk := range chunks {
+		for _, row := range chunk {
+			if err := w.Write(row); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestJSONLAndCSV(t *testing.T) {
+	row := []interface{}{"AAPL", testTime, 187.5, int64(42)}
+
+	jsonl := string(writeRows(t, FormatJSONL, [][]interface{}{row}))
+	want := `{"symbol":"AAPL","timestamp":"2024-03-01T14:30:00.123456789Z","price":187.5,"id":42}` + "\n"
+	if jsonl != want {
+		t.Errorf("expected %s, got %s", want, jsonl)
+	}
+
+	csv := string(writeRows(t, FormatCSV, [][]interface{}{row}))
+	want = "symbol,timestamp,price,id\nAAPL,2024-03-01T14:30:00.123456789Z,187.5,42\n"
+	if csv != want {
+		t.Errorf("expected %q, got %q", want, csv)
+	}
+}
+
+func TestParquetReadsBack(t *testing.T) {
+	file := writeRows(t, FormatParquet,
+		[][]interface{}{{"AAPL", testTime, 187.5, int64(1)}, {"AAPL", testTime.Add(time.Second), 187.25, int64(2)}},
+		nil,
+		[][]interface{}{{"MSFT", testTime.Add(2 * time.Second), 410.0, int64(3)}},
+	)
+
+	f, err := parquet.OpenFile(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.NumRows() != 3 {
+		t.Errorf("expected 3 rows, got %d", f.NumRows())
+	}
+	// The empty flush adds no row group
+	if groups := f.RowGroups(); len(groups) != 2 || groups[0].NumRows() != 2 {
+		t.Errorf("expected row groups of 2 and 1 rows, got %d groups", len(groups))
+	}
+	for _, column := range testColumns {
+		leaf, ok := f.Schema().Lookup(column.Name)
+		if !ok || !leaf.Node.Required() {
+			t.Errorf("expected a required %s column", column.Name)
+		}
+	}
+	if leaf, _ := f.Schema().Lookup("timestamp"); leaf.Node.Type().LogicalType().Timestamp == nil {
+		t.Errorf("expected timestamp to be a TIMESTAMP column, got %v", leaf.Node.Type())
+	}
+
+	type row struct {
+		Symbol    string    `parquet:"symbol"`
+		Timestamp time.Time `parquet:"timestamp,timestamp(nanosecond)"`
+		Price     float64   `parquet:"price"`
+		ID        int64     `parquet:"id"`
+	}
+	rows, err := parquet.Read[row](bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []row{
+		{"AAPL", testTime, 187.5, 1},
+		{"AAPL", testTime.Add(time.Second), 187.25, 2},
+		{"MSFT", testTime.Add(2 * time.Second), 410, 3},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("expected %d rows, got %v", len(want), rows)
+	}
+	for i := range want {
+		if !rows[i].Timestamp.Equal(want[i].Timestamp) || rows[i].Symbol != want[i].Symbol || rows[i].Price != want[i].Price || rows[i].ID != want[i].ID {
+			t.Errorf("row %d: expected %+v, got %+v", i, want[i], rows[i])
+		}
+	}
+}
+
+func TestParquetRejectsMismatchedValues(t *testing.T) {
+	w, err := NewWriter(FormatParquet, &bytes.Buffer{}, testColumns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write([]interface{}{"AAPL", testTime, int64(187), int64(1)}); err == nil {
+		t.Error("expected an int64 in the price column to be rejected")
+	}
+}
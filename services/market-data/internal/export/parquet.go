@@ -0,0 +1,96 @@
+// file: market-data/internal/export/parquet.go
+package export
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetWriter streams a Parquet file with one row group per Flush, so only the
+// current row group and the footer metadata are held in memory. Every column is
+// required; timestamps are UTC nanoseconds.
+type parquetWriter struct {
+	w       *parquet.Writer
+	columns []Column
+	// leaves is each column's index in the schema, which orders fields by name
+	leaves []int
+	row    parquet.Row
+}
+
+func newParquetWriter(w io.Writer, columns []Column) (*parquetWriter, error) {
+	group := make(parquet.Group, len(columns))
+	for _, column := range columns {
+		if _, ok := group[column.Name]; ok {
+			return nil, fmt.Errorf("duplicate column %q", column.Name)
+		}
+		group[column.Name] = parquetNode(column.Type)
+	}
+	schema := parquet.NewSchema("export", group)
+
+	p := &parquetWriter{
+		w:       parquet.NewWriter(w, schema, parquet.CreatedBy("trading-app market-data", "", "")),
+		columns: columns,
+		leaves:  make([]int, len(columns)),
+		row:     make(parquet.Row, len(columns)),
+	}
+	for i, column := range columns {
+		leaf, _ := schema.Lookup(column.Name)
+		p.leaves[i] = leaf.ColumnIndex
+	}
+	return p, nil
+}
+
+func parquetNode(t ColumnType) parquet.Node {
+	switch t {
+	case String:
+		return parquet.String()
+	case Float64:
+		return parquet.Leaf(parquet.DoubleType)
+	case Timestamp:
+		return parquet.Timestamp(parquet.Nanosecond)
+	default:
+		return parquet.Int(64)
+	}
+}
+
+func (p *parquetWriter) Write(values []interface{}) error {
+	if len(values) != len(p.columns) {
+		return fmt.Errorf("expected %d values, got %d", len(p.columns), len(values))
+	}
+	for i, value := range values {
+		var v parquet.Value
+		var t ColumnType
+		switch x := value.(type) {
+		case string:
+			v, t = parquet.ByteArrayValue([]byte(x)), String
+		case int64:
+			v, t = parquet.Int64Value(x), Int64
+		case float64:
+			v, t = parquet.DoubleValue(x), Float64
+		case time.Time:
+			v, t = parquet.Int64Value(x.UnixNano()), Timestamp
+		default:
+			return fmt.Errorf("unsupported value %T in column %d", value, i)
+		}
+		if t != p.columns[i].Type {
+			return fmt.Errorf("value %T does not match column %q", value, p.columns[i].Name)
+		}
+		leaf := p.leaves[i]
+		p.row[leaf] = v.Level(0, 0, leaf)
+	}
+	_, err := p.w.WriteRows([]parquet.Row{p.row})
+	return err
+}
+
+// Flush writes the pending rows out as a row group
+func (p *parquetWriter) Flush() error {
+	return p.w.Flush()
+}
+
+// Close writes the last row group and the footer
+func (p *parquetWriter) Close() error {
+	return p.w.Close()
+}
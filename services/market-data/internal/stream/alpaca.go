@@ -0,0 +1,254 @@
+// file: market-data/internal/stream/alpaca.go
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Config locates and authenticates Alpaca's market data stream
+type Config struct {
+	URL        string
+	Key        string
+	Secret     string
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// ReadTimeout is how long the connection may stay silent before it is treated as
+	// dead. Pings go out at a third of it, so a healthy idle connection answers with
+	// pongs in time; zero disables the deadline.
+	ReadTimeout time.Duration
+}
+
+// ConfigFromEnv reads ALPACA_STREAM_URL (default: the IEX feed), ALPACA_API_KEY and
+// ALPACA_SECRET_KEY. Reconnects back off from 1s up to 30s, and a connection silent
+// for a minute is dropped and reconnected.
+func ConfigFromEnv() Config {
+	url := os.Getenv("ALPACA_STREAM_URL")
+	if url == "" {
+		url = "wss://stream.data.alpaca.markets/v2/iex"
+	}
+	return Config{
+		URL:         url,
+		Key:         os.Getenv("ALPACA_API_KEY"),
+		Secret:      os.Getenv("ALPACA_SECRET_KEY"),
+		MinBackoff:  time.Second,
+		MaxBackoff:  30 * time.Second,
+		ReadTimeout: time.Minute,
+	}
+}
+
+// AlpacaStream holds the single upstream connection to Alpaca's data stream. Alpaca
+// allows one connection per key, so every client is served from it through the hub.
+type AlpacaStream struct {
+	config  Config
+	hub     *Hub
+	changed chan struct{}
+}
+
+// NewAlpacaStream creates the upstream and registers it with hub
+func NewAlpacaStream(config Config, hub *Hub) *AlpacaStream {
+	s := &AlpacaStream{config: config, hub: hub, changed: make(chan struct{}, 1)}
+	hub.SetUpstream(s)
+	return s
+}
+
+// Notify asks the stream to resync its subscription with the hub
+func (s *AlpacaStream) Notify() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Run keeps the upstream connected until ctx is done, reconnecting with
+// exponential backoff whenever the connection drops or cannot be established
+func (s *AlpacaStream) Run(ctx context.Context) {
+	backoff := s.config.MinBackoff
+	for {
+		start := time.Now()
+		err := s.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		// A connection that stayed up for a while starts the backoff over
+		if time.Since(start) > s.config.MaxBackoff {
+			backoff = s.config.MinBackoff
+		}
+		log.Printf("market data stream disconnected: %v; reconnecting in %s", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > s.config.MaxBackoff {
+			backoff = s.config.MaxBackoff
+		}
+	}
+}
+
+// session runs one connection: authenticate, subscribe to what the hub needs,
+// then relay messages until the connection fails
+func (s *AlpacaStream) session(ctx context.Context) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, s.config.URL, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := s.authenticate(conn); err != nil {
+		return err
+	}
+	log.Printf("market data stream connected to %s", s.config.URL)
+
+	errc := make(chan error, 1)
+	go func() { errc <- s.relay(conn) }()
+
+	// Without a read timeout nothing waits on pongs, so there is no need to ping
+	var pings <-chan time.Time
+	if s.config.ReadTimeout > 0 {
+		ticker := time.NewTicker(s.config.ReadTimeout / 3)
+		defer ticker.Stop()
+		pings = ticker.C
+	}
+
+	// Nothing is subscribed on a fresh connection
+	subscribed := Subscription{}
+	if subscribed, err = s.sync(conn, subscribed); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+			return ctx.Err()
+		case err := <-errc:
+			return err
+		case <-pings:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second)); err != nil {
+				return err
+			}
+		case <-s.changed:
+			if subscribed, err = s.sync(conn, subscribed); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// authenticate waits for the welcome message, sends the credentials and waits for
+// Alpaca to confirm them
+func (s *AlpacaStream) authenticate(conn *websocket.Conn) error {
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+
+	if err := expect(conn, "connected"); err != nil {
+		return err
+	}
+	if err := conn.WriteJSON(upstreamRequest{Action: "auth", Key: s.config.Key, Secret: s.config.Secret}); err != nil {
+		return err
+	}
+	return expect(conn, "authenticated")
+}
+
+// expect reads one batch of control messages and checks it holds a success with msg
+func expect(conn *websocket.Conn, msg string) error {
+	var batch []upstreamMessage
+	if err := conn.ReadJSON(&batch); err != nil {
+		return err
+	}
+	for _, m := range batch {
+		if m.Type == "error" {
+			return fmt.Errorf("alpaca stream error %d: %s", m.Code, m.Message)
+		}
+		if m.Type == "success" && m.Message == msg {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected %q from alpaca stream", msg)
+}
+
+// sync sends the subscribe and unsubscribe messages that move the upstream from
+// subscribed to what the hub currently needs, and returns the new state
+func (s *AlpacaStream) sync(conn *websocket.Conn, subscribed Subscription) (Subscription, error) {
+	want := s.hub.Subscriptions()
+	add := difference(want, subscribed)
+	remove := difference(subscribed, want)
+
+	if !add.Empty() {
+		if err := conn.WriteJSON(upstreamRequest{Action: "subscribe", Trades: add.Trades, Quotes: add.Quotes, Bars: add.Bars}); err != nil {
+			return subscribed, err
+		}
+	}
+	if !remove.Empty() {
+		if err := conn.WriteJSON(upstreamRequest{Action: "unsubscribe", Trades: remove.Trades, Quotes: remove.Quotes, Bars: remove.Bars}); err != nil {
+			return subscribed, err
+		}
+	}
+	return want, nil
+}
+
+// relay reads market data from the connection and publishes it to the hub. Every
+// message or pong extends the read deadline, so a connection that goes silent fails
+// the read and the session reconnects instead of waiting forever.
+func (s *AlpacaStream) relay(conn *websocket.Conn) error {
+	extend := func(string) error {
+		if s.config.ReadTimeout <= 0 {
+			return nil
+		}
+		return conn.SetReadDeadline(time.Now().Add(s.config.ReadTimeout))
+	}
+	conn.SetPongHandler(extend)
+	for {
+		if err := extend(""); err != nil {
+			return err
+		}
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		var batch []json.RawMessage
+		if err := json.Unmarshal(data, &batch); err != nil {
+			log.Printf("market data stream sent an invalid message: %v", err)
+			continue
+		}
+		for _, raw := range batch {
+			var m upstreamMessage
+			if err := json.Unmarshal(raw, &m); err != nil {
+				continue
+			}
+			if c, ok := eventChannels[m.Type]; ok {
+				s.hub.Publish(Event{Type: c.eventType, Symbol: m.Symbol, Data: raw})
+				continue
+			}
+			if m.Type == "error" {
+				// Errors such as an invalid symbol do not end the session
+				log.Printf("market data stream error %d: %s", m.Code, m.Message)
+			}
+		}
+	}
+}
+
+// difference returns the symbols in a that are not in b, per channel
+func difference(a, b Subscription) Subscription {
+	minus := func(a, b []string) []string {
+		in := make(map[string]bool, len(b))
+		for _, symbol := range b {
+			in[symbol] = true
+		}
+		var out []string
+		for _, symbol := range a {
+			if !in[symbol] {
+				out = append(out, symbol)
+			}
+		}
+		return out
+	}
+	return Subscription{Trades: minus(a.Trades, b.Trades), Quotes: minus(a.Quotes, b.Quotes), Bars: minus(a.Bars, b.Bars)}
+}
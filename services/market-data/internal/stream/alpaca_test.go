@@ -0,0 +1,140 @@
+package stream
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeAlpaca speaks enough of Alpaca's data stream protocol to authenticate, record
+// subscribe requests and push a quote; it drops the first connection after subscribing
+type fakeAlpaca struct {
+	requests chan upstreamRequest
+	sessions int
+}
+
+func (f *fakeAlpaca) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	f.sessions++
+	session := f.sessions
+
+	conn.WriteMessage(websocket.TextMessage, []byte(`[{"T":"success","msg":"connected"}]`))
+	var auth upstreamRequest
+	if err := conn.ReadJSON(&auth); err != nil || auth.Key != "key" {
+		conn.WriteMessage(websocket.TextMessage, []byte(`[{"T":"error","code":402,"msg":"auth failed"}]`))
+		return
+	}
+	conn.WriteMessage(websocket.TextMessage, []byte(`[{"T":"success","msg":"authenticated"}]`))
+
+	for {
+		var req upstreamRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		f.requests <- req
+		if session == 1 {
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(`[{"T":"subscription","quotes":["AAPL"]},{"T":"q","S":"AAPL","ap":101,"bp":99}]`))
+	}
+}
+
+func TestAlpacaStreamResubscribesAfterReconnect(t *testing.T) {
+	fake := &fakeAlpaca{requests: make(chan upstreamRequest, 10)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	hub := NewHub(Limits{})
+	client := hub.Register()
+	hub.Subscribe(client, Subscription{Quotes: []string{"AAPL"}})
+
+	upstream := NewAlpacaStream(Config{
+		URL:        "ws" + strings.TrimPrefix(server.URL, "http"),
+		Key:        "key",
+		Secret:     "secret",
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 50 * time.Millisecond,
+	}, hub)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go upstream.Run(ctx)
+
+	for session := 1; session <= 2; session++ {
+		select {
+		case req := <-fake.requests:
+			if req.Action != "subscribe" || len(req.Quotes) != 1 || req.Quotes[0] != "AAPL" {
+				t.Fatalf("session %d: unexpected request %+v", session, req)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("session %d: no subscribe request", session)
+		}
+	}
+
+	select {
+	case event := <-client.Events():
+		if event.Type != "quote" || event.Symbol != "AAPL" || !strings.Contains(string(event.Data), `"ap":101`) {
+			t.Errorf("unexpected event %+v", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no quote relayed")
+	}
+
+	// Dropping the last client unsubscribes upstream
+	hub.Unregister(client)
+	select {
+	case req := <-fake.requests:
+		if req.Action != "unsubscribe" || len(req.Quotes) != 1 {
+			t.Errorf("unexpected request %+v", req)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no unsubscribe request")
+	}
+}
+
+func TestAlpacaStreamReconnectsWhenUpstreamGoesSilent(t *testing.T) {
+	sessions := make(chan struct{}, 10)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		sessions <- struct{}{}
+		conn.WriteMessage(websocket.TextMessage, []byte(`[{"T":"success","msg":"connected"}]`))
+		conn.ReadMessage()
+		conn.WriteMessage(websocket.TextMessage, []byte(`[{"T":"success","msg":"authenticated"}]`))
+		// Stop reading, so pings go unanswered and nothing else arrives
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	upstream := NewAlpacaStream(Config{
+		URL:         "ws" + strings.TrimPrefix(server.URL, "http"),
+		Key:         "key",
+		MinBackoff:  10 * time.Millisecond,
+		MaxBackoff:  50 * time.Millisecond,
+		ReadTimeout: 100 * time.Millisecond,
+	}, NewHub(Limits{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go upstream.Run(ctx)
+
+	for want := 1; want <= 2; want++ {
+		select {
+		case <-sessions:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected session %d; a silent upstream should time out and reconnect", want)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// file: market-data/internal/stream/hub.go
+package stream
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// clientBuffer is how many events a slow client may fall behind before further
+// events are dropped for it
+const clientBuffer = 256
+
+// Errors returned by Subscribe when a subscription goes beyond the hub's limits
+var (
+	ErrWildcardNotAllowed = errors.New("subscribing to all symbols with \"*\" is not allowed")
+	ErrTooManySymbols     = errors.New("too many symbols")
+)
+
+// Limits bound what a single client may subscribe to
+type Limits struct {
+	// MaxSymbols caps a client's subscriptions, counted per channel; 0 is unlimited
+	MaxSymbols int
+	// AllowWildcard lets clients subscribe to "*", every symbol on a channel, which
+	// makes the upstream send the whole feed
+	AllowWildcard bool
+}
+
+// DefaultLimits allow 100 symbols per client and no wildcard
+var DefaultLimits = Limits{MaxSymbols: 100}
+
+// LimitsFromEnv reads STREAM_MAX_SYMBOLS and STREAM_ALLOW_WILDCARD over DefaultLimits
+func LimitsFromEnv() Limits {
+	limits := DefaultLimits
+	if n, err := strconv.Atoi(os.Getenv("STREAM_MAX_SYMBOLS")); err == nil && n >= 0 {
+		limits.MaxSymbols = n
+	}
+	if allow, err := strconv.ParseBool(os.Getenv("STREAM_ALLOW_WILDCARD")); err == nil {
+		limits.AllowWildcard = allow
+	}
+	return limits
+}
+
+// Upstream is told whenever the union of client subscriptions changes
+type Upstream interface {
+	// Notify must not block; the upstream reads Hub.Subscriptions when it syncs
+	Notify()
+}
+
+// Client is one downstream connection and the symbols it is subscribed to
+type Client struct {
+	ID     string
+	events chan Event
+	subs   map[string]map[string]bool // channel -> symbols
+}
+
+// Events delivers the client's market data; it is closed when the client is removed
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+// Hub fans upstream market data out to clients, keeping track of which symbols
+// any client still needs so the upstream subscription can follow
+type Hub struct {
+	mu       sync.Mutex
+	limits   Limits
+	upstream Upstream
+	clients  map[string]*Client
+	// subscribers indexes clients by channel and symbol
+	subscribers map[string]map[string]map[*Client]bool
+}
+
+// NewHub creates a hub with no clients that holds each client to limits
+func NewHub(limits Limits) *Hub {
+	return &Hub{
+		limits:      limits,
+		clients:     make(map[string]*Client),
+		subscribers: make(map[string]map[string]map[*Client]bool),
+	}
+}
+
+// SetUpstream registers the upstream to notify about subscription changes
+func (h *Hub) SetUpstream(upstream Upstream) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.upstream = upstream
+}
+
+// Register adds a client with no subscriptions
+func (h *Hub) Register() *Client {
+	id := make([]byte, 16)
+	rand.Read(id)
+	client := &Client{
+		ID:     hex.EncodeToString(id),
+		events: make(chan Event, clientBuffer),
+		subs:   make(map[string]map[string]bool),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[client.ID] = client
+	return client
+}
+
+// Client looks up a registered client by ID
+func (h *Hub) Client(id string) (*Client, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	client, ok := h.clients[id]
+	return client, ok
+}
+
+// Unregister drops the client and its subscriptions and closes its events channel
+func (h *Hub) Unregister(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[client.ID]; !ok {
+		return
+	}
+	h.update(client, subscriptionFrom(client.subs), false)
+	delete(h.clients, client.ID)
+	close(client.events)
+}
+
+// Subscribe adds symbols to the client's subscription and returns the result. A
+// subscription beyond the hub's limits is refused as a whole, leaving the client's
+// subscription unchanged.
+func (h *Hub) Subscribe(client *Client, sub Subscription) (Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sub = sub.normalize()
+	count := 0
+	for _, symbols := range client.subs {
+		count += len(symbols)
+	}
+	for channel, symbols := range sub.channels() {
+		for _, symbol := range symbols {
+			if symbol == "*" && !h.limits.AllowWildcard {
+				return subscriptionFrom(client.subs), ErrWildcardNotAllowed
+			}
+			if !client.subs[channel][symbol] {
+				count++
+			}
+		}
+	}
+	if h.limits.MaxSymbols > 0 && count > h.limits.MaxSymbols {
+		return subscriptionFrom(client.subs), fmt.Errorf("%w: a client can subscribe to at most %d", ErrTooManySymbols, h.limits.MaxSymbols)
+	}
+	h.update(client, sub, true)
+	return subscriptionFrom(client.subs), nil
+}
+
+// Unsubscribe removes symbols from the client's subscription and returns the result
+func (h *Hub) Unsubscribe(client *Client, sub Subscription) Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.update(client, sub.normalize(), false)
+	return subscriptionFrom(client.subs)
+}
+
+// update adds or removes symbols for a client and notifies the upstream if the
+// union changed; callers must hold the lock
+func (h *Hub) update(client *Client, sub Subscription, add bool) {
+	if _, ok := h.clients[client.ID]; !ok {
+		return
+	}
+	changed := false
+	for channel, symbols := range sub.channels() {
+		for _, symbol := range symbols {
+			if add && !client.subs[channel][symbol] {
+				if client.subs[channel] == nil {
+					client.subs[channel] = make(map[string]bool)
+				}
+				client.subs[channel][symbol] = true
+				if h.subscribers[channel] == nil {
+					h.subscribers[channel] = make(map[string]map[*Client]bool)
+				}
+				if h.subscribers[channel][symbol] == nil {
+					h.subscribers[channel][symbol] = make(map[*Client]bool)
+					changed = true
+				}
+				h.subscribers[channel][symbol][client] = true
+			}
+			if !add && client.subs[channel][symbol] {
+				delete(client.subs[channel], symbol)
+				delete(h.subscribers[channel][symbol], client)
+				if len(h.subscribers[channel][symbol]) == 0 {
+					delete(h.subscribers[channel], symbol)
+					changed = true
+				}
+			}
+		}
+	}
+	if changed && h.upstream != nil {
+		h.upstream.Notify()
+	}
+}
+
+// Subscriptions returns the union of every client's subscription
+func (h *Hub) Subscriptions() Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sets := make(map[string]map[string]bool)
+	for channel, symbols := range h.subscribers {
+		sets[channel] = make(map[string]bool)
+		for symbol := range symbols {
+			sets[channel][symbol] = true
+		}
+	}
+	return subscriptionFrom(sets)
+}
+
+// Publish delivers an event, without blocking, to every client subscribed to its
+// symbol or to all symbols on its channel
+func (h *Hub) Publish(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	channel := event.channel()
+	delivered := make(map[*Client]bool)
+	for _, symbol := range []string{event.Symbol, "*"} {
+		for client := range h.subscribers[channel][symbol] {
+			if delivered[client] {
+				continue
+			}
+			delivered[client] = true
+			select {
+			case client.events <- event:
+			default:
+				// Client is not keeping up; drop rather than stall every other client
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type countingUpstream struct{ notified int }
+
+func (u *countingUpstream) Notify() { u.notified++ }
+
+func TestHubTracksUnionOfSubscriptions(t *testing.T) {
+	hub := NewHub(Limits{})
+	upstream := &countingUpstream{}
+	hub.SetUpstream(upstream)
+
+	a, b := hub.Register(), hub.Register()
+	hub.Subscribe(a, Subscription{Quotes: []string{"aapl", "MSFT"}})
+	got, _ := hub.Subscribe(b, Subscription{Quotes: []string{"AAPL"}, Trades: []string{"TSLA"}})
+	if !reflect.DeepEqual(got, Subscription{Trades: []string{"TSLA"}, Quotes: []string{"AAPL"}, Bars: []string{}}) {
+		t.Errorf("unexpected client subscription: %+v", got)
+	}
+
+	want := Subscription{Trades: []string{"TSLA"}, Quotes: []string{"AAPL", "MSFT"}, Bars: []string{}}
+	if union := hub.Subscriptions(); !reflect.DeepEqual(union, want) {
+		t.Errorf("expected %+v, got %+v", want, union)
+	}
+
+	// AAPL quotes are still needed by b, so only MSFT leaves the union
+	notified := upstream.notified
+	hub.Unregister(a)
+	if union := hub.Subscriptions(); !reflect.DeepEqual(union.Quotes, []string{"AAPL"}) {
+		t.Errorf("expected only AAPL quotes to remain, got %+v", union)
+	}
+	if upstream.notified != notified+1 {
+		t.Errorf("expected the upstream to be notified once, got %d", upstream.notified-notified)
+	}
+
+	// Subscribing to something already in the union does not resync the upstream
+	notified = upstream.notified
+	c := hub.Register()
+	hub.Subscribe(c, Subscription{Quotes: []string{"AAPL"}})
+	if upstream.notified != notified {
+		t.Errorf("expected no upstream notification")
+	}
+}
+
+func TestHubPublishRoutesBySymbolAndChannel(t *testing.T) {
+	hub := NewHub(Limits{AllowWildcard: true})
+	quotes, trades, all := hub.Register(), hub.Register(), hub.Register()
+	hub.Subscribe(quotes, Subscription{Quotes: []string{"AAPL"}})
+	hub.Subscribe(trades, Subscription{Trades: []string{"AAPL"}})
+	hub.Subscribe(all, Subscription{Quotes: []string{"*", "AAPL"}})
+
+	hub.Publish(Event{Type: "quote", Symbol: "AAPL", Data: json.RawMessage(`{}`)})
+	hub.Publish(Event{Type: "quote", Symbol: "MSFT", Data: json.RawMessage(`{}`)})
+
+	if n := len(quotes.Events()); n != 1 {
+		t.Errorf("expected 1 event for the AAPL quote client, got %d", n)
+	}
+	if n := len(trades.Events()); n != 0 {
+		t.Errorf("expected no events for the trades client, got %d", n)
+	}
+	if n := len(all.Events()); n != 2 {
+		t.Errorf("expected 2 events for the wildcard client, got %d", n)
+	}
+
+	hub.Unregister(quotes)
+	for range quotes.Events() {
+	}
+	if _, ok := hub.Client(quotes.ID); ok {
+		t.Errorf("expected the client to be removed")
+	}
+}
+
+func TestHubEnforcesLimits(t *testing.T) {
+	hub := NewHub(Limits{MaxSymbols: 3})
+	upstream := &countingUpstream{}
+	hub.SetUpstream(upstream)
+	client := hub.Register()
+
+	if _, err := hub.Subscribe(client, Subscription{Quotes: []string{"*"}}); !errors.Is(err, ErrWildcardNotAllowed) {
+		t.Fatalf("expected the wildcard to be refused, got %v", err)
+	}
+	if _, err := hub.Subscribe(client, Subscription{Quotes: []string{"AAPL", "MSFT"}, Trades: []string{"AAPL"}}); err != nil {
+		t.Fatalf("expected three symbols to fit, got %v", err)
+	}
+	// Symbols the client already has do not count again
+	if _, err := hub.Subscribe(client, Subscription{Quotes: []string{"aapl"}}); err != nil {
+		t.Fatalf("expected a repeated symbol to fit, got %v", err)
+	}
+
+	notified := upstream.notified
+	got, err := hub.Subscribe(client, Subscription{Quotes: []string{"TSLA", "NVDA"}})
+	if !errors.Is(err, ErrTooManySymbols) {
+		t.Fatalf("expected too many symbols, got %v", err)
+	}
+	want := Subscription{Trades: []string{"AAPL"}, Quotes: []string{"AAPL", "MSFT"}, Bars: []string{}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the subscription to be unchanged, got %+v", got)
+	}
+	if upstream.notified != notified {
+		t.Errorf("expected a refused subscription not to notify the upstream")
+	}
+}
@@ -0,0 +1,112 @@
+// file: market-data/internal/stream/protocol.go
+package stream
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Channels a client can subscribe to, named as in Alpaca's subscribe message
+const (
+	ChannelTrades = "trades"
+	ChannelQuotes = "quotes"
+	ChannelBars   = "bars"
+)
+
+// eventChannels maps Alpaca's message type codes to our event type and channel
+var eventChannels = map[string]struct{ eventType, channel string }{
+	"t": {"trade", ChannelTrades},
+	"q": {"quote", ChannelQuotes},
+	"b": {"bar", ChannelBars},
+}
+
+// Subscription lists symbols per channel; "*" subscribes to every symbol
+type Subscription struct {
+	Trades []string `json:"trades"`
+	Quotes []string `json:"quotes"`
+	Bars   []string `json:"bars"`
+}
+
+// Empty reports whether the subscription has no symbols on any channel
+func (s Subscription) Empty() bool {
+	return len(s.Trades) == 0 && len(s.Quotes) == 0 && len(s.Bars) == 0
+}
+
+// normalize upper-cases, trims and de-duplicates every symbol list
+func (s Subscription) normalize() Subscription {
+	return Subscription{Trades: cleanSymbols(s.Trades), Quotes: cleanSymbols(s.Quotes), Bars: cleanSymbols(s.Bars)}
+}
+
+func (s Subscription) channels() map[string][]string {
+	return map[string][]string{ChannelTrades: s.Trades, ChannelQuotes: s.Quotes, ChannelBars: s.Bars}
+}
+
+// subscriptionFrom builds a Subscription from per-channel symbol sets
+func subscriptionFrom(sets map[string]map[string]bool) Subscription {
+	list := func(set map[string]bool) []string {
+		symbols := make([]string, 0, len(set))
+		for symbol := range set {
+			symbols = append(symbols, symbol)
+		}
+		sort.Strings(symbols)
+		return symbols
+	}
+	return Subscription{Trades: list(sets[ChannelTrades]), Quotes: list(sets[ChannelQuotes]), Bars: list(sets[ChannelBars])}
+}
+
+func cleanSymbols(symbols []string) []string {
+	seen := map[string]bool{}
+	var cleaned []string
+	for _, symbol := range symbols {
+		for _, part := range strings.Split(symbol, ",") {
+			part = strings.ToUpper(strings.TrimSpace(part))
+			if part != "" && !seen[part] {
+				seen[part] = true
+				cleaned = append(cleaned, part)
+			}
+		}
+	}
+	return cleaned
+}
+
+// ClientMessage is what downstream clients send to change their subscription
+type ClientMessage struct {
+	Action string `json:"action"` // "subscribe" or "unsubscribe"
+	Subscription
+}
+
+// Event is one market data update delivered to clients. Data is Alpaca's message as received.
+type Event struct {
+	Type   string          `json:"type"` // trade, quote or bar
+	Symbol string          `json:"symbol"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// channel returns the subscription channel the event belongs to
+func (e Event) channel() string {
+	for _, c := range eventChannels {
+		if c.eventType == e.Type {
+			return c.channel
+		}
+	}
+	return ""
+}
+
+// upstreamMessage is the envelope shared by every message on Alpaca's data stream
+type upstreamMessage struct {
+	Type    string `json:"T"`
+	Symbol  string `json:"S"`
+	Message string `json:"msg"`
+	Code    int    `json:"code"`
+}
+
+// upstreamRequest is an auth, subscribe or unsubscribe message sent to Alpaca
+type upstreamRequest struct {
+	Action string   `json:"action"`
+	Key    string   `json:"key,omitempty"`
+	Secret string   `json:"secret,omitempty"`
+	Trades []string `json:"trades,omitempty"`
+	Quotes []string `json:"quotes,omitempty"`
+	Bars   []string `json:"bars,omitempty"`
+}
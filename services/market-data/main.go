@@ -10,6 +10,9 @@ import (
 )
 
 func main() {
+	handlers.InitCache()
+	handlers.InitStream()
+	handlers.InitAssets()
 
 	r := gin.Default()
 	r.GET("/health", func(c *gin.Context) {
@@ -23,6 +26,20 @@ func main() {
 	r.GET("/bars/:symbol", func(c *gin.Context) {
 		handlers.GetLatestBar(c)
 	})
+	r.GET("/bars/:symbol/history", func(c *gin.Context) {
+		handlers.GetBarHistory(c)
+	})
+
+	r.GET("/trades/:symbol", func(c *gin.Context) {
+		handlers.GetTrades(c)
+	})
+	r.GET("/quotes/:symbol/history", func(c *gin.Context) {
+		handlers.GetQuoteHistory(c)
+	})
+
+	r.GET("/indicators/:symbol", func(c *gin.Context) {
+		handlers.GetIndicators(c)
+	})
 
 	r.GET("/quotes", func(c *gin.Context) {
 		handlers.GetLatestQuoteMulti(c)
@@ -32,5 +49,42 @@ func main() {
 		handlers.GetLatestBarMulti(c)
 	})
 
+	r.GET("/snapshots", func(c *gin.Context) {
+		handlers.GetSnapshots(c)
+	})
+
+	r.GET("/clock", func(c *gin.Context) {
+		handlers.GetClock(c)
+	})
+
+	r.GET("/calendar", func(c *gin.Context) {
+		handlers.GetCalendar(c)
+	})
+
+	r.GET("/corporate-actions", func(c *gin.Context) {
+		handlers.GetCorporateActions(c)
+	})
+
+	r.GET("/corporate-actions/:symbol", func(c *gin.Context) {
+		handlers.GetCorporateActions(c)
+	})
+
+	r.GET("/assets", func(c *gin.Context) {
+		handlers.GetAssets(c)
+	})
+
+	r.GET("/assets/search", func(c *gin.Context) {
+		handlers.SearchAssets(c)
+	})
+
+	r.GET("/assets/:symbol", func(c *gin.Context) {
+		handlers.GetAsset(c)
+	})
+
+	// Streaming
+	r.GET("/stream", handlers.StreamSSE)
+	r.GET("/stream/ws", handlers.StreamWebSocket)
+	r.POST("/stream/:client_id/subscription", handlers.UpdateStreamSubscription)
+
 	r.Run(":8082") // listen and serve on 0.0.0.0:8080 (for windows "localhost:8080")
 }
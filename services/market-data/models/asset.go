@@ -0,0 +1,22 @@
+package models
+
+// Asset is one instrument from the broker's asset master, in the broker's own schema.
+// Sector, Industry and AssetClass come from the classifications file and are empty
+// for assets it does not list.
+type Asset struct {
+	ID           string `json:"id"`
+	Class        string `json:"class"`
+	Exchange     string `json:"exchange"`
+	Symbol       string `json:"symbol"`
+	Name         string `json:"name"`
+	Status       string `json:"status"`
+	Tradable     bool   `json:"tradable"`
+	Marginable   bool   `json:"marginable"`
+	Shortable    bool   `json:"shortable"`
+	EasyToBorrow bool   `json:"easy_to_borrow"`
+	Fractionable bool   `json:"fractionable"`
+
+	Sector     string `json:"sector,omitempty"`
+	Industry   string `json:"industry,omitempty"`
+	AssetClass string `json:"asset_class,omitempty"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Bar is one OHLCV candle in the schema market-data returns to its clients
+type Bar struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Open       float64   `json:"open"`
+	High       float64   `json:"high"`
+	Low        float64   `json:"low"`
+	Close      float64   `json:"close"`
+	Volume     float64   `json:"volume"`
+	TradeCount int64     `json:"trade_count"`
+	VWAP       float64   `json:"vwap"`
+}
+
+// BarHistory is the body of GET /bars/:symbol/history, keyed by symbol
+type BarHistory struct {
+	Timeframe string           `json:"timeframe"`
+	Bars      map[string][]Bar `json:"bars"`
+}
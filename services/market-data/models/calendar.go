@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// CalendarDay is one trading day. Open and Close bound the regular session;
+// SessionOpen and SessionClose include pre- and post-market trading.
+type CalendarDay struct {
+	Date         string    `json:"date"`
+	Open         time.Time `json:"open"`
+	Close        time.Time `json:"close"`
+	SessionOpen  time.Time `json:"session_open"`
+	SessionClose time.Time `json:"session_close"`
+}
+
+// Clock says whether the regular session is open and when it next opens and closes
+type Clock struct {
+	Timestamp time.Time `json:"timestamp"`
+	IsOpen    bool      `json:"is_open"`
+	NextOpen  time.Time `json:"next_open"`
+	NextClose time.Time `json:"next_close"`
+}
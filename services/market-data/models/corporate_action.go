@@ -0,0 +1,28 @@
+package models
+
+// Corporate action types
+const (
+	ActionSplit         = "split"
+	ActionReverseSplit  = "reverse_split"
+	ActionCashDividend  = "cash_dividend"
+	ActionStockDividend = "stock_dividend"
+	ActionSymbolChange  = "symbol_change"
+)
+
+// CorporateAction is a split, dividend or symbol change. Dates are YYYY-MM-DD.
+// Rate is the cash paid per share for cash dividends and the new shares per share
+// for stock dividends; splits turn OldRate shares into NewRate shares.
+type CorporateAction struct {
+	Type        string  `json:"type"`
+	Symbol      string  `json:"symbol"`
+	NewSymbol   string  `json:"new_symbol,omitempty"`
+	ExDate      string  `json:"ex_date,omitempty"`
+	RecordDate  string  `json:"record_date,omitempty"`
+	PayableDate string  `json:"payable_date,omitempty"`
+	ProcessDate string  `json:"process_date"`
+	Rate        float64 `json:"rate,omitempty"`
+	OldRate     float64 `json:"old_rate,omitempty"`
+	NewRate     float64 `json:"new_rate,omitempty"`
+	Special     bool    `json:"special,omitempty"`
+	Foreign     bool    `json:"foreign,omitempty"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// ErrorResponse is the body of every error market-data returns
+type ErrorResponse struct {
+	// Error is a human-readable message
+	Error string `json:"error"`
+	// Code is a stable reason clients can branch on, e.g. "invalid_symbol"
+	Code string `json:"code"`
+	// UpstreamStatus is the status Alpaca answered with when the error came from Alpaca
+	UpstreamStatus int `json:"upstream_status,omitempty"`
+}
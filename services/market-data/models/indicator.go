@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// IndicatorSeries is the body of GET /indicators/:symbol. Each indicator, keyed by its
+// name and parameters (e.g. "rsi_14"), maps output names to values aligned with
+// Timestamps; values are null until the indicator has enough history.
+type IndicatorSeries struct {
+	Symbol     string                           `json:"symbol"`
+	Timeframe  string                           `json:"timeframe"`
+	Timestamps []time.Time                      `json:"timestamps"`
+	Indicators map[string]map[string][]*float64 `json:"indicators"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Trade is a single print on the tape
+type Trade struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Price      float64   `json:"price"`
+	Size       float64   `json:"size"`
+	Exchange   string    `json:"exchange"`
+	ID         int64     `json:"id"`
+	Conditions []string  `json:"conditions"`
+	Tape       string    `json:"tape"`
+}
+
+// Quote is the national best bid and offer at a point in time
+type Quote struct {
+	Timestamp   time.Time `json:"timestamp"`
+	AskPrice    float64   `json:"ask_price"`
+	AskSize     float64   `json:"ask_size"`
+	AskExchange string    `json:"ask_exchange"`
+	BidPrice    float64   `json:"bid_price"`
+	BidSize     float64   `json:"bid_size"`
+	BidExchange string    `json:"bid_exchange"`
+	Conditions  []string  `json:"conditions"`
+	Tape        string    `json:"tape"`
+}
+
+// Snapshot is the current state of one symbol. Change and ChangePct compare the
+// latest price with the previous day's close and are null when either is missing.
+type Snapshot struct {
+	Symbol       string   `json:"symbol"`
+	LatestTrade  *Trade   `json:"latest_trade"`
+	LatestQuote  *Quote   `json:"latest_quote"`
+	MinuteBar    *Bar     `json:"minute_bar"`
+	DailyBar     *Bar     `json:"daily_bar"`
+	PrevDailyBar *Bar     `json:"prev_daily_bar"`
+	Change       *float64 `json:"change"`
+	ChangePct    *float64 `json:"change_pct"`
+}
@@ -0,0 +1,395 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
+	"github.com/seunghoon34/trading-app/services/paper-exchange/internal/exchange"
+	"github.com/seunghoon34/trading-app/services/paper-exchange/internal/pricefeed"
+)
+
+var (
+	sim        *exchange.Exchange
+	staticFeed *pricefeed.StaticFeed
+)
+
+// Init wires the handlers to the exchange and the adjustable price table
+func Init(ex *exchange.Exchange, feed *pricefeed.StaticFeed) {
+	sim = ex
+	staticFeed = feed
+}
+
+// respondError writes errors in the broker's {"code", "message"} shape
+func respondError(c *gin.Context, err error) {
+	if apiErr, ok := broker.AsAPIError(err); ok {
+		c.JSON(apiErr.StatusCode, apiErr)
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"code": 50010000, "message": err.Error()})
+}
+
+func invalidParam(c *gin.Context, format string, args ...interface{}) {
+	c.JSON(http.StatusUnprocessableEntity, gin.H{"code": exchange.CodeValidation, "message": fmt.Sprintf(format, args...)})
+}
+
+// RequireBasicAuth rejects requests whose Basic credentials don't match key and secret
+func RequireBasicAuth(key, secret string) gin.HandlerFunc {
+	expected := "Basic " + base64.StdEncoding.EncodeToString([]byte(key+":"+secret))
+	return func(c *gin.Context) {
+		if c.Request.URL.Path == "/health" {
+			c.Next()
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte(expected)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"code": exchange.CodeUnauthorized, "message": "request is not authorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func CreateAccount(c *gin.Context) {
+	var req exchange.CreateAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidParam(c, "invalid account request: %v", err)
+		return
+	}
+	c.JSON(http.StatusOK, sim.CreateAccount(req))
+}
+
+func ListAccounts(c *gin.Context) {
+	c.JSON(http.StatusOK, sim.ListAccounts())
+}
+
+func GetAccountRecord(c *gin.Context) {
+	record, err := sim.GetAccountRecord(c.Param("account_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, record)
+}
+
+func CreateACHRelationship(c *gin.Context) {
+	var req broker.ACHRelationshipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidParam(c, "invalid ach relationship request: %v", err)
+		return
+	}
+	relationship, err := sim.CreateACHRelationship(c.Param("account_id"), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, relationship)
+}
+
+func ListACHRelationships(c *gin.Context) {
+	relationships, err := sim.ListACHRelationships(c.Param("account_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, relationships)
+}
+
+func CreateTransfer(c *gin.Context) {
+	var req broker.TransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidParam(c, "invalid transfer request: %v", err)
+		return
+	}
+	transfer, err := sim.CreateTransfer(c.Param("account_id"), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, transfer)
+}
+
+func ListTransfers(c *gin.Context) {
+	transfers, err := sim.ListTransfers(c.Param("account_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, transfers)
+}
+
+// ListActivities serves the broker's account activity log; account_id is required
+func ListActivities(c *gin.Context) {
+	params := broker.ListActivitiesParams{
+		Direction: c.Query("direction"),
+		PageToken: c.Query("page_token"),
+	}
+	if types := c.Query("activity_types"); types != "" {
+		params.ActivityTypes = strings.Split(types, ",")
+	}
+	var err error
+	if params.After, err = parseTime(c.Query("after")); err != nil {
+		invalidParam(c, "invalid after %q", c.Query("after"))
+		return
+	}
+	if params.Until, err = parseTime(c.Query("until")); err != nil {
+		invalidParam(c, "invalid until %q", c.Query("until"))
+		return
+	}
+	if size := c.Query("page_size"); size != "" {
+		if params.PageSize, err = strconv.Atoi(size); err != nil || params.PageSize < 1 {
+			invalidParam(c, "invalid page_size %q", size)
+			return
+		}
+	}
+	accountID := c.Query("account_id")
+	if accountID == "" {
+		invalidParam(c, "account_id is required")
+		return
+	}
+
+	activities, err := sim.ListActivities(accountID, params)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, activities)
+}
+
+func SubmitOrder(c *gin.Context) {
+	var req broker.OrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidParam(c, "invalid order request: %v", err)
+		return
+	}
+	order, err := sim.SubmitOrder(c.Param("account_id"), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+func GetOrder(c *gin.Context) {
+	order, err := sim.GetOrder(c.Param("account_id"), c.Param("order_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+func ListOrders(c *gin.Context) {
+	params := broker.ListOrdersParams{
+		Status:    c.Query("status"),
+		Direction: c.Query("direction"),
+		Side:      c.Query("side"),
+		Nested:    c.Query("nested") == "true",
+	}
+	if symbols := c.Query("symbols"); symbols != "" {
+		params.Symbols = strings.Split(symbols, ",")
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			invalidParam(c, "invalid limit %q", limit)
+			return
+		}
+		params.Limit = n
+	}
+	var err error
+	if params.After, err = parseTime(c.Query("after")); err != nil {
+		invalidParam(c, "invalid after %q", c.Query("after"))
+		return
+	}
+	if params.Until, err = parseTime(c.Query("until")); err != nil {
+		invalidParam(c, "invalid until %q", c.Query("until"))
+		return
+	}
+
+	orders, err := sim.ListOrders(c.Param("account_id"), params)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, orders)
+}
+
+func ReplaceOrder(c *gin.Context) {
+	var req broker.ReplaceOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidParam(c, "invalid replace request: %v", err)
+		return
+	}
+	order, err := sim.ReplaceOrder(c.Param("account_id"), c.Param("order_id"), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+func CancelOrder(c *gin.Context) {
+	if err := sim.CancelOrder(c.Param("account_id"), c.Param("order_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func CancelAllOrders(c *gin.Context) {
+	statuses, err := sim.CancelAllOrders(c.Param("account_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusMultiStatus, statuses)
+}
+
+func ListPositions(c *gin.Context) {
+	positions, err := sim.ListPositions(c.Param("account_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, positions)
+}
+
+func GetPosition(c *gin.Context) {
+	position, err := sim.GetPosition(c.Param("account_id"), c.Param("symbol"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, position)
+}
+
+func ClosePosition(c *gin.Context) {
+	params := broker.ClosePositionParams{Qty: c.Query("qty"), Percentage: c.Query("percentage")}
+	order, err := sim.ClosePosition(c.Param("account_id"), c.Param("symbol"), params)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+func CloseAllPositions(c *gin.Context) {
+	statuses, err := sim.CloseAllPositions(c.Param("account_id"), c.Query("cancel_orders") == "true")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusMultiStatus, statuses)
+}
+
+func GetAccount(c *gin.Context) {
+	account, err := sim.GetAccount(c.Param("account_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+func GetPortfolioHistory(c *gin.Context) {
+	params := broker.PortfolioHistoryParams{
+		Period:            c.Query("period"),
+		Timeframe:         c.Query("timeframe"),
+		IntradayReporting: c.Query("intraday_reporting"),
+		PnlReset:          c.Query("pnl_reset"),
+		CashflowTypes:     c.Query("cashflow_types"),
+	}
+	var err error
+	if params.Start, err = parseTime(c.Query("start")); err != nil {
+		invalidParam(c, "invalid start %q", c.Query("start"))
+		return
+	}
+	if params.End, err = parseTime(c.Query("end")); err != nil {
+		invalidParam(c, "invalid end %q", c.Query("end"))
+		return
+	}
+
+	history, err := sim.GetPortfolioHistory(c.Param("account_id"), params)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// heartbeatInterval keeps idle event streams from being closed by proxies
+const heartbeatInterval = 15 * time.Second
+
+// StreamTradeEvents streams order updates as server-sent events
+func StreamTradeEvents(c *gin.Context) {
+	events, unsubscribe := sim.Events().Subscribe()
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(c.Writer, "id: %s\ndata: %s\n\n", event.EventID, data)
+		}
+		c.Writer.Flush()
+	}
+}
+
+// GetPrices returns the simulator's static price table
+func GetPrices(c *gin.Context) {
+	c.JSON(http.StatusOK, staticFeed.Prices())
+}
+
+// SetPrice moves a symbol's price and fills any orders the move triggers
+func SetPrice(c *gin.Context) {
+	var body struct {
+		Price float64 `json:"price" binding:"required,gt=0"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		invalidParam(c, "price must be a positive number")
+		return
+	}
+
+	symbol := strings.ToUpper(c.Param("symbol"))
+	staticFeed.SetPrice(symbol, body.Price)
+	sim.Sweep()
+
+	c.JSON(http.StatusOK, gin.H{"symbol": symbol, "price": body.Price})
+}
+
+// parseTime accepts RFC3339 timestamps or plain dates
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
+	"github.com/seunghoon34/trading-app/services/paper-exchange/internal/exchange"
+	"github.com/seunghoon34/trading-app/services/paper-exchange/internal/pricefeed"
+)
+
+// setupServer mounts the simulator behind the same routes main registers
+func setupServer(t *testing.T) (*httptest.Server, *broker.AlpacaBroker) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	feed := pricefeed.NewStaticFeed(map[string]float64{"AAPL": 100}, 0)
+	Init(exchange.New(feed, exchange.Config{InitialCash: 1000, AutoCreateAccounts: true}), feed)
+
+	r := gin.New()
+	r.Use(RequireBasicAuth("key", "secret"))
+	r.GET("/v1/accounts/activities", ListActivities)
+	r.GET("/v1/accounts/:account_id", GetAccountRecord)
+	r.POST("/v1/accounts/:account_id/ach_relationships", CreateACHRelationship)
+	r.POST("/v1/accounts/:account_id/transfers", CreateTransfer)
+	trading := r.Group("/v1/trading/accounts/:account_id")
+	trading.POST("/orders", SubmitOrder)
+	trading.GET("/orders", ListOrders)
+	trading.DELETE("/orders", CancelAllOrders)
+	trading.GET("/orders/:order_id", GetOrder)
+	trading.PATCH("/orders/:order_id", ReplaceOrder)
+	trading.DELETE("/orders/:order_id", CancelOrder)
+	trading.GET("/positions", ListPositions)
+	trading.GET("/positions/:symbol", GetPosition)
+	trading.DELETE("/positions", CloseAllPositions)
+	trading.DELETE("/positions/:symbol", ClosePosition)
+	trading.GET("/account", GetAccount)
+	trading.GET("/account/portfolio/history", GetPortfolioHistory)
+	r.GET("/v2/events/trades", StreamTradeEvents)
+	r.PUT("/sim/prices/:symbol", SetPrice)
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	client := broker.NewAlpaca(broker.Config{BaseURL: server.URL, APIKey: "key", APISecret: "secret"})
+	return server, client
+}
+
+func TestBrokerClientRoundTrip(t *testing.T) {
+	server, client := setupServer(t)
+	ctx := context.Background()
+
+	order, err := client.SubmitOrder(ctx, "acct", broker.OrderRequest{Symbol: "AAPL", Side: "buy", Type: "market", Qty: "2", TimeInForce: "day"})
+	if err != nil {
+		t.Fatalf("SubmitOrder failed: %v", err)
+	}
+	if order.Status != "filled" || order.FilledAvgPrice == nil || *order.FilledAvgPrice != "100" {
+		t.Fatalf("expected immediate fill at 100, got %+v", order)
+	}
+
+	limit, err := client.SubmitOrder(ctx, "acct", broker.OrderRequest{Symbol: "AAPL", Side: "sell", Type: "limit", Qty: "1", LimitPrice: "120", TimeInForce: "gtc"})
+	if err != nil {
+		t.Fatalf("SubmitOrder failed: %v", err)
+	}
+
+	open, err := client.ListOrders(ctx, "acct", broker.ListOrdersParams{Status: "open"})
+	if err != nil || len(open) != 1 || open[0].ID != limit.ID {
+		t.Fatalf("expected the limit order to be open, got %v (%v)", open, err)
+	}
+
+	// Moving the price through the limit fills the resting order
+	priceReq, err := http.NewRequest(http.MethodPut, server.URL+"/sim/prices/AAPL", strings.NewReader(`{"price": 125}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	priceReq.SetBasicAuth("key", "secret")
+	if res, err := http.DefaultClient.Do(priceReq); err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("SetPrice failed: %v", err)
+	}
+
+	filled, err := client.GetOrder(ctx, "acct", limit.ID)
+	if err != nil || filled.Status != "filled" {
+		t.Fatalf("expected limit order to fill, got %+v (%v)", filled, err)
+	}
+
+	position, err := client.GetPosition(ctx, "acct", "AAPL")
+	if err != nil {
+		t.Fatalf("GetPosition failed: %v", err)
+	}
+	if position.Quantity != "1" || position.CurrentPrice != "125" {
+		t.Errorf("unexpected position: %+v", position)
+	}
+
+	account, err := client.GetAccount(ctx, "acct")
+	if err != nil {
+		t.Fatalf("GetAccount failed: %v", err)
+	}
+	if account.Cash != "925" || account.Equity != "1050" {
+		t.Errorf("unexpected account balances: cash %s equity %s", account.Cash, account.Equity)
+	}
+
+	if _, err := client.GetPortfolioHistory(ctx, "acct", broker.PortfolioHistoryParams{Period: "1D", Timeframe: "15Min"}); err != nil {
+		t.Errorf("GetPortfolioHistory failed: %v", err)
+	}
+}
+
+func TestActivitiesRoundTrip(t *testing.T) {
+	_, client := setupServer(t)
+	ctx := context.Background()
+
+	relationship, err := client.CreateACHRelationship(ctx, "acct", broker.ACHRelationshipRequest{
+		AccountOwnerName: "Test User", BankAccountType: "CHECKING", BankAccountNumber: "123", BankRoutingNumber: "456",
+	})
+	if err != nil {
+		t.Fatalf("CreateACHRelationship failed: %v", err)
+	}
+	for _, transfer := range []broker.TransferRequest{
+		{TransferType: "ach", RelationshipID: relationship.ID, Amount: "500", Direction: "INCOMING"},
+		{TransferType: "ach", RelationshipID: relationship.ID, Amount: "200", Direction: "OUTGOING"},
+		{TransferType: "ach", RelationshipID: relationship.ID, Amount: "300", Direction: "INCOMING"},
+	} {
+		if _, err := client.CreateTransfer(ctx, "acct", transfer); err != nil {
+			t.Fatalf("CreateTransfer failed: %v", err)
+		}
+	}
+
+	page, err := client.ListActivities(ctx, "acct", broker.ListActivitiesParams{PageSize: 2})
+	if err != nil {
+		t.Fatalf("ListActivities failed: %v", err)
+	}
+	if len(page) != 2 || page[0].NetAmount != "300" || page[1].ActivityType != broker.ActivityWithdrawal || page[1].NetAmount != "-200" {
+		t.Fatalf("expected the newest two transfers, got %+v", page)
+	}
+	rest, err := client.ListActivities(ctx, "acct", broker.ListActivitiesParams{PageSize: 2, PageToken: page[1].ID})
+	if err != nil || len(rest) != 1 || rest[0].NetAmount != "500" {
+		t.Fatalf("expected the first deposit on the next page, got %+v (%v)", rest, err)
+	}
+
+	deposits, err := client.ListActivities(ctx, "acct", broker.ListActivitiesParams{ActivityTypes: []string{broker.ActivityDeposit}, Direction: "asc"})
+	if err != nil || len(deposits) != 2 || deposits[0].NetAmount != "500" {
+		t.Errorf("expected both deposits oldest first, got %+v (%v)", deposits, err)
+	}
+
+	order, err := client.SubmitOrder(ctx, "acct", broker.OrderRequest{Symbol: "AAPL", Side: "buy", Type: "market", Qty: "2", TimeInForce: "day"})
+	if err != nil {
+		t.Fatalf("SubmitOrder failed: %v", err)
+	}
+	fills, err := client.ListActivities(ctx, "acct", broker.ListActivitiesParams{ActivityTypes: []string{broker.ActivityFill}})
+	if err != nil || len(fills) != 1 {
+		t.Fatalf("expected one fill, got %+v (%v)", fills, err)
+	}
+	if fill := fills[0]; fill.OrderID != order.ID || fill.Symbol != "AAPL" || fill.Side != "buy" || fill.Qty != "2" || fill.Price != "100" || fill.TransactionTime == nil {
+		t.Errorf("unexpected fill %+v", fill)
+	}
+	if all, _ := client.ListActivities(ctx, "acct", broker.ListActivitiesParams{}); len(all) != 4 || all[0].ActivityType != broker.ActivityFill {
+		t.Errorf("expected the fill first among all activities, got %+v", all)
+	}
+}
+
+func TestBrokerClientErrors(t *testing.T) {
+	_, client := setupServer(t)
+	ctx := context.Background()
+
+	_, err := client.SubmitOrder(ctx, "acct", broker.OrderRequest{Symbol: "AAPL", Side: "buy", Type: "market", Qty: "100", TimeInForce: "day"})
+	apiErr, ok := broker.AsAPIError(err)
+	if !ok || apiErr.StatusCode != http.StatusForbidden || apiErr.Code != exchange.CodeForbidden {
+		t.Errorf("expected 403 insufficient buying power, got %v", err)
+	}
+
+	if _, err := client.GetPosition(ctx, "acct", "MSFT"); !broker.IsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+
+	if _, err := client.GetOrder(ctx, "acct", "missing"); !broker.IsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+
+	unauthorized := broker.NewAlpaca(broker.Config{BaseURL: strings.TrimSuffix(client.BaseURL(), "/"), APIKey: "key", APISecret: "wrong"})
+	_, err = unauthorized.GetAccount(ctx, "acct")
+	if apiErr, ok := broker.AsAPIError(err); !ok || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %v", err)
+	}
+}
+
+func TestStreamTradeEvents(t *testing.T) {
+	server, client := setupServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/v2/events/trades", nil)
+	req.SetBasicAuth("key", "secret")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	defer res.Body.Close()
+
+	if _, err := client.SubmitOrder(ctx, "acct", broker.OrderRequest{Symbol: "AAPL", Side: "buy", Type: "market", Qty: "1", TimeInForce: "day"}); err != nil {
+		t.Fatalf("SubmitOrder failed: %v", err)
+	}
+
+	var events []broker.TradeEvent
+	scanner := bufio.NewScanner(res.Body)
+	for len(events) < 2 && scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var event broker.TradeEvent
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+
+	if len(events) != 2 || events[0].Event != "new" || events[1].Event != "fill" {
+		t.Fatalf("expected new and fill events, got %+v", events)
+	}
+	if events[1].Order.Symbol != "AAPL" || events[1].AccountID != "acct" {
+		t.Errorf("unexpected fill event: %+v", events[1])
+	}
+}
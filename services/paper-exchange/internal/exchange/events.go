@@ -0,0 +1,59 @@
+package exchange
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/seunghoon34/trading-app/pkg/broker"
+)
+
+// subscriberBuffer is how many events a slow subscriber may fall behind before
+// further events are dropped for it
+const subscriberBuffer = 100
+
+// Hub fans trade events out to stream subscribers
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[chan broker.TradeEvent]struct{}
+	sequence    int64
+}
+
+// NewHub creates a hub with no subscribers
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[chan broker.TradeEvent]struct{})}
+}
+
+// Subscribe registers a subscriber and returns its channel and an unsubscribe func
+func (h *Hub) Subscribe() (<-chan broker.TradeEvent, func()) {
+	ch := make(chan broker.TradeEvent, subscriberBuffer)
+
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subscribers[ch]; ok {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish assigns the event an ID and delivers it without blocking
+func (h *Hub) Publish(event broker.TradeEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sequence++
+	event.EventID = strconv.FormatInt(h.sequence, 10)
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Subscriber is not keeping up; drop rather than stall the exchange
+		}
+	}
+}
@@ -0,0 +1,296 @@
+// Package exchange is an in-memory simulation of the brokerage: accounts, cash,
+// orders, positions and funding. Market orders fill immediately against the price
+// feed; resting limit and stop orders fill when Sweep sees the price cross them.
+package exchange
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/seunghoon34/trading-app/pkg/broker"
+	"github.com/seunghoon34/trading-app/services/paper-exchange/internal/pricefeed"
+)
+
+// Alpaca-style error codes returned alongside the HTTP status
+const (
+	CodeValidation   = 42210000
+	CodeForbidden    = 40310000
+	CodeNotFound     = 40410000
+	CodeUnauthorized = 40110000
+)
+
+// Config controls how new accounts are set up
+type Config struct {
+	// InitialCash is credited to every new account
+	InitialCash float64
+	// AutoCreateAccounts opens an account the first time an unknown ID is used,
+	// so services keep working after the simulator restarts and loses its state
+	AutoCreateAccounts bool
+}
+
+// ConfigFromEnv reads PAPER_INITIAL_CASH and PAPER_AUTO_CREATE_ACCOUNTS (default true)
+func ConfigFromEnv() (Config, error) {
+	config := Config{AutoCreateAccounts: true}
+
+	if value := os.Getenv("PAPER_INITIAL_CASH"); value != "" {
+		cash, err := strconv.ParseFloat(value, 64)
+		if err != nil || cash < 0 {
+			return config, fmt.Errorf("invalid PAPER_INITIAL_CASH %q", value)
+		}
+		config.InitialCash = cash
+	}
+	if value := os.Getenv("PAPER_AUTO_CREATE_ACCOUNTS"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid PAPER_AUTO_CREATE_ACCOUNTS %q", value)
+		}
+		config.AutoCreateAccounts = enabled
+	}
+	return config, nil
+}
+
+// AccountRecord is the brokerage account as returned by the accounts API
+type AccountRecord struct {
+	ID            string          `json:"id"`
+	AccountNumber string          `json:"account_number"`
+	Status        string          `json:"status"`
+	Currency      string          `json:"currency"`
+	AccountType   string          `json:"account_type"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Contact       json.RawMessage `json:"contact,omitempty"`
+	Identity      json.RawMessage `json:"identity,omitempty"`
+}
+
+// CreateAccountRequest is the subset of the account application the simulator keeps
+type CreateAccountRequest struct {
+	AccountType string          `json:"account_type"`
+	Contact     json.RawMessage `json:"contact"`
+	Identity    json.RawMessage `json:"identity"`
+}
+
+// Exchange holds the state of every simulated account
+type Exchange struct {
+	mu       sync.Mutex
+	feed     pricefeed.Feed
+	config   Config
+	accounts map[string]*account
+	ids      []string
+	events   *Hub
+	now      func() time.Time
+}
+
+type account struct {
+	record        AccountRecord
+	cash          float64
+	positions     map[string]*position
+	orders        map[string]*order
+	orderIDs      []string
+	relationships []broker.ACHRelationship
+	transfers     []broker.Transfer
+	fills         []broker.Activity
+	history       []snapshot
+}
+
+// New creates an empty exchange pricing orders from feed
+func New(feed pricefeed.Feed, config Config) *Exchange {
+	return &Exchange{
+		feed:     feed,
+		config:   config,
+		accounts: make(map[string]*account),
+		events:   NewHub(),
+		now:      time.Now,
+	}
+}
+
+// Events is the trade event hub the SSE stream subscribes to
+func (e *Exchange) Events() *Hub {
+	return e.events
+}
+
+// CreateAccount opens a new account funded with the configured initial cash
+func (e *Exchange) CreateAccount(req CreateAccountRequest) AccountRecord {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.openAccount(newID(), req).record
+}
+
+// GetAccountRecord returns the account application record
+func (e *Exchange) GetAccountRecord(accountID string) (*AccountRecord, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	acct, err := e.account(accountID)
+	if err != nil {
+		return nil, err
+	}
+	record := acct.record
+	return &record, nil
+}
+
+// ListAccounts returns every account in creation order
+func (e *Exchange) ListAccounts() []AccountRecord {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	records := make([]AccountRecord, 0, len(e.ids))
+	for _, id := range e.ids {
+		records = append(records, e.accounts[id].record)
+	}
+	return records
+}
+
+// openAccount must be called with e.mu held
+func (e *Exchange) openAccount(id string, req CreateAccountRequest) *account {
+	now := e.now()
+	accountType := req.AccountType
+	if accountType == "" {
+		accountType = "trading"
+	}
+
+	acct := &account{
+		record: AccountRecord{
+			ID:            id,
+			AccountNumber: fmt.Sprintf("PA%08d", len(e.ids)+1),
+			Status:        "ACTIVE",
+			Currency:      "USD",
+			AccountType:   accountType,
+			CreatedAt:     now,
+			Contact:       req.Contact,
+			Identity:      req.Identity,
+		},
+		cash:      e.config.InitialCash,
+		positions: make(map[string]*position),
+		orders:    make(map[string]*order),
+	}
+	acct.history = append(acct.history, snapshot{at: now, equity: acct.cash})
+
+	e.accounts[id] = acct
+	e.ids = append(e.ids, id)
+	return acct
+}
+
+// account looks up an account, opening it when auto-creation is enabled.
+// Must be called with e.mu held.
+func (e *Exchange) account(accountID string) (*account, error) {
+	if acct, ok := e.accounts[accountID]; ok {
+		return acct, nil
+	}
+	if e.config.AutoCreateAccounts && accountID != "" {
+		return e.openAccount(accountID, CreateAccountRequest{}), nil
+	}
+	return nil, notFound("account not found")
+}
+
+// prices looks up the feed price of each symbol without holding the lock, since
+// feeds may call out over the network. Symbols without a price are left out.
+func (e *Exchange) prices(symbols []string) map[string]float64 {
+	prices := make(map[string]float64, len(symbols))
+	for _, symbol := range symbols {
+		if _, ok := prices[symbol]; ok {
+			continue
+		}
+		if price, err := e.feed.Price(symbol); err == nil && price > 0 {
+			prices[symbol] = price
+		}
+	}
+	return prices
+}
+
+// heldSymbols lists the symbols an account has positions in
+func (e *Exchange) heldSymbols(accountID string) []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	acct, ok := e.accounts[accountID]
+	if !ok {
+		return nil
+	}
+	symbols := make([]string, 0, len(acct.positions))
+	for symbol := range acct.positions {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+func apiError(status, code int, format string, args ...interface{}) *broker.APIError {
+	return &broker.APIError{StatusCode: status, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func notFound(message string) *broker.APIError {
+	return apiError(http.StatusNotFound, CodeNotFound, "%s", message)
+}
+
+func invalid(format string, args ...interface{}) *broker.APIError {
+	return apiError(http.StatusUnprocessableEntity, CodeValidation, format, args...)
+}
+
+func forbidden(format string, args ...interface{}) *broker.APIError {
+	return apiError(http.StatusForbidden, CodeForbidden, format, args...)
+}
+
+// newID returns a random UUIDv4
+func newID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// assetID derives a stable UUID-shaped asset ID from the symbol
+func assetID(symbol string) string {
+	b := sha1.Sum([]byte("asset:" + symbol))
+	b[6] = (b[6] & 0x0f) | 0x50
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// Decimal places used when formatting amounts the way the broker does
+const (
+	moneyPlaces = 2
+	pricePlaces = 4
+	qtyPlaces   = 9
+)
+
+// epsilon absorbs float rounding when comparing quantities and cash
+const epsilon = 1e-9
+
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
+
+func formatDecimal(value float64, places int) string {
+	value = round(value, places)
+	if value == 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func parseDecimal(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+func stringPtr(value string) *string {
+	return &value
+}
+
+func timePtr(value time.Time) *time.Time {
+	return &value
+}
@@ -0,0 +1,513 @@
+package exchange
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/seunghoon34/trading-app/pkg/broker"
+	"github.com/seunghoon34/trading-app/services/paper-exchange/internal/pricefeed"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestExchange(t *testing.T, cash float64) (*Exchange, *pricefeed.StaticFeed, *testClock) {
+	t.Helper()
+	feed := pricefeed.NewStaticFeed(map[string]float64{"AAPL": 100, "MSFT": 200}, 0)
+	clock := &testClock{now: time.Date(2024, 3, 4, 15, 0, 0, 0, time.UTC)}
+	ex := New(feed, Config{InitialCash: cash, AutoCreateAccounts: true})
+	ex.now = clock.Now
+	return ex, feed, clock
+}
+
+func expectStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	apiErr, ok := broker.AsAPIError(err)
+	if !ok {
+		t.Fatalf("expected APIError with status %d, got %v", status, err)
+	}
+	if apiErr.StatusCode != status {
+		t.Fatalf("expected status %d, got %d (%s)", status, apiErr.StatusCode, apiErr.Message)
+	}
+}
+
+func TestMarketOrderFillsAgainstFeed(t *testing.T) {
+	ex, _, _ := newTestExchange(t, 10000)
+
+	order, err := ex.SubmitOrder("acct", broker.OrderRequest{Symbol: "aapl", Side: "buy", Type: "market", Qty: "10"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.Status != StatusFilled || order.FilledQty != "10" || *order.FilledAvgPrice != "100" {
+		t.Fatalf("expected a fill of 10 @ 100, got %+v", order)
+	}
+
+	account, _ := ex.GetAccount("acct")
+	if account.Cash != "9000" || account.Equity != "10000" || account.LongMarketValue != "1000" {
+		t.Errorf("unexpected balances: %+v", account)
+	}
+
+	position, err := ex.GetPosition("acct", "AAPL")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if position.Quantity != "10" || position.AvgEntryPrice != "100" || position.MarketValue != "1000" {
+		t.Errorf("unexpected position: %+v", position)
+	}
+}
+
+func TestNotionalOrderBuysFractionalShares(t *testing.T) {
+	ex, _, _ := newTestExchange(t, 1000)
+
+	order, err := ex.SubmitOrder("acct", broker.OrderRequest{Symbol: "MSFT", Side: "buy", Type: "market", Notional: "50"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.FilledQty != "0.25" {
+		t.Errorf("expected 0.25 shares, got %s", order.FilledQty)
+	}
+}
+
+func TestOrderRejections(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    broker.OrderRequest
+		status int
+	}{
+		{"insufficient buying power", broker.OrderRequest{Symbol: "AAPL", Side: "buy", Type: "market", Qty: "11"}, http.StatusForbidden},
+		{"selling shares not held", broker.OrderRequest{Symbol: "AAPL", Side: "sell", Type: "market", Qty: "1"}, http.StatusForbidden},
+		{"unknown symbol", broker.OrderRequest{Symbol: "ZZZZ", Side: "buy", Type: "market", Qty: "1"}, http.StatusUnprocessableEntity},
+		{"missing limit price", broker.OrderRequest{Symbol: "AAPL", Side: "buy", Type: "limit", Qty: "1"}, http.StatusUnprocessableEntity},
+		{"qty and notional", broker.OrderRequest{Symbol: "AAPL", Side: "buy", Type: "market", Qty: "1", Notional: "10"}, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ex, _, _ := newTestExchange(t, 1000)
+			_, err := ex.SubmitOrder("acct", tt.req)
+			expectStatus(t, err, tt.status)
+		})
+	}
+}
+
+func TestLimitOrderRestsUntilPriceCrosses(t *testing.T) {
+	ex, feed, _ := newTestExchange(t, 1000)
+
+	order, err := ex.SubmitOrder("acct", broker.OrderRequest{Symbol: "AAPL", Side: "buy", Type: "limit", Qty: "5", LimitPrice: "95", TimeInForce: "gtc"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.Status != StatusNew {
+		t.Fatalf("expected resting order, got %s", order.Status)
+	}
+
+	// The open order holds back buying power
+	account, _ := ex.GetAccount("acct")
+	if account.BuyingPower != "525" {
+		t.Errorf("expected buying power 525, got %s", account.BuyingPower)
+	}
+
+	feed.SetPrice("AAPL", 94)
+	ex.Sweep()
+
+	filled, _ := ex.GetOrder("acct", order.ID)
+	if filled.Status != StatusFilled || *filled.FilledAvgPrice != "94" {
+		t.Fatalf("expected fill at 94, got %+v", filled)
+	}
+}
+
+func TestStopOrderTriggers(t *testing.T) {
+	ex, feed, _ := newTestExchange(t, 1000)
+	ex.SubmitOrder("acct", broker.OrderRequest{Symbol: "AAPL", Side: "buy", Type: "market", Qty: "5"})
+
+	stop, err := ex.SubmitOrder("acct", broker.OrderRequest{Symbol: "AAPL", Side: "sell", Type: "stop", Qty: "5", StopPrice: "90"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	feed.SetPrice("AAPL", 91)
+	ex.Sweep()
+	if order, _ := ex.GetOrder("acct", stop.ID); order.Status != StatusNew {
+		t.Fatalf("stop should not trigger above 90, got %s", order.Status)
+	}
+
+	feed.SetPrice("AAPL", 89)
+	ex.Sweep()
+	if order, _ := ex.GetOrder("acct", stop.ID); order.Status != StatusFilled {
+		t.Fatalf("expected stop to fill, got %s", order.Status)
+	}
+	if _, err := ex.GetPosition("acct", "AAPL"); !broker.IsNotFound(err) {
+		t.Errorf("expected position to be closed, got %v", err)
+	}
+}
+
+func TestTrailingStopFollowsHighWaterMark(t *testing.T) {
+	ex, feed, _ := newTestExchange(t, 1000)
+	ex.SubmitOrder("acct", broker.OrderRequest{Symbol: "AAPL", Side: "buy", Type: "market", Qty: "1"})
+
+	trailing, err := ex.SubmitOrder("acct", broker.OrderRequest{Symbol: "AAPL", Side: "sell", Type: "trailing_stop", Qty: "1", TrailPrice: "5", TimeInForce: "gtc"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *trailing.StopPrice != "95" {
+		t.Fatalf("expected initial stop at 95, got %s", *trailing.StopPrice)
+	}
+
+	feed.SetPrice("AAPL", 120)
+	ex.Sweep()
+	feed.SetPrice("AAPL", 116)
+	ex.Sweep()
+	order, _ := ex.GetOrder("acct", trailing.ID)
+	if order.Status != StatusNew || *order.StopPrice != "115" || *order.HWM != "120" {
+		t.Fatalf("expected stop to trail to 115, got %+v", order)
+	}
+
+	feed.SetPrice("AAPL", 114)
+	ex.Sweep()
+	if order, _ := ex.GetOrder("acct", trailing.ID); order.Status != StatusFilled {
+		t.Fatalf("expected trailing stop to fill, got %s", order.Status)
+	}
+}
+
+func TestBracketOrderLegs(t *testing.T) {
+	ex, feed, _ := newTestExchange(t, 1000)
+
+	order, err := ex.SubmitOrder("acct", broker.OrderRequest{
+		Symbol: "AAPL", Side: "buy", Type: "market", Qty: "2", TimeInForce: "gtc",
+		OrderClass: "bracket",
+		TakeProfit: &broker.TakeProfit{LimitPrice: "110"},
+		StopLoss:   &broker.StopLoss{StopPrice: "90"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.Status != StatusFilled || len(order.Legs) != 2 {
+		t.Fatalf("expected filled entry with two legs, got %+v", order)
+	}
+	for _, leg := range order.Legs {
+		if leg.Status != StatusNew || leg.Side != "sell" {
+			t.Fatalf("expected working sell legs after entry fill, got %+v", leg)
+		}
+	}
+
+	// Both exits cover the same shares, so they only reserve them once
+	position, _ := ex.GetPosition("acct", "AAPL")
+	if position.QtyAvailable != "0" {
+		t.Errorf("expected no shares available, got %s", position.QtyAvailable)
+	}
+
+	feed.SetPrice("AAPL", 111)
+	ex.Sweep()
+
+	takeProfit, _ := ex.GetOrder("acct", order.Legs[0].ID)
+	stopLoss, _ := ex.GetOrder("acct", order.Legs[1].ID)
+	if takeProfit.Status != StatusFilled || stopLoss.Status != StatusCanceled {
+		t.Errorf("expected take-profit filled and stop-loss canceled, got %s and %s", takeProfit.Status, stopLoss.Status)
+	}
+}
+
+func TestBracketLegsHeldUntilEntryFills(t *testing.T) {
+	ex, _, _ := newTestExchange(t, 1000)
+
+	order, err := ex.SubmitOrder("acct", broker.OrderRequest{
+		Symbol: "AAPL", Side: "buy", Type: "limit", LimitPrice: "95", Qty: "1", TimeInForce: "gtc",
+		OrderClass: "bracket",
+		TakeProfit: &broker.TakeProfit{LimitPrice: "110"},
+		StopLoss:   &broker.StopLoss{StopPrice: "90", LimitPrice: "89"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.Legs[0].Status != StatusHeld || order.Legs[1].Type != "stop_limit" {
+		t.Fatalf("unexpected legs: %+v", order.Legs)
+	}
+
+	if err := ex.CancelOrder("acct", order.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	canceled, _ := ex.GetOrder("acct", order.ID)
+	for _, leg := range canceled.Legs {
+		if leg.Status != StatusCanceled {
+			t.Errorf("expected legs to be canceled with the entry, got %s", leg.Status)
+		}
+	}
+
+	err = ex.CancelOrder("acct", order.ID)
+	expectStatus(t, err, http.StatusUnprocessableEntity)
+}
+
+func TestListOrdersFilters(t *testing.T) {
+	ex, _, clock := newTestExchange(t, 10000)
+
+	ex.SubmitOrder("acct", broker.OrderRequest{Symbol: "AAPL", Side: "buy", Type: "market", Qty: "1"})
+	clock.Advance(time.Minute)
+	open, _ := ex.SubmitOrder("acct", broker.OrderRequest{Symbol: "MSFT", Side: "buy", Type: "limit", LimitPrice: "150", Qty: "1"})
+	clock.Advance(time.Minute)
+	ex.SubmitOrder("acct", broker.OrderRequest{Symbol: "MSFT", Side: "buy", Type: "market", Qty: "1"})
+
+	tests := []struct {
+		name   string
+		params broker.ListOrdersParams
+		want   int
+	}{
+		{"default is open", broker.ListOrdersParams{}, 1},
+		{"closed", broker.ListOrdersParams{Status: "closed"}, 2},
+		{"all for symbol", broker.ListOrdersParams{Status: "all", Symbols: []string{"MSFT"}}, 2},
+		{"after", broker.ListOrdersParams{Status: "all", After: open.CreatedAt}, 1},
+		{"limit", broker.ListOrdersParams{Status: "all", Limit: 2}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders, err := ex.ListOrders("acct", tt.params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(orders) != tt.want {
+				t.Errorf("expected %d orders, got %d", tt.want, len(orders))
+			}
+		})
+	}
+
+	orders, _ := ex.ListOrders("acct", broker.ListOrdersParams{Status: "all"})
+	if orders[0].Symbol != "MSFT" || orders[2].Symbol != "AAPL" {
+		t.Errorf("expected newest first")
+	}
+}
+
+func TestClosePosition(t *testing.T) {
+	ex, _, _ := newTestExchange(t, 10000)
+	if _, err := ex.SubmitOrder("acct", broker.OrderRequest{Symbol: "AAPL", Side: "buy", Type: "market", Qty: "10"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	order, err := ex.ClosePosition("acct", "aapl", broker.ClosePositionParams{Percentage: "25"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.Side != "sell" || order.FilledQty != "2.5" {
+		t.Errorf("expected a sell of 2.5 shares, got %+v", order)
+	}
+
+	_, err = ex.ClosePosition("acct", "AAPL", broker.ClosePositionParams{Qty: "8"})
+	expectStatus(t, err, http.StatusForbidden)
+	_, err = ex.ClosePosition("acct", "AAPL", broker.ClosePositionParams{Percentage: "150"})
+	expectStatus(t, err, http.StatusUnprocessableEntity)
+	_, err = ex.ClosePosition("acct", "AAPL", broker.ClosePositionParams{Qty: "1", Percentage: "10"})
+	expectStatus(t, err, http.StatusUnprocessableEntity)
+	_, err = ex.ClosePosition("acct", "MSFT", broker.ClosePositionParams{})
+	expectStatus(t, err, http.StatusNotFound)
+
+	if _, err := ex.ClosePosition("acct", "AAPL", broker.ClosePositionParams{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = ex.GetPosition("acct", "AAPL")
+	expectStatus(t, err, http.StatusNotFound)
+}
+
+func TestCloseAllPositions(t *testing.T) {
+	ex, _, _ := newTestExchange(t, 10000)
+	for _, symbol := range []string{"AAPL", "MSFT"} {
+		if _, err := ex.SubmitOrder("acct", broker.OrderRequest{Symbol: symbol, Side: "buy", Type: "market", Qty: "5"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// A resting sell holds the MSFT shares, so closing it fails unless orders are cancelled
+	if _, err := ex.SubmitOrder("acct", broker.OrderRequest{Symbol: "MSFT", Side: "sell", Type: "limit", Qty: "5", LimitPrice: "300", TimeInForce: "gtc"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	statuses, err := ex.CloseAllPositions("acct", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].Status != http.StatusOK || statuses[1].Status != http.StatusForbidden {
+		t.Fatalf("expected AAPL to close and MSFT to fail, got %+v", statuses)
+	}
+
+	statuses, err = ex.CloseAllPositions("acct", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].Symbol != "MSFT" || statuses[0].Status != http.StatusOK {
+		t.Fatalf("expected MSFT to close, got %+v", statuses)
+	}
+	if positions, _ := ex.ListPositions("acct"); len(positions) != 0 {
+		t.Errorf("expected no positions, got %+v", positions)
+	}
+}
+
+func TestTransfersAndHistory(t *testing.T) {
+	ex, feed, clock := newTestExchange(t, 0)
+
+	if _, err := ex.CreateTransfer("acct", broker.TransferRequest{TransferType: "ach", RelationshipID: "missing", Amount: "100", Direction: "INCOMING"}); err == nil {
+		t.Fatalf("expected unknown relationship to be rejected")
+	}
+
+	relationship, err := ex.CreateACHRelationship("acct", broker.ACHRelationshipRequest{
+		AccountOwnerName: "Test User", BankAccountType: "CHECKING", BankAccountNumber: "123", BankRoutingNumber: "456",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	clock.Advance(24 * time.Hour)
+	if _, err := ex.CreateTransfer("acct", broker.TransferRequest{TransferType: "ach", RelationshipID: relationship.ID, Amount: "1000", Direction: "INCOMING"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ex.SubmitOrder("acct", broker.OrderRequest{Symbol: "AAPL", Side: "buy", Type: "market", Qty: "10"})
+
+	clock.Advance(24 * time.Hour)
+	feed.SetPrice("AAPL", 110)
+
+	history, err := ex.GetPortfolioHistory("acct", broker.PortfolioHistoryParams{Period: "1W", Timeframe: "1D", CashflowTypes: "ALL"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(history.Equity) != 3 {
+		t.Fatalf("expected 3 daily points since the account opened, got %d", len(history.Equity))
+	}
+	if history.Equity[1] != 1000 || history.Equity[2] != 1100 {
+		t.Errorf("unexpected equity series: %v", history.Equity)
+	}
+	// The deposit is a cash flow, not profit
+	if history.ProfitLoss[1] != 0 || history.ProfitLoss[2] != 100 {
+		t.Errorf("unexpected profit/loss series: %v", history.ProfitLoss)
+	}
+	if history.Cashflow["CSD"][1] != 1000 {
+		t.Errorf("expected the deposit in the cash flow series, got %v", history.Cashflow["CSD"])
+	}
+
+	_, err = ex.CreateTransfer("acct", broker.TransferRequest{TransferType: "ach", RelationshipID: relationship.ID, Amount: "5000", Direction: "OUTGOING"})
+	expectStatus(t, err, http.StatusForbidden)
+}
+
+func TestEventsPublished(t *testing.T) {
+	ex, _, _ := newTestExchange(t, 1000)
+	events, unsubscribe := ex.Events().Subscribe()
+	defer unsubscribe()
+
+	ex.SubmitOrder("acct", broker.OrderRequest{Symbol: "AAPL", Side: "buy", Type: "market", Qty: "1"})
+
+	first, second := <-events, <-events
+	if first.Event != "new" || second.Event != "fill" {
+		t.Fatalf("expected new then fill, got %s then %s", first.Event, second.Event)
+	}
+	if second.Price != "100" || second.PositionQty != "1" || second.AccountID != "acct" {
+		t.Errorf("unexpected fill event: %+v", second)
+	}
+	if first.EventID == second.EventID {
+		t.Errorf("expected distinct event IDs")
+	}
+}
+
+func TestUnknownAccountWithoutAutoCreate(t *testing.T) {
+	ex := New(pricefeed.NewStaticFeed(nil, 100), Config{})
+
+	_, err := ex.GetAccount("missing")
+	expectStatus(t, err, http.StatusNotFound)
+
+	record := ex.CreateAccount(CreateAccountRequest{})
+	if _, err := ex.GetAccount(record.ID); err != nil {
+		t.Errorf("expected created account to exist, got %v", err)
+	}
+}
+
+func TestReplaceOrder(t *testing.T) {
+	ex, feed, _ := newTestExchange(t, 1000)
+
+	original, _ := ex.SubmitOrder("acct", broker.OrderRequest{Symbol: "AAPL", Side: "buy", Type: "limit", Qty: "5", LimitPrice: "90", TimeInForce: "gtc"})
+
+	replacement, err := ex.ReplaceOrder("acct", original.ID, broker.ReplaceOrderRequest{Qty: "8", LimitPrice: "95"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if replacement.ID == original.ID || *replacement.Replaces != original.ID || *replacement.Qty != "8" || *replacement.LimitPrice != "95" {
+		t.Fatalf("unexpected replacement: %+v", replacement)
+	}
+
+	replaced, _ := ex.GetOrder("acct", original.ID)
+	if replaced.Status != StatusReplaced || *replaced.ReplacedBy != replacement.ID {
+		t.Errorf("expected original to be replaced, got %+v", replaced)
+	}
+
+	// Only the replacement holds buying power
+	account, _ := ex.GetAccount("acct")
+	if account.BuyingPower != "240" {
+		t.Errorf("expected buying power 240, got %s", account.BuyingPower)
+	}
+
+	feed.SetPrice("AAPL", 94)
+	ex.Sweep()
+	if filled, _ := ex.GetOrder("acct", replacement.ID); filled.Status != StatusFilled {
+		t.Errorf("expected replacement to fill, got %s", filled.Status)
+	}
+}
+
+func TestReplaceOrderRejections(t *testing.T) {
+	tests := []struct {
+		name   string
+		order  broker.OrderRequest
+		change broker.ReplaceOrderRequest
+		status int
+	}{
+		{"no changes", broker.OrderRequest{Type: "limit", LimitPrice: "90"}, broker.ReplaceOrderRequest{}, http.StatusUnprocessableEntity},
+		{"stop price on limit", broker.OrderRequest{Type: "limit", LimitPrice: "90"}, broker.ReplaceOrderRequest{StopPrice: "80"}, http.StatusUnprocessableEntity},
+		{"trail on stop", broker.OrderRequest{Type: "stop", StopPrice: "110"}, broker.ReplaceOrderRequest{Trail: "1"}, http.StatusUnprocessableEntity},
+		{"bad time in force", broker.OrderRequest{Type: "limit", LimitPrice: "90"}, broker.ReplaceOrderRequest{TimeInForce: "week"}, http.StatusUnprocessableEntity},
+		{"not enough buying power", broker.OrderRequest{Type: "limit", LimitPrice: "90"}, broker.ReplaceOrderRequest{Qty: "20"}, http.StatusForbidden},
+		{"filled order", broker.OrderRequest{Type: "market"}, broker.ReplaceOrderRequest{Qty: "2"}, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ex, _, _ := newTestExchange(t, 1000)
+			tt.order.Symbol, tt.order.Side, tt.order.Qty, tt.order.TimeInForce = "AAPL", "buy", "1", "gtc"
+			order, err := ex.SubmitOrder("acct", tt.order)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, err = ex.ReplaceOrder("acct", order.ID, tt.change)
+			expectStatus(t, err, tt.status)
+
+			// A rejected replace leaves the original untouched
+			if unchanged, _ := ex.GetOrder("acct", order.ID); unchanged.ReplacedBy != nil {
+				t.Errorf("expected original to stay in place, got %+v", unchanged)
+			}
+		})
+	}
+}
+
+func TestReplaceBracketLeg(t *testing.T) {
+	ex, _, _ := newTestExchange(t, 1000)
+
+	order, _ := ex.SubmitOrder("acct", broker.OrderRequest{
+		Symbol: "AAPL", Side: "buy", Type: "market", Qty: "2", TimeInForce: "gtc",
+		OrderClass: "bracket",
+		TakeProfit: &broker.TakeProfit{LimitPrice: "110"},
+		StopLoss:   &broker.StopLoss{StopPrice: "90"},
+	})
+
+	replacement, err := ex.ReplaceOrder("acct", order.Legs[1].ID, broker.ReplaceOrderRequest{StopPrice: "95"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parent, _ := ex.GetOrder("acct", order.ID)
+	if parent.Legs[1].ID != replacement.ID || *parent.Legs[1].StopPrice != "95" {
+		t.Errorf("expected the parent to carry the replacement leg, got %+v", parent.Legs[1])
+	}
+
+	_, err = ex.ReplaceOrder("acct", order.Legs[0].ID, broker.ReplaceOrderRequest{Qty: "1"})
+	expectStatus(t, err, http.StatusUnprocessableEntity)
+}
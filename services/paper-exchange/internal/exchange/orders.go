@@ -0,0 +1,994 @@
+package exchange
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/seunghoon34/trading-app/pkg/broker"
+)
+
+// Order statuses used by the simulator
+const (
+	StatusNew      = "new"
+	StatusHeld     = "held"
+	StatusFilled   = "filled"
+	StatusCanceled = "canceled"
+	StatusReplaced = "replaced"
+)
+
+// Order types and classes accepted by the simulator
+const (
+	typeMarket       = "market"
+	typeLimit        = "limit"
+	typeStop         = "stop"
+	typeStopLimit    = "stop_limit"
+	typeTrailingStop = "trailing_stop"
+
+	classSimple  = "simple"
+	classBracket = "bracket"
+	classOCO     = "oco"
+	classOTO     = "oto"
+)
+
+var validTimeInForce = map[string]bool{
+	"day": true, "gtc": true, "opg": true, "cls": true, "ioc": true, "fok": true,
+}
+
+// order is an order together with the parsed prices the matcher works with
+type order struct {
+	view         broker.Order
+	qty          float64
+	notional     float64
+	limit        float64
+	stop         float64
+	trailPrice   float64
+	trailPercent float64
+	hwm          float64
+	triggered    bool
+	reservedCost float64
+	parent       *order
+	legs         []*order
+}
+
+func (o *order) isOpen() bool {
+	return o.view.Status == StatusNew || o.view.Status == StatusHeld
+}
+
+func (o *order) isBuy() bool {
+	return o.view.Side == "buy"
+}
+
+// snapshot copies the broker view of the order, optionally with its legs nested
+func (o *order) snapshot(nested bool) broker.Order {
+	view := o.view
+	view.Legs = nil
+	if nested && len(o.legs) > 0 {
+		view.Legs = make([]broker.Order, 0, len(o.legs))
+		for _, leg := range o.legs {
+			view.Legs = append(view.Legs, leg.snapshot(false))
+		}
+	}
+	return view
+}
+
+// reservesShares reports whether an open sell order holds back shares from new sells.
+// Sibling exit legs only reserve once, since at most one of them can fill.
+func (o *order) reservesShares() bool {
+	if o.view.Status != StatusNew || o.isBuy() {
+		return false
+	}
+	if o.parent == nil {
+		return true
+	}
+	if o.parent.view.OrderClass == classOCO {
+		return false
+	}
+	for _, leg := range o.parent.legs {
+		if leg.view.Status == StatusNew {
+			return leg == o
+		}
+	}
+	return false
+}
+
+// newOrder validates a request and builds the order with any legs
+func newOrder(req broker.OrderRequest) (*order, error) {
+	req.Symbol = strings.ToUpper(strings.TrimSpace(req.Symbol))
+	if req.TimeInForce == "" {
+		req.TimeInForce = "day"
+	}
+	if req.OrderClass == "" {
+		req.OrderClass = classSimple
+	}
+
+	if req.Symbol == "" {
+		return nil, invalid("symbol is required")
+	}
+	if req.Side != "buy" && req.Side != "sell" {
+		return nil, invalid("side must be buy or sell")
+	}
+	if !validTimeInForce[req.TimeInForce] {
+		return nil, invalid("invalid time_in_force %q", req.TimeInForce)
+	}
+
+	o := &order{}
+	var err error
+	if o.qty, err = parsePositive("qty", req.Qty); err != nil {
+		return nil, err
+	}
+	if o.notional, err = parsePositive("notional", req.Notional); err != nil {
+		return nil, err
+	}
+	if (o.qty > 0) == (o.notional > 0) {
+		return nil, invalid("exactly one of qty or notional is required")
+	}
+	if o.notional > 0 && req.Type != typeMarket {
+		return nil, invalid("notional orders must be market orders")
+	}
+	if o.limit, err = parsePositive("limit_price", req.LimitPrice); err != nil {
+		return nil, err
+	}
+	if o.stop, err = parsePositive("stop_price", req.StopPrice); err != nil {
+		return nil, err
+	}
+	if o.trailPrice, err = parsePositive("trail_price", req.TrailPrice); err != nil {
+		return nil, err
+	}
+	if o.trailPercent, err = parsePositive("trail_percent", req.TrailPercent); err != nil {
+		return nil, err
+	}
+
+	switch req.Type {
+	case typeMarket:
+	case typeLimit:
+		if o.limit == 0 {
+			return nil, invalid("limit_price is required for limit orders")
+		}
+	case typeStop:
+		if o.stop == 0 {
+			return nil, invalid("stop_price is required for stop orders")
+		}
+	case typeStopLimit:
+		if o.limit == 0 || o.stop == 0 {
+			return nil, invalid("limit_price and stop_price are required for stop_limit orders")
+		}
+	case typeTrailingStop:
+		if (o.trailPrice > 0) == (o.trailPercent > 0) {
+			return nil, invalid("exactly one of trail_price or trail_percent is required")
+		}
+	default:
+		return nil, invalid("invalid order type %q", req.Type)
+	}
+
+	o.view = broker.Order{
+		ClientOrderID: req.ClientOrderID,
+		Symbol:        req.Symbol,
+		AssetID:       assetID(req.Symbol),
+		AssetClass:    "us_equity",
+		OrderClass:    req.OrderClass,
+		Type:          req.Type,
+		OrderType:     req.Type,
+		Side:          req.Side,
+		TimeInForce:   req.TimeInForce,
+		ExtendedHours: req.ExtendedHours,
+		FilledQty:     "0",
+		Status:        StatusNew,
+	}
+	if req.Qty != "" {
+		o.view.Qty = stringPtr(req.Qty)
+	}
+	if req.Notional != "" {
+		o.view.Notional = stringPtr(req.Notional)
+	}
+	if req.LimitPrice != "" {
+		o.view.LimitPrice = stringPtr(req.LimitPrice)
+	}
+	if req.StopPrice != "" {
+		o.view.StopPrice = stringPtr(req.StopPrice)
+	}
+	if req.TrailPrice != "" {
+		o.view.TrailPrice = stringPtr(req.TrailPrice)
+	}
+	if req.TrailPercent != "" {
+		o.view.TrailPercent = stringPtr(req.TrailPercent)
+	}
+
+	if err := o.attachLegs(req); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// attachLegs builds the take-profit and stop-loss legs of bracket, OCO and OTO orders
+func (o *order) attachLegs(req broker.OrderRequest) error {
+	switch req.OrderClass {
+	case classSimple:
+		return nil
+	case classBracket, classOTO, classOCO:
+	default:
+		return invalid("invalid order_class %q", req.OrderClass)
+	}
+
+	if o.qty == 0 {
+		return invalid("qty is required for %s orders", req.OrderClass)
+	}
+	hasTakeProfit := req.TakeProfit != nil && req.TakeProfit.LimitPrice != ""
+	hasStopLoss := req.StopLoss != nil && req.StopLoss.StopPrice != ""
+
+	switch req.OrderClass {
+	case classBracket:
+		if !hasTakeProfit || !hasStopLoss {
+			return invalid("bracket orders require take_profit and stop_loss")
+		}
+	case classOTO:
+		if hasTakeProfit == hasStopLoss {
+			return invalid("oto orders require exactly one of take_profit or stop_loss")
+		}
+	case classOCO:
+		if !hasTakeProfit || !hasStopLoss {
+			return invalid("oco orders require take_profit and stop_loss")
+		}
+		if req.Type != typeLimit {
+			return invalid("oco orders must be limit orders")
+		}
+	}
+
+	// OCO orders are exits: the parent is the take-profit and the stop-loss is its
+	// only leg, both working immediately. Bracket and OTO legs exit the entry, so
+	// they take the opposite side and wait until the entry fills.
+	legSide, legStatus := o.view.Side, StatusNew
+	if req.OrderClass == classOCO {
+		takeProfit, err := parsePositive("take_profit.limit_price", req.TakeProfit.LimitPrice)
+		if err != nil {
+			return err
+		}
+		o.limit = takeProfit
+		o.view.LimitPrice = stringPtr(req.TakeProfit.LimitPrice)
+	} else {
+		legStatus = StatusHeld
+		legSide = "buy"
+		if o.isBuy() {
+			legSide = "sell"
+		}
+	}
+
+	if hasTakeProfit && req.OrderClass != classOCO {
+		leg, err := o.newLeg(legSide, legStatus, typeLimit, req.TakeProfit.LimitPrice, "")
+		if err != nil {
+			return err
+		}
+		o.legs = append(o.legs, leg)
+	}
+	if hasStopLoss {
+		legType := typeStop
+		if req.StopLoss.LimitPrice != "" {
+			legType = typeStopLimit
+		}
+		leg, err := o.newLeg(legSide, legStatus, legType, req.StopLoss.LimitPrice, req.StopLoss.StopPrice)
+		if err != nil {
+			return err
+		}
+		o.legs = append(o.legs, leg)
+	}
+	return nil
+}
+
+func (o *order) newLeg(side, status, orderType, limitPrice, stopPrice string) (*order, error) {
+	leg := &order{qty: o.qty, parent: o}
+	var err error
+	if leg.limit, err = parsePositive("limit_price", limitPrice); err != nil {
+		return nil, err
+	}
+	if leg.stop, err = parsePositive("stop_price", stopPrice); err != nil {
+		return nil, err
+	}
+
+	leg.view = broker.Order{
+		Symbol:      o.view.Symbol,
+		AssetID:     o.view.AssetID,
+		AssetClass:  o.view.AssetClass,
+		OrderClass:  o.view.OrderClass,
+		Type:        orderType,
+		OrderType:   orderType,
+		Side:        side,
+		TimeInForce: o.view.TimeInForce,
+		Qty:         o.view.Qty,
+		FilledQty:   "0",
+		Status:      status,
+	}
+	if limitPrice != "" {
+		leg.view.LimitPrice = stringPtr(limitPrice)
+	}
+	if stopPrice != "" {
+		leg.view.StopPrice = stringPtr(stopPrice)
+	}
+	return leg, nil
+}
+
+func parsePositive(name, value string) (float64, error) {
+	parsed, err := parseDecimal(value)
+	if err != nil || parsed < 0 || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+		return 0, invalid("%s must be a positive number", name)
+	}
+	return parsed, nil
+}
+
+// SubmitOrder validates and books an order, filling it right away when it is marketable
+func (e *Exchange) SubmitOrder(accountID string, req broker.OrderRequest) (*broker.Order, error) {
+	o, err := newOrder(req)
+	if err != nil {
+		return nil, err
+	}
+
+	price, ok := e.prices([]string{o.view.Symbol})[o.view.Symbol]
+	if !ok {
+		return nil, invalid("asset %q not found", o.view.Symbol)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	acct, err := e.account(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if o.view.ClientOrderID != "" {
+		for _, existing := range acct.orders {
+			if existing.view.ClientOrderID == o.view.ClientOrderID {
+				return nil, invalid("client_order_id must be unique")
+			}
+		}
+	}
+	if err := e.checkOrder(acct, o, price); err != nil {
+		return nil, err
+	}
+
+	e.accept(acct, o, price)
+	e.match(acct, o, price)
+
+	// Immediate-or-cancel style orders never rest on the book
+	if o.isOpen() && (o.view.TimeInForce == "ioc" || o.view.TimeInForce == "fok") {
+		e.cancel(acct, o)
+	}
+
+	view := o.snapshot(true)
+	return &view, nil
+}
+
+// checkOrder rejects orders the account cannot pay for or deliver. Must be called with e.mu held.
+func (e *Exchange) checkOrder(acct *account, o *order, price float64) error {
+	if o.isBuy() {
+		cost := o.estimatedCost(price)
+		if available := e.buyingPower(acct); cost > available+epsilon {
+			return forbidden("insufficient buying power")
+		}
+		o.reservedCost = cost
+		return nil
+	}
+
+	qty := o.qty
+	if qty == 0 {
+		qty = o.notional / price
+	}
+	if available := e.availableQty(acct, o.view.Symbol); qty > available+epsilon {
+		return forbidden("insufficient qty available for order (requested: %s, available: %s)",
+			formatDecimal(qty, qtyPlaces), formatDecimal(available, qtyPlaces))
+	}
+	return nil
+}
+
+// estimatedCost is what a buy order is expected to cost at most
+func (o *order) estimatedCost(price float64) float64 {
+	if o.notional > 0 {
+		return o.notional
+	}
+	switch o.view.Type {
+	case typeLimit, typeStopLimit:
+		return o.qty * o.limit
+	case typeStop:
+		return o.qty * math.Max(price, o.stop)
+	}
+	return o.qty * price
+}
+
+// buyingPower is cash not already committed to open buy orders
+func (e *Exchange) buyingPower(acct *account) float64 {
+	reserved := 0.0
+	for _, o := range acct.orders {
+		if o.view.Status == StatusNew && o.isBuy() {
+			reserved += o.reservedCost
+		}
+	}
+	return math.Max(acct.cash-reserved, 0)
+}
+
+// availableQty is the position not already committed to open sell orders
+func (e *Exchange) availableQty(acct *account, symbol string) float64 {
+	pos, ok := acct.positions[symbol]
+	if !ok {
+		return 0
+	}
+	reserved := 0.0
+	for _, o := range acct.orders {
+		if o.view.Symbol == symbol && o.reservesShares() {
+			reserved += o.qty
+		}
+	}
+	return math.Max(pos.qty-reserved, 0)
+}
+
+// accept assigns IDs and timestamps and announces the new order
+func (e *Exchange) accept(acct *account, o *order, price float64) {
+	now := e.now()
+	for _, each := range append([]*order{o}, o.legs...) {
+		each.view.ID = newID()
+		if each.view.ClientOrderID == "" {
+			each.view.ClientOrderID = newID()
+		}
+		each.view.CreatedAt = now
+		each.view.UpdatedAt = timePtr(now)
+		each.view.SubmittedAt = timePtr(now)
+		if each.view.Type == typeTrailingStop {
+			each.trail(price)
+		}
+
+		acct.orders[each.view.ID] = each
+		acct.orderIDs = append(acct.orderIDs, each.view.ID)
+		if each.view.Status == StatusNew {
+			e.publish(acct, each, "new", nil)
+		}
+	}
+}
+
+// trail moves a trailing stop after the best price seen since it was placed
+func (o *order) trail(price float64) {
+	if o.isBuy() {
+		if o.hwm == 0 || price < o.hwm {
+			o.hwm = price
+		}
+		o.stop = o.hwm + o.trailPrice + o.hwm*o.trailPercent/100
+	} else {
+		if o.hwm == 0 || price > o.hwm {
+			o.hwm = price
+		}
+		o.stop = o.hwm - o.trailPrice - o.hwm*o.trailPercent/100
+	}
+	o.view.HWM = stringPtr(formatDecimal(o.hwm, pricePlaces))
+	o.view.StopPrice = stringPtr(formatDecimal(o.stop, pricePlaces))
+}
+
+// marketable reports whether the order trades at price
+func (o *order) marketable(price float64) bool {
+	buy := o.isBuy()
+	stopCrossed := func() bool {
+		if buy {
+			return price >= o.stop-epsilon
+		}
+		return price <= o.stop+epsilon
+	}
+	limitOK := func() bool {
+		if buy {
+			return price <= o.limit+epsilon
+		}
+		return price >= o.limit-epsilon
+	}
+
+	switch o.view.Type {
+	case typeMarket:
+		return true
+	case typeLimit:
+		return limitOK()
+	case typeStop:
+		return stopCrossed()
+	case typeStopLimit:
+		if !o.triggered && stopCrossed() {
+			o.triggered = true
+		}
+		return o.triggered && limitOK()
+	case typeTrailingStop:
+		o.trail(price)
+		return stopCrossed()
+	}
+	return false
+}
+
+// match fills the order if it is working and marketable at price. Must be called with e.mu held.
+func (e *Exchange) match(acct *account, o *order, price float64) {
+	if o.view.Status != StatusNew || !o.marketable(price) {
+		return
+	}
+	e.fill(acct, o, price)
+}
+
+// fill executes the whole order at price, then activates its legs or cancels its siblings
+func (e *Exchange) fill(acct *account, o *order, price float64) {
+	qty := o.qty
+	if qty == 0 {
+		qty = math.Floor(o.notional/price*1e9) / 1e9
+	}
+	now := e.now()
+	symbol := o.view.Symbol
+	pos := acct.positions[symbol]
+
+	if o.isBuy() {
+		cost := qty * price
+		if cost > acct.cash+epsilon {
+			// Cash left since the order was placed; leave it working
+			return
+		}
+		acct.cash -= cost
+		if pos == nil {
+			pos = &position{symbol: symbol, lastdayPrice: price, markDay: dayOf(now)}
+			acct.positions[symbol] = pos
+		}
+		pos.qty += qty
+		pos.costBasis += cost
+	} else {
+		if pos == nil || pos.qty+epsilon < qty {
+			return
+		}
+		avgCost := pos.costBasis / pos.qty
+		acct.cash += qty * price
+		pos.qty -= qty
+		pos.costBasis -= avgCost * qty
+		if pos.qty < epsilon {
+			delete(acct.positions, symbol)
+		}
+	}
+	pos.mark(price, now)
+
+	o.view.Status = StatusFilled
+	o.view.FilledQty = formatDecimal(qty, qtyPlaces)
+	o.view.FilledAvgPrice = stringPtr(formatDecimal(price, pricePlaces))
+	o.view.FilledAt = timePtr(now)
+	o.view.UpdatedAt = timePtr(now)
+	acct.fills = append(acct.fills, broker.Activity{
+		ID:              now.UTC().Format("20060102150405.000000000") + "::" + o.view.ID,
+		AccountID:       acct.record.ID,
+		ActivityType:    broker.ActivityFill,
+		TransactionTime: timePtr(now),
+		Symbol:          symbol,
+		OrderID:         o.view.ID,
+		Qty:             o.view.FilledQty,
+		Price:           *o.view.FilledAvgPrice,
+		Side:            o.view.Side,
+	})
+
+	positionQty := 0.0
+	if held, ok := acct.positions[symbol]; ok {
+		positionQty = held.qty
+	}
+	e.publish(acct, o, "fill", &broker.TradeEvent{
+		Price:       formatDecimal(price, pricePlaces),
+		Qty:         formatDecimal(qty, qtyPlaces),
+		PositionQty: formatDecimal(positionQty, qtyPlaces),
+	})
+	e.record(acct, 0)
+
+	// Entry filled: its exit legs start working
+	var activated []*order
+	for _, leg := range o.legs {
+		if leg.view.Status == StatusHeld {
+			leg.view.Status = StatusNew
+			leg.view.UpdatedAt = timePtr(now)
+			e.publish(acct, leg, "new", nil)
+			activated = append(activated, leg)
+		}
+	}
+	e.cancelSiblings(acct, o)
+	for _, leg := range activated {
+		e.match(acct, leg, price)
+	}
+}
+
+// cancelSiblings cancels the rest of a one-cancels-other group after o filled
+func (e *Exchange) cancelSiblings(acct *account, o *order) {
+	switch {
+	case o.parent != nil:
+		for _, leg := range o.parent.legs {
+			if leg != o && leg.isOpen() {
+				e.cancel(acct, leg)
+			}
+		}
+		if o.parent.view.OrderClass == classOCO && o.parent.isOpen() {
+			e.cancel(acct, o.parent)
+		}
+	case o.view.OrderClass == classOCO:
+		for _, leg := range o.legs {
+			if leg.isOpen() {
+				e.cancel(acct, leg)
+			}
+		}
+	}
+}
+
+// cancel cancels an open order and any of its legs that are still open
+func (e *Exchange) cancel(acct *account, o *order) {
+	now := e.now()
+	o.view.Status = StatusCanceled
+	o.view.CanceledAt = timePtr(now)
+	o.view.UpdatedAt = timePtr(now)
+	e.publish(acct, o, "canceled", nil)
+
+	for _, leg := range o.legs {
+		if leg.isOpen() {
+			e.cancel(acct, leg)
+		}
+	}
+}
+
+func (e *Exchange) publish(acct *account, o *order, event string, details *broker.TradeEvent) {
+	tradeEvent := broker.TradeEvent{}
+	if details != nil {
+		tradeEvent = *details
+	}
+	tradeEvent.AccountID = acct.record.ID
+	tradeEvent.Event = event
+	tradeEvent.Timestamp = e.now()
+	tradeEvent.Order = o.snapshot(true)
+	e.events.Publish(tradeEvent)
+}
+
+// GetOrder returns an order with its legs
+func (e *Exchange) GetOrder(accountID, orderID string) (*broker.Order, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	acct, err := e.account(accountID)
+	if err != nil {
+		return nil, err
+	}
+	o, ok := acct.orders[orderID]
+	if !ok {
+		return nil, notFound("order not found")
+	}
+	view := o.snapshot(true)
+	return &view, nil
+}
+
+// ListOrders filters the account's orders the way the broker API does
+func (e *Exchange) ListOrders(accountID string, params broker.ListOrdersParams) ([]broker.Order, error) {
+	status := params.Status
+	if status == "" {
+		status = "open"
+	}
+	if status != "open" && status != "closed" && status != "all" {
+		return nil, invalid("invalid status %q", status)
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 500 {
+		limit = 500
+	}
+	symbols := make(map[string]bool, len(params.Symbols))
+	for _, symbol := range params.Symbols {
+		symbols[strings.ToUpper(symbol)] = true
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	acct, err := e.account(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]*order, 0)
+	for _, id := range acct.orderIDs {
+		o := acct.orders[id]
+		if params.Nested && o.parent != nil {
+			continue
+		}
+		if status == "open" && !o.isOpen() || status == "closed" && o.isOpen() {
+			continue
+		}
+		if len(symbols) > 0 && !symbols[o.view.Symbol] {
+			continue
+		}
+		if params.Side != "" && o.view.Side != params.Side {
+			continue
+		}
+		if !params.After.IsZero() && !o.view.CreatedAt.After(params.After) {
+			continue
+		}
+		if !params.Until.IsZero() && !o.view.CreatedAt.Before(params.Until) {
+			continue
+		}
+		matches = append(matches, o)
+	}
+
+	if params.Direction != "asc" {
+		for i, j := 0, len(matches)-1; i < j; i, j = i+1, j-1 {
+			matches[i], matches[j] = matches[j], matches[i]
+		}
+	}
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	orders := make([]broker.Order, 0, len(matches))
+	for _, o := range matches {
+		orders = append(orders, o.snapshot(params.Nested))
+	}
+	return orders, nil
+}
+
+// CancelOrder cancels a working order
+func (e *Exchange) CancelOrder(accountID, orderID string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	acct, err := e.account(accountID)
+	if err != nil {
+		return err
+	}
+	o, ok := acct.orders[orderID]
+	if !ok {
+		return notFound("order not found")
+	}
+	if !o.isOpen() {
+		return invalid("order is already in %q state", o.view.Status)
+	}
+	e.cancel(acct, o)
+	return nil
+}
+
+// ReplaceOrder amends a working order. The original is closed as replaced and a new
+// order carrying the changes takes its place, keeping its legs or its parent.
+func (e *Exchange) ReplaceOrder(accountID, orderID string, req broker.ReplaceOrderRequest) (*broker.Order, error) {
+	e.mu.Lock()
+	acct, err := e.account(accountID)
+	var symbol string
+	if err == nil {
+		if o, ok := acct.orders[orderID]; ok {
+			symbol = o.view.Symbol
+		}
+	}
+	e.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if symbol == "" {
+		return nil, notFound("order not found")
+	}
+
+	price, ok := e.prices([]string{symbol})[symbol]
+	if !ok {
+		return nil, invalid("asset %q not found", symbol)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	original := acct.orders[orderID]
+	if !original.isOpen() {
+		return nil, invalid("order is already in %q state", original.view.Status)
+	}
+	if req.ClientOrderID != "" {
+		for _, existing := range acct.orders {
+			if existing.view.ClientOrderID == req.ClientOrderID {
+				return nil, invalid("client_order_id must be unique")
+			}
+		}
+	}
+
+	replacement, err := original.amend(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The original stops reserving cash and shares while the replacement is checked.
+	// Legs keep their parent's quantity, which the group has already reserved.
+	status := original.view.Status
+	original.view.Status = StatusReplaced
+	if status == StatusNew && replacement.parent == nil {
+		if err := e.checkOrder(acct, replacement, price); err != nil {
+			original.view.Status = status
+			return nil, err
+		}
+	}
+
+	now := e.now()
+	replacement.view.ID = newID()
+	replacement.view.ClientOrderID = req.ClientOrderID
+	if replacement.view.ClientOrderID == "" {
+		replacement.view.ClientOrderID = newID()
+	}
+	replacement.view.Replaces = stringPtr(original.view.ID)
+	replacement.view.CreatedAt = now
+	replacement.view.UpdatedAt = timePtr(now)
+	replacement.view.SubmittedAt = timePtr(now)
+
+	original.view.ReplacedBy = stringPtr(replacement.view.ID)
+	original.view.ReplacedAt = timePtr(now)
+	original.view.UpdatedAt = timePtr(now)
+	original.legs = nil
+
+	for _, leg := range replacement.legs {
+		leg.parent = replacement
+	}
+	if parent := replacement.parent; parent != nil {
+		for i, leg := range parent.legs {
+			if leg == original {
+				parent.legs[i] = replacement
+			}
+		}
+	}
+
+	acct.orders[replacement.view.ID] = replacement
+	acct.orderIDs = append(acct.orderIDs, replacement.view.ID)
+	e.publish(acct, original, "replaced", nil)
+	if replacement.view.Status == StatusNew {
+		e.publish(acct, replacement, "new", nil)
+		e.match(acct, replacement, price)
+	}
+
+	view := replacement.snapshot(true)
+	return &view, nil
+}
+
+// amend copies the order with the requested changes applied, checking each change
+// makes sense for the order's type
+func (o *order) amend(req broker.ReplaceOrderRequest) (*order, error) {
+	if req.Qty == "" && req.TimeInForce == "" && req.LimitPrice == "" && req.StopPrice == "" && req.Trail == "" {
+		return nil, invalid("at least one of qty, time_in_force, limit_price, stop_price or trail is required")
+	}
+
+	amended := *o
+	amended.view.ReplacedBy = nil
+	amended.view.ReplacedAt = nil
+	amended.legs = append([]*order(nil), o.legs...)
+	orderType := o.view.Type
+
+	if req.Qty != "" {
+		if o.notional > 0 {
+			return nil, invalid("qty cannot be changed on a notional order")
+		}
+		if o.parent != nil || len(o.legs) > 0 {
+			return nil, invalid("qty cannot be changed on %s orders", o.view.OrderClass)
+		}
+		qty, err := parsePositive("qty", req.Qty)
+		if err != nil || qty == 0 {
+			return nil, invalid("qty must be a positive number")
+		}
+		amended.qty = qty
+		amended.view.Qty = stringPtr(req.Qty)
+	}
+	if req.TimeInForce != "" {
+		if !validTimeInForce[req.TimeInForce] {
+			return nil, invalid("invalid time_in_force %q", req.TimeInForce)
+		}
+		amended.view.TimeInForce = req.TimeInForce
+	}
+	if req.LimitPrice != "" {
+		if orderType != typeLimit && orderType != typeStopLimit {
+			return nil, invalid("limit_price cannot be set on %s orders", orderType)
+		}
+		limit, err := parsePositive("limit_price", req.LimitPrice)
+		if err != nil || limit == 0 {
+			return nil, invalid("limit_price must be a positive number")
+		}
+		amended.limit = limit
+		amended.view.LimitPrice = stringPtr(req.LimitPrice)
+	}
+	if req.StopPrice != "" {
+		if orderType != typeStop && orderType != typeStopLimit {
+			return nil, invalid("stop_price cannot be set on %s orders", orderType)
+		}
+		stop, err := parsePositive("stop_price", req.StopPrice)
+		if err != nil || stop == 0 {
+			return nil, invalid("stop_price must be a positive number")
+		}
+		amended.stop = stop
+		amended.view.StopPrice = stringPtr(req.StopPrice)
+	}
+	if req.Trail != "" {
+		if orderType != typeTrailingStop {
+			return nil, invalid("trail cannot be set on %s orders", orderType)
+		}
+		trail, err := parsePositive("trail", req.Trail)
+		if err != nil || trail == 0 {
+			return nil, invalid("trail must be a positive number")
+		}
+		if o.trailPercent > 0 {
+			amended.trailPercent = trail
+			amended.view.TrailPercent = stringPtr(req.Trail)
+		} else {
+			amended.trailPrice = trail
+			amended.view.TrailPrice = stringPtr(req.Trail)
+		}
+		amended.hwm = 0
+		amended.trail(o.hwm)
+	}
+	return &amended, nil
+}
+
+// CancelAllOrders cancels every working order and reports each one
+func (e *Exchange) CancelAllOrders(accountID string) ([]broker.CancelStatus, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	acct, err := e.account(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]broker.CancelStatus, 0)
+	for _, id := range acct.orderIDs {
+		o := acct.orders[id]
+		if !o.isOpen() {
+			continue
+		}
+		e.cancel(acct, o)
+		body, _ := json.Marshal(o.snapshot(true))
+		statuses = append(statuses, broker.CancelStatus{ID: id, Status: http.StatusOK, Body: body})
+	}
+	return statuses, nil
+}
+
+// Sweep re-prices every working order and held position, filling whatever the
+// feed has moved through. Run it on a timer and after changing feed prices.
+func (e *Exchange) Sweep() {
+	prices := e.prices(e.activeSymbols())
+	now := e.now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, accountID := range e.ids {
+		acct := e.accounts[accountID]
+		for _, id := range acct.orderIDs {
+			o := acct.orders[id]
+			if price, ok := prices[o.view.Symbol]; ok {
+				e.match(acct, o, price)
+			}
+		}
+
+		for symbol, pos := range acct.positions {
+			if price, ok := prices[symbol]; ok {
+				pos.mark(price, now)
+			}
+		}
+		if len(acct.positions) > 0 && now.Sub(acct.history[len(acct.history)-1].at) >= snapshotInterval {
+			e.record(acct, 0)
+		}
+	}
+}
+
+// activeSymbols lists symbols with working orders or positions in any account
+func (e *Exchange) activeSymbols() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var symbols []string
+	add := func(symbol string) {
+		if !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	for _, accountID := range e.ids {
+		acct := e.accounts[accountID]
+		for _, o := range acct.orders {
+			if o.view.Status == StatusNew {
+				add(o.view.Symbol)
+			}
+		}
+		for symbol := range acct.positions {
+			add(symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+func dayOf(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
@@ -0,0 +1,634 @@
+package exchange
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seunghoon34/trading-app/pkg/broker"
+)
+
+// snapshotInterval is how often Sweep records equity for accounts holding positions
+const snapshotInterval = time.Minute
+
+// maxHistoryPoints bounds the size of a portfolio history response
+const maxHistoryPoints = 10000
+
+type position struct {
+	symbol       string
+	qty          float64
+	costBasis    float64
+	markPrice    float64
+	lastdayPrice float64
+	markDay      string
+}
+
+// mark updates the position's price, rolling the previous mark into the prior
+// close the first time it is marked on a new day
+func (p *position) mark(price float64, now time.Time) {
+	if day := dayOf(now); day != p.markDay {
+		if p.markPrice > 0 {
+			p.lastdayPrice = p.markPrice
+		}
+		p.markDay = day
+	}
+	p.markPrice = price
+}
+
+func (p *position) view(available float64) broker.Position {
+	marketValue := p.qty * p.markPrice
+	unrealized := marketValue - p.costBasis
+	intraday := (p.markPrice - p.lastdayPrice) * p.qty
+
+	var unrealizedPct, intradayPct, changeToday float64
+	if p.costBasis > 0 {
+		unrealizedPct = unrealized / p.costBasis
+	}
+	if p.lastdayPrice > 0 {
+		changeToday = (p.markPrice - p.lastdayPrice) / p.lastdayPrice
+		intradayPct = changeToday
+	}
+
+	return broker.Position{
+		AssetID:                assetID(p.symbol),
+		Symbol:                 p.symbol,
+		Exchange:               "PAPER",
+		AssetClass:             "us_equity",
+		Quantity:               formatDecimal(p.qty, qtyPlaces),
+		QtyAvailable:           formatDecimal(available, qtyPlaces),
+		AvgEntryPrice:          formatDecimal(p.costBasis/p.qty, pricePlaces),
+		CurrentPrice:           formatDecimal(p.markPrice, pricePlaces),
+		LastdayPrice:           formatDecimal(p.lastdayPrice, pricePlaces),
+		ChangeToday:            formatDecimal(changeToday, pricePlaces),
+		MarketValue:            formatDecimal(marketValue, moneyPlaces),
+		CostBasis:              formatDecimal(p.costBasis, moneyPlaces),
+		UnrealizedPL:           formatDecimal(unrealized, moneyPlaces),
+		UnrealizedPLPC:         formatDecimal(unrealizedPct, pricePlaces),
+		UnrealizedIntradayPL:   formatDecimal(intraday, moneyPlaces),
+		UnrealizedIntradayPLPC: formatDecimal(intradayPct, pricePlaces),
+		Side:                   "long",
+	}
+}
+
+// snapshot is the account equity at a point in time; cashflow is the net deposit
+// (positive) or withdrawal (negative) that happened at that moment
+type snapshot struct {
+	at       time.Time
+	equity   float64
+	cashflow float64
+}
+
+// record appends the current equity to the account history
+func (e *Exchange) record(acct *account, cashflow float64) {
+	acct.history = append(acct.history, snapshot{at: e.now(), equity: e.equity(acct), cashflow: cashflow})
+}
+
+func (e *Exchange) equity(acct *account) float64 {
+	equity := acct.cash
+	for _, pos := range acct.positions {
+		equity += pos.qty * pos.markPrice
+	}
+	return equity
+}
+
+// equityAt is the last recorded equity at or before t
+func (acct *account) equityAt(t time.Time) float64 {
+	equity := acct.history[0].equity
+	for _, snap := range acct.history {
+		if snap.at.After(t) {
+			break
+		}
+		equity = snap.equity
+	}
+	return equity
+}
+
+// markPositions re-prices the account's positions from the feed
+func (e *Exchange) markPositions(accountID string) {
+	prices := e.prices(e.heldSymbols(accountID))
+	now := e.now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if acct, ok := e.accounts[accountID]; ok {
+		for symbol, pos := range acct.positions {
+			if price, ok := prices[symbol]; ok {
+				pos.mark(price, now)
+			}
+		}
+	}
+}
+
+// ListPositions returns the account's open positions marked to the feed
+func (e *Exchange) ListPositions(accountID string) ([]broker.Position, error) {
+	e.markPositions(accountID)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	acct, err := e.account(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	positions := make([]broker.Position, 0, len(acct.positions))
+	for _, symbol := range sortedSymbols(acct.positions) {
+		positions = append(positions, acct.positions[symbol].view(e.availableQty(acct, symbol)))
+	}
+	return positions, nil
+}
+
+// GetPosition returns a single open position
+func (e *Exchange) GetPosition(accountID, symbol string) (*broker.Position, error) {
+	e.markPositions(accountID)
+	symbol = strings.ToUpper(symbol)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	acct, err := e.account(accountID)
+	if err != nil {
+		return nil, err
+	}
+	pos, ok := acct.positions[symbol]
+	if !ok {
+		return nil, notFound("position does not exist")
+	}
+	view := pos.view(e.availableQty(acct, symbol))
+	return &view, nil
+}
+
+// ClosePosition sells all or part of a position at market
+func (e *Exchange) ClosePosition(accountID, symbol string, params broker.ClosePositionParams) (*broker.Order, error) {
+	symbol = strings.ToUpper(symbol)
+	if params.Qty != "" && params.Percentage != "" {
+		return nil, invalid("only one of qty or percentage may be set")
+	}
+
+	e.mu.Lock()
+	acct, err := e.account(accountID)
+	var held float64
+	if err == nil {
+		if pos, ok := acct.positions[symbol]; ok {
+			held = pos.qty
+		}
+	}
+	e.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if held == 0 {
+		return nil, notFound("position does not exist")
+	}
+
+	qty := held
+	switch {
+	case params.Qty != "":
+		if qty, err = parsePositive("qty", params.Qty); err != nil || qty == 0 {
+			return nil, invalid("qty must be a positive number")
+		}
+		if qty > held+epsilon {
+			return nil, forbidden("insufficient qty available for order (requested: %s, available: %s)",
+				formatDecimal(qty, qtyPlaces), formatDecimal(held, qtyPlaces))
+		}
+	case params.Percentage != "":
+		percentage, err := parsePositive("percentage", params.Percentage)
+		if err != nil || percentage == 0 || percentage > 100 {
+			return nil, invalid("percentage must be between 0 and 100")
+		}
+		qty = round(held*percentage/100, qtyPlaces)
+	}
+
+	return e.SubmitOrder(accountID, broker.OrderRequest{
+		Symbol:      symbol,
+		Side:        "sell",
+		Type:        typeMarket,
+		TimeInForce: "day",
+		Qty:         formatDecimal(qty, qtyPlaces),
+	})
+}
+
+// CloseAllPositions closes every position, optionally cancelling open orders first
+// so they no longer hold back shares
+func (e *Exchange) CloseAllPositions(accountID string, cancelOrders bool) ([]broker.ClosePositionStatus, error) {
+	if cancelOrders {
+		if _, err := e.CancelAllOrders(accountID); err != nil {
+			return nil, err
+		}
+	}
+
+	e.mu.Lock()
+	acct, err := e.account(accountID)
+	var symbols []string
+	if err == nil {
+		symbols = sortedSymbols(acct.positions)
+	}
+	e.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]broker.ClosePositionStatus, 0, len(symbols))
+	for _, symbol := range symbols {
+		status := broker.ClosePositionStatus{Symbol: symbol, Status: http.StatusOK}
+		order, err := e.ClosePosition(accountID, symbol, broker.ClosePositionParams{})
+		if err != nil {
+			apiErr, ok := broker.AsAPIError(err)
+			if !ok {
+				return nil, err
+			}
+			status.Status = apiErr.StatusCode
+			status.Body, _ = json.Marshal(apiErr)
+		} else {
+			status.Body, _ = json.Marshal(order)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// GetAccount returns the account's balances
+func (e *Exchange) GetAccount(accountID string) (*broker.Account, error) {
+	e.markPositions(accountID)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	acct, err := e.account(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	var longMarketValue float64
+	for _, pos := range acct.positions {
+		longMarketValue += pos.qty * pos.markPrice
+	}
+	equity := acct.cash + longMarketValue
+	startOfDay := e.now().UTC().Truncate(24 * time.Hour)
+	buyingPower := formatDecimal(e.buyingPower(acct), moneyPlaces)
+
+	return &broker.Account{
+		ID:                       acct.record.ID,
+		AccountNumber:            acct.record.AccountNumber,
+		Status:                   acct.record.Status,
+		Currency:                 acct.record.Currency,
+		Cash:                     formatDecimal(acct.cash, moneyPlaces),
+		BuyingPower:              buyingPower,
+		Equity:                   formatDecimal(equity, moneyPlaces),
+		LastEquity:               formatDecimal(acct.equityAt(startOfDay), moneyPlaces),
+		PortfolioValue:           formatDecimal(equity, moneyPlaces),
+		LongMarketValue:          formatDecimal(longMarketValue, moneyPlaces),
+		ShortMarketValue:         "0",
+		Multiplier:               "1",
+		InitialMargin:            formatDecimal(longMarketValue, moneyPlaces),
+		MaintenanceMargin:        "0",
+		NonMarginableBuyingPower: buyingPower,
+		RegTBuyingPower:          buyingPower,
+		DaytradingBuyingPower:    "0",
+	}, nil
+}
+
+var timeframes = map[string]time.Duration{
+	"1Min":  time.Minute,
+	"5Min":  5 * time.Minute,
+	"15Min": 15 * time.Minute,
+	"1H":    time.Hour,
+	"1D":    24 * time.Hour,
+}
+
+// parsePeriod parses periods such as 1D, 2W, 3M or 1A
+func parsePeriod(period string, end time.Time) (time.Time, error) {
+	if len(period) < 2 {
+		return time.Time{}, invalid("invalid period %q", period)
+	}
+	n, err := strconv.Atoi(period[:len(period)-1])
+	if err != nil || n <= 0 {
+		return time.Time{}, invalid("invalid period %q", period)
+	}
+	switch period[len(period)-1] {
+	case 'D':
+		return end.AddDate(0, 0, -n), nil
+	case 'W':
+		return end.AddDate(0, 0, -7*n), nil
+	case 'M':
+		return end.AddDate(0, -n, 0), nil
+	case 'A':
+		return end.AddDate(-n, 0, 0), nil
+	}
+	return time.Time{}, invalid("invalid period %q", period)
+}
+
+// GetPortfolioHistory samples the account equity on the requested timeframe
+func (e *Exchange) GetPortfolioHistory(accountID string, params broker.PortfolioHistoryParams) (*broker.PortfolioHistory, error) {
+	timeframe := params.Timeframe
+	if timeframe == "" {
+		timeframe = "1D"
+		if params.Period == "1D" {
+			timeframe = "15Min"
+		}
+	}
+	step, ok := timeframes[timeframe]
+	if !ok {
+		return nil, invalid("invalid timeframe %q", timeframe)
+	}
+
+	end := params.End
+	if end.IsZero() {
+		end = e.now()
+	}
+	start := params.Start
+	if start.IsZero() {
+		period := params.Period
+		if period == "" {
+			period = "1M"
+		}
+		var err error
+		if start, err = parsePeriod(period, end); err != nil {
+			return nil, err
+		}
+	}
+
+	e.markPositions(accountID)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	acct, err := e.account(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	// History starts when the account was opened
+	if opened := acct.record.CreatedAt; start.Before(opened) {
+		start = opened
+	}
+	start = start.Truncate(step)
+
+	var points []time.Time
+	for t := start; !t.After(end); t = t.Add(step) {
+		points = append(points, t)
+	}
+	if len(points) > maxHistoryPoints {
+		return nil, invalid("timeframe %s is too fine for the requested period", timeframe)
+	}
+
+	history := &broker.PortfolioHistory{Timeframe: timeframe}
+	withCashflow := params.CashflowTypes != "" && params.CashflowTypes != "NONE"
+	if withCashflow {
+		history.Cashflow = map[string][]float64{"CSD": {}, "CSW": {}}
+	}
+
+	// Each point is labelled with the start of its bucket and carries the
+	// equity at the bucket's close, like the broker's daily bars
+	live := params.End.IsZero()
+	history.BaseValue = acct.equityAt(points[0])
+	var netFlows float64
+	for i, t := range points {
+		// The last bucket is cut off at end and includes it
+		last := i == len(points)-1
+		closeAt := t.Add(step)
+		if last {
+			closeAt = end.Add(time.Nanosecond)
+		}
+		equity := acct.equityAt(closeAt.Add(-time.Nanosecond))
+		if live && last {
+			equity = e.equity(acct)
+		}
+
+		var deposits, withdrawals float64
+		for _, snap := range acct.history {
+			if !snap.at.Before(t) && snap.at.Before(closeAt) {
+				if snap.cashflow > 0 {
+					deposits += snap.cashflow
+				} else {
+					withdrawals += snap.cashflow
+				}
+			}
+		}
+		netFlows += deposits + withdrawals
+
+		pl := equity - history.BaseValue - netFlows
+		var plPct float64
+		if base := history.BaseValue + netFlows; base > 0 {
+			plPct = pl / base
+		}
+
+		history.Timestamp = append(history.Timestamp, t.Unix())
+		history.Equity = append(history.Equity, round(equity, moneyPlaces))
+		history.ProfitLoss = append(history.ProfitLoss, round(pl, moneyPlaces))
+		history.ProfitLossPct = append(history.ProfitLossPct, round(plPct, 6))
+		if withCashflow {
+			history.Cashflow["CSD"] = append(history.Cashflow["CSD"], round(deposits, moneyPlaces))
+			history.Cashflow["CSW"] = append(history.Cashflow["CSW"], round(withdrawals, moneyPlaces))
+		}
+	}
+	return history, nil
+}
+
+// CreateACHRelationship links a (pretend) bank account
+func (e *Exchange) CreateACHRelationship(accountID string, req broker.ACHRelationshipRequest) (*broker.ACHRelationship, error) {
+	if req.AccountOwnerName == "" || req.BankAccountNumber == "" || req.BankRoutingNumber == "" {
+		return nil, invalid("account_owner_name, bank_account_number and bank_routing_number are required")
+	}
+	if req.BankAccountType != "CHECKING" && req.BankAccountType != "SAVINGS" {
+		return nil, invalid("bank_account_type must be CHECKING or SAVINGS")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	acct, err := e.account(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	relationship := broker.ACHRelationship{
+		ID:                newID(),
+		AccountID:         acct.record.ID,
+		Status:            "APPROVED",
+		AccountOwnerName:  req.AccountOwnerName,
+		BankAccountType:   req.BankAccountType,
+		BankAccountNumber: req.BankAccountNumber,
+		BankRoutingNumber: req.BankRoutingNumber,
+		Nickname:          req.Nickname,
+		CreatedAt:         e.now(),
+	}
+	acct.relationships = append(acct.relationships, relationship)
+	return &relationship, nil
+}
+
+// ListACHRelationships returns the account's linked bank accounts
+func (e *Exchange) ListACHRelationships(accountID string) ([]broker.ACHRelationship, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	acct, err := e.account(accountID)
+	if err != nil {
+		return nil, err
+	}
+	return append([]broker.ACHRelationship{}, acct.relationships...), nil
+}
+
+// CreateTransfer moves cash in or out of the account; transfers complete immediately
+func (e *Exchange) CreateTransfer(accountID string, req broker.TransferRequest) (*broker.Transfer, error) {
+	amount, err := parseDecimal(req.Amount)
+	if err != nil || amount <= 0 || math.IsInf(amount, 0) {
+		return nil, invalid("amount must be a positive number")
+	}
+	if req.TransferType != "ach" {
+		return nil, invalid("transfer_type must be ach")
+	}
+	if req.Direction != "INCOMING" && req.Direction != "OUTGOING" {
+		return nil, invalid("direction must be INCOMING or OUTGOING")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	acct, err := e.account(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	linked := false
+	for _, relationship := range acct.relationships {
+		if relationship.ID == req.RelationshipID {
+			linked = true
+			break
+		}
+	}
+	if !linked {
+		return nil, invalid("relationship_id %q not found", req.RelationshipID)
+	}
+
+	cashflow := amount
+	if req.Direction == "OUTGOING" {
+		if amount > e.buyingPower(acct)+epsilon {
+			return nil, forbidden("insufficient funds for withdrawal")
+		}
+		cashflow = -amount
+	}
+	acct.cash += cashflow
+	e.record(acct, cashflow)
+
+	transfer := broker.Transfer{
+		ID:             newID(),
+		RelationshipID: req.RelationshipID,
+		AccountID:      acct.record.ID,
+		Type:           req.TransferType,
+		Status:         "COMPLETE",
+		Amount:         formatDecimal(amount, moneyPlaces),
+		Direction:      req.Direction,
+		CreatedAt:      e.now(),
+	}
+	acct.transfers = append(acct.transfers, transfer)
+	return &transfer, nil
+}
+
+// ListTransfers returns the account's transfers, newest first
+func (e *Exchange) ListTransfers(accountID string) ([]broker.Transfer, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	acct, err := e.account(accountID)
+	if err != nil {
+		return nil, err
+	}
+	transfers := make([]broker.Transfer, 0, len(acct.transfers))
+	for i := len(acct.transfers) - 1; i >= 0; i-- {
+		transfers = append(transfers, acct.transfers[i])
+	}
+	return transfers, nil
+}
+
+// ListActivities returns the account's activity log: cash transfers as CSD and CSW
+// activities and order fills as FILL activities
+func (e *Exchange) ListActivities(accountID string, params broker.ListActivitiesParams) ([]broker.Activity, error) {
+	if params.Direction != "" && params.Direction != "asc" && params.Direction != "desc" {
+		return nil, invalid("direction must be asc or desc")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	acct, err := e.account(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	type entry struct {
+		at       time.Time
+		activity broker.Activity
+	}
+	entries := make([]entry, 0, len(acct.transfers)+len(acct.fills))
+	for _, transfer := range acct.transfers {
+		activity := broker.Activity{
+			ID:           transfer.ID,
+			AccountID:    transfer.AccountID,
+			ActivityType: broker.ActivityDeposit,
+			Date:         transfer.CreatedAt.Format("2006-01-02"),
+			NetAmount:    transfer.Amount,
+			Description:  "ACH transfer",
+			Status:       "executed",
+		}
+		if transfer.Direction == "OUTGOING" {
+			activity.ActivityType = broker.ActivityWithdrawal
+			activity.NetAmount = "-" + transfer.Amount
+		}
+		entries = append(entries, entry{transfer.CreatedAt, activity})
+	}
+	for _, fill := range acct.fills {
+		entries = append(entries, entry{*fill.TransactionTime, fill})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].at.Before(entries[j].at) })
+
+	types := make(map[string]bool, len(params.ActivityTypes))
+	for _, t := range params.ActivityTypes {
+		types[t] = true
+	}
+	activities := make([]broker.Activity, 0, len(entries))
+	for _, entry := range entries {
+		if len(types) > 0 && !types[entry.activity.ActivityType] {
+			continue
+		}
+		if !params.After.IsZero() && !entry.at.After(params.After) {
+			continue
+		}
+		if !params.Until.IsZero() && entry.at.After(params.Until) {
+			continue
+		}
+		activities = append(activities, entry.activity)
+	}
+
+	// Newest first unless asked otherwise
+	if params.Direction != "asc" {
+		for i, j := 0, len(activities)-1; i < j; i, j = i+1, j-1 {
+			activities[i], activities[j] = activities[j], activities[i]
+		}
+	}
+	if params.PageToken != "" {
+		for i, activity := range activities {
+			if activity.ID == params.PageToken {
+				activities = activities[i+1:]
+				break
+			}
+		}
+	}
+	if params.PageSize > 0 && len(activities) > params.PageSize {
+		activities = activities[:params.PageSize]
+	}
+	return activities, nil
+}
+
+func sortedSymbols(positions map[string]*position) []string {
+	symbols := make([]string, 0, len(positions))
+	for symbol := range positions {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
@@ -0,0 +1,188 @@
+// Package pricefeed supplies the prices the paper exchange fills orders at
+package pricefeed
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Feed returns the current trade price for a symbol
+type Feed interface {
+	Price(symbol string) (float64, error)
+}
+
+// StaticFeed serves prices from a fixed table that can be changed at runtime
+type StaticFeed struct {
+	mu           sync.RWMutex
+	prices       map[string]float64
+	defaultPrice float64
+}
+
+// NewStaticFeed creates a feed from a symbol → price table. Unknown symbols get
+// defaultPrice, or an error when defaultPrice is zero.
+func NewStaticFeed(prices map[string]float64, defaultPrice float64) *StaticFeed {
+	table := make(map[string]float64, len(prices))
+	for symbol, price := range prices {
+		table[strings.ToUpper(symbol)] = price
+	}
+	return &StaticFeed{prices: table, defaultPrice: defaultPrice}
+}
+
+func (f *StaticFeed) Price(symbol string) (float64, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if price, ok := f.prices[strings.ToUpper(symbol)]; ok {
+		return price, nil
+	}
+	if f.defaultPrice > 0 {
+		return f.defaultPrice, nil
+	}
+	return 0, fmt.Errorf("no price for %s", symbol)
+}
+
+// SetPrice moves the price of a symbol, e.g. to trigger resting limit or stop orders
+func (f *StaticFeed) SetPrice(symbol string, price float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prices[strings.ToUpper(symbol)] = price
+}
+
+// Prices returns a copy of the configured price table
+func (f *StaticFeed) Prices() map[string]float64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	prices := make(map[string]float64, len(f.prices))
+	for symbol, price := range f.prices {
+		prices[symbol] = price
+	}
+	return prices
+}
+
+// ParsePrices parses a list such as "AAPL=190.5,MSFT=410"
+func ParsePrices(list string) (map[string]float64, error) {
+	prices := make(map[string]float64)
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		symbol, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid price entry %q, expected SYMBOL=PRICE", entry)
+		}
+		price, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || price <= 0 {
+			return nil, fmt.Errorf("invalid price for %s: %q", symbol, value)
+		}
+		prices[strings.ToUpper(strings.TrimSpace(symbol))] = price
+	}
+	return prices, nil
+}
+
+// MarketDataFeed prices symbols from the market-data service's latest quotes and
+// falls back to another feed when the service has no answer
+type MarketDataFeed struct {
+	baseURL  string
+	client   *http.Client
+	fallback Feed
+}
+
+// NewMarketDataFeed creates a feed backed by the market-data service at baseURL
+func NewMarketDataFeed(baseURL string, fallback Feed) *MarketDataFeed {
+	return &MarketDataFeed{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		client:   &http.Client{Timeout: 5 * time.Second},
+		fallback: fallback,
+	}
+}
+
+type quoteResponse struct {
+	Quote struct {
+		AskPrice float64 `json:"ap"`
+		BidPrice float64 `json:"bp"`
+	} `json:"quote"`
+}
+
+func (f *MarketDataFeed) Price(symbol string) (float64, error) {
+	price, err := f.quoteMidpoint(symbol)
+	if err == nil {
+		return price, nil
+	}
+	if f.fallback != nil {
+		return f.fallback.Price(symbol)
+	}
+	return 0, err
+}
+
+func (f *MarketDataFeed) quoteMidpoint(symbol string) (float64, error) {
+	res, err := f.client.Get(f.baseURL + "/quotes/" + url.PathEscape(symbol))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("market-data returned status %d", res.StatusCode)
+	}
+
+	var quote quoteResponse
+	if err := json.Unmarshal(body, &quote); err != nil {
+		return 0, err
+	}
+
+	ask, bid := quote.Quote.AskPrice, quote.Quote.BidPrice
+	switch {
+	case ask > 0 && bid > 0:
+		return (ask + bid) / 2, nil
+	case ask > 0:
+		return ask, nil
+	case bid > 0:
+		return bid, nil
+	}
+	return 0, fmt.Errorf("no quote available for %s", symbol)
+}
+
+// FromEnv builds the feed selected by PRICE_FEED ("static", the default, or "market-data").
+// PAPER_PRICES seeds the static table and PAPER_DEFAULT_PRICE prices everything else.
+func FromEnv() (Feed, *StaticFeed, error) {
+	prices, err := ParsePrices(os.Getenv("PAPER_PRICES"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var defaultPrice float64
+	if value := os.Getenv("PAPER_DEFAULT_PRICE"); value != "" {
+		defaultPrice, err = strconv.ParseFloat(value, 64)
+		if err != nil || defaultPrice < 0 {
+			return nil, nil, fmt.Errorf("invalid PAPER_DEFAULT_PRICE %q", value)
+		}
+	}
+	static := NewStaticFeed(prices, defaultPrice)
+
+	switch os.Getenv("PRICE_FEED") {
+	case "", "static":
+		return static, static, nil
+	case "market-data":
+		baseURL := os.Getenv("MARKET_DATA_SERVICE_URL")
+		if baseURL == "" {
+			baseURL = "http://market-data:8082" // Default for local development
+		}
+		return NewMarketDataFeed(baseURL, static), static, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown PRICE_FEED %q", os.Getenv("PRICE_FEED"))
+	}
+}
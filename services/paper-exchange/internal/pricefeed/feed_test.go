@@ -0,0 +1,71 @@
+package pricefeed
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParsePrices(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]float64
+		wantErr bool
+	}{
+		{"empty", "", map[string]float64{}, false},
+		{"several", "aapl=190.5, MSFT=410", map[string]float64{"AAPL": 190.5, "MSFT": 410}, false},
+		{"missing price", "AAPL", nil, true},
+		{"not a number", "AAPL=abc", nil, true},
+		{"non-positive", "AAPL=0", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePrices(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for symbol, price := range tt.want {
+				if got[symbol] != price {
+					t.Errorf("expected %s=%v, got %v", symbol, price, got[symbol])
+				}
+			}
+		})
+	}
+}
+
+func TestStaticFeedDefaultPrice(t *testing.T) {
+	feed := NewStaticFeed(map[string]float64{"AAPL": 190}, 0)
+	if _, err := feed.Price("MSFT"); err == nil {
+		t.Errorf("expected unknown symbol without a default price to fail")
+	}
+
+	feed = NewStaticFeed(nil, 50)
+	if price, err := feed.Price("msft"); err != nil || price != 50 {
+		t.Errorf("expected default price 50, got %v (%v)", price, err)
+	}
+}
+
+func TestMarketDataFeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/quotes/AAPL" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"quote": {"ap": 101, "bp": 99}}`))
+	}))
+	defer server.Close()
+
+	feed := NewMarketDataFeed(server.URL, NewStaticFeed(map[string]float64{"MSFT": 400}, 0))
+
+	if price, err := feed.Price("AAPL"); err != nil || price != 100 {
+		t.Errorf("expected quote midpoint 100, got %v (%v)", price, err)
+	}
+	if price, err := feed.Price("MSFT"); err != nil || price != 400 {
+		t.Errorf("expected fallback price 400, got %v (%v)", price, err)
+	}
+}
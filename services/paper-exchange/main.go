@@ -0,0 +1,86 @@
+// File: services/paper-exchange/main.go
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/paper-exchange/handlers"
+	"github.com/seunghoon34/trading-app/services/paper-exchange/internal/exchange"
+	"github.com/seunghoon34/trading-app/services/paper-exchange/internal/pricefeed"
+)
+
+func main() {
+	feed, static, err := pricefeed.FromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure price feed: %v", err)
+	}
+	config, err := exchange.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure exchange: %v", err)
+	}
+
+	sim := exchange.New(feed, config)
+	handlers.Init(sim, static)
+
+	// Resting limit and stop orders are matched against the feed on a timer
+	interval := time.Second
+	if value := os.Getenv("PAPER_MATCH_INTERVAL"); value != "" {
+		if interval, err = time.ParseDuration(value); err != nil || interval <= 0 {
+			log.Fatalf("Invalid PAPER_MATCH_INTERVAL %q", value)
+		}
+	}
+	go func() {
+		for range time.Tick(interval) {
+			sim.Sweep()
+		}
+	}()
+
+	r := gin.Default()
+	if key, secret := os.Getenv("PAPER_API_KEY"), os.Getenv("PAPER_API_SECRET"); key != "" {
+		r.Use(handlers.RequireBasicAuth(key, secret))
+	}
+
+	r.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "health endpoint",
+		})
+	})
+
+	// Accounts and funding
+	r.POST("/v1/accounts", handlers.CreateAccount)
+	r.GET("/v1/accounts", handlers.ListAccounts)
+	r.GET("/v1/accounts/activities", handlers.ListActivities)
+	r.GET("/v1/accounts/:account_id", handlers.GetAccountRecord)
+	r.POST("/v1/accounts/:account_id/ach_relationships", handlers.CreateACHRelationship)
+	r.GET("/v1/accounts/:account_id/ach_relationships", handlers.ListACHRelationships)
+	r.POST("/v1/accounts/:account_id/transfers", handlers.CreateTransfer)
+	r.GET("/v1/accounts/:account_id/transfers", handlers.ListTransfers)
+
+	// Trading
+	trading := r.Group("/v1/trading/accounts/:account_id")
+	trading.POST("/orders", handlers.SubmitOrder)
+	trading.GET("/orders", handlers.ListOrders)
+	trading.DELETE("/orders", handlers.CancelAllOrders)
+	trading.GET("/orders/:order_id", handlers.GetOrder)
+	trading.PATCH("/orders/:order_id", handlers.ReplaceOrder)
+	trading.DELETE("/orders/:order_id", handlers.CancelOrder)
+	trading.GET("/positions", handlers.ListPositions)
+	trading.GET("/positions/:symbol", handlers.GetPosition)
+	trading.DELETE("/positions", handlers.CloseAllPositions)
+	trading.DELETE("/positions/:symbol", handlers.ClosePosition)
+	trading.GET("/account", handlers.GetAccount)
+	trading.GET("/account/portfolio/history", handlers.GetPortfolioHistory)
+
+	// Events
+	r.GET("/v2/events/trades", handlers.StreamTradeEvents)
+
+	// Simulator controls
+	r.GET("/sim/prices", handlers.GetPrices)
+	r.PUT("/sim/prices/:symbol", handlers.SetPrice)
+
+	r.Run(":8091")
+}
@@ -41,3 +41,7 @@ require (
 	google.golang.org/protobuf v1.36.6 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+require github.com/seunghoon34/trading-app/pkg/broker v0.0.0
+
+replace github.com/seunghoon34/trading-app/pkg/broker => ../../pkg/broker
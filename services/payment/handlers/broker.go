@@ -0,0 +1,13 @@
+package handlers
+
+import (
+	"github.com/seunghoon34/trading-app/pkg/broker"
+)
+
+// brokerClient is the brokerage used by all handlers in this package
+var brokerClient broker.Broker = broker.NewFromEnv()
+
+// SetBroker replaces the brokerage client, e.g. with a simulator or a test double
+func SetBroker(b broker.Broker) {
+	brokerClient = b
+}
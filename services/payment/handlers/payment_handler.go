@@ -2,16 +2,13 @@ package handlers
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
-	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
 
 	rdb "github.com/seunghoon34/trading-app/services/payment/redis"
 )
@@ -20,51 +17,18 @@ type ACHDetails struct {
 	Id string `json:"id"`
 }
 
-func makeAlpacaRequest(method, url string, payload io.Reader) (*http.Response, error) {
-	req, err := http.NewRequest(method, url, payload)
-	if err != nil {
-		return nil, err
-	}
-
-	// Add authentication headers
-	auth := os.Getenv("ALPACA_API_KEY") + ":" + os.Getenv("ALPACA_SECRET_KEY")
-	basicAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte(auth))
-	req.Header.Add("Authorization", basicAuth)
-	req.Header.Add("Accept", "application/json")
-
-	if method == "POST" {
-		req.Header.Add("Content-Type", "application/json")
-	}
-
-	return http.DefaultClient.Do(req)
-}
-
 func createACHDetails(account_id string) (*ACHDetails, error) {
-	url := fmt.Sprintf("https://broker-api.sandbox.alpaca.markets/v1/accounts/%s/ach_relationships", account_id)
-	payload := strings.NewReader("{\"bank_account_type\":\"CHECKING\",\"account_owner_name\":\"seunghoon han\",\"bank_account_number\":\"32131231ab\",\"bank_routing_number\":\"123103716\"}")
-	res, err := makeAlpacaRequest("POST", url, payload)
-	if err != nil {
-		return nil, err
-	}
-	defer res.Body.Close()
-
-	// Check HTTP status code
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d", res.StatusCode)
-	}
-
-	body, err := io.ReadAll(res.Body)
+	relationship, err := brokerClient.CreateACHRelationship(context.Background(), account_id, broker.ACHRelationshipRequest{
+		BankAccountType:   "CHECKING",
+		AccountOwnerName:  "seunghoon han",
+		BankAccountNumber: "32131231ab",
+		BankRoutingNumber: "123103716",
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	fmt.Printf("Response body: %s\n", string(body))
-
-	var ach_details ACHDetails
-	if err := json.Unmarshal(body, &ach_details); err != nil {
-		return nil, err
-	}
-	return &ach_details, nil
+	return &ACHDetails{Id: relationship.ID}, nil
 
 }
 
@@ -86,30 +50,10 @@ func retrieveACHDetails(account_id string) (*ACHDetails, error) {
 	// Cache miss - fetch from API
 	fmt.Println("Cache miss - fetching from API")
 
-	url := fmt.Sprintf("https://broker-api.sandbox.alpaca.markets/v1/accounts/%s/ach_relationships", account_id)
-	fmt.Printf("Making request to: %s\n", url)
-	res, err := makeAlpacaRequest("GET", url, nil)
+	ach_relationships, err := brokerClient.ListACHRelationships(context.Background(), account_id)
 	if err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
-
-	// Check HTTP status code
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %d", res.StatusCode)
-	}
-
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	fmt.Printf("Response body: %s\n", string(body))
-
-	var ach_relationships []ACHDetails
-	if err := json.Unmarshal(body, &ach_relationships); err != nil {
-		return nil, err
-	}
 
 	// Check if we have at least one relationship
 	if len(ach_relationships) == 0 {
@@ -123,12 +67,14 @@ func retrieveACHDetails(account_id string) (*ACHDetails, error) {
 		return ach_relationship, nil
 	}
 
+	// Return the first one (or you could add logic to pick a specific one)
+	ach_details := &ACHDetails{Id: ach_relationships[0].ID}
+
 	// // Cache the result for 1 hour
-	achJSON, _ := json.Marshal(ach_relationships[0])
+	achJSON, _ := json.Marshal(ach_details)
 	rdb.Client.Set(context.Background(), cacheKey, achJSON, time.Hour)
 
-	// Return the first one (or you could add logic to pick a specific one)
-	return &ach_relationships[0], nil
+	return ach_details, nil
 
 }
 
@@ -149,18 +95,15 @@ func DepositFunds(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve ACH details"})
 		return
 	}
-	url := fmt.Sprintf("https://broker-api.sandbox.alpaca.markets/v1/accounts/%s/transfers", accountID)
-	payload := strings.NewReader(fmt.Sprintf("{\"transfer_type\":\"ach\",\"direction\":\"INCOMING\",\"timing\":\"immediate\",\"relationship_id\":\"%s\",\"amount\":\"%s\"}", ach_details.Id, amount))
-	res, err := makeAlpacaRequest("POST", url, payload)
+	_, err = brokerClient.CreateTransfer(c.Request.Context(), accountID, broker.TransferRequest{
+		TransferType:   "ach",
+		Direction:      "INCOMING",
+		Timing:         "immediate",
+		RelationshipID: ach_details.Id,
+		Amount:         amount,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute request"})
-		return
-	}
-	defer res.Body.Close()
-
-	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(res.Body)
-		fmt.Printf("Transfer failed with status %d: %s\n", res.StatusCode, string(body))
+		fmt.Printf("Transfer failed: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transfer failed"})
 		return
 	}
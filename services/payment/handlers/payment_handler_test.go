@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -15,6 +14,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/seunghoon34/trading-app/pkg/broker"
 	rdb "github.com/seunghoon34/trading-app/services/payment/redis"
 )
 
@@ -39,6 +39,15 @@ func TestMain(m *testing.M) {
 	m.Run()
 }
 
+// useTestBroker points the handlers at a mock broker server and returns a restore func
+func useTestBroker(url string) func() {
+	original := brokerClient
+	SetBroker(broker.NewAlpaca(broker.Config{BaseURL: url, APIKey: "test_key", APISecret: "test_secret"}))
+	return func() {
+		SetBroker(original)
+	}
+}
+
 func setupMockRedis() (redismock.ClientMock, func()) {
 	db, mock := redismock.NewClientMock()
 	originalClient := rdb.Client
@@ -63,7 +72,7 @@ func TestDepositFunds_Success(t *testing.T) {
 	achJSON, _ := json.Marshal(achDetails)
 
 	mock.ExpectGet(cacheKey).RedisNil()
-	mock.ExpectSet(cacheKey, string(achJSON), time.Hour).SetVal("OK")
+	mock.ExpectSet(cacheKey, achJSON, time.Hour).SetVal("OK")
 
 	// Create a test server to mock Alpaca API
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -88,6 +97,7 @@ func TestDepositFunds_Success(t *testing.T) {
 		}
 	}))
 	defer server.Close()
+	defer useTestBroker(server.URL)()
 
 	// Create a test gin context
 	gin.SetMode(gin.TestMode)
@@ -99,13 +109,39 @@ func TestDepositFunds_Success(t *testing.T) {
 	c.Request.Header.Set("X-Account-ID", accountID)
 	c.Params = gin.Params{{Key: "amount", Value: "100"}}
 
-	// Unfortunately, we need to refactor the code to make HTTP requests testable
-	// For now, let's test the parameter validation
 	DepositFunds(c)
 
-	// The function will fail because it tries to make real HTTP requests
-	// We need to refactor the code to inject HTTP client for proper testing
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Funds deposited successfully")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDepositFunds_TransferRejected(t *testing.T) {
+	mock, cleanup := setupMockRedis()
+	defer cleanup()
+
+	accountID := "test-account-123"
+	cacheKey := fmt.Sprintf("ach_details:%s", accountID)
+	achJSON, _ := json.Marshal(ACHDetails{Id: "ach-123"})
+	mock.ExpectGet(cacheKey).SetVal(string(achJSON))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"code": 40310000, "message": "transfers blocked"}`))
+	}))
+	defer server.Close()
+	defer useTestBroker(server.URL)()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/deposit/100", nil)
+	c.Request.Header.Set("X-Account-ID", accountID)
+	c.Params = gin.Params{{Key: "amount", Value: "100"}}
+
+	DepositFunds(c)
+
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Transfer failed")
 }
 
 func TestDepositFunds_MissingAccountID(t *testing.T) {
@@ -161,70 +197,52 @@ func TestRetrieveACHDetails_CacheMiss(t *testing.T) {
 
 	accountID := "test-account-123"
 	cacheKey := fmt.Sprintf("ach_details:%s", accountID)
+	achJSON, _ := json.Marshal(ACHDetails{Id: "existing-ach-123"})
 
-	// Mock cache miss
+	// Mock cache miss followed by caching the broker's answer
 	mock.ExpectGet(cacheKey).RedisNil()
+	mock.ExpectSet(cacheKey, achJSON, time.Hour).SetVal("OK")
 
-	// The function will try to make HTTP request which will fail in test
-	// We need to refactor the code to make it testable
-	_, err := retrieveACHDetails(accountID)
-
-	// Should get an error because we can't make real HTTP requests
-	assert.Error(t, err)
-	assert.NoError(t, mock.ExpectationsWereMet())
-}
-
-func TestMakeAlpacaRequest_AuthHeaders(t *testing.T) {
-	// Create a test server to capture the request
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Check if Authorization header is present and correctly formatted
-		auth := r.Header.Get("Authorization")
-		assert.Contains(t, auth, "Basic")
-
-		// Check Accept header
-		accept := r.Header.Get("Accept")
-		assert.Equal(t, "application/json", accept)
-
+		assert.Equal(t, "GET", r.Method)
 		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"test": "response"}`))
+		w.Write([]byte(`[{"id": "existing-ach-123"}, {"id": "other-ach"}]`))
 	}))
 	defer server.Close()
+	defer useTestBroker(server.URL)()
 
-	// Test the function
-	resp, err := makeAlpacaRequest("GET", server.URL, nil)
+	result, err := retrieveACHDetails(accountID)
 
 	assert.NoError(t, err)
-	assert.Equal(t, http.StatusOK, resp.StatusCode)
-	resp.Body.Close()
+	assert.Equal(t, "existing-ach-123", result.Id)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestMakeAlpacaRequest_POST_ContentType(t *testing.T) {
-	// Create a test server to capture the request
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Check Content-Type header for POST requests
-		if r.Method == "POST" {
-			contentType := r.Header.Get("Content-Type")
-			assert.Equal(t, "application/json", contentType)
-		}
+func TestRetrieveACHDetails_BrokerError(t *testing.T) {
+	mock, cleanup := setupMockRedis()
+	defer cleanup()
 
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"test": "response"}`))
+	accountID := "test-account-123"
+	mock.ExpectGet(fmt.Sprintf("ach_details:%s", accountID)).RedisNil()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
 	}))
 	defer server.Close()
+	defer useTestBroker(server.URL)()
 
-	// Test POST request
-	payload := bytes.NewReader([]byte(`{"test": "data"}`))
-	resp, err := makeAlpacaRequest("POST", server.URL, payload)
+	_, err := retrieveACHDetails(accountID)
 
-	assert.NoError(t, err)
-	assert.Equal(t, http.StatusOK, resp.StatusCode)
-	resp.Body.Close()
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestCreateACHDetails_Success(t *testing.T) {
 	// Create a test server to mock Alpaca API
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "POST" && r.URL.Path == "/v1/accounts/test-account/ach_relationships" {
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.Contains(t, r.Header.Get("Authorization"), "Basic")
 			w.WriteHeader(http.StatusOK)
 			w.Write([]byte(`{"id": "new-ach-123"}`))
 		} else {
@@ -232,13 +250,12 @@ func TestCreateACHDetails_Success(t *testing.T) {
 		}
 	}))
 	defer server.Close()
+	defer useTestBroker(server.URL)()
 
-	// This test will fail because createACHDetails uses hardcoded URL
-	// We need to refactor the code to accept base URL for testing
-	_, err := createACHDetails("test-account")
+	result, err := createACHDetails("test-account")
 
-	// Should get an error because it tries to connect to real Alpaca API
-	assert.Error(t, err)
+	assert.NoError(t, err)
+	assert.Equal(t, "new-ach-123", result.Id)
 }
 
 // Integration test helper
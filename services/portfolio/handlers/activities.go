@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seunghoon34/trading-app/pkg/broker"
+)
+
+// activitiesPageSize is the largest page the broker's activity log returns
+const activitiesPageSize = 100
+
+// dividendsTTL is how long an account's dividend totals are reused. Dividends are paid
+// at most once a day, so a few minutes of staleness costs nothing and spares paging
+// through the activity log on every request.
+const dividendsTTL = 5 * time.Minute
+
+// eachActivity pages through the account's activities matching params, newest first,
+// until visit returns false
+func eachActivity(ctx context.Context, accountID string, params broker.ListActivitiesParams, visit func(broker.Activity) bool) error {
+	params.Direction = "desc"
+	params.PageSize = activitiesPageSize
+	for {
+		page, err := brokerClient.ListActivities(ctx, accountID, params)
+		if err != nil {
+			return err
+		}
+		for _, activity := range page {
+			if !visit(activity) {
+				return nil
+			}
+		}
+		if len(page) < activitiesPageSize {
+			return nil
+		}
+		params.PageToken = page[len(page)-1].ID
+	}
+}
+
+// listActivities pages through the account's activities matching params, newest first
+func listActivities(ctx context.Context, accountID string, params broker.ListActivitiesParams) ([]broker.Activity, error) {
+	var activities []broker.Activity
+	err := eachActivity(ctx, accountID, params, func(activity broker.Activity) bool {
+		activities = append(activities, activity)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return activities, nil
+}
+
+// signedQty is the position's quantity, negative when short
+func signedQty(position broker.Position) float64 {
+	qty, _ := strconv.ParseFloat(position.Quantity, 64)
+	if position.Side == "short" && qty > 0 {
+		qty = -qty
+	}
+	return qty
+}
+
+// fillQty is the change a fill made to the position, negative for sales
+func fillQty(activity broker.Activity) float64 {
+	qty, _ := strconv.ParseFloat(activity.Qty, 64)
+	if strings.ToLower(activity.Side) != "buy" {
+		qty = -qty
+	}
+	return qty
+}
+
+// holdingPeriodStarts finds the market date each position was opened on. Walking the
+// fills back from the held quantity, the holding period starts at the fill that took
+// the position up from flat, or flipped its side. Paging stops once every position is
+// accounted for; one opened before the fill history begins has no entry.
+func holdingPeriodStarts(ctx context.Context, accountID string, positions []broker.Position) (map[string]string, error) {
+	remaining := make(map[string]float64, len(positions))
+	for _, position := range positions {
+		if qty := signedQty(position); qty != 0 {
+			remaining[position.Symbol] = qty
+		}
+	}
+	starts := make(map[string]string, len(remaining))
+	if len(remaining) == 0 {
+		return starts, nil
+	}
+
+	err := eachActivity(ctx, accountID, broker.ListActivitiesParams{ActivityTypes: []string{broker.ActivityFill}}, func(activity broker.Activity) bool {
+		held, ok := remaining[activity.Symbol]
+		if !ok || activity.ActivityType != broker.ActivityFill {
+			return true
+		}
+		// Undo the fill; the position was opened here if that leaves it flat or on
+		// the other side
+		before := held - fillQty(activity)
+		if math.Abs(before) < 1e-9 || math.Signbit(before) != math.Signbit(held) {
+			starts[activity.Symbol] = activityDate(activity)
+			delete(remaining, activity.Symbol)
+			return len(remaining) > 0
+		}
+		remaining[activity.Symbol] = before
+		return true
+	})
+	return starts, err
+}
+
+// qtyHeldBefore finds how much of each position was held going into a market date on
+// or after since, by undoing the fills dated from then on. Quantities are negative for
+// short positions.
+func qtyHeldBefore(ctx context.Context, accountID string, positions []broker.Position, since string) (func(symbol, date string) float64, error) {
+	current := make(map[string]float64, len(positions))
+	for _, position := range positions {
+		current[position.Symbol] = signedQty(position)
+	}
+	type fill struct {
+		date string
+		qty  float64
+	}
+	fills := make(map[string][]fill)
+
+	params := broker.ListActivitiesParams{ActivityTypes: []string{broker.ActivityFill}}
+	if date, err := time.ParseInLocation("2006-01-02", since, marketTZ); err == nil {
+		// The broker's after bound is exclusive
+		params.After = date.AddDate(0, 0, -1)
+	}
+	err := eachActivity(ctx, accountID, params, func(activity broker.Activity) bool {
+		if _, ok := current[activity.Symbol]; !ok || activity.ActivityType != broker.ActivityFill {
+			return true
+		}
+		date := activityDate(activity)
+		if date < since {
+			return false
+		}
+		fills[activity.Symbol] = append(fills[activity.Symbol], fill{date: date, qty: fillQty(activity)})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func(symbol, date string) float64 {
+		held := current[symbol]
+		for _, f := range fills[symbol] {
+			if f.date >= date {
+				held -= f.qty
+			}
+		}
+		return held
+	}, nil
+}
+
+// dividendsEntry is an account's cached dividend totals, valid for the positions they
+// were computed for
+type dividendsEntry struct {
+	positions string
+	received  map[string]float64
+	at        time.Time
+}
+
+var dividendsCache = struct {
+	sync.Mutex
+	entries map[string]dividendsEntry
+}{entries: make(map[string]dividendsEntry)}
+
+// positionsKey identifies a set of holdings, so a trade invalidates the cached totals
+func positionsKey(positions []broker.Position) string {
+	keys := make([]string, len(positions))
+	for i, position := range positions {
+		keys[i] = position.Symbol + "=" + position.Quantity
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// dividendsReceived sums the cash dividends paid into the account per held symbol, net
+// of any withholding, since the position was opened. Dividends from earlier holding
+// periods of the same symbol are left out. Totals are cached for dividendsTTL.
+func dividendsReceived(ctx context.Context, accountID string, positions []broker.Position) (map[string]float64, error) {
+	key := positionsKey(positions)
+	dividendsCache.Lock()
+	entry, ok := dividendsCache.entries[accountID]
+	dividendsCache.Unlock()
+	if ok && entry.positions == key && now().Sub(entry.at) < dividendsTTL {
+		return entry.received, nil
+	}
+
+	received := make(map[string]float64)
+	if len(positions) > 0 {
+		starts, err := holdingPeriodStarts(ctx, accountID, positions)
+		if err != nil {
+			return nil, err
+		}
+		// Only dividends since the earliest opening matter, unless a position predates
+		// the fill history
+		params := broker.ListActivitiesParams{ActivityTypes: []string{broker.ActivityDividend}}
+		earliest := ""
+		for _, position := range positions {
+			start, ok := starts[position.Symbol]
+			if !ok {
+				earliest = ""
+				break
+			}
+			if earliest == "" || start < earliest {
+				earliest = start
+			}
+		}
+		if date, err := time.ParseInLocation("2006-01-02", earliest, marketTZ); err == nil {
+			// The broker's after bound is exclusive
+			params.After = date.AddDate(0, 0, -1)
+		}
+
+		activities, err := listActivities(ctx, accountID, params)
+		if err != nil {
+			return nil, err
+		}
+		for _, activity := range activities {
+			if activity.ActivityType != broker.ActivityDividend {
+				continue
+			}
+			if start, ok := starts[activity.Symbol]; ok && activityDate(activity) < start {
+				continue
+			}
+			amount, _ := strconv.ParseFloat(activity.NetAmount, 64)
+			received[activity.Symbol] += amount
+		}
+	}
+
+	dividendsCache.Lock()
+	dividendsCache.entries[accountID] = dividendsEntry{positions: key, received: received, at: now()}
+	dividendsCache.Unlock()
+	return received, nil
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
+	"github.com/seunghoon34/trading-app/services/portfolio/internal/analytics"
+	"github.com/seunghoon34/trading-app/services/portfolio/internal/marketdata"
+)
+
+const (
+	defaultAnalyticsPeriod = "3M"
+	defaultBenchmark       = "SPY"
+)
+
+// analyticsPeriods are the history windows the analytics endpoints accept
+var analyticsPeriods = map[string]bool{"1M": true, "3M": true, "1A": true}
+
+// externalCashflowTypes are the activities that move money in or out of the account;
+// they change equity without being a gain or loss
+var externalCashflowTypes = []string{broker.ActivityDeposit, broker.ActivityWithdrawal}
+
+// loadBars is replaced in tests
+var loadBars = marketdata.Bars
+
+// equitySeries is an account's equity over time and the external cash flows of each
+// point, dated on the exchange's calendar
+type equitySeries struct {
+	times  []time.Time
+	dates  []string
+	equity []float64
+	flows  []float64
+}
+
+// returns are the daily returns net of cash flows; returns[i] ends on dates[i+1]
+func (s equitySeries) returns() []float64 {
+	return analytics.Returns(s.equity, s.flows)
+}
+
+// loadEquitySeries fetches the account's daily equity over period
+func loadEquitySeries(ctx context.Context, accountID, period string) (equitySeries, error) {
+	history, err := brokerClient.GetPortfolioHistory(ctx, accountID, broker.PortfolioHistoryParams{
+		Period:        period,
+		Timeframe:     "1D",
+		CashflowTypes: strings.Join(externalCashflowTypes, ","),
+	})
+	if err != nil {
+		return equitySeries{}, err
+	}
+	return newEquitySeries(history), nil
+}
+
+// newEquitySeries reads a portfolio history, dropping the points before the account
+// was first funded
+func newEquitySeries(history *broker.PortfolioHistory) equitySeries {
+	var s equitySeries
+	for i, ts := range history.Timestamp {
+		if i >= len(history.Equity) {
+			break
+		}
+		if len(s.equity) == 0 && history.Equity[i] <= 0 {
+			continue
+		}
+		var flow float64
+		for _, flows := range history.Cashflow {
+			if i < len(flows) {
+				flow += flows[i]
+			}
+		}
+		at := time.Unix(ts, 0).In(marketTZ)
+		s.times = append(s.times, at)
+		s.dates = append(s.dates, at.Format("2006-01-02"))
+		s.equity = append(s.equity, history.Equity[i])
+		s.flows = append(s.flows, flow)
+	}
+	return s
+}
+
+// benchmarkReturns pairs each portfolio return with the benchmark's return over the
+// same points, given the benchmark's closes aligned with the series, skipping points
+// the benchmark has no close for
+func benchmarkReturns(s equitySeries, closes []float64) (portfolio, benchmark []float64) {
+	returns := s.returns()
+	for i := 1; i < len(s.dates); i++ {
+		prev, cur := closes[i-1], closes[i]
+		if prev <= 0 || cur <= 0 {
+			continue
+		}
+		portfolio = append(portfolio, returns[i-1])
+		benchmark = append(benchmark, cur/prev-1)
+	}
+	return portfolio, benchmark
+}
+
+// riskFreeRate reads ?risk_free_rate as an annual decimal rate, defaulting to
+// RISK_FREE_RATE and then to 0
+func riskFreeRate(c *gin.Context) (float64, error) {
+	value := c.Query("risk_free_rate")
+	if value == "" {
+		value = os.Getenv("RISK_FREE_RATE")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate <= -1 || rate >= 1 {
+		return 0, errors.New("risk_free_rate must be an annual rate as a decimal, e.g. 0.04")
+	}
+	return rate, nil
+}
+
+// metric drops undefined statistics, which JSON cannot represent
+func metric(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+// DrawdownReport is the largest fall from a peak. RecoveryDate is when the peak was
+// regained, or null if it has not been.
+type DrawdownReport struct {
+	Depth        float64 `json:"depth"`
+	PeakDate     string  `json:"peak_date,omitempty"`
+	TroughDate   string  `json:"trough_date,omitempty"`
+	RecoveryDate *string `json:"recovery_date"`
+}
+
+// RiskReport holds the risk statistics of an account's daily returns. Rates and
+// returns are decimals; statistics that are undefined for the history are null.
+type RiskReport struct {
+	AccountID             string         `json:"account_id"`
+	Period                string         `json:"period"`
+	Start                 string         `json:"start"`
+	End                   string         `json:"end"`
+	Observations          int            `json:"observations"`
+	RiskFreeRate          float64        `json:"risk_free_rate"`
+	TotalReturn           *float64       `json:"total_return"`
+	AnnualizedReturn      *float64       `json:"annualized_return"`
+	AnnualizedVolatility  *float64       `json:"annualized_volatility"`
+	SharpeRatio           *float64       `json:"sharpe_ratio"`
+	SortinoRatio          *float64       `json:"sortino_ratio"`
+	MaxDrawdown           DrawdownReport `json:"max_drawdown"`
+	CalmarRatio           *float64       `json:"calmar_ratio"`
+	Benchmark             string         `json:"benchmark"`
+	BenchmarkObservations int            `json:"benchmark_observations"`
+	BenchmarkReturn       *float64       `json:"benchmark_return"`
+	Beta                  *float64       `json:"beta"`
+	Correlation           *float64       `json:"correlation"`
+	Warnings              []string       `json:"warnings,omitempty"`
+}
+
+// GetRiskAnalytics computes volatility, Sharpe and Sortino ratios, maximum drawdown,
+// Calmar ratio and beta against ?benchmark (default SPY) from the account's daily
+// equity over ?period (1M, 3M or 1A). Returns are net of deposits and withdrawals.
+func GetRiskAnalytics(c *gin.Context) {
+	accountID := c.GetHeader("X-Account-ID")
+	if accountID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account ID header missing"})
+		return
+	}
+
+	period := strings.ToUpper(c.DefaultQuery("period", defaultAnalyticsPeriod))
+	if !analyticsPeriods[period] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be one of 1M, 3M or 1A"})
+		return
+	}
+	benchmark := strings.ToUpper(c.DefaultQuery("benchmark", defaultBenchmark))
+	rate, err := riskFreeRate(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	series, err := loadEquitySeries(c.Request.Context(), accountID, period)
+	if err != nil {
+		respondBrokerError(c, err, "Failed to fetch portfolio history")
+		return
+	}
+	if len(series.dates) < 3 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Not enough portfolio history to compute risk statistics"})
+		return
+	}
+
+	returns := series.returns()
+	drawdown := analytics.MaxDrawdown(returns)
+	report := RiskReport{
+		AccountID:            accountID,
+		Period:               period,
+		Start:                series.dates[0],
+		End:                  series.dates[len(series.dates)-1],
+		Observations:         len(returns),
+		RiskFreeRate:         rate,
+		TotalReturn:          metric(analytics.TotalReturn(returns)),
+		AnnualizedReturn:     metric(analytics.AnnualizedReturn(returns)),
+		AnnualizedVolatility: metric(analytics.Volatility(returns)),
+		SharpeRatio:          metric(analytics.Sharpe(returns, rate)),
+		SortinoRatio:         metric(analytics.Sortino(returns, rate)),
+		MaxDrawdown:          DrawdownReport{Depth: drawdown.Depth},
+		CalmarRatio:          metric(analytics.Calmar(returns)),
+		Benchmark:            benchmark,
+	}
+	if drawdown.Depth > 0 {
+		report.MaxDrawdown.PeakDate = series.dates[drawdown.Peak]
+		report.MaxDrawdown.TroughDate = series.dates[drawdown.Trough]
+		if drawdown.Recovery >= 0 {
+			report.MaxDrawdown.RecoveryDate = &series.dates[drawdown.Recovery]
+		}
+	}
+
+	// The benchmark only adds beta and correlation, so without it the rest still stands
+	start, _ := time.ParseInLocation("2006-01-02", report.Start, marketTZ)
+	end, _ := time.ParseInLocation("2006-01-02", report.End, marketTZ)
+	bars, err := loadBars(benchmark, "1Day", start, end.AddDate(0, 0, 1))
+	if err != nil {
+		log.Printf("risk analytics for %s: loading %s bars: %v", accountID, benchmark, err)
+		report.Warnings = append(report.Warnings, "benchmark "+benchmark+" unavailable; beta and correlation are not computed")
+	} else {
+		portfolio, bench := benchmarkReturns(series, alignCloses(series.times, bars, dailyBar))
+		report.BenchmarkObservations = len(bench)
+		if len(bench) > 0 {
+			report.BenchmarkReturn = metric(analytics.TotalReturn(bench))
+		}
+		report.Beta = metric(analytics.Beta(portfolio, bench))
+		report.Correlation = metric(analytics.Correlation(portfolio, bench))
+	}
+
+	c.JSON(http.StatusOK, report)
+}
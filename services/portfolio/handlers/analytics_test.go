@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
+	"github.com/seunghoon34/trading-app/services/portfolio/internal/marketdata"
+)
+
+// historyBroker serves a fixed portfolio history and activity log
+type historyBroker struct {
+	broker.Broker
+	mu            sync.Mutex
+	params        broker.PortfolioHistoryParams
+	history       broker.PortfolioHistory
+	activities    []broker.Activity
+	activitiesErr error
+}
+
+func (b *historyBroker) GetPortfolioHistory(ctx context.Context, accountID string, params broker.PortfolioHistoryParams) (*broker.PortfolioHistory, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.params = params
+	history := b.history
+	return &history, nil
+}
+
+func (b *historyBroker) ListActivities(ctx context.Context, accountID string, params broker.ListActivitiesParams) ([]broker.Activity, error) {
+	return b.activities, b.activitiesErr
+}
+
+// day is midnight in New York on the given March 2024 day
+func day(d int) time.Time {
+	return time.Date(2024, 3, d, 0, 0, 0, 0, marketTZ)
+}
+
+func dailyHistory(equity []float64, deposits []float64) broker.PortfolioHistory {
+	history := broker.PortfolioHistory{Equity: equity, Cashflow: map[string][]float64{broker.ActivityDeposit: deposits}}
+	for i := range equity {
+		history.Timestamp = append(history.Timestamp, day(i+1).Unix())
+	}
+	return history
+}
+
+func useDailyBars(t *testing.T, bars []marketdata.Bar, err error) {
+	t.Helper()
+	previous := loadBars
+	loadBars = func(symbol, timeframe string, start, end time.Time) ([]marketdata.Bar, error) {
+		if timeframe != "1Day" {
+			t.Errorf("expected daily bars, got %s", timeframe)
+		}
+		return bars, err
+	}
+	t.Cleanup(func() { loadBars = previous })
+}
+
+func TestGetRiskAnalytics(t *testing.T) {
+	// Unfunded on day 1, a 1000 deposit lands on day 2 and another 1000 on day 4
+	b := &historyBroker{history: dailyHistory(
+		[]float64{0, 1000, 1100, 2045, 1840.5, 1766.88},
+		[]float64{0, 1000, 0, 1000, 0, 0},
+	)}
+	// The benchmark moves half as much as the portfolio, and has no day 4 close
+	useDailyBars(t, []marketdata.Bar{
+		{Timestamp: day(2).Add(5 * time.Hour), Close: 100},
+		{Timestamp: day(3).Add(5 * time.Hour), Close: 105},
+		{Timestamp: day(5).Add(5 * time.Hour), Close: 100},
+		{Timestamp: day(6).Add(5 * time.Hour), Close: 98},
+	}, nil)
+
+	body := serveHoldings(t, b, "/v1/analytics/risk", GetRiskAnalytics, "/v1/analytics/risk?period=1m&benchmark=qqq&risk_free_rate=0.02")
+
+	if b.params.Period != "1M" || b.params.Timeframe != "1D" || b.params.CashflowTypes != "CSD,CSW" {
+		t.Errorf("unexpected history params %+v", b.params)
+	}
+	if body["start"] != "2024-03-02" || body["end"] != "2024-03-06" || body["observations"].(float64) != 4 {
+		t.Errorf("expected 4 returns from the funding date, got %v", body)
+	}
+	// Returns are +10%, -5%, -10%, -4%
+	if total := body["total_return"].(float64); math.Abs(total-(1.1*0.95*0.9*0.96-1)) > 1e-9 {
+		t.Errorf("unexpected total return %v", total)
+	}
+	drawdown := body["max_drawdown"].(map[string]interface{})
+	if math.Abs(drawdown["depth"].(float64)-(1-0.95*0.9*0.96)) > 1e-9 || drawdown["peak_date"] != "2024-03-03" ||
+		drawdown["trough_date"] != "2024-03-06" || drawdown["recovery_date"] != nil {
+		t.Errorf("unexpected drawdown %v", drawdown)
+	}
+	if body["benchmark"] != "QQQ" || body["benchmark_observations"].(float64) != 2 {
+		t.Errorf("expected 2 days paired with the benchmark, got %v", body)
+	}
+	if beta := body["beta"].(float64); math.Abs(beta-2) > 1e-9 {
+		t.Errorf("expected beta 2, got %v", beta)
+	}
+	if body["sharpe_ratio"] == nil || body["sortino_ratio"] == nil || body["calmar_ratio"] == nil {
+		t.Errorf("expected all ratios to be defined, got %v", body)
+	}
+}
+
+func TestGetRiskAnalyticsWithoutBenchmark(t *testing.T) {
+	b := &historyBroker{history: dailyHistory([]float64{1000, 1010, 1000, 1020}, nil)}
+	useDailyBars(t, nil, errors.New("market-data returned status 503"))
+
+	body := serveHoldings(t, b, "/v1/analytics/risk", GetRiskAnalytics, "/v1/analytics/risk")
+
+	if body["period"] != "3M" || body["benchmark"] != "SPY" || body["beta"] != nil {
+		t.Errorf("expected defaults and no beta, got %v", body)
+	}
+	if body["annualized_volatility"] == nil || len(body["warnings"].([]interface{})) != 1 {
+		t.Errorf("expected the portfolio statistics and a warning, got %v", body)
+	}
+}
+
+func TestGetRiskAnalyticsValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := brokerClient
+	SetBroker(&historyBroker{history: dailyHistory([]float64{0, 1000}, nil)})
+	t.Cleanup(func() { SetBroker(previous) })
+	r := gin.New()
+	r.GET("/v1/analytics/risk", GetRiskAnalytics)
+
+	for target, want := range map[string]int{
+		"/v1/analytics/risk?period=5Y":           http.StatusBadRequest,
+		"/v1/analytics/risk?risk_free_rate=4":    http.StatusBadRequest,
+		"/v1/analytics/risk?risk_free_rate=abc":  http.StatusBadRequest,
+		"/v1/analytics/risk?period=1A":           http.StatusUnprocessableEntity,
+		"/v1/analytics/risk?risk_free_rate=0.04": http.StatusUnprocessableEntity,
+	} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("X-Account-ID", "acct")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%s: expected %d, got %d: %s", target, want, w.Code, w.Body)
+		}
+	}
+}
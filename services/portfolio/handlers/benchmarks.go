@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/seunghoon34/trading-app/pkg/broker"
+	"github.com/seunghoon34/trading-app/services/portfolio/internal/analytics"
+	"github.com/seunghoon34/trading-app/services/portfolio/internal/marketdata"
+)
+
+// maxBenchmarks bounds ?benchmark, as each benchmark is a market-data request per
+// history timeframe
+const maxBenchmarks = 5
+
+// dailyBar is the length of a daily bar; shorter bars are intraday
+const dailyBar = 24 * time.Hour
+
+// barTimeframe is the market-data bar matching a portfolio history timeframe
+type barTimeframe struct {
+	name   string
+	length time.Duration
+}
+
+var barTimeframes = map[string]barTimeframe{
+	"1Min":  {"1Min", time.Minute},
+	"5Min":  {"5Min", 5 * time.Minute},
+	"15Min": {"15Min", 15 * time.Minute},
+	"1H":    {"1Hour", time.Hour},
+	"1D":    {"1Day", dailyBar},
+}
+
+// parseBenchmarks reads a comma separated list of benchmark symbols such as SPY,QQQ
+func parseBenchmarks(value string) ([]string, error) {
+	var symbols []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ",") {
+		symbol := strings.ToUpper(strings.TrimSpace(part))
+		if symbol == "" || seen[symbol] {
+			continue
+		}
+		if len(symbol) > 10 || strings.Trim(symbol, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789.") != "" {
+			return nil, fmt.Errorf("invalid benchmark symbol %q", part)
+		}
+		seen[symbol] = true
+		symbols = append(symbols, symbol)
+	}
+	if len(symbols) > maxBenchmarks {
+		return nil, fmt.Errorf("at most %d benchmarks can be compared", maxBenchmarks)
+	}
+	return symbols, nil
+}
+
+// alignCloses gives the benchmark's close at each time, or 0 where it has none. Daily
+// bars match by date, as daily history points are the day's closing equity; intraday
+// points take the close of the last bar that had ended by then.
+func alignCloses(times []time.Time, bars []marketdata.Bar, length time.Duration) []float64 {
+	closes := make([]float64, len(times))
+	if length >= dailyBar {
+		byDate := make(map[string]float64, len(bars))
+		for _, bar := range bars {
+			byDate[bar.Timestamp.In(marketTZ).Format("2006-01-02")] = bar.Close
+		}
+		for i, t := range times {
+			closes[i] = byDate[t.In(marketTZ).Format("2006-01-02")]
+		}
+		return closes
+	}
+
+	var last float64
+	j := 0
+	for i, t := range times {
+		for ; j < len(bars) && !bars[j].Timestamp.Add(length).After(t); j++ {
+			last = bars[j].Close
+		}
+		closes[i] = last
+	}
+	return closes
+}
+
+// BenchmarkComparison compares a portfolio history with a benchmark. CumulativeReturn
+// is the benchmark's growth since the first point it has a price for, aligned with the
+// history's timestamps and null where it has none. Return and ExcessReturn compound the
+// periods both have returns for; alpha, tracking error and information ratio are
+// annualized and only computed for daily histories.
+type BenchmarkComparison struct {
+	Symbol           string     `json:"symbol"`
+	CumulativeReturn []*float64 `json:"cumulative_return"`
+	Observations     int        `json:"observations"`
+	Return           *float64   `json:"return"`
+	ExcessReturn     *float64   `json:"excess_return"`
+	Beta             *float64   `json:"beta"`
+	Alpha            *float64   `json:"alpha"`
+	TrackingError    *float64   `json:"tracking_error"`
+	InformationRatio *float64   `json:"information_ratio"`
+}
+
+// compareBenchmark compares history with a benchmark's bars of the given length
+func compareBenchmark(symbol string, history *broker.PortfolioHistory, bars []marketdata.Bar, length time.Duration, riskFree float64) BenchmarkComparison {
+	comparison := BenchmarkComparison{Symbol: symbol, CumulativeReturn: make([]*float64, len(history.Timestamp))}
+
+	times := make([]time.Time, len(history.Timestamp))
+	for i, ts := range history.Timestamp {
+		times[i] = time.Unix(ts, 0).In(marketTZ)
+	}
+	var base float64
+	for i, close := range alignCloses(times, bars, length) {
+		if close <= 0 {
+			continue
+		}
+		if base == 0 {
+			base = close
+		}
+		comparison.CumulativeReturn[i] = metric(close/base - 1)
+	}
+
+	series := newEquitySeries(history)
+	portfolio, benchmark := benchmarkReturns(series, alignCloses(series.times, bars, length))
+	comparison.Observations = len(benchmark)
+	if len(benchmark) == 0 {
+		return comparison
+	}
+	comparison.Return = metric(analytics.TotalReturn(benchmark))
+	comparison.ExcessReturn = metric(analytics.TotalReturn(portfolio) - analytics.TotalReturn(benchmark))
+	if length >= dailyBar {
+		comparison.Beta = metric(analytics.Beta(portfolio, benchmark))
+		comparison.Alpha = metric(analytics.Alpha(portfolio, benchmark, riskFree))
+		comparison.TrackingError = metric(analytics.TrackingError(portfolio, benchmark))
+		comparison.InformationRatio = metric(analytics.InformationRatio(portfolio, benchmark))
+	}
+	return comparison
+}
+
+// benchmarkedHistory is a portfolio history and the timeframe it was fetched at
+type benchmarkedHistory struct {
+	history   *broker.PortfolioHistory
+	timeframe string
+}
+
+// compareBenchmarks compares each history, keyed by period, with each benchmark. Bars
+// are loaded once per benchmark and timeframe over all the histories using it. A
+// benchmark that fails to load is left out with a warning rather than failing the
+// request.
+func compareBenchmarks(accountID string, symbols []string, histories map[string]benchmarkedHistory, riskFree float64) (map[string][]BenchmarkComparison, []string) {
+	// The span each bar timeframe has to cover
+	type span struct{ start, end time.Time }
+	spans := make(map[string]span)
+	for _, h := range histories {
+		n := len(h.history.Timestamp)
+		tf, ok := barTimeframes[h.timeframe]
+		if !ok || n == 0 {
+			continue
+		}
+		start := time.Unix(h.history.Timestamp[0], 0)
+		end := time.Unix(h.history.Timestamp[n-1], 0)
+		if tf.length >= dailyBar {
+			y, m, d := start.In(marketTZ).Date()
+			start = time.Date(y, m, d, 0, 0, 0, 0, marketTZ)
+			end = end.Add(dailyBar)
+		} else {
+			// Intraday points need the bar ending at the first of them
+			start = start.Add(-tf.length)
+		}
+		if s, ok := spans[tf.name]; ok {
+			if s.start.Before(start) {
+				start = s.start
+			}
+			if s.end.After(end) {
+				end = s.end
+			}
+		}
+		spans[tf.name] = span{start, end}
+	}
+
+	comparisons := make(map[string][]BenchmarkComparison, len(histories))
+	for period := range histories {
+		comparisons[period] = []BenchmarkComparison{}
+	}
+	var warnings []string
+	for _, symbol := range symbols {
+		bars := make(map[string][]marketdata.Bar, len(spans))
+		var failed error
+		for timeframe, s := range spans {
+			if bars[timeframe], failed = loadBars(symbol, timeframe, s.start, s.end); failed != nil {
+				break
+			}
+		}
+		if failed != nil {
+			log.Printf("benchmarks for %s: loading %s bars: %v", accountID, symbol, failed)
+			warnings = append(warnings, "benchmark "+symbol+" unavailable; it is not compared")
+			continue
+		}
+		for period, h := range histories {
+			tf, ok := barTimeframes[h.timeframe]
+			if !ok {
+				continue
+			}
+			comparisons[period] = append(comparisons[period], compareBenchmark(symbol, h.history, bars[tf.name], tf.length, riskFree))
+		}
+	}
+	return comparisons, warnings
+}
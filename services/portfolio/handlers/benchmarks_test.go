@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/portfolio/internal/analytics"
+	"github.com/seunghoon34/trading-app/services/portfolio/internal/marketdata"
+)
+
+// useBars serves bars by symbol and timeframe, failing for symbols it has none for,
+// and records the requests as symbol/timeframe
+func useBars(t *testing.T, bars map[string][]marketdata.Bar) *[]string {
+	t.Helper()
+	var requests []string
+	previous := loadBars
+	loadBars = func(symbol, timeframe string, start, end time.Time) ([]marketdata.Bar, error) {
+		requests = append(requests, symbol+"/"+timeframe)
+		if symbol == "SPY" && timeframe == "1Day" && (!start.Equal(day(1)) || !end.After(day(4))) {
+			t.Errorf("expected bars from day 1 through day 4, got %v to %v", start, end)
+		}
+		b, ok := bars[symbol+"/"+timeframe]
+		if !ok {
+			return nil, errors.New("market-data returned status 404")
+		}
+		return b, nil
+	}
+	t.Cleanup(func() { loadBars = previous })
+	return &requests
+}
+
+func dailyBars(closes ...float64) []marketdata.Bar {
+	bars := make([]marketdata.Bar, len(closes))
+	for i, close := range closes {
+		bars[i] = marketdata.Bar{Timestamp: day(i + 1).Add(5 * time.Hour), Close: close}
+	}
+	return bars
+}
+
+func TestGetReturnsBenchmarks(t *testing.T) {
+	useBars(t, map[string][]marketdata.Bar{"SPY/1Day": dailyBars(100, 105, 100, 110)})
+
+	body := serveHoldings(t, depositHistory(), "/v1/performance/returns", GetReturns,
+		"/v1/performance/returns?start=2024-03-01&end=2024-03-04&benchmark=spy,QQQ,SPY")
+
+	if len(body["timestamp"].([]interface{})) != 4 {
+		t.Errorf("expected the history's timestamps, got %v", body["timestamp"])
+	}
+	benchmarks := body["benchmarks"].([]interface{})
+	if len(benchmarks) != 1 {
+		t.Fatalf("expected SPY alone to be compared, got %v", benchmarks)
+	}
+	spy := benchmarks[0].(map[string]interface{})
+	if spy["symbol"] != "SPY" || spy["observations"].(float64) != 3 {
+		t.Errorf("unexpected comparison %v", spy)
+	}
+	for i, want := range []float64{0, 0.05, 0, 0.1} {
+		if got := spy["cumulative_return"].([]interface{})[i].(float64); math.Abs(got-want) > 1e-9 {
+			t.Errorf("cumulative return %d: expected %v, got %v", i, want, got)
+		}
+	}
+
+	// The deposit on day 3 is not part of the portfolio's return
+	portfolio := []float64{0.1, -0.05, 0.1}
+	benchmark := []float64{0.05, 100.0/105 - 1, 0.1}
+	for field, want := range map[string]float64{
+		"return":            0.1,
+		"excess_return":     analytics.TotalReturn(portfolio) - 0.1,
+		"beta":              analytics.Beta(portfolio, benchmark),
+		"alpha":             analytics.Alpha(portfolio, benchmark, 0),
+		"tracking_error":    analytics.TrackingError(portfolio, benchmark),
+		"information_ratio": analytics.InformationRatio(portfolio, benchmark),
+	} {
+		if got, ok := spy[field].(float64); !ok || math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v", field, want, spy[field])
+		}
+	}
+
+	warnings := body["warnings"].([]interface{})
+	if len(warnings) != 1 || warnings[0] != "benchmark QQQ unavailable; it is not compared" {
+		t.Errorf("expected a warning for QQQ, got %v", warnings)
+	}
+}
+
+func TestGetMultiTimeFramePerformanceBenchmarks(t *testing.T) {
+	requests := useBars(t, map[string][]marketdata.Bar{
+		"SPY/1Day":  dailyBars(100, 105, 100, 110),
+		"SPY/1Hour": dailyBars(100, 105, 100, 110),
+	})
+
+	body := serveHoldings(t, depositHistory(), "/v1/performance/all", GetMultiTimeFramePerformance, "/v1/performance/all?benchmark=SPY")
+
+	sort.Strings(*requests)
+	if len(*requests) != 2 || (*requests)[0] != "SPY/1Day" || (*requests)[1] != "SPY/1Hour" {
+		t.Errorf("expected one request per timeframe, got %v", *requests)
+	}
+	comparisons := body["benchmarks"].(map[string]interface{})
+	if len(comparisons) != 4 {
+		t.Fatalf("expected comparisons for all four periods, got %v", comparisons)
+	}
+	week := comparisons["1W"].([]interface{})[0].(map[string]interface{})
+	if week["tracking_error"] == nil {
+		t.Errorf("expected a tracking error for the daily history, got %v", week)
+	}
+	intraday := comparisons["1D"].([]interface{})[0].(map[string]interface{})
+	if intraday["return"] == nil || intraday["tracking_error"] != nil {
+		t.Errorf("expected returns but no annualized statistics for the hourly history, got %v", intraday)
+	}
+}
+
+func TestAlignClosesIntraday(t *testing.T) {
+	open := day(4).Add(9*time.Hour + 30*time.Minute)
+	bars := []marketdata.Bar{
+		{Timestamp: day(4).Add(9 * time.Hour), Close: 1},
+		{Timestamp: day(4).Add(10 * time.Hour), Close: 2},
+		{Timestamp: day(4).Add(11 * time.Hour), Close: 3},
+	}
+	closes := alignCloses([]time.Time{open, open.Add(time.Hour), open.Add(2 * time.Hour)}, bars, time.Hour)
+	// Each point takes the last bar that had closed by then
+	if closes[0] != 0 || closes[1] != 1 || closes[2] != 2 {
+		t.Errorf("expected [0 1 2], got %v", closes)
+	}
+}
+
+func TestBenchmarkValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, query := range []string{
+		"?benchmark=SPY!",
+		"?benchmark=A,B,C,D,E,F",
+		"?benchmark=SPY&risk_free_rate=abc",
+	} {
+		for path, handler := range map[string]gin.HandlerFunc{
+			"/v1/performance/all":     GetMultiTimeFramePerformance,
+			"/v1/performance/returns": GetReturns,
+		} {
+			r := gin.New()
+			r.GET(path, handler)
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, path+query, nil)
+			req.Header.Set("X-Account-ID", "acct-1")
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%s%s: expected 400, got %d: %s", path, query, w.Code, w.Body)
+			}
+		}
+	}
+}
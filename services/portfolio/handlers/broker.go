@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
+)
+
+// brokerClient is the brokerage used by all handlers in this package
+var brokerClient broker.Broker = broker.NewFromEnv()
+
+// SetBroker replaces the brokerage client, e.g. with a simulator or a test double
+func SetBroker(b broker.Broker) {
+	brokerClient = b
+}
+
+// respondBrokerError passes broker rejections through with their status code and
+// reports transport failures as a 500 with the given message
+func respondBrokerError(c *gin.Context, err error, message string) {
+	if apiErr, ok := broker.AsAPIError(err); ok {
+		c.JSON(apiErr.StatusCode, gin.H{"error": apiErr.Message, "code": apiErr.Code})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
+)
+
+// CloseResult represents the outcome of closing a single position
+type CloseResult struct {
+	Symbol  string `json:"symbol"`
+	Success bool   `json:"success"`
+	OrderID string `json:"order_id,omitempty"`
+	Qty     string `json:"qty,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ClosePositionsResult represents the outcome of closing every position
+type ClosePositionsResult struct {
+	Results      []CloseResult `json:"results"`
+	SuccessCount int           `json:"success_count"`
+	FailureCount int           `json:"failure_count"`
+}
+
+// parseCloseParams reads the optional qty or percentage to close
+func parseCloseParams(c *gin.Context) (broker.ClosePositionParams, error) {
+	params := broker.ClosePositionParams{Qty: c.Query("qty"), Percentage: c.Query("percentage")}
+	if params.Qty != "" && params.Percentage != "" {
+		return params, errors.New("specify either qty or percentage, not both")
+	}
+	if params.Qty != "" {
+		qty, err := strconv.ParseFloat(params.Qty, 64)
+		if err != nil || qty <= 0 {
+			return params, errors.New("qty must be a positive number")
+		}
+	}
+	if params.Percentage != "" {
+		percentage, err := strconv.ParseFloat(params.Percentage, 64)
+		if err != nil || percentage <= 0 || percentage > 100 {
+			return params, errors.New("percentage must be greater than 0 and at most 100")
+		}
+	}
+	return params, nil
+}
+
+func closedResult(symbol string, order *broker.Order) CloseResult {
+	result := CloseResult{
+		Symbol:  symbol,
+		Success: true,
+		OrderID: order.ID,
+		Status:  order.Status,
+	}
+	if order.Qty != nil {
+		result.Qty = *order.Qty
+	}
+	return result
+}
+
+// ClosePosition sells all of a position, or the given qty or percentage of it, at market
+func ClosePosition(c *gin.Context) {
+	accountID := c.GetHeader("X-Account-ID")
+	if accountID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account ID header missing"})
+		return
+	}
+
+	symbol := strings.ToUpper(c.Param("symbol"))
+	params, err := parseCloseParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := brokerClient.ClosePosition(c.Request.Context(), accountID, symbol, params)
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to close position"
+		if apiErr, ok := broker.AsAPIError(err); ok {
+			status, message = apiErr.StatusCode, apiErr.Message
+		}
+		c.JSON(status, gin.H{
+			"error":  message,
+			"result": CloseResult{Symbol: symbol, Error: message},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Position closed",
+		"result":  closedResult(symbol, order),
+	})
+}
+
+// CloseAllPositions liquidates every position, cancelling open orders first when
+// cancel_orders=true so shares held by them can be sold
+func CloseAllPositions(c *gin.Context) {
+	accountID := c.GetHeader("X-Account-ID")
+	if accountID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account ID header missing"})
+		return
+	}
+
+	cancelOrders := false
+	if value := c.Query("cancel_orders"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cancel_orders must be true or false"})
+			return
+		}
+		cancelOrders = parsed
+	}
+
+	statuses, err := brokerClient.CloseAllPositions(c.Request.Context(), accountID, cancelOrders)
+	if err != nil {
+		respondBrokerError(c, err, "Failed to close positions")
+		return
+	}
+
+	result := ClosePositionsResult{Results: make([]CloseResult, 0, len(statuses))}
+	for _, status := range statuses {
+		if status.Status >= 200 && status.Status < 300 {
+			var order broker.Order
+			if err := json.Unmarshal(status.Body, &order); err == nil {
+				result.Results = append(result.Results, closedResult(status.Symbol, &order))
+				result.SuccessCount++
+				continue
+			}
+		}
+
+		var apiErr broker.APIError
+		message := "Failed to close position"
+		if err := json.Unmarshal(status.Body, &apiErr); err == nil && apiErr.Message != "" {
+			message = apiErr.Message
+		}
+		result.Results = append(result.Results, CloseResult{Symbol: status.Symbol, Error: message})
+		result.FailureCount++
+	}
+
+	if result.FailureCount > 0 && result.SuccessCount == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "All positions failed to close",
+			"result":  result,
+		})
+	} else if result.FailureCount > 0 {
+		c.JSON(http.StatusPartialContent, gin.H{
+			"message": "Positions closed with some failures",
+			"result":  result,
+		})
+	} else {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "All positions closed",
+			"result":  result,
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
+)
+
+// closingBroker answers the close endpoints with canned results; every other method
+// comes from the embedded nil interface
+type closingBroker struct {
+	broker.Broker
+	params   broker.ClosePositionParams
+	cancel   bool
+	statuses []broker.ClosePositionStatus
+}
+
+func (b *closingBroker) ClosePosition(ctx context.Context, accountID, symbol string, params broker.ClosePositionParams) (*broker.Order, error) {
+	b.params = params
+	if symbol != "AAPL" {
+		return nil, &broker.APIError{StatusCode: http.StatusNotFound, Code: 40410000, Message: "position does not exist"}
+	}
+	qty := "5"
+	return &broker.Order{ID: "order-1", Symbol: symbol, Qty: &qty, Status: "accepted"}, nil
+}
+
+func (b *closingBroker) CloseAllPositions(ctx context.Context, accountID string, cancelOrders bool) ([]broker.ClosePositionStatus, error) {
+	b.cancel = cancelOrders
+	return b.statuses, nil
+}
+
+func serveClose(t *testing.T, b broker.Broker, method, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	previous := brokerClient
+	SetBroker(b)
+	t.Cleanup(func() { SetBroker(previous) })
+
+	r := gin.New()
+	r.DELETE("/v1/positions", CloseAllPositions)
+	r.DELETE("/v1/positions/:symbol", ClosePosition)
+
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("X-Account-ID", "acct")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestClosePosition(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantParams broker.ClosePositionParams
+	}{
+		{"whole position", "/v1/positions/aapl", http.StatusOK, broker.ClosePositionParams{}},
+		{"by qty", "/v1/positions/AAPL?qty=2.5", http.StatusOK, broker.ClosePositionParams{Qty: "2.5"}},
+		{"by percentage", "/v1/positions/AAPL?percentage=50", http.StatusOK, broker.ClosePositionParams{Percentage: "50"}},
+		{"qty and percentage", "/v1/positions/AAPL?qty=1&percentage=50", http.StatusBadRequest, broker.ClosePositionParams{}},
+		{"percentage over 100", "/v1/positions/AAPL?percentage=120", http.StatusBadRequest, broker.ClosePositionParams{}},
+		{"no position", "/v1/positions/MSFT", http.StatusNotFound, broker.ClosePositionParams{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &closingBroker{}
+			w := serveClose(t, b, http.MethodDelete, tt.target)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body)
+			}
+			if b.params != tt.wantParams {
+				t.Errorf("expected params %+v, got %+v", tt.wantParams, b.params)
+			}
+		})
+	}
+}
+
+func TestCloseAllPositionsReportsEachSymbol(t *testing.T) {
+	b := &closingBroker{statuses: []broker.ClosePositionStatus{
+		{Symbol: "AAPL", Status: http.StatusOK, Body: json.RawMessage(`{"id":"order-1","qty":"5","status":"accepted"}`)},
+		{Symbol: "MSFT", Status: http.StatusForbidden, Body: json.RawMessage(`{"code":40310000,"message":"insufficient qty available"}`)},
+	}}
+
+	w := serveClose(t, b, http.MethodDelete, "/v1/positions?cancel_orders=true")
+	if w.Code != http.StatusPartialContent {
+		t.Fatalf("expected 206, got %d: %s", w.Code, w.Body)
+	}
+	if !b.cancel {
+		t.Errorf("expected open orders to be cancelled")
+	}
+
+	var body struct {
+		Result ClosePositionsResult `json:"result"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	results := body.Result.Results
+	if body.Result.SuccessCount != 1 || body.Result.FailureCount != 1 || len(results) != 2 {
+		t.Fatalf("unexpected result: %+v", body.Result)
+	}
+	if results[0].OrderID != "order-1" || results[0].Qty != "5" || results[1].Error != "insufficient qty available" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+
+	b.statuses = nil
+	if w := serveClose(t, b, http.MethodDelete, "/v1/positions"); w.Code != http.StatusOK {
+		t.Errorf("expected 200 with no positions, got %d", w.Code)
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"time"
+	// The service image has no zoneinfo, so embed it for America/New_York
+	_ "time/tzdata"
+
+	"github.com/seunghoon34/trading-app/services/portfolio/internal/marketdata"
+)
+
+// pendingActionWindow is how far around today to look for share actions the broker
+// has not processed yet
+const pendingActionWindow = 30
+
+// marketTZ is the exchange's time zone, in which corporate action dates are published
+var marketTZ, _ = time.LoadLocation("America/New_York")
+
+var (
+	// now and loadCorporateActions are replaced in tests
+	now                  = time.Now
+	loadCorporateActions = marketdata.CorporateActions
+)
+
+// marketToday is the current date on the exchange's calendar
+func marketToday() time.Time {
+	y, m, d := now().In(marketTZ).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, marketTZ)
+}
+
+// shareRatio is how many shares each share becomes through a split or stock
+// dividend; zero for other actions
+func shareRatio(action marketdata.CorporateAction) float64 {
+	switch action.Type {
+	case marketdata.ActionSplit, marketdata.ActionReverseSplit:
+		if action.OldRate > 0 {
+			return action.NewRate / action.OldRate
+		}
+	case marketdata.ActionStockDividend:
+		return 1 + action.Rate
+	}
+	return 0
+}
+
+// pendingShareActions returns, per symbol, the combined share ratio of splits and
+// stock dividends that have gone ex but that the broker has not processed yet. Until
+// then positions still hold the old share count while prices already trade on the
+// new basis.
+func pendingShareActions(symbols []string) (map[string]float64, error) {
+	ratios := make(map[string]float64)
+	if len(symbols) == 0 {
+		return ratios, nil
+	}
+
+	today := marketToday()
+	actions, err := loadCorporateActions(symbols,
+		[]string{marketdata.ActionSplit, marketdata.ActionReverseSplit, marketdata.ActionStockDividend},
+		today.AddDate(0, 0, -pendingActionWindow), today.AddDate(0, 0, pendingActionWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	date := today.Format("2006-01-02")
+	for _, action := range actions {
+		ratio := shareRatio(action)
+		if ratio <= 0 || action.ExDate == "" || action.ExDate > date || action.ProcessDate <= date {
+			continue
+		}
+		if ratios[action.Symbol] == 0 {
+			ratios[action.Symbol] = 1
+		}
+		ratios[action.Symbol] *= ratio
+	}
+	return ratios, nil
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
+	"github.com/seunghoon34/trading-app/services/portfolio/internal/marketdata"
+)
+
+const (
+	defaultDividendDays = 90
+	maxDividendDays     = 365
+)
+
+// Upcoming dividend statuses
+const (
+	// DividendAnnounced dividends are paid to holders on the ex-date, still ahead
+	DividendAnnounced = "announced"
+	// DividendEntitled dividends have gone ex while the position was held and await payment
+	DividendEntitled = "entitled"
+)
+
+// UpcomingDividend is an expected payment on a current holding
+type UpcomingDividend struct {
+	Symbol      string  `json:"symbol"`
+	Status      string  `json:"status"`
+	ExDate      string  `json:"ex_date"`
+	RecordDate  string  `json:"record_date,omitempty"`
+	PayableDate string  `json:"payable_date"`
+	Rate        float64 `json:"rate"`
+	Qty         float64 `json:"qty"`
+	Amount      float64 `json:"amount"`
+	Special     bool    `json:"special,omitempty"`
+}
+
+// HoldingDividends projects a holding's yearly dividend income from the regular
+// dividends it paid over the past year
+type HoldingDividends struct {
+	Symbol                string  `json:"symbol"`
+	Qty                   float64 `json:"qty"`
+	TrailingAnnualRate    float64 `json:"trailing_annual_rate"`
+	ProjectedAnnualIncome float64 `json:"projected_annual_income"`
+	Yield                 float64 `json:"yield"`
+}
+
+// GetDividends estimates dividend income for the current holdings: payments announced
+// or already earned over the next ?days (default 90), and a yearly projection from
+// each holding's trailing twelve months of regular dividends. A dividend already gone
+// ex is only earned on the shares held going into the ex-date; announced payments and
+// the projection use today's quantities, assuming positions are held unchanged.
+func GetDividends(c *gin.Context) {
+	accountID := c.GetHeader("X-Account-ID")
+	if accountID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account ID header missing"})
+		return
+	}
+
+	days := defaultDividendDays
+	if value := c.Query("days"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxDividendDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 365"})
+			return
+		}
+		days = n
+	}
+
+	positions, err := getPositionsHelper(c.Request.Context(), accountID)
+	if err != nil {
+		respondBrokerError(c, err, "Failed to get positions")
+		return
+	}
+
+	holdings := make(map[string]*HoldingDividends)
+	prices := make(map[string]float64)
+	var symbols []string
+	var totalMarketValue float64
+	for _, position := range positions {
+		qty, _ := strconv.ParseFloat(position.Quantity, 64)
+		if qty == 0 {
+			continue
+		}
+		marketValue, _ := strconv.ParseFloat(position.MarketValue, 64)
+		prices[position.Symbol], _ = strconv.ParseFloat(position.CurrentPrice, 64)
+		holdings[position.Symbol] = &HoldingDividends{Symbol: position.Symbol, Qty: qty}
+		symbols = append(symbols, position.Symbol)
+		totalMarketValue += marketValue
+	}
+
+	today := marketToday()
+	var actions []marketdata.CorporateAction
+	if len(symbols) > 0 {
+		actions, err = loadCorporateActions(symbols, []string{marketdata.ActionCashDividend}, today.AddDate(-1, 0, 0), today.AddDate(0, 0, days))
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch dividends", "details": err.Error()})
+			return
+		}
+	}
+
+	date := today.Format("2006-01-02")
+	yearAgo := today.AddDate(-1, 0, 0).Format("2006-01-02")
+
+	// Dividends gone ex but not yet paid are owed only on positions held into the
+	// ex-date, so look up when each of those positions was opened and what it held
+	exSymbols := make(map[string]bool)
+	earliestEx := ""
+	for _, action := range actions {
+		if holdings[action.Symbol] == nil || action.Type != marketdata.ActionCashDividend || action.ExDate >= date || action.PayableDate < date {
+			continue
+		}
+		exSymbols[action.Symbol] = true
+		if earliestEx == "" || action.ExDate < earliestEx {
+			earliestEx = action.ExDate
+		}
+	}
+	var exPositions []broker.Position
+	for _, position := range positions {
+		if exSymbols[position.Symbol] {
+			exPositions = append(exPositions, position)
+		}
+	}
+	starts := map[string]string{}
+	heldBefore := func(symbol, date string) float64 { return holdings[symbol].Qty }
+	if len(exPositions) > 0 {
+		starts, err = holdingPeriodStarts(c.Request.Context(), accountID, exPositions)
+		if err == nil {
+			heldBefore, err = qtyHeldBefore(c.Request.Context(), accountID, exPositions, earliestEx)
+		}
+		if err != nil {
+			respondBrokerError(c, err, "Failed to get account activities")
+			return
+		}
+	}
+
+	upcoming := []UpcomingDividend{}
+	var upcomingTotal float64
+	for _, action := range actions {
+		holding, ok := holdings[action.Symbol]
+		if !ok || action.Type != marketdata.ActionCashDividend {
+			continue
+		}
+
+		status := ""
+		qty := holding.Qty
+		switch {
+		case action.ExDate >= date:
+			status = DividendAnnounced
+		case action.PayableDate >= date:
+			// A position opened on or after the ex-date was bought without the dividend
+			start, ok := starts[action.Symbol]
+			held := heldBefore(action.Symbol, action.ExDate)
+			if (!ok || start < action.ExDate) && math.Abs(held) > 1e-9 {
+				status = DividendEntitled
+				qty = math.Copysign(held, holding.Qty)
+			}
+		}
+		if status != "" {
+			amount := action.Rate * qty
+			upcoming = append(upcoming, UpcomingDividend{
+				Symbol:      action.Symbol,
+				Status:      status,
+				ExDate:      action.ExDate,
+				RecordDate:  action.RecordDate,
+				PayableDate: action.PayableDate,
+				Rate:        action.Rate,
+				Qty:         qty,
+				Amount:      amount,
+				Special:     action.Special,
+			})
+			upcomingTotal += amount
+		}
+
+		// Special dividends are one-offs and say nothing about next year
+		if action.ExDate > yearAgo && action.ExDate < date && !action.Special {
+			holding.TrailingAnnualRate += action.Rate
+		}
+	}
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].PayableDate < upcoming[j].PayableDate
+	})
+
+	projected := make([]HoldingDividends, 0, len(symbols))
+	var annualIncome float64
+	for _, symbol := range symbols {
+		holding := holdings[symbol]
+		holding.ProjectedAnnualIncome = holding.TrailingAnnualRate * holding.Qty
+		if price := prices[symbol]; price > 0 {
+			holding.Yield = holding.TrailingAnnualRate / price * 100
+		}
+		annualIncome += holding.ProjectedAnnualIncome
+		projected = append(projected, *holding)
+	}
+	sort.Slice(projected, func(i, j int) bool { return projected[i].Symbol < projected[j].Symbol })
+
+	var portfolioYield float64
+	if totalMarketValue > 0 {
+		portfolioYield = annualIncome / totalMarketValue * 100
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"account_id":              accountID,
+		"days":                    days,
+		"upcoming":                upcoming,
+		"upcoming_total":          upcomingTotal,
+		"holdings":                projected,
+		"projected_annual_income": annualIncome,
+		"portfolio_yield":         portfolioYield,
+	})
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
+	"github.com/seunghoon34/trading-app/services/portfolio/internal/marketdata"
+)
+
+// holdingsBroker reports fixed positions and dividend activities
+type holdingsBroker struct {
+	broker.Broker
+	positions  []broker.Position
+	activities []broker.Activity
+}
+
+func (b *holdingsBroker) ListPositions(ctx context.Context, accountID string) ([]broker.Position, error) {
+	return b.positions, nil
+}
+
+func (b *holdingsBroker) ListActivities(ctx context.Context, accountID string, params broker.ListActivitiesParams) ([]broker.Activity, error) {
+	return b.activities, nil
+}
+
+// useCorporateActions pins today to 2024-06-10 and serves actions from the given list
+func useCorporateActions(t *testing.T, actions []marketdata.CorporateAction) {
+	t.Helper()
+	previousNow, previousLoad := now, loadCorporateActions
+	now = func() time.Time { return time.Date(2024, 6, 10, 15, 0, 0, 0, time.UTC) }
+	loadCorporateActions = func(symbols, types []string, start, end time.Time) ([]marketdata.CorporateAction, error) {
+		var matched []marketdata.CorporateAction
+		for _, action := range actions {
+			for _, typ := range types {
+				if action.Type == typ {
+					matched = append(matched, action)
+				}
+			}
+		}
+		return matched, nil
+	}
+	t.Cleanup(func() { now, loadCorporateActions = previousNow, previousLoad })
+}
+
+// resetDividendsCache empties the dividend totals cache for the test and after it
+func resetDividendsCache(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		dividendsCache.Lock()
+		dividendsCache.entries = make(map[string]dividendsEntry)
+		dividendsCache.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func serveHoldings(t *testing.T, b broker.Broker, route string, handler gin.HandlerFunc, target string) map[string]interface{} {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	previous := brokerClient
+	SetBroker(b)
+	t.Cleanup(func() { SetBroker(previous) })
+	resetDividendsCache(t)
+
+	r := gin.New()
+	r.GET(route, handler)
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Header.Set("X-Account-ID", "acct")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestGetDividends(t *testing.T) {
+	useCorporateActions(t, []marketdata.CorporateAction{
+		{Type: marketdata.ActionCashDividend, Symbol: "KO", ExDate: "2023-09-14", PayableDate: "2023-10-01", Rate: 0.46},
+		{Type: marketdata.ActionCashDividend, Symbol: "KO", ExDate: "2023-11-30", PayableDate: "2023-12-15", Rate: 0.46},
+		{Type: marketdata.ActionCashDividend, Symbol: "KO", ExDate: "2024-03-14", PayableDate: "2024-04-01", Rate: 0.485},
+		{Type: marketdata.ActionCashDividend, Symbol: "KO", ExDate: "2024-06-03", PayableDate: "2024-06-28", Rate: 0.485},
+		{Type: marketdata.ActionCashDividend, Symbol: "KO", ExDate: "2024-01-10", PayableDate: "2024-01-20", Rate: 1, Special: true},
+		{Type: marketdata.ActionCashDividend, Symbol: "AAPL", ExDate: "2024-08-12", PayableDate: "2024-08-15", Rate: 0.25},
+	})
+	b := &holdingsBroker{positions: []broker.Position{
+		{Symbol: "KO", Quantity: "100", CurrentPrice: "60", MarketValue: "6000"},
+		{Symbol: "AAPL", Quantity: "10", CurrentPrice: "200", MarketValue: "2000"},
+	}}
+
+	body := serveHoldings(t, b, "/v1/dividends", GetDividends, "/v1/dividends")
+
+	upcoming := body["upcoming"].([]interface{})
+	if len(upcoming) != 2 {
+		t.Fatalf("expected 2 upcoming payments, got %v", upcoming)
+	}
+	first, second := upcoming[0].(map[string]interface{}), upcoming[1].(map[string]interface{})
+	if first["symbol"] != "KO" || first["status"] != DividendEntitled || first["amount"].(float64) != 48.5 {
+		t.Errorf("expected KO's earned dividend first, got %v", first)
+	}
+	if second["symbol"] != "AAPL" || second["status"] != DividendAnnounced || second["amount"].(float64) != 2.5 {
+		t.Errorf("expected AAPL's announced dividend, got %v", second)
+	}
+
+	// KO paid 0.46 + 0.46 + 0.485 + 0.485 regular dividends over the past year
+	holdings := body["holdings"].([]interface{})
+	ko := holdings[1].(map[string]interface{})
+	if rate := ko["trailing_annual_rate"].(float64); rate < 1.889 || rate > 1.891 {
+		t.Errorf("expected a trailing rate of 1.89 excluding the special dividend, got %v", rate)
+	}
+	if income := body["projected_annual_income"].(float64); income < 188.9 || income > 189.1 {
+		t.Errorf("expected projected income of 189, got %v", income)
+	}
+}
+
+func TestGetDividendsEntitlementNeedsTheExDate(t *testing.T) {
+	useCorporateActions(t, []marketdata.CorporateAction{
+		{Type: marketdata.ActionCashDividend, Symbol: "KO", ExDate: "2024-06-03", PayableDate: "2024-06-28", Rate: 0.5},
+		{Type: marketdata.ActionCashDividend, Symbol: "PEP", ExDate: "2024-06-05", PayableDate: "2024-06-25", Rate: 1},
+	})
+	fill := func(id, symbol, date, qty string) broker.Activity {
+		at, _ := time.ParseInLocation("2006-01-02 15:04", date+" 10:00", marketTZ)
+		return broker.Activity{ID: id, ActivityType: broker.ActivityFill, Symbol: symbol, Side: "buy", Qty: qty, Price: "60", TransactionTime: &at}
+	}
+	b := &holdingsBroker{
+		positions: []broker.Position{
+			{Symbol: "KO", Quantity: "100", CurrentPrice: "60", MarketValue: "6000"},
+			{Symbol: "PEP", Quantity: "20", CurrentPrice: "170", MarketValue: "3400"},
+		},
+		// 40 KO were bought the day after its ex-date and PEP was opened after its own
+		activities: []broker.Activity{
+			fill("3", "PEP", "2024-06-06", "20"),
+			fill("2", "KO", "2024-06-04", "40"),
+			fill("1", "KO", "2024-01-02", "60"),
+		},
+	}
+
+	body := serveHoldings(t, b, "/v1/dividends", GetDividends, "/v1/dividends")
+
+	upcoming := body["upcoming"].([]interface{})
+	if len(upcoming) != 1 {
+		t.Fatalf("expected only KO's dividend, got %v", upcoming)
+	}
+	ko := upcoming[0].(map[string]interface{})
+	if ko["symbol"] != "KO" || ko["status"] != DividendEntitled || ko["qty"].(float64) != 60 || ko["amount"].(float64) != 30 {
+		t.Errorf("expected KO's dividend on the 60 shares held into the ex-date, got %v", ko)
+	}
+}
+
+func TestGetPortfolioPerformanceAppliesCorporateActions(t *testing.T) {
+	useCorporateActions(t, []marketdata.CorporateAction{
+		// Gone ex today but not yet processed by the broker
+		{Type: marketdata.ActionSplit, Symbol: "NVDA", ExDate: "2024-06-10", ProcessDate: "2024-06-11", OldRate: 1, NewRate: 10},
+		// Already reflected in the broker's position
+		{Type: marketdata.ActionSplit, Symbol: "AAPL", ExDate: "2024-06-03", ProcessDate: "2024-06-03", OldRate: 1, NewRate: 2},
+	})
+	b := &holdingsBroker{
+		positions: []broker.Position{{
+			Symbol: "NVDA", Quantity: "10", CurrentPrice: "121", LastdayPrice: "1200",
+			CostBasis: "10000", MarketValue: "1210", UnrealizedPL: "-8790", UnrealizedIntradayPL: "-10790",
+		}, {
+			Symbol: "AAPL", Quantity: "20", CurrentPrice: "100", LastdayPrice: "99",
+			CostBasis: "1500", MarketValue: "2000", UnrealizedPL: "500", UnrealizedIntradayPL: "20",
+		}},
+		activities: []broker.Activity{
+			{ID: "1", ActivityType: broker.ActivityDividend, Symbol: "AAPL", NetAmount: "30"},
+			{ID: "2", ActivityType: broker.ActivityDividend, Symbol: "MSFT", NetAmount: "99"},
+		},
+	}
+
+	body := serveHoldings(t, b, "/v1/performance", GetPortfolioPerformance, "/v1/performance")
+
+	// NVDA restated as 100 shares at 121 against a 120 previous close
+	if mv := body["total_market_value"].(float64); mv != 14100 {
+		t.Errorf("expected market value 14100, got %v", mv)
+	}
+	if pl := body["total_pl"].(float64); pl != 2600 {
+		t.Errorf("expected total P&L 2600, got %v", pl)
+	}
+	if daily := body["daily_pl"].(float64); daily != 120 {
+		t.Errorf("expected daily P&L 120, got %v", daily)
+	}
+	// Dividends on symbols no longer held are left out
+	if dividends := body["total_dividends"].(float64); dividends != 30 {
+		t.Errorf("expected dividends of 30, got %v", dividends)
+	}
+	if ret := body["total_return"].(float64); ret != 2630 {
+		t.Errorf("expected total return 2630, got %v", ret)
+	}
+	if cb := body["adjusted_cost_basis"].(float64); cb != 11470 {
+		t.Errorf("expected adjusted cost basis 11470, got %v", cb)
+	}
+	if adjusted := body["split_adjusted"].([]interface{}); len(adjusted) != 1 || adjusted[0] != "NVDA" {
+		t.Errorf("expected only NVDA to be adjusted, got %v", adjusted)
+	}
+	if _, ok := body["warnings"]; ok {
+		t.Errorf("expected no warnings, got %v", body["warnings"])
+	}
+}
+
+// countingHoldings counts the activity pages requested
+type countingHoldings struct {
+	*holdingsBroker
+	pages int
+}
+
+func (b *countingHoldings) ListActivities(ctx context.Context, accountID string, params broker.ListActivitiesParams) ([]broker.Activity, error) {
+	b.pages++
+	return b.holdingsBroker.ListActivities(ctx, accountID, params)
+}
+
+func TestDividendsReceivedCountsCurrentHoldingPeriod(t *testing.T) {
+	fill := func(id, date, side, qty string) broker.Activity {
+		at, _ := time.ParseInLocation("2006-01-02 15:04", date+" 10:00", marketTZ)
+		return broker.Activity{ID: id, ActivityType: broker.ActivityFill, Symbol: "AAPL", Side: side, Qty: qty, Price: "100", TransactionTime: &at}
+	}
+	b := &countingHoldings{holdingsBroker: &holdingsBroker{
+		positions: []broker.Position{{Symbol: "AAPL", Quantity: "10"}},
+		// Newest first, as the broker pages them
+		activities: []broker.Activity{
+			{ID: "6", ActivityType: broker.ActivityDividend, Symbol: "AAPL", Date: "2024-05-15", NetAmount: "7"},
+			fill("5", "2024-03-04", "buy", "4"),
+			fill("4", "2024-03-01", "buy", "6"),
+			{ID: "3", ActivityType: broker.ActivityDividend, Symbol: "AAPL", Date: "2024-02-15", NetAmount: "5"},
+			fill("2", "2024-01-10", "sell", "5"),
+			fill("1", "2023-06-01", "buy", "5"),
+		},
+	}}
+	resetDividendsCache(t)
+	previous := brokerClient
+	SetBroker(b)
+	t.Cleanup(func() { SetBroker(previous) })
+
+	starts, err := holdingPeriodStarts(context.Background(), "acct", b.positions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if starts["AAPL"] != "2024-03-01" {
+		t.Errorf("expected the holding period to start 2024-03-01, got %q", starts["AAPL"])
+	}
+
+	// The dividend from the position closed in January is not counted
+	received, err := dividendsReceived(context.Background(), "acct", b.positions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received["AAPL"] != 7 {
+		t.Errorf("expected 7 in dividends since the position was opened, got %v", received["AAPL"])
+	}
+
+	pages := b.pages
+	if _, err := dividendsReceived(context.Background(), "acct", b.positions); err != nil {
+		t.Fatal(err)
+	}
+	if b.pages != pages {
+		t.Errorf("expected the cached totals to be reused, got %d more pages", b.pages-pages)
+	}
+	// A trade changes the holdings and invalidates the cache
+	if _, err := dividendsReceived(context.Background(), "acct", []broker.Position{{Symbol: "AAPL", Quantity: "12"}}); err != nil {
+		t.Fatal(err)
+	}
+	if b.pages == pages {
+		t.Errorf("expected changed holdings to reload the dividends")
+	}
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/portfolio/internal/marketdata"
+)
+
+const (
+	// unclassified groups holdings market-data has no classification for
+	unclassified       = "Unclassified"
+	defaultTopHoldings = 5
+	maxTopHoldings     = 50
+	defaultMaxWeight   = 0.2
+	// unclassifiedWarning is the share of gross exposure without classifications above
+	// which the report warns that the classifications file is missing or incomplete
+	unclassifiedWarning = 0.5
+)
+
+// loadAssets is replaced in tests
+var loadAssets = marketdata.Assets
+
+// HoldingExposure is one position's share of the portfolio. Weight is its market value
+// over the gross exposure, negative for short positions.
+type HoldingExposure struct {
+	Symbol      string  `json:"symbol"`
+	MarketValue float64 `json:"market_value"`
+	Weight      float64 `json:"weight"`
+	Sector      string  `json:"sector"`
+	Industry    string  `json:"industry"`
+	AssetClass  string  `json:"asset_class"`
+}
+
+// ExposureGroup is the combined exposure of the holdings sharing a classification
+type ExposureGroup struct {
+	Name        string   `json:"name"`
+	MarketValue float64  `json:"market_value"`
+	Weight      float64  `json:"weight"`
+	Symbols     []string `json:"symbols"`
+}
+
+// Concentration measures how much of the portfolio rides on a few holdings. The
+// Herfindahl index is the sum of squared weights, from 1/n for n equal holdings up to
+// 1 for a single one; EffectiveHoldings is its inverse.
+type Concentration struct {
+	HerfindahlIndex   float64           `json:"herfindahl_index"`
+	EffectiveHoldings *float64          `json:"effective_holdings"`
+	TopN              int               `json:"top_n"`
+	TopNWeight        float64           `json:"top_n_weight"`
+	MaxWeight         float64           `json:"max_weight"`
+	Flagged           []HoldingExposure `json:"flagged"`
+}
+
+// ExposureReport breaks an account's positions down by classification
+type ExposureReport struct {
+	AccountID     string            `json:"account_id"`
+	GrossExposure float64           `json:"gross_exposure"`
+	NetExposure   float64           `json:"net_exposure"`
+	Holdings      []HoldingExposure `json:"holdings"`
+	Sectors       []ExposureGroup   `json:"sectors"`
+	Industries    []ExposureGroup   `json:"industries"`
+	AssetClasses  []ExposureGroup   `json:"asset_classes"`
+	Concentration Concentration     `json:"concentration"`
+	Warnings      []string          `json:"warnings,omitempty"`
+}
+
+// maxHoldingWeight reads ?max_weight as a decimal weight, defaulting to
+// EXPOSURE_MAX_WEIGHT and then to 20%
+func maxHoldingWeight(c *gin.Context) (float64, error) {
+	value := c.Query("max_weight")
+	if value == "" {
+		value = os.Getenv("EXPOSURE_MAX_WEIGHT")
+	}
+	if value == "" {
+		return defaultMaxWeight, nil
+	}
+	weight, err := strconv.ParseFloat(value, 64)
+	if err != nil || weight <= 0 || weight > 1 {
+		return 0, errors.New("max_weight must be a weight between 0 and 1, e.g. 0.2")
+	}
+	return weight, nil
+}
+
+// byWeight orders by absolute weight, largest first, then by name
+func byWeight(wi, wj float64, ni, nj string) bool {
+	if ai, aj := math.Abs(wi), math.Abs(wj); ai != aj {
+		return ai > aj
+	}
+	return ni < nj
+}
+
+// groupExposure sums the holdings by the classification key picks
+func groupExposure(holdings []HoldingExposure, key func(HoldingExposure) string) []ExposureGroup {
+	index := make(map[string]int)
+	groups := []ExposureGroup{}
+	for _, h := range holdings {
+		name := key(h)
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, ExposureGroup{Name: name, Symbols: []string{}})
+		}
+		groups[i].MarketValue += h.MarketValue
+		groups[i].Weight += h.Weight
+		groups[i].Symbols = append(groups[i].Symbols, h.Symbol)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return byWeight(groups[i].Weight, groups[j].Weight, groups[i].Name, groups[j].Name)
+	})
+	return groups
+}
+
+// GetExposure groups the account's positions by sector, industry, asset class and
+// market-cap bucket, and reports the Herfindahl index, the weight of the ?top (default
+// 5) holdings, and the holdings weighing more than ?max_weight
+func GetExposure(c *gin.Context) {
+	accountID := c.GetHeader("X-Account-ID")
+	if accountID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account ID header missing"})
+		return
+	}
+
+	top := defaultTopHoldings
+	if value := c.Query("top"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxTopHoldings {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "top must be between 1 and 50"})
+			return
+		}
+		top = n
+	}
+	maxWeight, err := maxHoldingWeight(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	positions, err := getPositionsHelper(c.Request.Context(), accountID)
+	if err != nil {
+		respondBrokerError(c, err, "Failed to get positions")
+		return
+	}
+
+	report := ExposureReport{
+		AccountID:     accountID,
+		Holdings:      []HoldingExposure{},
+		Concentration: Concentration{TopN: top, MaxWeight: maxWeight, Flagged: []HoldingExposure{}},
+	}
+
+	symbols := make([]string, len(positions))
+	for i, position := range positions {
+		symbols[i] = position.Symbol
+	}
+	// Without classifications the weights and concentration still hold
+	assets := make(map[string]marketdata.Asset, len(positions))
+	classificationsLoaded := false
+	if len(symbols) > 0 {
+		list, err := loadAssets(symbols)
+		classificationsLoaded = err == nil
+		if err != nil {
+			log.Printf("exposure for %s: loading asset classifications: %v", accountID, err)
+			report.Warnings = append(report.Warnings, "asset classifications unavailable; holdings are unclassified")
+		}
+		for _, asset := range list {
+			assets[asset.Symbol] = asset
+		}
+	}
+
+	var unclassifiedExposure float64
+	for _, position := range positions {
+		marketValue, _ := strconv.ParseFloat(position.MarketValue, 64)
+		asset := assets[position.Symbol]
+		h := HoldingExposure{
+			Symbol:      position.Symbol,
+			MarketValue: marketValue,
+			Sector:      asset.Sector,
+			Industry:    asset.Industry,
+			AssetClass:  asset.AssetClass,
+		}
+		if h.Sector == "" {
+			h.Sector = unclassified
+		}
+		if h.Industry == "" {
+			h.Industry = unclassified
+		}
+		// Prefer the classification's asset class, which tells funds from stocks
+		for _, class := range []string{asset.Class, position.AssetClass, unclassified} {
+			if h.AssetClass == "" {
+				h.AssetClass = class
+			}
+		}
+		report.Holdings = append(report.Holdings, h)
+		report.GrossExposure += math.Abs(marketValue)
+		report.NetExposure += marketValue
+		if asset.Sector == "" && asset.Industry == "" && asset.AssetClass == "" {
+			unclassifiedExposure += math.Abs(marketValue)
+		}
+	}
+	// Market-data serves classifications only from the operator's ASSET_CLASSIFICATIONS_FILE,
+	// so mostly unclassified holdings mean the file is missing or does not cover them
+	if classificationsLoaded && report.GrossExposure > 0 && unclassifiedExposure/report.GrossExposure > unclassifiedWarning {
+		share := unclassifiedExposure / report.GrossExposure
+		log.Printf("exposure for %s: %.0f%% of gross exposure is unclassified", accountID, share*100)
+		report.Warnings = append(report.Warnings, fmt.Sprintf(
+			"%.0f%% of the portfolio has no classification; check market-data's ASSET_CLASSIFICATIONS_FILE", share*100))
+	}
+
+	if report.GrossExposure > 0 {
+		for i := range report.Holdings {
+			h := &report.Holdings[i]
+			h.Weight = h.MarketValue / report.GrossExposure
+			report.Concentration.HerfindahlIndex += h.Weight * h.Weight
+		}
+		report.Concentration.EffectiveHoldings = metric(1 / report.Concentration.HerfindahlIndex)
+	}
+	sort.Slice(report.Holdings, func(i, j int) bool {
+		hi, hj := report.Holdings[i], report.Holdings[j]
+		return byWeight(hi.Weight, hj.Weight, hi.Symbol, hj.Symbol)
+	})
+	for i, h := range report.Holdings {
+		if i < top {
+			report.Concentration.TopNWeight += math.Abs(h.Weight)
+		}
+		if math.Abs(h.Weight) > maxWeight {
+			report.Concentration.Flagged = append(report.Concentration.Flagged, h)
+		}
+	}
+
+	report.Sectors = groupExposure(report.Holdings, func(h HoldingExposure) string { return h.Sector })
+	report.Industries = groupExposure(report.Holdings, func(h HoldingExposure) string { return h.Industry })
+	report.AssetClasses = groupExposure(report.Holdings, func(h HoldingExposure) string { return h.AssetClass })
+
+	c.JSON(http.StatusOK, report)
+}
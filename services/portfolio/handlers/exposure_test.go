@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/seunghoon34/trading-app/pkg/broker"
+	"github.com/seunghoon34/trading-app/services/portfolio/internal/marketdata"
+)
+
+func useAssets(t *testing.T, assets []marketdata.Asset, err error) {
+	t.Helper()
+	previous := loadAssets
+	loadAssets = func(symbols []string) ([]marketdata.Asset, error) {
+		return assets, err
+	}
+	t.Cleanup(func() { loadAssets = previous })
+}
+
+func exposureHoldings() *holdingsBroker {
+	return &holdingsBroker{positions: []broker.Position{
+		{Symbol: "SPY", AssetClass: "us_equity", MarketValue: "2000"},
+		{Symbol: "TSLA", AssetClass: "us_equity", MarketValue: "-1000", Side: "short"},
+		{Symbol: "AAPL", AssetClass: "us_equity", MarketValue: "4000"},
+		{Symbol: "MSFT", AssetClass: "us_equity", MarketValue: "3000"},
+	}}
+}
+
+// groupWeights maps each group's name to its weight
+func groupWeights(groups interface{}) map[string]float64 {
+	weights := make(map[string]float64)
+	for _, g := range groups.([]interface{}) {
+		group := g.(map[string]interface{})
+		weights[group["name"].(string)] = group["weight"].(float64)
+	}
+	return weights
+}
+
+func TestGetExposure(t *testing.T) {
+	t.Setenv("EXPOSURE_MAX_WEIGHT", "")
+	useAssets(t, []marketdata.Asset{
+		{Symbol: "AAPL", Class: "us_equity", Sector: "Information Technology", Industry: "Technology Hardware"},
+		{Symbol: "MSFT", Class: "us_equity", Sector: "Information Technology", Industry: "Software"},
+		{Symbol: "SPY", Class: "us_equity", AssetClass: "etf"},
+	}, nil)
+
+	body := serveHoldings(t, exposureHoldings(), "/v1/exposure", GetExposure, "/v1/exposure?top=2")
+
+	if body["gross_exposure"].(float64) != 10000 || body["net_exposure"].(float64) != 8000 {
+		t.Errorf("unexpected exposure %v / %v", body["gross_exposure"], body["net_exposure"])
+	}
+	holdings := body["holdings"].([]interface{})
+	order := []string{"AAPL", "MSFT", "SPY", "TSLA"}
+	for i, want := range []float64{0.4, 0.3, 0.2, -0.1} {
+		h := holdings[i].(map[string]interface{})
+		if h["symbol"] != order[i] || math.Abs(h["weight"].(float64)-want) > 1e-9 {
+			t.Errorf("holding %d: expected %s at %v, got %v", i, order[i], want, h)
+		}
+	}
+
+	expectGroups := func(name string, want map[string]float64) {
+		t.Helper()
+		got := groupWeights(body[name])
+		if len(got) != len(want) {
+			t.Errorf("%s: expected %v, got %v", name, want, got)
+		}
+		for group, weight := range want {
+			if math.Abs(got[group]-weight) > 1e-9 {
+				t.Errorf("%s %s: expected %v, got %v", name, group, weight, got[group])
+			}
+		}
+	}
+	expectGroups("sectors", map[string]float64{"Information Technology": 0.7, "Unclassified": 0.1})
+	expectGroups("industries", map[string]float64{"Technology Hardware": 0.4, "Software": 0.3, "Unclassified": 0.1})
+	expectGroups("asset_classes", map[string]float64{"us_equity": 0.6, "etf": 0.2})
+
+	concentration := body["concentration"].(map[string]interface{})
+	if hhi := concentration["herfindahl_index"].(float64); math.Abs(hhi-0.3) > 1e-9 {
+		t.Errorf("expected a Herfindahl index of 0.3, got %v", hhi)
+	}
+	if top := concentration["top_n_weight"].(float64); math.Abs(top-0.7) > 1e-9 {
+		t.Errorf("expected the top 2 to weigh 0.7, got %v", top)
+	}
+	flagged := concentration["flagged"].([]interface{})
+	if len(flagged) != 2 || concentration["max_weight"].(float64) != 0.2 {
+		t.Errorf("expected AAPL and MSFT above 20%%, got %v", concentration)
+	}
+	if _, ok := body["warnings"]; ok {
+		t.Errorf("expected no warnings, got %v", body["warnings"])
+	}
+}
+
+func TestGetExposureWarnsWhenMostlyUnclassified(t *testing.T) {
+	t.Setenv("EXPOSURE_MAX_WEIGHT", "")
+	// Only MSFT is in the classifications file; the rest come back bare
+	useAssets(t, []marketdata.Asset{
+		{Symbol: "AAPL", Class: "us_equity"},
+		{Symbol: "MSFT", Class: "us_equity", Sector: "Information Technology", Industry: "Software"},
+		{Symbol: "SPY", Class: "us_equity"},
+		{Symbol: "TSLA", Class: "us_equity"},
+	}, nil)
+
+	body := serveHoldings(t, exposureHoldings(), "/v1/exposure", GetExposure, "/v1/exposure")
+
+	warnings, _ := body["warnings"].([]interface{})
+	if len(warnings) != 1 || !strings.Contains(warnings[0].(string), "70%") {
+		t.Errorf("expected a warning that 70%% is unclassified, got %v", body["warnings"])
+	}
+}
+
+func TestGetExposureWithoutClassifications(t *testing.T) {
+	t.Setenv("EXPOSURE_MAX_WEIGHT", "0.35")
+	useAssets(t, nil, errors.New("market-data returned status 503"))
+
+	body := serveHoldings(t, exposureHoldings(), "/v1/exposure", GetExposure, "/v1/exposure")
+
+	if sectors := groupWeights(body["sectors"]); len(sectors) != 1 || math.Abs(sectors["Unclassified"]-0.8) > 1e-9 {
+		t.Errorf("expected every holding unclassified, got %v", sectors)
+	}
+	if classes := groupWeights(body["asset_classes"]); math.Abs(classes["us_equity"]-0.8) > 1e-9 {
+		t.Errorf("expected the broker's asset class, got %v", classes)
+	}
+	concentration := body["concentration"].(map[string]interface{})
+	if flagged := concentration["flagged"].([]interface{}); len(flagged) != 1 {
+		t.Errorf("expected AAPL alone above the configured 35%%, got %v", flagged)
+	}
+	if body["warnings"] == nil {
+		t.Error("expected a warning about the missing classifications")
+	}
+}
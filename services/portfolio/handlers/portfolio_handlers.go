@@ -1,102 +1,26 @@
 package handlers
 
 import (
-	"encoding/base64"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
+	"log"
 	"net/http"
-	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
 )
 
-type Performance struct {
-	Timestamp     []int64   `json:"timestamp"`
-	Equity        []float64 `json:"equity"`
-	ProfitLoss    []float64 `json:"profit_loss"`
-	ProfitLossPct []float64 `json:"profit_loss_pct"`
-}
-
-type BuyingPower struct {
-	BuyingPower string `json:"buying_power"`
-}
-
-type Position struct {
-	Symbol                 string `json:"symbol"`
-	Quantity               string `json:"qty"`
-	AvgEntryPrice          string `json:"avg_entry_price"`
-	CurrentPrice           string `json:"current_price"`
-	MarketValue            string `json:"market_value"`
-	CostBasis              string `json:"cost_basis"`
-	UnrealizedPL           string `json:"unrealized_pl"`
-	UnrealizedPLPC         string `json:"unrealized_plpc"`
-	UnrealizedIntradayPL   string `json:"unrealized_intraday_pl"`
-	UnrealizedIntradayPLPC string `json:"unrealized_intraday_plpc"`
-	Side                   string `json:"side"`
-}
-
 type PortfolioResponse struct {
-	Positions []Position `json:"positions"`
+	Positions []broker.Position `json:"positions"`
 }
 
-func makeAlpacaRequest(method, url string, payload io.Reader) (*http.Response, error) {
-	req, err := http.NewRequest(method, url, payload)
-	if err != nil {
-		return nil, err
-	}
-
-	// Add authentication headers
-	auth := os.Getenv("ALPACA_API_KEY") + ":" + os.Getenv("ALPACA_SECRET_KEY")
-	basicAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte(auth))
-	req.Header.Add("Authorization", basicAuth)
-	req.Header.Add("Accept", "application/json")
-
-	if method == "POST" {
-		req.Header.Add("Content-Type", "application/json")
-	}
-
-	return http.DefaultClient.Do(req)
+func getPositionsHelper(ctx context.Context, account_id string) ([]broker.Position, error) {
+	return brokerClient.ListPositions(ctx, account_id)
 }
 
-// func sendAlpacaResponse(c *gin.Context, res *http.Response) {
-// 	defer res.Body.Close()
-
-// 	body, err := io.ReadAll(res.Body)
-// 	if err != nil {
-// 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read response"})
-// 		return
-// 	}
-
-// 	c.Header("Content-Type", "application/json")
-// 	c.String(res.StatusCode, string(body))
-// }
-
-func getPositionsHelper(account_id string) ([]Position, error) {
-	url := fmt.Sprintf("https://broker-api.sandbox.alpaca.markets/v1/trading/accounts/%s/positions", account_id)
-	fmt.Printf("Making request to: %s\n", url)
-
-	res, err := makeAlpacaRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	defer res.Body.Close()
-
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	fmt.Printf("Response body: %s\n", string(body))
-
-	var positions []Position
-	if err := json.Unmarshal(body, &positions); err != nil {
-		return nil, err
-	}
-
-	return positions, nil
-}
 func GetPosition(c *gin.Context) {
 	symbol := c.Param("symbol")
 
@@ -106,30 +30,12 @@ func GetPosition(c *gin.Context) {
 		return
 	}
 
-	url := fmt.Sprintf("https://broker-api.sandbox.alpaca.markets/v1/trading/accounts/%s/positions/%s", accountID, symbol)
-
-	res, err := makeAlpacaRequest("GET", url, nil)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute request"})
-		return
-	}
-
-	defer res.Body.Close()
-
-	body, err := io.ReadAll(res.Body)
+	positions, err := brokerClient.GetPosition(c.Request.Context(), accountID, symbol)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read response"})
-		return
-	}
-
-	// Unmarshal directly into your struct - Go will ignore extra fields!
-	var positions Position
-	if err := json.Unmarshal(body, &positions); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse response"})
+		respondBrokerError(c, err, "Failed to execute request")
 		return
 	}
 
-	// Return clean response with only the fields you defined
 	c.JSON(http.StatusOK, gin.H{
 		"account_id": accountID,
 		"positions":  positions,
@@ -143,42 +49,17 @@ func GetPositions(c *gin.Context) {
 		return
 	}
 
-	positions, err := getPositionsHelper(accountID) // Use your helper!
+	positions, err := getPositionsHelper(c.Request.Context(), accountID) // Use your helper!
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get positions" + err.Error()})
 		return
 	}
 
-	accountURL := fmt.Sprintf("https://broker-api.sandbox.alpaca.markets/v1/trading/accounts/%s/account", accountID)
-	res, err := makeAlpacaRequest("GET", accountURL, nil)
+	account, err := brokerClient.GetAccount(c.Request.Context(), accountID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch account details"})
-		return
-	}
-	defer res.Body.Close()
-
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read account response"})
-		return
-	}
-
-	// Check if the API call was successful
-	if res.StatusCode != http.StatusOK {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":       "Failed to fetch account details from Alpaca",
-			"status_code": res.StatusCode,
-			"response":    string(body),
-		})
-		return
-	}
-
-	var buyingPower BuyingPower
-	if err := json.Unmarshal(body, &buyingPower); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":        "Failed to parse account details",
-			"raw_response": string(body),
-			"parse_error":  err.Error(),
+			"error":   "Failed to fetch account details",
+			"details": err.Error(),
 		})
 		return
 	}
@@ -186,7 +67,7 @@ func GetPositions(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"account_id": accountID,
 		"positions":  positions,
-		"Cash":       buyingPower.BuyingPower,
+		"Cash":       account.BuyingPower,
 	})
 }
 
@@ -197,7 +78,7 @@ func GetPortfolioWorth(c *gin.Context) {
 		return
 	}
 
-	positions, err := getPositionsHelper(accountID) // Get both data and error
+	positions, err := getPositionsHelper(c.Request.Context(), accountID) // Get both data and error
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get positions"})
 		return
@@ -215,6 +96,10 @@ func GetPortfolioWorth(c *gin.Context) {
 	})
 }
 
+// GetPortfolioPerformance sums the P&L of the open positions. Splits that have gone ex
+// but that the broker has not processed are applied, and dividends received on held
+// symbols since each position was opened count towards the total return and reduce the
+// adjusted cost basis.
 func GetPortfolioPerformance(c *gin.Context) {
 	accountID := c.GetHeader("X-Account-ID") // ← Get from header
 	if accountID == "" {
@@ -222,13 +107,33 @@ func GetPortfolioPerformance(c *gin.Context) {
 		return
 	}
 
-	positions, err := getPositionsHelper(accountID)
+	positions, err := getPositionsHelper(c.Request.Context(), accountID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get positions"})
 		return
 	}
 
-	var dailyPL, totalPL, totalCostBasis, totalMarketValue float64
+	symbols := make([]string, len(positions))
+	for i, position := range positions {
+		symbols[i] = position.Symbol
+	}
+
+	// Corporate actions only refine the figures, so a failure to load them is
+	// reported alongside the unadjusted numbers rather than failing the request
+	var warnings []string
+	pending, err := pendingShareActions(symbols)
+	if err != nil {
+		log.Printf("performance for %s: loading corporate actions: %v", accountID, err)
+		warnings = append(warnings, "corporate actions unavailable; pending splits are not applied")
+	}
+	dividends, err := dividendsReceived(c.Request.Context(), accountID, positions)
+	if err != nil {
+		log.Printf("performance for %s: loading dividends: %v", accountID, err)
+		warnings = append(warnings, "dividend history unavailable; dividends are not included")
+	}
+
+	var dailyPL, totalPL, totalCostBasis, totalMarketValue, totalDividends float64
+	adjusted := []string{}
 
 	for _, position := range positions {
 		unrealizedIntradayPL, _ := strconv.ParseFloat(position.UnrealizedIntradayPL, 64)
@@ -236,94 +141,119 @@ func GetPortfolioPerformance(c *gin.Context) {
 		costBasis, _ := strconv.ParseFloat(position.CostBasis, 64)
 		marketValue, _ := strconv.ParseFloat(position.MarketValue, 64)
 
+		// The broker still values a position at its pre-split share count until it
+		// processes the split, so restate it on the new basis
+		if ratio, ok := pending[position.Symbol]; ok {
+			qty, _ := strconv.ParseFloat(position.Quantity, 64)
+			currentPrice, _ := strconv.ParseFloat(position.CurrentPrice, 64)
+			lastdayPrice, _ := strconv.ParseFloat(position.LastdayPrice, 64)
+			qty *= ratio
+			marketValue = qty * currentPrice
+			unrealizedPL = marketValue - costBasis
+			unrealizedIntradayPL = (currentPrice - lastdayPrice/ratio) * qty
+			adjusted = append(adjusted, position.Symbol)
+		}
+
 		dailyPL += unrealizedIntradayPL
 		totalPL += unrealizedPL
 		totalCostBasis += costBasis
 		totalMarketValue += marketValue
+		totalDividends += dividends[position.Symbol]
 	}
 
 	// Calculate portfolio-level percentages
-	var dailyPLPC, totalPLPC float64
+	var dailyPLPC, totalPLPC, totalReturnPC float64
 	if totalMarketValue > 0 {
 		totalPLPC = (totalPL / totalCostBasis) * 100               // Portfolio total return %
 		dailyPLPC = (dailyPL / (totalMarketValue - dailyPL)) * 100 // Portfolio daily return %
+		totalReturnPC = ((totalPL + totalDividends) / totalCostBasis) * 100
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"account_id":         accountID,
-		"daily_pl":           dailyPL,
-		"daily_plpc":         dailyPLPC,
-		"total_pl":           totalPL,
-		"total_plpc":         totalPLPC,
-		"total_market_value": totalMarketValue,
-		"total_cost_basis":   totalCostBasis,
-	})
+	response := gin.H{
+		"account_id":          accountID,
+		"daily_pl":            dailyPL,
+		"daily_plpc":          dailyPLPC,
+		"total_pl":            totalPL,
+		"total_plpc":          totalPLPC,
+		"total_market_value":  totalMarketValue,
+		"total_cost_basis":    totalCostBasis,
+		"total_dividends":     totalDividends,
+		"adjusted_cost_basis": totalCostBasis - totalDividends,
+		"total_return":        totalPL + totalDividends,
+		"total_return_pc":     totalReturnPC,
+		"split_adjusted":      adjusted,
+	}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+	c.JSON(http.StatusOK, response)
 }
 
+// GetMultiTimeFramePerformance returns the equity history over 1D, 1W, 1M and 1Y with
+// the time- and money-weighted returns of each period, net of deposits and withdrawals,
+// and compares each period with the ?benchmark symbols, e.g. SPY,QQQ
 func GetMultiTimeFramePerformance(c *gin.Context) {
 	accountID := c.GetHeader("X-Account-ID")
 	if accountID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Account ID header missing"})
 		return
 	}
+	benchmarks, err := parseBenchmarks(c.Query("benchmark"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rate, err := riskFreeRate(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Portfolio history API works independently of positions
 	// Even accounts with no positions can have cash equity history
 
 	// Define the different periods and their parameters
 	// Valid timeframes: 1Min, 5Min, 15Min, 1H, 1D
-	periods := map[string]string{
-		"1D": "period=1D&timeframe=1H",
-		"1W": "period=1W&timeframe=1D",
-		"1M": "period=1M&timeframe=1D",
-		"1Y": "period=1A&timeframe=1D",
+	periods := map[string]broker.PortfolioHistoryParams{
+		"1D": {Period: "1D", Timeframe: "1H"},
+		"1W": {Period: "1W", Timeframe: "1D"},
+		"1M": {Period: "1M", Timeframe: "1D"},
+		"1Y": {Period: "1A", Timeframe: "1D"},
 	}
 
 	// Channel to collect results
 	type result struct {
 		period      string
-		performance Performance
+		performance broker.PortfolioHistory
 		err         error
 	}
 
 	results := make(chan result, len(periods))
+	ctx := c.Request.Context()
 
 	// Launch concurrent requests
 	for period, params := range periods {
-		go func(p, prms string) {
-			url := fmt.Sprintf("https://broker-api.sandbox.alpaca.markets/v1/trading/accounts/%s/account/portfolio/history?%s&intraday_reporting=market_hours&pnl_reset=per_day&cashflow_types=NONE", accountID, prms)
+		go func(p string, prms broker.PortfolioHistoryParams) {
+			prms.IntradayReporting = "market_hours"
+			prms.PnlReset = "per_day"
+			// Report deposits and withdrawals as cash flows so they don't count as profit
+			prms.CashflowTypes = strings.Join(externalCashflowTypes, ",")
 
-			res, err := makeAlpacaRequest("GET", url, nil)
+			performance, err := brokerClient.GetPortfolioHistory(ctx, accountID, prms)
 			if err != nil {
 				results <- result{period: p, err: fmt.Errorf("failed to fetch %s performance: %w", p, err)}
 				return
 			}
-			defer res.Body.Close()
 
-			body, err := io.ReadAll(res.Body)
-			if err != nil {
-				results <- result{period: p, err: fmt.Errorf("failed to read %s response: %w", p, err)}
-				return
-			}
-
-			if res.StatusCode != http.StatusOK {
-				fmt.Printf("Alpaca API error for %s: Status %d, Body: %s\n", p, res.StatusCode, string(body))
-				results <- result{period: p, err: fmt.Errorf("failed to fetch %s performance from Alpaca: status %d, response: %s", p, res.StatusCode, string(body))}
-				return
-			}
-
-			var performance Performance
-			if err := json.Unmarshal(body, &performance); err != nil {
-				results <- result{period: p, err: fmt.Errorf("failed to parse %s performance: %w", p, err)}
-				return
-			}
-
-			results <- result{period: p, performance: performance, err: nil}
+			results <- result{period: p, performance: *performance, err: nil}
 		}(period, params)
 	}
 
+	// The longest period is a year; a week more covers weekends and holidays at its start
+	activities, activitiesErr := listCashFlows(ctx, accountID, marketToday().AddDate(-1, 0, -7), time.Time{})
+
 	// Collect all results
-	performanceData := make(map[string]Performance)
+	performanceData := make(map[string]broker.PortfolioHistory)
 	for i := 0; i < len(periods); i++ {
 		result := <-results
 		if result.err != nil {
@@ -336,12 +266,42 @@ func GetMultiTimeFramePerformance(c *gin.Context) {
 		performanceData[result.period] = result.performance
 	}
 
-	// Return successful response
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"account_id": accountID,
 		"1D":         performanceData["1D"],
 		"1W":         performanceData["1W"],
 		"1M":         performanceData["1M"],
 		"1Y":         performanceData["1Y"],
-	})
+	}
+
+	// Returns need the cash flows; without them the histories are still worth returning
+	var warnings []string
+	if activitiesErr != nil {
+		log.Printf("performance for %s: loading cash flows: %v", accountID, activitiesErr)
+		warnings = append(warnings, "cash flows unavailable; returns are not computed")
+	} else {
+		returns := make(map[string]PeriodReturns, len(periods))
+		for period, history := range performanceData {
+			if r, ok := periodReturns(newEquitySeries(&history), activities); ok {
+				returns[period] = r
+			}
+		}
+		response["returns"] = returns
+	}
+
+	if len(benchmarks) > 0 {
+		histories := make(map[string]benchmarkedHistory, len(periods))
+		for period, history := range performanceData {
+			histories[period] = benchmarkedHistory{history: &history, timeframe: periods[period].Timeframe}
+		}
+		comparisons, benchmarkWarnings := compareBenchmarks(accountID, benchmarks, histories, rate)
+		response["benchmarks"] = comparisons
+		warnings = append(warnings, benchmarkWarnings...)
+	}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+
+	// Return successful response
+	c.JSON(http.StatusOK, response)
 }
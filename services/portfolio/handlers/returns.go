@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
+	"github.com/seunghoon34/trading-app/services/portfolio/internal/analytics"
+)
+
+// year is the span from which returns are also reported annualized
+const year = 365 * 24 * time.Hour
+
+// PeriodReturns separates investment performance from deposits and withdrawals. The
+// time-weighted return chains the returns between cash flows, so it measures the
+// investments alone; the money-weighted return is the internal rate of return of the
+// flows, so it also reflects their timing. Both cover the whole period and are
+// annualized only for periods of a year or more.
+type PeriodReturns struct {
+	Start                         string   `json:"start"`
+	End                           string   `json:"end"`
+	StartValue                    float64  `json:"start_value"`
+	EndValue                      float64  `json:"end_value"`
+	NetDeposits                   float64  `json:"net_deposits"`
+	TimeWeightedReturn            *float64 `json:"time_weighted_return"`
+	MoneyWeightedReturn           *float64 `json:"money_weighted_return"`
+	AnnualizedTimeWeightedReturn  *float64 `json:"annualized_time_weighted_return,omitempty"`
+	AnnualizedMoneyWeightedReturn *float64 `json:"annualized_money_weighted_return,omitempty"`
+}
+
+// listCashFlows returns the account's deposits and withdrawals between after and until
+func listCashFlows(ctx context.Context, accountID string, after, until time.Time) ([]broker.Activity, error) {
+	return listActivities(ctx, accountID, broker.ListActivitiesParams{
+		ActivityTypes: externalCashflowTypes,
+		After:         after,
+		Until:         until,
+	})
+}
+
+// activityDate is the exchange date an activity happened on
+func activityDate(activity broker.Activity) string {
+	if activity.Date == "" && activity.TransactionTime != nil {
+		return activity.TransactionTime.In(marketTZ).Format("2006-01-02")
+	}
+	return activity.Date
+}
+
+// periodReturns computes the returns of an equity series with its cash flows taken from
+// the broker's activities. A flow counts towards the first point dated on or after it;
+// flows on the first day are already part of the starting value.
+func periodReturns(s equitySeries, activities []broker.Activity) (PeriodReturns, bool) {
+	if len(s.equity) < 2 {
+		return PeriodReturns{}, false
+	}
+	last := len(s.equity) - 1
+	r := PeriodReturns{
+		Start:      s.dates[0],
+		End:        s.dates[last],
+		StartValue: s.equity[0],
+		EndValue:   s.equity[last],
+	}
+
+	flows := make([]float64, len(s.equity))
+	irrFlows := []analytics.CashFlow{{At: s.times[0], Amount: -s.equity[0]}}
+	for _, activity := range activities {
+		date := activityDate(activity)
+		if date <= s.dates[0] || date > s.dates[last] {
+			continue
+		}
+		amount, _ := strconv.ParseFloat(activity.NetAmount, 64)
+		for i := 1; i <= last; i++ {
+			if date <= s.dates[i] {
+				flows[i] += amount
+				break
+			}
+		}
+		at, _ := time.ParseInLocation("2006-01-02", date, marketTZ)
+		irrFlows = append(irrFlows, analytics.CashFlow{At: at, Amount: -amount})
+		r.NetDeposits += amount
+	}
+	irrFlows = append(irrFlows, analytics.CashFlow{At: s.times[last], Amount: s.equity[last]})
+
+	twr := analytics.TotalReturn(analytics.Returns(s.equity, flows))
+	r.TimeWeightedReturn = metric(twr)
+	span := s.times[last].Sub(s.times[0])
+	if mwr, err := analytics.IRR(irrFlows, span); err == nil {
+		r.MoneyWeightedReturn = metric(mwr)
+	}
+	if span >= year {
+		r.AnnualizedTimeWeightedReturn = metric(annualize(twr, span))
+		if xirr, err := analytics.XIRR(irrFlows); err == nil {
+			r.AnnualizedMoneyWeightedReturn = metric(xirr)
+		}
+	}
+	return r, true
+}
+
+// annualize converts a return over span into a yearly rate
+func annualize(r float64, span time.Duration) float64 {
+	return math.Pow(1+r, float64(year)/float64(span)) - 1
+}
+
+// GetReturns reports the time- and money-weighted returns between ?start and ?end
+// (YYYY-MM-DD). end defaults to today and start to a year before end. Benchmarks given
+// as ?benchmark=SPY,QQQ are compared over the daily history, whose timestamps are
+// returned to align their cumulative returns with.
+func GetReturns(c *gin.Context) {
+	accountID := c.GetHeader("X-Account-ID")
+	if accountID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account ID header missing"})
+		return
+	}
+	benchmarks, err := parseBenchmarks(c.Query("benchmark"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rate, err := riskFreeRate(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	end := marketToday()
+	if value := c.Query("end"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", value, marketTZ)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "end must be a YYYY-MM-DD date"})
+			return
+		}
+		end = parsed
+	}
+	start := end.AddDate(-1, 0, 0)
+	if value := c.Query("start"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", value, marketTZ)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "start must be a YYYY-MM-DD date"})
+			return
+		}
+		start = parsed
+	}
+	if !start.Before(end) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start must be before end"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	history, err := brokerClient.GetPortfolioHistory(ctx, accountID, broker.PortfolioHistoryParams{
+		Timeframe:     "1D",
+		Start:         start,
+		End:           end,
+		CashflowTypes: strings.Join(externalCashflowTypes, ","),
+	})
+	if err != nil {
+		respondBrokerError(c, err, "Failed to fetch portfolio history")
+		return
+	}
+	activities, err := listCashFlows(ctx, accountID, start, end.AddDate(0, 0, 1))
+	if err != nil {
+		respondBrokerError(c, err, "Failed to fetch cash flows")
+		return
+	}
+
+	returns, ok := periodReturns(newEquitySeries(history), activities)
+	if !ok {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Not enough portfolio history in the requested range"})
+		return
+	}
+	response := gin.H{
+		"account_id": accountID,
+		"returns":    returns,
+	}
+	if len(benchmarks) > 0 {
+		comparisons, warnings := compareBenchmarks(accountID, benchmarks, map[string]benchmarkedHistory{
+			"": {history: history, timeframe: "1D"},
+		}, rate)
+		response["timestamp"] = history.Timestamp
+		response["benchmarks"] = comparisons[""]
+		if len(warnings) > 0 {
+			response["warnings"] = warnings
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/seunghoon34/trading-app/pkg/broker"
+)
+
+// depositHistory gains 10%, takes a 1000 deposit on day 3 while losing 5%, then gains 10%
+func depositHistory() *historyBroker {
+	return &historyBroker{
+		history: dailyHistory([]float64{1000, 1100, 2045, 2249.5}, []float64{0, 0, 1000, 0}),
+		activities: []broker.Activity{
+			{ID: "3", ActivityType: broker.ActivityDeposit, Date: "2024-03-09", NetAmount: "500"},
+			{ID: "2", ActivityType: broker.ActivityDeposit, Date: "2024-03-03", NetAmount: "1000"},
+			// Already part of the starting value
+			{ID: "1", ActivityType: broker.ActivityDeposit, Date: "2024-03-01", NetAmount: "1000"},
+		},
+	}
+}
+
+func TestGetReturns(t *testing.T) {
+	body := serveHoldings(t, depositHistory(), "/v1/performance/returns", GetReturns, "/v1/performance/returns?start=2024-03-01&end=2024-03-04")
+	returns := body["returns"].(map[string]interface{})
+
+	if returns["start"] != "2024-03-01" || returns["end"] != "2024-03-04" || returns["net_deposits"].(float64) != 1000 {
+		t.Errorf("unexpected range or deposits %v", returns)
+	}
+	if twr := returns["time_weighted_return"].(float64); math.Abs(twr-(1.1*0.95*1.1-1)) > 1e-9 {
+		t.Errorf("expected the deposit to be excluded from the TWR, got %v", twr)
+	}
+
+	// Over the three days: -1000 - 1000/(1+r)^(2/3) + 2249.5/(1+r) = 0
+	r := returns["money_weighted_return"].(float64)
+	if npv := -1000 - 1000/math.Pow(1+r, 2.0/3) + 2249.5/(1+r); math.Abs(npv) > 1e-6 {
+		t.Errorf("expected a zero-NPV money-weighted return, got %v (npv %v)", r, npv)
+	}
+	if _, ok := returns["annualized_time_weighted_return"]; ok {
+		t.Errorf("expected no annualized returns for a short range, got %v", returns)
+	}
+}
+
+func TestGetMultiTimeFramePerformanceReturns(t *testing.T) {
+	b := depositHistory()
+	body := serveHoldings(t, b, "/v1/performance/all", GetMultiTimeFramePerformance, "/v1/performance/all")
+
+	if b.params.CashflowTypes != "CSD,CSW" {
+		t.Errorf("expected deposits and withdrawals as cash flows, got %q", b.params.CashflowTypes)
+	}
+	returns := body["returns"].(map[string]interface{})
+	if len(returns) != 4 {
+		t.Fatalf("expected returns for all four periods, got %v", returns)
+	}
+	week := returns["1W"].(map[string]interface{})
+	if twr := week["time_weighted_return"].(float64); math.Abs(twr-(1.1*0.95*1.1-1)) > 1e-9 {
+		t.Errorf("unexpected 1W TWR %v", twr)
+	}
+
+	b.activitiesErr = errors.New("activities unavailable")
+	body = serveHoldings(t, b, "/v1/performance/all", GetMultiTimeFramePerformance, "/v1/performance/all")
+	if _, ok := body["returns"]; ok || body["1W"] == nil || body["warnings"] == nil {
+		t.Errorf("expected the histories with a warning and no returns, got %v", body)
+	}
+}
@@ -0,0 +1,433 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
+	"github.com/seunghoon34/trading-app/services/portfolio/internal/taxlots"
+)
+
+// designationStore keeps each account's specific-ID lot designations by sell order
+// ID, saving them to a JSON file when it has a path so they survive restarts
+type designationStore struct {
+	mu        sync.Mutex
+	path      string
+	loaded    bool
+	byAccount map[string]map[string][]taxlots.Designation
+}
+
+var designations = &designationStore{path: os.Getenv("TAX_LOT_DESIGNATIONS_FILE")}
+
+// load reads the file on first use. Must be called with s.mu held.
+func (s *designationStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	s.byAccount = make(map[string]map[string][]taxlots.Designation)
+	if s.path != "" {
+		data, err := os.ReadFile(s.path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &s.byAccount); err != nil {
+				return fmt.Errorf("invalid designations file %s: %w", s.path, err)
+			}
+		}
+	}
+	s.loaded = true
+	return nil
+}
+
+// Get returns the account's designations by sell order ID
+func (s *designationStore) Get(accountID string) (map[string][]taxlots.Designation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	orders := make(map[string][]taxlots.Designation, len(s.byAccount[accountID]))
+	for orderID, lots := range s.byAccount[accountID] {
+		orders[orderID] = lots
+	}
+	return orders, nil
+}
+
+// Set replaces the designations of a sell order, removing them when lots is empty
+func (s *designationStore) Set(accountID, orderID string, lots []taxlots.Designation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	if s.byAccount[accountID] == nil {
+		s.byAccount[accountID] = make(map[string][]taxlots.Designation)
+	}
+	if len(lots) == 0 {
+		delete(s.byAccount[accountID], orderID)
+	} else {
+		s.byAccount[accountID][orderID] = lots
+	}
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(s.byAccount)
+	if err != nil {
+		return err
+	}
+	// Write then rename, so a crash never leaves a half-written file
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".designations-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// taxLotMethod reads ?method, defaulting to TAX_LOT_METHOD and then to FIFO
+func taxLotMethod(c *gin.Context) (taxlots.Method, error) {
+	value := c.Query("method")
+	if value == "" {
+		value = os.Getenv("TAX_LOT_METHOD")
+	}
+	if value == "" {
+		return taxlots.FIFO, nil
+	}
+	method, err := taxlots.ParseMethod(value)
+	if err != nil {
+		return "", errors.New("method must be one of fifo, lifo, hifo or specific_id")
+	}
+	return method, nil
+}
+
+// fillFromActivity reads a FILL activity, reporting false if it lacks what a lot needs
+func fillFromActivity(activity broker.Activity) (taxlots.Fill, bool) {
+	qty, err := strconv.ParseFloat(activity.Qty, 64)
+	if err != nil || qty <= 0 {
+		return taxlots.Fill{}, false
+	}
+	price, err := strconv.ParseFloat(activity.Price, 64)
+	if err != nil {
+		return taxlots.Fill{}, false
+	}
+	var at time.Time
+	if activity.TransactionTime != nil {
+		at = activity.TransactionTime.In(marketTZ)
+	} else if at, err = time.ParseInLocation("2006-01-02", activity.Date, marketTZ); err != nil {
+		return taxlots.Fill{}, false
+	}
+	side := strings.ToLower(activity.Side)
+	if side == "sell_short" {
+		side = "sell"
+	}
+	return taxlots.Fill{
+		ID:      activity.ID,
+		OrderID: activity.OrderID,
+		Symbol:  activity.Symbol,
+		Side:    side,
+		Qty:     qty,
+		Price:   price,
+		Time:    at,
+	}, true
+}
+
+// loadLedger replays every fill in the account into a lot ledger
+func loadLedger(ctx context.Context, accountID string, method taxlots.Method) (*taxlots.Ledger, error) {
+	activities, err := listActivities(ctx, accountID, broker.ListActivitiesParams{ActivityTypes: []string{broker.ActivityFill}})
+	if err != nil {
+		return nil, err
+	}
+	fills := make([]taxlots.Fill, 0, len(activities))
+	var skipped int
+	for _, activity := range activities {
+		fill, ok := fillFromActivity(activity)
+		if !ok {
+			skipped++
+			continue
+		}
+		fills = append(fills, fill)
+	}
+
+	var designated map[string][]taxlots.Designation
+	if method == taxlots.SpecificID {
+		if designated, err = designations.Get(accountID); err != nil {
+			return nil, err
+		}
+	}
+	ledger := taxlots.Build(fills, method, designated)
+	if skipped > 0 {
+		ledger.Warnings = append(ledger.Warnings, fmt.Sprintf("%d fills without a quantity, price or time were skipped", skipped))
+	}
+	return ledger, nil
+}
+
+// LotReport is an open lot valued at the position's current price. The terms are those
+// of a sale today: LongTerm when every remaining share would be long-term, and
+// MixedTerm when wash sale replacement shares give part of the lot an earlier holding
+// period than the rest.
+type LotReport struct {
+	*taxlots.Lot
+	LongTerm       bool     `json:"long_term"`
+	MixedTerm      bool     `json:"mixed_term"`
+	LongTermQty    float64  `json:"long_term_qty"`
+	ShortTermQty   float64  `json:"short_term_qty"`
+	CurrentPrice   *float64 `json:"current_price"`
+	MarketValue    *float64 `json:"market_value"`
+	UnrealizedGain *float64 `json:"unrealized_gain"`
+}
+
+// GetTaxLots lists the account's open tax lots, matched by ?method (fifo, lifo, hifo or
+// specific_id; default TAX_LOT_METHOD or fifo) and optionally filtered by ?symbol, with
+// their unrealized gains. Lots that disagree with the broker's positions are warned
+// about, as the fill history may be incomplete.
+func GetTaxLots(c *gin.Context) {
+	accountID := c.GetHeader("X-Account-ID")
+	if accountID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account ID header missing"})
+		return
+	}
+	method, err := taxLotMethod(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	symbol := strings.ToUpper(strings.TrimSpace(c.Query("symbol")))
+
+	ctx := c.Request.Context()
+	ledger, err := loadLedger(ctx, accountID, method)
+	if err != nil {
+		respondBrokerError(c, err, "Failed to build tax lots")
+		return
+	}
+	warnings := ledger.Warnings
+
+	// Prices only add unrealized gains, so the lots stand without them
+	positions, positionsErr := getPositionsHelper(ctx, accountID)
+	if positionsErr != nil {
+		log.Printf("tax lots for %s: loading positions: %v", accountID, positionsErr)
+		warnings = append(warnings, "positions unavailable; unrealized gains are not computed")
+	}
+	prices := make(map[string]float64, len(positions))
+	held := make(map[string]float64, len(positions))
+	for _, position := range positions {
+		prices[position.Symbol], _ = strconv.ParseFloat(position.CurrentPrice, 64)
+		qty, _ := strconv.ParseFloat(position.Quantity, 64)
+		if position.Side == "short" && qty > 0 {
+			qty = -qty
+		}
+		held[position.Symbol] = qty
+	}
+
+	today := now().In(marketTZ)
+	lots := []LotReport{}
+	inLots := make(map[string]float64)
+	var costBasis, unrealized float64
+	for _, lot := range ledger.OpenLots() {
+		inLots[lot.Symbol] += lot.Remaining
+		if symbol != "" && lot.Symbol != symbol {
+			continue
+		}
+		report := LotReport{Lot: lot}
+		report.LongTermQty, report.ShortTermQty = lot.HeldTerms(today)
+		report.LongTerm = report.ShortTermQty == 0
+		report.MixedTerm = report.LongTermQty > 0 && report.ShortTermQty > 0
+		if price, ok := prices[lot.Symbol]; ok && price > 0 {
+			value := price * lot.Remaining
+			gain := value - lot.CostBasis
+			report.CurrentPrice, report.MarketValue, report.UnrealizedGain = &price, &value, &gain
+			unrealized += gain
+		}
+		costBasis += lot.CostBasis
+		lots = append(lots, report)
+	}
+
+	if positionsErr == nil {
+		symbols := make(map[string]bool, len(held)+len(inLots))
+		for s := range held {
+			symbols[s] = true
+		}
+		for s := range inLots {
+			symbols[s] = true
+		}
+		var mismatched []string
+		for s := range symbols {
+			if (symbol == "" || s == symbol) && !nearlyEqual(inLots[s], held[s]) {
+				mismatched = append(mismatched, fmt.Sprintf("%s (lots %g, position %g)", s, inLots[s], held[s]))
+			}
+		}
+		sort.Strings(mismatched)
+		if len(mismatched) > 0 {
+			warnings = append(warnings, "lots do not match positions for "+strings.Join(mismatched, ", "))
+		}
+	}
+
+	response := gin.H{
+		"account_id":       accountID,
+		"method":           method,
+		"lots":             lots,
+		"total_cost_basis": costBasis,
+		"unrealized_gain":  unrealized,
+	}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// nearlyEqual compares share quantities, allowing for fractional share rounding
+func nearlyEqual(a, b float64) bool {
+	d := a - b
+	return d < 1e-6 && d > -1e-6
+}
+
+// RealizedSummary totals the dispositions of one holding term. NetGain is the gain
+// recognized this year: the gain plus any loss disallowed by wash sales.
+type RealizedSummary struct {
+	Proceeds       float64 `json:"proceeds"`
+	CostBasis      float64 `json:"cost_basis"`
+	Gain           float64 `json:"gain"`
+	DisallowedLoss float64 `json:"disallowed_loss"`
+	NetGain        float64 `json:"net_gain"`
+	Dispositions   int     `json:"dispositions"`
+}
+
+func (s *RealizedSummary) add(d taxlots.Disposition) {
+	s.Proceeds += d.Proceeds
+	s.CostBasis += d.CostBasis
+	s.Gain += d.Gain
+	s.DisallowedLoss += d.DisallowedLoss
+	s.NetGain += d.Gain + d.DisallowedLoss
+	s.Dispositions++
+}
+
+// GetRealizedGains reports the gains realized by sells in ?year (default this year),
+// matched by ?method, split into short- and long-term, with wash sales flagged
+func GetRealizedGains(c *gin.Context) {
+	accountID := c.GetHeader("X-Account-ID")
+	if accountID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account ID header missing"})
+		return
+	}
+	method, err := taxLotMethod(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	year := marketToday().Year()
+	if value := c.Query("year"); value != "" {
+		y, err := strconv.Atoi(value)
+		if err != nil || y < 1900 || y > year {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "year must be a past or the current year"})
+			return
+		}
+		year = y
+	}
+
+	ledger, err := loadLedger(c.Request.Context(), accountID, method)
+	if err != nil {
+		respondBrokerError(c, err, "Failed to build tax lots")
+		return
+	}
+
+	dispositions := []taxlots.Disposition{}
+	var shortTerm, longTerm RealizedSummary
+	var washSales int
+	for _, d := range ledger.Dispositions {
+		if d.Sold.In(marketTZ).Year() != year {
+			continue
+		}
+		dispositions = append(dispositions, d)
+		if d.LongTerm {
+			longTerm.add(d)
+		} else {
+			shortTerm.add(d)
+		}
+		if d.WashSale {
+			washSales++
+		}
+	}
+
+	response := gin.H{
+		"account_id":   accountID,
+		"year":         year,
+		"method":       method,
+		"short_term":   shortTerm,
+		"long_term":    longTerm,
+		"net_gain":     shortTerm.NetGain + longTerm.NetGain,
+		"wash_sales":   washSales,
+		"dispositions": dispositions,
+	}
+	if len(ledger.Warnings) > 0 {
+		response["warnings"] = ledger.Warnings
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetLotDesignations lists the account's specific-ID designations by sell order ID
+func GetLotDesignations(c *gin.Context) {
+	accountID := c.GetHeader("X-Account-ID")
+	if accountID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account ID header missing"})
+		return
+	}
+	orders, err := designations.Get(accountID)
+	if err != nil {
+		log.Printf("tax lot designations for %s: %v", accountID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load lot designations"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"account_id": accountID, "designations": orders})
+}
+
+// SetLotDesignations records which lots a sell order sells under specific_id, as
+// {"lots": [{"lot_id": "...", "qty": 10}]}. An empty list removes them.
+func SetLotDesignations(c *gin.Context) {
+	accountID := c.GetHeader("X-Account-ID")
+	if accountID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account ID header missing"})
+		return
+	}
+	orderID := c.Param("order_id")
+
+	var body struct {
+		Lots []taxlots.Designation `json:"lots"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	for _, lot := range body.Lots {
+		if lot.LotID == "" || lot.Qty <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "every designation needs a lot_id and a positive qty"})
+			return
+		}
+	}
+
+	if err := designations.Set(accountID, orderID, body.Lots); err != nil {
+		log.Printf("tax lot designations for %s: %v", accountID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save lot designations"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"account_id": accountID, "order_id": orderID, "lots": body.Lots})
+}
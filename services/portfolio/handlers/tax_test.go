@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
+	"github.com/seunghoon34/trading-app/services/portfolio/internal/taxlots"
+)
+
+func fillActivity(id, side, qty, price string, at time.Time) broker.Activity {
+	return broker.Activity{
+		ID: id, ActivityType: broker.ActivityFill, OrderID: "o-" + id, Symbol: "AAPL",
+		Side: side, Qty: qty, Price: price, TransactionTime: &at,
+	}
+}
+
+// taxHoldings bought 10 AAPL at 100 in January 2023 and 10 at 120 in May 2024, then
+// sold 5 at 130, leaving 15 shares now priced at 140
+func taxHoldings(positionQty string) *holdingsBroker {
+	return &holdingsBroker{
+		positions: []broker.Position{{Symbol: "AAPL", Quantity: positionQty, CurrentPrice: "140", Side: "long"}},
+		activities: []broker.Activity{
+			fillActivity("s", "sell", "5", "130", time.Date(2024, 5, 10, 15, 0, 0, 0, time.UTC)),
+			fillActivity("b", "buy", "10", "120", time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC)),
+			fillActivity("a", "buy", "10", "100", time.Date(2023, 1, 3, 15, 0, 0, 0, time.UTC)),
+		},
+	}
+}
+
+func useDesignations(t *testing.T) string {
+	t.Helper()
+	previous := designations
+	path := filepath.Join(t.TempDir(), "designations.json")
+	designations = &designationStore{path: path}
+	t.Cleanup(func() { designations = previous })
+	return path
+}
+
+func TestGetTaxLots(t *testing.T) {
+	useCorporateActions(t, nil)
+	t.Setenv("TAX_LOT_METHOD", "")
+
+	body := serveHoldings(t, taxHoldings("15"), "/v1/tax/lots", GetTaxLots, "/v1/tax/lots")
+
+	lots := body["lots"].([]interface{})
+	if len(lots) != 2 || body["method"] != "fifo" {
+		t.Fatalf("expected two open lots by FIFO, got %v", body)
+	}
+	first := lots[0].(map[string]interface{})
+	if first["id"] != "a" || first["remaining"].(float64) != 5 || first["long_term"] != true || first["unrealized_gain"].(float64) != 200 {
+		t.Errorf("unexpected first lot %v", first)
+	}
+	if first["long_term_qty"].(float64) != 5 || first["short_term_qty"].(float64) != 0 || first["mixed_term"] != false {
+		t.Errorf("expected all of lot a long-term, got %v", first)
+	}
+	second := lots[1].(map[string]interface{})
+	if second["id"] != "b" || second["remaining"].(float64) != 10 || second["long_term"] != false {
+		t.Errorf("unexpected second lot %v", second)
+	}
+	if body["total_cost_basis"].(float64) != 1700 || body["unrealized_gain"].(float64) != 400 {
+		t.Errorf("unexpected totals %v / %v", body["total_cost_basis"], body["unrealized_gain"])
+	}
+	if _, ok := body["warnings"]; ok {
+		t.Errorf("expected no warnings, got %v", body["warnings"])
+	}
+
+	body = serveHoldings(t, taxHoldings("20"), "/v1/tax/lots", GetTaxLots, "/v1/tax/lots?method=LIFO")
+	lots = body["lots"].([]interface{})
+	if first := lots[0].(map[string]interface{}); first["id"] != "a" || first["remaining"].(float64) != 10 {
+		t.Errorf("expected LIFO to leave lot a whole, got %v", first)
+	}
+	warnings := body["warnings"].([]interface{})
+	if len(warnings) != 1 || !strings.Contains(warnings[0].(string), "AAPL (lots 15, position 20)") {
+		t.Errorf("expected a reconciliation warning, got %v", warnings)
+	}
+}
+
+func TestGetRealizedGains(t *testing.T) {
+	for _, tc := range []struct {
+		method    string
+		shortTerm float64
+		longTerm  float64
+	}{
+		{"fifo", 0, 150},
+		{"hifo", 50, 0},
+	} {
+		body := serveHoldings(t, taxHoldings("15"), "/v1/tax/realized", GetRealizedGains, "/v1/tax/realized?year=2024&method="+tc.method)
+		short := body["short_term"].(map[string]interface{})
+		long := body["long_term"].(map[string]interface{})
+		if short["net_gain"].(float64) != tc.shortTerm || long["net_gain"].(float64) != tc.longTerm {
+			t.Errorf("%s: expected %v short and %v long, got %v / %v", tc.method, tc.shortTerm, tc.longTerm, short, long)
+		}
+		if len(body["dispositions"].([]interface{})) != 1 || body["wash_sales"].(float64) != 0 {
+			t.Errorf("%s: unexpected dispositions %v", tc.method, body["dispositions"])
+		}
+	}
+
+	body := serveHoldings(t, taxHoldings("15"), "/v1/tax/realized", GetRealizedGains, "/v1/tax/realized?year=2023")
+	if len(body["dispositions"].([]interface{})) != 0 || body["net_gain"].(float64) != 0 {
+		t.Errorf("expected nothing realized in 2023, got %v", body)
+	}
+}
+
+func TestSpecificIDDesignations(t *testing.T) {
+	path := useDesignations(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PUT("/v1/tax/designations/:order_id", SetLotDesignations)
+	put := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/v1/tax/designations/o-s", strings.NewReader(body))
+		req.Header.Set("X-Account-ID", "acct")
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := put(`{"lots":[{"lot_id":"b","qty":0}]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a zero quantity, got %d", w.Code)
+	}
+	if w := put(`{"lots":[{"lot_id":"b","qty":5}]}`); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	body := serveHoldings(t, taxHoldings("15"), "/v1/tax/realized", GetRealizedGains, "/v1/tax/realized?year=2024&method=specific_id")
+	d := body["dispositions"].([]interface{})[0].(map[string]interface{})
+	if d["lot_id"] != "b" || d["method"] != "specific_id" || math.Abs(d["gain"].(float64)-50) > 1e-9 {
+		t.Errorf("expected the designated lot b to be sold, got %v", d)
+	}
+
+	// Designations survive a restart
+	reloaded, err := (&designationStore{path: path}).Get("acct")
+	if err != nil || len(reloaded["o-s"]) != 1 || reloaded["o-s"][0] != (taxlots.Designation{LotID: "b", Qty: 5}) {
+		t.Errorf("expected the designation on disk, got %v (%v)", reloaded, err)
+	}
+}
+
+func TestTaxValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, target := range []string{"/v1/tax/lots?method=average", "/v1/tax/realized?method=average", "/v1/tax/realized?year=abc", "/v1/tax/realized?year=3000"} {
+		r := gin.New()
+		r.GET("/v1/tax/lots", GetTaxLots)
+		r.GET("/v1/tax/realized", GetRealizedGains)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("X-Account-ID", "acct")
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", target, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Package analytics computes portfolio risk and return statistics from series of
+// periodic returns. Functions return NaN when a statistic is undefined for the
+// input, e.g. a ratio over zero volatility.
+package analytics
+
+import "math"
+
+// TradingDays is the number of daily returns in a year, used to annualize
+const TradingDays = 252
+
+// Returns converts a series of values into per-period returns, net of external cash
+// flows: each return is (v[i] - v[i-1] - flows[i]) / v[i-1]. flows may be nil.
+// Periods starting from a non-positive value have nothing at risk and return 0.
+func Returns(values, flows []float64) []float64 {
+	if len(values) < 2 {
+		return nil
+	}
+	returns := make([]float64, len(values)-1)
+	for i := 1; i < len(values); i++ {
+		if values[i-1] <= 0 {
+			continue
+		}
+		var flow float64
+		if i < len(flows) {
+			flow = flows[i]
+		}
+		returns[i-1] = (values[i] - values[i-1] - flow) / values[i-1]
+	}
+	return returns
+}
+
+// Mean is the arithmetic mean of xs
+func Mean(xs []float64) float64 {
+	if len(xs) == 0 {
+		return math.NaN()
+	}
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+// StdDev is the sample standard deviation of xs
+func StdDev(xs []float64) float64 {
+	if len(xs) < 2 {
+		return math.NaN()
+	}
+	mean := Mean(xs)
+	var sum float64
+	for _, x := range xs {
+		sum += (x - mean) * (x - mean)
+	}
+	return math.Sqrt(sum / float64(len(xs)-1))
+}
+
+// TotalReturn compounds the returns
+func TotalReturn(returns []float64) float64 {
+	growth := 1.0
+	for _, r := range returns {
+		growth *= 1 + r
+	}
+	return growth - 1
+}
+
+// AnnualizedReturn is the compound annual growth rate of daily returns
+func AnnualizedReturn(returns []float64) float64 {
+	if len(returns) == 0 {
+		return math.NaN()
+	}
+	return math.Pow(1+TotalReturn(returns), TradingDays/float64(len(returns))) - 1
+}
+
+// Volatility is the annualized standard deviation of daily returns
+func Volatility(returns []float64) float64 {
+	return StdDev(returns) * math.Sqrt(TradingDays)
+}
+
+// Sharpe is the annualized mean excess return over the annualized volatility.
+// riskFree is an annual rate, e.g. 0.04.
+func Sharpe(returns []float64, riskFree float64) float64 {
+	return ratio(annualExcess(returns, riskFree), Volatility(returns))
+}
+
+// Sortino is like Sharpe but only penalizes returns below the risk-free rate
+func Sortino(returns []float64, riskFree float64) float64 {
+	if len(returns) == 0 {
+		return math.NaN()
+	}
+	daily := riskFree / TradingDays
+	var sum float64
+	for _, r := range returns {
+		if shortfall := r - daily; shortfall < 0 {
+			sum += shortfall * shortfall
+		}
+	}
+	downside := math.Sqrt(sum/float64(len(returns))) * math.Sqrt(TradingDays)
+	return ratio(annualExcess(returns, riskFree), downside)
+}
+
+func annualExcess(returns []float64, riskFree float64) float64 {
+	return (Mean(returns) - riskFree/TradingDays) * TradingDays
+}
+
+// Drawdown is the largest peak-to-trough fall of the growth of returns. Peak, Trough
+// and Recovery index the growth series, where 0 is the start and i is the value after
+// returns[i-1]; Recovery is -1 when the peak was not regained.
+type Drawdown struct {
+	// Depth is the fall as a positive fraction of the peak
+	Depth    float64
+	Peak     int
+	Trough   int
+	Recovery int
+}
+
+// MaxDrawdown finds the largest drawdown over the returns
+func MaxDrawdown(returns []float64) Drawdown {
+	worst := Drawdown{Recovery: -1}
+	growth, peakGrowth, peak := 1.0, 1.0, 0
+	for i, r := range returns {
+		growth *= 1 + r
+		if growth >= peakGrowth {
+			if worst.Depth > 0 && worst.Peak == peak && worst.Recovery < 0 {
+				worst.Recovery = i + 1
+			}
+			peakGrowth, peak = growth, i+1
+			continue
+		}
+		if depth := 1 - growth/peakGrowth; depth > worst.Depth {
+			worst = Drawdown{Depth: depth, Peak: peak, Trough: i + 1, Recovery: -1}
+		}
+	}
+	return worst
+}
+
+// Calmar is the annualized return over the maximum drawdown
+func Calmar(returns []float64) float64 {
+	return ratio(AnnualizedReturn(returns), MaxDrawdown(returns).Depth)
+}
+
+// covariance is the sample covariance of two equally long series
+func covariance(xs, ys []float64) float64 {
+	if len(xs) < 2 || len(xs) != len(ys) {
+		return math.NaN()
+	}
+	mx, my := Mean(xs), Mean(ys)
+	var sum float64
+	for i := range xs {
+		sum += (xs[i] - mx) * (ys[i] - my)
+	}
+	return sum / float64(len(xs)-1)
+}
+
+// Beta is the sensitivity of returns to the benchmark's returns over the same periods
+func Beta(returns, benchmark []float64) float64 {
+	sd := StdDev(benchmark)
+	return ratio(covariance(returns, benchmark), sd*sd)
+}
+
+// Correlation is the Pearson correlation of returns with the benchmark's
+func Correlation(returns, benchmark []float64) float64 {
+	return ratio(covariance(returns, benchmark), StdDev(returns)*StdDev(benchmark))
+}
+
+// Alpha is Jensen's alpha: the annualized return in excess of what the beta to the
+// benchmark explains. riskFree is an annual rate.
+func Alpha(returns, benchmark []float64, riskFree float64) float64 {
+	expected := riskFree + Beta(returns, benchmark)*(AnnualizedReturn(benchmark)-riskFree)
+	return AnnualizedReturn(returns) - expected
+}
+
+// TrackingError is the annualized volatility of the returns in excess of the benchmark's
+func TrackingError(returns, benchmark []float64) float64 {
+	return Volatility(activeReturns(returns, benchmark))
+}
+
+// InformationRatio is the annualized mean return in excess of the benchmark's over the
+// tracking error
+func InformationRatio(returns, benchmark []float64) float64 {
+	active := activeReturns(returns, benchmark)
+	return ratio(Mean(active)*TradingDays, Volatility(active))
+}
+
+// activeReturns are the returns less the benchmark's over the same periods
+func activeReturns(returns, benchmark []float64) []float64 {
+	if len(returns) != len(benchmark) {
+		return nil
+	}
+	active := make([]float64, len(returns))
+	for i := range returns {
+		active[i] = returns[i] - benchmark[i]
+	}
+	return active
+}
+
+// ratio divides, returning NaN rather than an infinity for a zero denominator
+func ratio(numerator, denominator float64) float64 {
+	if denominator == 0 || math.IsNaN(denominator) {
+		return math.NaN()
+	}
+	return numerator / denominator
+}
@@ -0,0 +1,98 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+)
+
+func approx(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("%s: expected %v, got %v", name, want, got)
+	}
+}
+
+func TestReturnsNetOfFlows(t *testing.T) {
+	// A 1000 deposit on day two is not a gain
+	returns := Returns([]float64{0, 1000, 2100, 2079}, []float64{0, 1000, 1000, 0})
+	if len(returns) != 3 {
+		t.Fatalf("expected 3 returns, got %v", returns)
+	}
+	approx(t, "unfunded", returns[0], 0)
+	approx(t, "after deposit", returns[1], 0.1)
+	approx(t, "fall", returns[2], -0.01)
+}
+
+func TestVolatilityAndRatios(t *testing.T) {
+	returns := []float64{0.01, -0.01, 0.02, -0.02}
+	sd := math.Sqrt((0.0001 + 0.0001 + 0.0004 + 0.0004) / 3)
+	approx(t, "volatility", Volatility(returns), sd*math.Sqrt(252))
+
+	// Mean return is 0, so the excess is just the risk-free drag
+	approx(t, "sharpe", Sharpe(returns, 0.0252), -0.0252/(sd*math.Sqrt(252)))
+
+	// Returns above the daily risk-free rate of 0.0001 do not count as downside
+	downside := math.Sqrt((0.0101*0.0101+0.0201*0.0201)/4) * math.Sqrt(252)
+	approx(t, "sortino", Sortino(returns, 0.0252), -0.0252/downside)
+
+	if !math.IsNaN(Sharpe([]float64{0.01, 0.01, 0.01}, 0)) {
+		t.Error("expected Sharpe to be undefined without volatility")
+	}
+}
+
+func TestMaxDrawdown(t *testing.T) {
+	// Growth: 1, 1.1, 0.99, 0.891, 1.0692, 1.12266, 1.0104
+	returns := []float64{0.1, -0.1, -0.1, 0.2, 0.05, -0.1}
+	dd := MaxDrawdown(returns)
+	approx(t, "depth", dd.Depth, 1-0.891/1.1)
+	if dd.Peak != 1 || dd.Trough != 3 || dd.Recovery != 5 {
+		t.Errorf("expected peak 1, trough 3 and recovery at 5, got %+v", dd)
+	}
+
+	dd = MaxDrawdown([]float64{0.1, -0.1, 0.05})
+	if dd.Peak != 1 || dd.Trough != 2 || dd.Recovery != -1 {
+		t.Errorf("expected no recovery, got %+v", dd)
+	}
+
+	if dd := MaxDrawdown([]float64{0.01, 0.02}); dd.Depth != 0 || !math.IsNaN(Calmar([]float64{0.01, 0.02})) {
+		t.Errorf("expected no drawdown and an undefined Calmar, got %+v", dd)
+	}
+}
+
+func TestBetaAndCorrelation(t *testing.T) {
+	benchmark := []float64{0.01, -0.02, 0.015, 0.005}
+	levered := make([]float64, len(benchmark))
+	inverse := make([]float64, len(benchmark))
+	for i, r := range benchmark {
+		levered[i] = 2*r + 0.001
+		inverse[i] = -r
+	}
+	approx(t, "beta", Beta(levered, benchmark), 2)
+	approx(t, "correlation", Correlation(levered, benchmark), 1)
+	approx(t, "inverse correlation", Correlation(inverse, benchmark), -1)
+	if !math.IsNaN(Beta(levered, benchmark[:2])) {
+		t.Error("expected beta of mismatched series to be undefined")
+	}
+}
+
+func TestAlphaAndTrackingError(t *testing.T) {
+	benchmark := []float64{0.01, -0.02, 0.015, 0.005}
+	active := []float64{0.002, 0, 0.001, 0.001}
+	returns := make([]float64, len(benchmark))
+	shifted := make([]float64, len(benchmark))
+	for i, r := range benchmark {
+		returns[i] = r + active[i]
+		shifted[i] = r + 0.001
+	}
+
+	trackingError := StdDev(active) * math.Sqrt(TradingDays)
+	approx(t, "tracking error", TrackingError(returns, benchmark), trackingError)
+	approx(t, "information ratio", InformationRatio(returns, benchmark), Mean(active)*TradingDays/trackingError)
+
+	// Moving one for one with the benchmark, alpha is the difference in annual returns
+	// whatever the risk-free rate
+	approx(t, "alpha", Alpha(shifted, benchmark, 0.04), AnnualizedReturn(shifted)-AnnualizedReturn(benchmark))
+	if !math.IsNaN(TrackingError(returns, benchmark[:2])) {
+		t.Error("expected the tracking error of mismatched series to be undefined")
+	}
+}
@@ -0,0 +1,110 @@
+package analytics
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// CashFlow is money moving between the investor and the portfolio. Amounts are from
+// the investor's side: money paid in is negative, money taken out or still held at
+// the end is positive.
+type CashFlow struct {
+	At     time.Time
+	Amount float64
+}
+
+// ErrNoIRR is returned when the cash flows have no internal rate of return, e.g. when
+// they all have the same sign
+var ErrNoIRR = errors.New("cash flows have no internal rate of return")
+
+const (
+	irrTolerance  = 1e-10
+	irrIterations = 100
+	// minRate keeps 1+rate positive
+	minRate = -0.999999
+)
+
+// XIRR is the annualized money-weighted return of irregularly dated cash flows, with
+// years of 365 days
+func XIRR(flows []CashFlow) (float64, error) {
+	return IRR(flows, 365*24*time.Hour)
+}
+
+// IRR is the money-weighted return per period of irregularly dated cash flows: the
+// rate at which their net present value is zero. With period set to the span of the
+// flows it is the return over the whole span, which stays meaningful for spans too
+// short to annualize.
+func IRR(flows []CashFlow, period time.Duration) (float64, error) {
+	if len(flows) < 2 {
+		return 0, ErrNoIRR
+	}
+	flows = append([]CashFlow(nil), flows...)
+	sort.SliceStable(flows, func(i, j int) bool { return flows[i].At.Before(flows[j].At) })
+
+	if period <= 0 {
+		return 0, ErrNoIRR
+	}
+	var in, out bool
+	periods := make([]float64, len(flows))
+	for i, flow := range flows {
+		periods[i] = float64(flow.At.Sub(flows[0].At)) / float64(period)
+		in = in || flow.Amount < 0
+		out = out || flow.Amount > 0
+	}
+	if !in || !out {
+		return 0, ErrNoIRR
+	}
+
+	npv := func(rate float64) (value, derivative float64) {
+		for i, flow := range flows {
+			discount := math.Pow(1+rate, -periods[i])
+			value += flow.Amount * discount
+			derivative -= periods[i] * flow.Amount * discount / (1 + rate)
+		}
+		return value, derivative
+	}
+
+	// Newton's method converges quickly from a sensible guess
+	rate := 0.1
+	for i := 0; i < irrIterations; i++ {
+		value, derivative := npv(rate)
+		if math.Abs(value) < irrTolerance {
+			return rate, nil
+		}
+		if derivative == 0 {
+			break
+		}
+		next := rate - value/derivative
+		if next <= minRate || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		if math.Abs(next-rate) < irrTolerance {
+			return next, nil
+		}
+		rate = next
+	}
+
+	// Otherwise bisect over a bracket where the present value changes sign
+	low, high := minRate, 1.0
+	lowValue, _ := npv(low)
+	highValue, _ := npv(high)
+	for highValue*lowValue > 0 && high < 1e6 {
+		high *= 10
+		highValue, _ = npv(high)
+	}
+	if highValue*lowValue > 0 {
+		return 0, ErrNoIRR
+	}
+	for i := 0; i < 200 && high-low > irrTolerance; i++ {
+		mid := (low + high) / 2
+		midValue, _ := npv(mid)
+		if midValue*lowValue > 0 {
+			low, lowValue = mid, midValue
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2, nil
+}
@@ -0,0 +1,57 @@
+package analytics
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestXIRR(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 1000 growing to 1100 over exactly a year is 10%
+	rate, err := XIRR([]CashFlow{{start, -1000}, {start.AddDate(0, 0, 365), 1100}})
+	if err != nil || math.Abs(rate-0.1) > 1e-8 {
+		t.Errorf("expected 10%%, got %v (%v)", rate, err)
+	}
+
+	// A deposit halfway through earns only half a year, so the rate exceeds the simple gain
+	flows := []CashFlow{
+		{start, -1000},
+		{start.AddDate(0, 0, 182), -1000},
+		{start.AddDate(0, 0, 365), 2150},
+	}
+	rate, err = XIRR(flows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var npv float64
+	for _, flow := range flows {
+		npv += flow.Amount / math.Pow(1+rate, flow.At.Sub(start).Hours()/24/365)
+	}
+	if math.Abs(npv) > 1e-6 || rate < 0.075 || rate > 0.115 {
+		t.Errorf("expected a rate near 10%% with zero NPV, got %v (npv %v)", rate, npv)
+	}
+
+	// Heavy losses still solve: halving in 200 days
+	rate, err = XIRR([]CashFlow{{start, -1000}, {start.AddDate(0, 0, 200), 500}})
+	if want := math.Pow(0.5, 365.0/200) - 1; err != nil || math.Abs(rate-want) > 1e-8 {
+		t.Errorf("expected %v, got %v (%v)", want, rate, err)
+	}
+
+	if _, err := XIRR([]CashFlow{{start, -1000}, {start.AddDate(1, 0, 0), -5}}); !errors.Is(err, ErrNoIRR) {
+		t.Errorf("expected ErrNoIRR for flows of one sign, got %v", err)
+	}
+}
+
+func TestIRROverSpan(t *testing.T) {
+	start := time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC)
+	end := start.Add(6 * time.Hour)
+
+	// Over a few hours the annualized rate is meaningless, but the return over the span is not
+	rate, err := IRR([]CashFlow{{start, -1000}, {end, 1010}}, end.Sub(start))
+	if err != nil || math.Abs(rate-0.01) > 1e-9 {
+		t.Errorf("expected 1%% over the span, got %v (%v)", rate, err)
+	}
+}
@@ -0,0 +1,158 @@
+package marketdata
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Corporate action types, as market-data names them
+const (
+	ActionSplit         = "split"
+	ActionReverseSplit  = "reverse_split"
+	ActionCashDividend  = "cash_dividend"
+	ActionStockDividend = "stock_dividend"
+	ActionSymbolChange  = "symbol_change"
+)
+
+// maxSymbols is the most symbols market-data accepts per request
+const maxSymbols = 100
+
+// CorporateAction is a split, dividend or symbol change. Dates are YYYY-MM-DD.
+// Rate is the cash paid per share for cash dividends and the new shares per share
+// for stock dividends; splits turn OldRate shares into NewRate shares.
+type CorporateAction struct {
+	Type        string  `json:"type"`
+	Symbol      string  `json:"symbol"`
+	NewSymbol   string  `json:"new_symbol,omitempty"`
+	ExDate      string  `json:"ex_date,omitempty"`
+	RecordDate  string  `json:"record_date,omitempty"`
+	PayableDate string  `json:"payable_date,omitempty"`
+	ProcessDate string  `json:"process_date"`
+	Rate        float64 `json:"rate,omitempty"`
+	OldRate     float64 `json:"old_rate,omitempty"`
+	NewRate     float64 `json:"new_rate,omitempty"`
+	Special     bool    `json:"special,omitempty"`
+}
+
+var httpClient = &http.Client{Timeout: 5 * time.Second}
+
+func baseURL() string {
+	if u := os.Getenv("MARKET_DATA_SERVICE_URL"); u != "" {
+		return u
+	}
+	return "http://market-data:8082" // Default for local development
+}
+
+// get fetches a market-data endpoint and decodes the JSON body into out
+func get(path string, out interface{}) error {
+	res, err := httpClient.Get(baseURL() + path)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("market-data returned status %d: %s", res.StatusCode, string(body))
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+// CorporateActions returns the actions of the given types for symbols between start
+// and end inclusive, in date order per request
+func CorporateActions(symbols, types []string, start, end time.Time) ([]CorporateAction, error) {
+	var actions []CorporateAction
+	for len(symbols) > 0 {
+		batch := symbols
+		if len(batch) > maxSymbols {
+			batch = batch[:maxSymbols]
+		}
+		symbols = symbols[len(batch):]
+
+		query := url.Values{
+			"symbols": {strings.Join(batch, ",")},
+			"start":   {start.Format("2006-01-02")},
+			"end":     {end.Format("2006-01-02")},
+		}
+		if len(types) > 0 {
+			query.Set("types", strings.Join(types, ","))
+		}
+		var res struct {
+			CorporateActions []CorporateAction `json:"corporate_actions"`
+		}
+		if err := get("/corporate-actions?"+query.Encode(), &res); err != nil {
+			return nil, err
+		}
+		actions = append(actions, res.CorporateActions...)
+	}
+	return actions, nil
+}
+
+// Bar is a bar's start time and close
+type Bar struct {
+	Timestamp time.Time `json:"timestamp"`
+	Close     float64   `json:"close"`
+}
+
+// Bars returns symbol's bars of timeframe (e.g. 1Hour or 1Day) starting between start
+// and end, adjusted for splits and dividends so closes chain into total returns
+func Bars(symbol, timeframe string, start, end time.Time) ([]Bar, error) {
+	query := url.Values{
+		"timeframe":  {timeframe},
+		"start":      {start.UTC().Format(time.RFC3339)},
+		"end":        {end.UTC().Format(time.RFC3339)},
+		"adjustment": {"all"},
+		"limit":      {"10000"},
+	}
+	var res struct {
+		Bars map[string][]Bar `json:"bars"`
+	}
+	if err := get("/bars/"+url.PathEscape(symbol)+"/history?"+query.Encode(), &res); err != nil {
+		return nil, err
+	}
+	return res.Bars[symbol], nil
+}
+
+// Asset is an instrument's classification from market-data's asset master. Sector,
+// Industry and AssetClass are empty when market-data has no classification.
+type Asset struct {
+	Symbol     string `json:"symbol"`
+	Name       string `json:"name"`
+	Class      string `json:"class"`
+	Sector     string `json:"sector"`
+	Industry   string `json:"industry"`
+	AssetClass string `json:"asset_class"`
+}
+
+// Assets returns the asset master entries of symbols, leaving out those market-data
+// does not know
+func Assets(symbols []string) ([]Asset, error) {
+	var assets []Asset
+	for len(symbols) > 0 {
+		batch := symbols
+		if len(batch) > maxSymbols {
+			batch = batch[:maxSymbols]
+		}
+		symbols = symbols[len(batch):]
+
+		var res struct {
+			Assets []Asset `json:"assets"`
+		}
+		if err := get("/assets?"+url.Values{"symbols": {strings.Join(batch, ",")}}.Encode(), &res); err != nil {
+			return nil, err
+		}
+		assets = append(assets, res.Assets...)
+	}
+	return assets, nil
+}
@@ -0,0 +1,410 @@
+// Package taxlots keeps a ledger of the lots bought in an account and matches sells
+// against them, reporting realized gains with their holding periods and wash sales.
+package taxlots
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Method chooses which open lots a sell closes
+type Method string
+
+const (
+	FIFO Method = "fifo"
+	LIFO Method = "lifo"
+	// HIFO sells the lots with the highest cost per share first, minimizing gains
+	HIFO Method = "hifo"
+	// SpecificID sells the lots designated for the order, falling back to FIFO for
+	// anything not designated
+	SpecificID Method = "specific_id"
+)
+
+// ParseMethod reads a matching method name, ignoring case
+func ParseMethod(value string) (Method, error) {
+	switch m := Method(strings.ToLower(strings.TrimSpace(value))); m {
+	case FIFO, LIFO, HIFO, SpecificID:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown lot matching method %q", value)
+}
+
+const (
+	// washSaleWindow is how far either side of a loss sale a purchase makes it a wash sale
+	washSaleWindow = 30 * 24 * time.Hour
+	// epsilon absorbs rounding in fractional share quantities
+	epsilon = 1e-9
+)
+
+// Fill is one execution. Qty is positive for both buys and sells.
+type Fill struct {
+	ID      string
+	OrderID string
+	Symbol  string
+	Side    string // buy or sell
+	Qty     float64
+	Price   float64
+	Time    time.Time
+}
+
+// Designation picks Qty shares of a lot for a sell order under SpecificID
+type Designation struct {
+	LotID string  `json:"lot_id"`
+	Qty   float64 `json:"qty"`
+}
+
+// tranche is a run of a lot's shares sharing a basis and holding period; a wash sale
+// splits the replacement shares off into their own tranche
+type tranche struct {
+	qty          float64
+	cost         float64 // per share, including disallowed losses
+	holdingStart time.Time
+	replacement  bool
+}
+
+// Lot is the shares bought in one fill. CostBasis, WashAdjustment and
+// HoldingPeriodStart describe the shares still held; HoldingPeriodStart is the earliest
+// when wash sale replacements give part of the lot an earlier one.
+type Lot struct {
+	ID                 string    `json:"id"`
+	OrderID            string    `json:"order_id"`
+	Symbol             string    `json:"symbol"`
+	Acquired           time.Time `json:"acquired"`
+	Qty                float64   `json:"qty"`
+	Price              float64   `json:"price"`
+	Remaining          float64   `json:"remaining"`
+	CostBasis          float64   `json:"cost_basis"`
+	WashAdjustment     float64   `json:"wash_adjustment"`
+	HoldingPeriodStart time.Time `json:"holding_period_start"`
+
+	seq      int
+	tranches []tranche
+}
+
+// summarize refreshes the exported figures from the tranches
+func (l *Lot) summarize() {
+	l.Remaining, l.CostBasis, l.WashAdjustment = 0, 0, 0
+	l.HoldingPeriodStart = l.Acquired
+	for _, t := range l.tranches {
+		l.Remaining += t.qty
+		l.CostBasis += t.qty * t.cost
+		l.WashAdjustment += t.qty * (t.cost - l.Price)
+		if t.holdingStart.Before(l.HoldingPeriodStart) {
+			l.HoldingPeriodStart = t.holdingStart
+		}
+	}
+	if l.Remaining < epsilon {
+		l.Remaining, l.CostBasis, l.WashAdjustment = 0, 0, 0
+	}
+}
+
+// costPerShare is the average basis of the shares still held
+func (l *Lot) costPerShare() float64 {
+	if l.Remaining <= 0 {
+		return l.Price
+	}
+	return l.CostBasis / l.Remaining
+}
+
+// marketTZ is the exchange's time zone, which dates the trades
+var marketTZ, _ = time.LoadLocation("America/New_York")
+
+// marketDate is the trading date of t, at midnight UTC so dates compare exactly
+func marketDate(t time.Time) time.Time {
+	y, m, d := t.In(marketTZ).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// LongTerm reports whether shares held since start and sold at sold count as long-term,
+// that is held for more than a year. The holding period runs in calendar days on the
+// trade dates, so a sale on the anniversary is short-term whatever the time of day and
+// long-term begins the day after.
+func LongTerm(start, sold time.Time) bool {
+	return marketDate(sold).After(marketDate(start).AddDate(1, 0, 0))
+}
+
+// HeldTerms splits the shares still held into those that would be long-term and
+// short-term if sold at sold. Wash sale replacement shares carry the holding period of
+// the shares they replace, so one lot can hold both.
+func (l *Lot) HeldTerms(sold time.Time) (longTerm, shortTerm float64) {
+	for _, t := range l.tranches {
+		if t.qty <= epsilon {
+			continue
+		}
+		if LongTerm(t.holdingStart, sold) {
+			longTerm += t.qty
+		} else {
+			shortTerm += t.qty
+		}
+	}
+	return longTerm, shortTerm
+}
+
+// Disposition is the sale of shares from one lot. Gain is proceeds less basis; a wash
+// sale's DisallowedLoss is not deductible now and was added to the basis of the
+// replacement lots instead.
+type Disposition struct {
+	SellID             string    `json:"sell_id"`
+	OrderID            string    `json:"order_id"`
+	Symbol             string    `json:"symbol"`
+	LotID              string    `json:"lot_id"`
+	Method             Method    `json:"method"`
+	Qty                float64   `json:"qty"`
+	Acquired           time.Time `json:"acquired"`
+	HoldingPeriodStart time.Time `json:"holding_period_start"`
+	Sold               time.Time `json:"sold"`
+	Proceeds           float64   `json:"proceeds"`
+	CostBasis          float64   `json:"cost_basis"`
+	Gain               float64   `json:"gain"`
+	LongTerm           bool      `json:"long_term"`
+	WashSale           bool      `json:"wash_sale"`
+	DisallowedLoss     float64   `json:"disallowed_loss"`
+	ReplacementLots    []string  `json:"replacement_lots,omitempty"`
+}
+
+// Ledger is the result of replaying an account's fills
+type Ledger struct {
+	// Lots are every lot bought, in order of acquisition
+	Lots         []*Lot
+	Dispositions []Disposition
+	// Warnings note fills the ledger could not account for, such as sells of shares it
+	// has no lots for, or designations it could not honor
+	Warnings []string
+}
+
+// OpenLots returns the lots with shares still held
+func (l *Ledger) OpenLots() []*Lot {
+	var open []*Lot
+	for _, lot := range l.Lots {
+		if lot.Remaining > 0 {
+			open = append(open, lot)
+		}
+	}
+	return open
+}
+
+// Build replays fills in time order, opening a lot for every buy and matching every
+// sell against the lots open at the time by method. designations are keyed by sell
+// order ID and only used with SpecificID.
+func Build(fills []Fill, method Method, designations map[string][]Designation) *Ledger {
+	sorted := append([]Fill(nil), fills...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	// Every lot exists up front so a loss sale can find the replacement shares bought
+	// in the 30 days after it
+	ledger := &Ledger{}
+	bySymbol := make(map[string][]*Lot)
+	lotsByID := make(map[string]*Lot)
+	for seq, fill := range sorted {
+		if fill.Side != "buy" || fill.Qty <= 0 {
+			continue
+		}
+		lot := &Lot{
+			ID:       fill.ID,
+			OrderID:  fill.OrderID,
+			Symbol:   fill.Symbol,
+			Acquired: fill.Time,
+			Qty:      fill.Qty,
+			Price:    fill.Price,
+			seq:      seq,
+			tranches: []tranche{{qty: fill.Qty, cost: fill.Price, holdingStart: fill.Time}},
+		}
+		lot.summarize()
+		ledger.Lots = append(ledger.Lots, lot)
+		bySymbol[fill.Symbol] = append(bySymbol[fill.Symbol], lot)
+		lotsByID[lot.ID] = lot
+	}
+
+	// Designated shares already sold, so an order split into several fills uses each
+	// designation once
+	used := make(map[string]float64)
+	for seq, fill := range sorted {
+		if fill.Side != "sell" || fill.Qty <= 0 {
+			continue
+		}
+		var open []*Lot
+		for _, lot := range bySymbol[fill.Symbol] {
+			if lot.seq < seq && lot.Remaining > 0 {
+				open = append(open, lot)
+			}
+		}
+
+		start := len(ledger.Dispositions)
+		need := fill.Qty
+		if method == SpecificID {
+			for _, d := range designations[fill.OrderID] {
+				key := fill.OrderID + "/" + d.LotID
+				lot := lotsByID[d.LotID]
+				if lot == nil || lot.Symbol != fill.Symbol || lot.seq > seq {
+					ledger.Warnings = append(ledger.Warnings, fmt.Sprintf("order %s designates lot %s, which is not an earlier %s lot", fill.OrderID, d.LotID, fill.Symbol))
+					continue
+				}
+				qty := math.Min(math.Min(d.Qty-used[key], need), lot.Remaining)
+				if qty <= epsilon {
+					continue
+				}
+				used[key] += qty
+				need -= qty
+				ledger.sell(fill, lot, qty, SpecificID)
+			}
+		}
+		if need > epsilon {
+			fallback := method
+			if method == SpecificID {
+				if len(designations[fill.OrderID]) == 0 {
+					ledger.Warnings = append(ledger.Warnings, fmt.Sprintf("order %s has no lot designation; sold by FIFO", fill.OrderID))
+				}
+				fallback = FIFO
+			}
+			order(open, fallback)
+			for _, lot := range open {
+				if need <= epsilon {
+					break
+				}
+				qty := math.Min(need, lot.Remaining)
+				if qty <= epsilon {
+					continue
+				}
+				need -= qty
+				ledger.sell(fill, lot, qty, fallback)
+			}
+		}
+		if need > epsilon {
+			ledger.Warnings = append(ledger.Warnings, fmt.Sprintf("sell %s of %s exceeds the open lots by %g shares", fill.ID, fill.Symbol, need))
+		}
+
+		ledger.washSales(fill, start, bySymbol[fill.Symbol])
+	}
+	return ledger
+}
+
+// order sorts open lots into the order method sells them in
+func order(lots []*Lot, method Method) {
+	sort.SliceStable(lots, func(i, j int) bool {
+		a, b := lots[i], lots[j]
+		switch method {
+		case LIFO:
+			return a.seq > b.seq
+		case HIFO:
+			if ca, cb := a.costPerShare(), b.costPerShare(); ca != cb {
+				return ca > cb
+			}
+		}
+		return a.seq < b.seq
+	})
+}
+
+// sell takes qty shares of lot for the sell fill, wash-sale replacement shares first,
+// recording a disposition per tranche
+func (l *Ledger) sell(fill Fill, lot *Lot, qty float64, method Method) {
+	sort.SliceStable(lot.tranches, func(i, j int) bool {
+		return lot.tranches[i].replacement && !lot.tranches[j].replacement
+	})
+	for i := range lot.tranches {
+		t := &lot.tranches[i]
+		take := math.Min(qty, t.qty)
+		if take <= epsilon {
+			continue
+		}
+		t.qty -= take
+		qty -= take
+		d := Disposition{
+			SellID:             fill.ID,
+			OrderID:            fill.OrderID,
+			Symbol:             fill.Symbol,
+			LotID:              lot.ID,
+			Method:             method,
+			Qty:                take,
+			Acquired:           lot.Acquired,
+			HoldingPeriodStart: t.holdingStart,
+			Sold:               fill.Time,
+			Proceeds:           take * fill.Price,
+			CostBasis:          take * t.cost,
+			LongTerm:           LongTerm(t.holdingStart, fill.Time),
+		}
+		d.Gain = d.Proceeds - d.CostBasis
+		l.Dispositions = append(l.Dispositions, d)
+		if qty <= epsilon {
+			break
+		}
+	}
+	kept := lot.tranches[:0]
+	for _, t := range lot.tranches {
+		if t.qty > epsilon {
+			kept = append(kept, t)
+		}
+	}
+	lot.tranches = kept
+	lot.summarize()
+}
+
+// washSales checks the dispositions from dispositions[start:] of the sell fill for
+// wash sales: a loss is disallowed to the extent the same symbol was bought within 30
+// days before or after the sale. Each replacement share covers one sold share; its
+// lot's basis grows by the disallowed loss and its holding period by the time the
+// sold shares were held. The sold shares have already left their lots, so only the
+// shares still held can replace, including the rest of a lot the sale drew from.
+func (l *Ledger) washSales(fill Fill, start int, lots []*Lot) {
+	for i := start; i < len(l.Dispositions); i++ {
+		d := &l.Dispositions[i]
+		if d.Gain >= 0 {
+			continue
+		}
+		lossPerShare := -d.Gain / d.Qty
+		need := d.Qty
+		for _, lot := range lots {
+			if need <= epsilon {
+				break
+			}
+			if lot.Acquired.Before(fill.Time.Add(-washSaleWindow)) || lot.Acquired.After(fill.Time.Add(washSaleWindow)) {
+				continue
+			}
+			washed := lot.replace(need, lossPerShare, fill.Time.Sub(d.HoldingPeriodStart))
+			if washed <= 0 {
+				continue
+			}
+			need -= washed
+			d.WashSale = true
+			d.DisallowedLoss += washed * lossPerShare
+			d.ReplacementLots = append(d.ReplacementLots, lot.ID)
+		}
+	}
+}
+
+// replace marks up to qty of the lot's shares that are not yet replacements as
+// replacing shares sold at a loss, adding the loss per share to their basis and held
+// to their holding period. It returns how many shares it marked.
+func (l *Lot) replace(qty, lossPerShare float64, held time.Duration) float64 {
+	var marked float64
+	var split []tranche
+	for i := range l.tranches {
+		t := &l.tranches[i]
+		if t.replacement || t.qty <= epsilon || qty-marked <= epsilon {
+			continue
+		}
+		take := math.Min(qty-marked, t.qty)
+		t.qty -= take
+		marked += take
+		split = append(split, tranche{
+			qty:          take,
+			cost:         t.cost + lossPerShare,
+			holdingStart: t.holdingStart.Add(-held),
+			replacement:  true,
+		})
+	}
+	if marked == 0 {
+		return 0
+	}
+	kept := split
+	for _, t := range l.tranches {
+		if t.qty > epsilon {
+			kept = append(kept, t)
+		}
+	}
+	l.tranches = kept
+	l.summarize()
+	return marked
+}
@@ -0,0 +1,190 @@
+package taxlots
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 15, 0, 0, 0, time.UTC)
+}
+
+func buy(id string, at time.Time, qty, price float64) Fill {
+	return Fill{ID: id, OrderID: "o-" + id, Symbol: "AAPL", Side: "buy", Qty: qty, Price: price, Time: at}
+}
+
+func sell(id string, at time.Time, qty, price float64) Fill {
+	return Fill{ID: id, OrderID: "o-" + id, Symbol: "AAPL", Side: "sell", Qty: qty, Price: price, Time: at}
+}
+
+func approx(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("%s: expected %v, got %v", name, want, got)
+	}
+}
+
+// threeLots are bought at 100, 120 and 110, the first more than a year before the sale
+var threeLots = []Fill{
+	sell("s1", date(2024, 3, 5), 15, 130),
+	buy("l1", date(2023, 1, 2), 10, 100),
+	buy("l2", date(2023, 6, 1), 10, 120),
+	buy("l3", date(2024, 3, 1), 10, 110),
+}
+
+func TestMatchingMethods(t *testing.T) {
+	for _, tc := range []struct {
+		method Method
+		lots   []string
+		qtys   []float64
+		gains  []float64
+		long   []bool
+	}{
+		{FIFO, []string{"l1", "l2"}, []float64{10, 5}, []float64{300, 50}, []bool{true, false}},
+		{LIFO, []string{"l3", "l2"}, []float64{10, 5}, []float64{200, 50}, []bool{false, false}},
+		{HIFO, []string{"l2", "l3"}, []float64{10, 5}, []float64{100, 100}, []bool{false, false}},
+	} {
+		ledger := Build(threeLots, tc.method, nil)
+		if len(ledger.Dispositions) != len(tc.lots) || len(ledger.Warnings) != 0 {
+			t.Fatalf("%s: unexpected dispositions %+v, warnings %v", tc.method, ledger.Dispositions, ledger.Warnings)
+		}
+		for i, d := range ledger.Dispositions {
+			if d.LotID != tc.lots[i] || d.Qty != tc.qtys[i] || d.LongTerm != tc.long[i] || d.Method != tc.method || d.WashSale {
+				t.Errorf("%s %d: unexpected disposition %+v", tc.method, i, d)
+			}
+			approx(t, string(tc.method)+" gain", d.Gain, tc.gains[i])
+		}
+		var remaining float64
+		for _, lot := range ledger.OpenLots() {
+			remaining += lot.Remaining
+		}
+		approx(t, string(tc.method)+" remaining", remaining, 15)
+	}
+}
+
+func TestSpecificID(t *testing.T) {
+	ledger := Build(threeLots, SpecificID, map[string][]Designation{
+		"o-s1": {{LotID: "l2", Qty: 5}, {LotID: "missing", Qty: 5}},
+	})
+	if len(ledger.Dispositions) != 2 {
+		t.Fatalf("unexpected dispositions %+v", ledger.Dispositions)
+	}
+	// The designated 5 shares of l2, then the rest by FIFO
+	if d := ledger.Dispositions[0]; d.LotID != "l2" || d.Qty != 5 || d.Method != SpecificID {
+		t.Errorf("expected the designated lot first, got %+v", d)
+	}
+	if d := ledger.Dispositions[1]; d.LotID != "l1" || d.Qty != 10 || d.Method != FIFO {
+		t.Errorf("expected the rest from the oldest lot, got %+v", d)
+	}
+	if len(ledger.Warnings) != 1 {
+		t.Errorf("expected a warning about the unknown lot, got %v", ledger.Warnings)
+	}
+
+	ledger = Build(append(threeLots, sell("s2", date(2024, 3, 6), 20, 130)), SpecificID, nil)
+	if len(ledger.Warnings) != 3 {
+		t.Errorf("expected two undesignated orders and an oversell, got %v", ledger.Warnings)
+	}
+}
+
+func TestWashSale(t *testing.T) {
+	ledger := Build([]Fill{
+		buy("a", date(2024, 1, 2), 10, 100),
+		sell("s1", date(2024, 3, 1), 10, 80),
+		buy("b", date(2024, 3, 15), 6, 85),
+		// Too late to replace the March loss
+		buy("c", date(2024, 5, 1), 10, 90),
+		sell("s2", date(2024, 4, 1), 6, 110),
+	}, FIFO, nil)
+
+	loss := ledger.Dispositions[0]
+	approx(t, "loss", loss.Gain, -200)
+	approx(t, "disallowed loss", loss.DisallowedLoss, 120)
+	if !loss.WashSale || len(loss.ReplacementLots) != 1 || loss.ReplacementLots[0] != "b" {
+		t.Errorf("expected lot b to replace 6 of the shares, got %+v", loss)
+	}
+
+	// b carries the disallowed 20 a share and the 59 days a was held
+	later := ledger.Dispositions[1]
+	if later.LotID != "b" || !later.HoldingPeriodStart.Equal(date(2024, 3, 15).AddDate(0, 0, -59)) {
+		t.Errorf("unexpected replacement sale %+v", later)
+	}
+	approx(t, "replacement basis", later.CostBasis, 6*105)
+	approx(t, "replacement gain", later.Gain, 30)
+
+	open := ledger.OpenLots()
+	if len(open) != 1 || open[0].ID != "c" || open[0].WashAdjustment != 0 {
+		t.Errorf("expected lot c open and unadjusted, got %+v", open)
+	}
+}
+
+func TestWashSaleFromPartOfARecentLot(t *testing.T) {
+	// The six shares of a still held were bought within 30 days before the loss sale
+	ledger := Build([]Fill{
+		buy("a", date(2024, 3, 1), 10, 100),
+		sell("s1", date(2024, 3, 15), 4, 80),
+	}, FIFO, nil)
+
+	if len(ledger.Dispositions) != 1 {
+		t.Fatalf("expected one disposition, got %+v", ledger.Dispositions)
+	}
+	d := ledger.Dispositions[0]
+	if !d.WashSale || len(d.ReplacementLots) != 1 || d.ReplacementLots[0] != "a" {
+		t.Errorf("expected the rest of lot a to replace the sold shares, got %+v", d)
+	}
+	approx(t, "disallowed loss", d.DisallowedLoss, 80)
+
+	open := ledger.OpenLots()
+	if len(open) != 1 {
+		t.Fatalf("expected lot a open, got %+v", open)
+	}
+	approx(t, "remaining", open[0].Remaining, 6)
+	approx(t, "cost basis", open[0].CostBasis, 680)
+}
+
+func TestHeldTermsSplitsWashSaleReplacements(t *testing.T) {
+	ledger := Build([]Fill{
+		buy("a", date(2023, 1, 2), 10, 100),
+		sell("s1", date(2023, 3, 1), 5, 80),
+		buy("b", date(2023, 3, 15), 10, 85),
+	}, FIFO, nil)
+
+	// Five of b's shares replace the loss and carry the 58 days a was held
+	var b *Lot
+	for _, lot := range ledger.OpenLots() {
+		if lot.ID == "b" {
+			b = lot
+		}
+	}
+	if b == nil {
+		t.Fatalf("expected lot b open, got %+v", ledger.OpenLots())
+	}
+	long, short := b.HeldTerms(date(2024, 2, 1))
+	if long != 5 || short != 5 {
+		t.Errorf("expected 5 long-term and 5 short-term shares, got %v and %v", long, short)
+	}
+	if long, short := b.HeldTerms(date(2024, 3, 16)); long != 10 || short != 0 {
+		t.Errorf("expected the whole lot long-term a year on, got %v and %v", long, short)
+	}
+}
+
+func TestLongTerm(t *testing.T) {
+	start := date(2023, 3, 1)
+	if LongTerm(start, date(2024, 3, 1)) || !LongTerm(start, date(2024, 3, 2)) {
+		t.Error("expected long-term to begin the day after the anniversary")
+	}
+
+	// Bought at 10:00 and sold at 15:00 on the anniversary is still short-term
+	bought := time.Date(2023, 3, 1, 10, 0, 0, 0, marketTZ)
+	if LongTerm(bought, time.Date(2024, 3, 1, 15, 0, 0, 0, marketTZ)) {
+		t.Error("expected a sale later on the anniversary to be short-term")
+	}
+	// Dates are the exchange's: 21:00 New York on the anniversary is already the next
+	// day in UTC
+	if LongTerm(bought, time.Date(2024, 3, 1, 21, 0, 0, 0, marketTZ).UTC()) {
+		t.Error("expected the holding period to count New York dates")
+	}
+	if !LongTerm(bought, time.Date(2024, 3, 2, 9, 30, 0, 0, marketTZ)) {
+		t.Error("expected a sale the next morning to be long-term")
+	}
+}
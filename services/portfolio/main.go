@@ -17,13 +17,32 @@ func main() {
 			"message": "Portfolio health endpoint",
 		})
 	})
-	r.GET("/positions", handlers.GetPositions)
-	r.GET("/value", handlers.GetPortfolioWorth)
 
-	r.GET("/performance", handlers.GetPortfolioPerformance)
-	r.GET("/performance/all", handlers.GetMultiTimeFramePerformance)
-
-	r.GET("/:symbol", handlers.GetPosition)
+	v1 := r.Group("/v1")
+	{
+		v1.GET("/positions", handlers.GetPositions)
+		v1.GET("/positions/:symbol", handlers.GetPosition)
+		v1.DELETE("/positions", handlers.CloseAllPositions)
+		v1.DELETE("/positions/:symbol", handlers.ClosePosition)
+		v1.GET("/value", handlers.GetPortfolioWorth)
+		v1.GET("/performance", handlers.GetPortfolioPerformance)
+		v1.GET("/performance/all", handlers.GetMultiTimeFramePerformance)
+		v1.GET("/performance/returns", handlers.GetReturns)
+		v1.GET("/dividends", handlers.GetDividends)
+		v1.GET("/analytics/risk", handlers.GetRiskAnalytics)
+		v1.GET("/exposure", handlers.GetExposure)
+		v1.GET("/tax/lots", handlers.GetTaxLots)
+		v1.GET("/tax/realized", handlers.GetRealizedGains)
+		v1.GET("/tax/designations", handlers.GetLotDesignations)
+		v1.PUT("/tax/designations/:order_id", handlers.SetLotDesignations)
+	}
+
+	// Deprecated unversioned aliases, kept until clients move to /v1
+	r.GET("/positions", handlers.Deprecated("/v1/positions"), handlers.GetPositions)
+	r.GET("/value", handlers.Deprecated("/v1/value"), handlers.GetPortfolioWorth)
+	r.GET("/performance", handlers.Deprecated("/v1/performance"), handlers.GetPortfolioPerformance)
+	r.GET("/performance/all", handlers.Deprecated("/v1/performance/all"), handlers.GetMultiTimeFramePerformance)
+	r.GET("/:symbol", handlers.Deprecated("/v1/positions/:symbol"), handlers.GetPosition)
 
 	r.Run(":8084") // listen and serve on 0.0.0.0:8080 (for windows "localhost:8080")
 }
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
+)
+
+// brokerClient is the brokerage used by all handlers in this package
+var brokerClient broker.Broker = broker.NewFromEnv()
+
+// SetBroker replaces the brokerage client, e.g. with a simulator or a test double
+func SetBroker(b broker.Broker) {
+	brokerClient = b
+}
+
+// respondBrokerError passes broker rejections through with their status code and
+// reports transport failures as a 500 with the given message
+func respondBrokerError(c *gin.Context, err error, message string) {
+	if apiErr, ok := broker.AsAPIError(err); ok {
+		c.JSON(apiErr.StatusCode, gin.H{"error": apiErr.Message, "code": apiErr.Code})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
+
+// toBrokerOrder converts a validated order into the broker request
+func toBrokerOrder(o OrderRequest) broker.OrderRequest {
+	req := broker.OrderRequest{
+		Symbol:        o.Symbol,
+		Side:          o.Side,
+		Type:          o.Type,
+		TimeInForce:   o.TimeInForce,
+		Qty:           o.Qty,
+		Notional:      o.Notional,
+		LimitPrice:    o.LimitPrice,
+		StopPrice:     o.StopPrice,
+		TrailPrice:    o.TrailPrice,
+		TrailPercent:  o.TrailPercent,
+		ExtendedHours: o.ExtendedHours,
+		ClientOrderID: o.ClientOrderID,
+		OrderClass:    o.OrderClass,
+	}
+	if o.TakeProfit != nil {
+		req.TakeProfit = &broker.TakeProfit{LimitPrice: o.TakeProfit.LimitPrice}
+	}
+	if o.StopLoss != nil {
+		req.StopLoss = &broker.StopLoss{StopPrice: o.StopLoss.StopPrice, LimitPrice: o.StopLoss.LimitPrice}
+	}
+	return req
+}
+
+// toBrokerReplace converts a validated replace into the broker request
+func toBrokerReplace(r ReplaceOrderRequest) broker.ReplaceOrderRequest {
+	return broker.ReplaceOrderRequest{
+		Qty:           r.Qty,
+		TimeInForce:   r.TimeInForce,
+		LimitPrice:    r.LimitPrice,
+		StopPrice:     r.StopPrice,
+		Trail:         r.Trail,
+		ClientOrderID: r.ClientOrderID,
+	}
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecated marks a legacy route alias. Responses carry a Deprecation header and a
+// Link to the successor route, with the request's path parameters filled in.
+func Deprecated(successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		target := successor
+		for _, param := range c.Params {
+			target = strings.Replace(target, ":"+param.Key, param.Value, 1)
+		}
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+target+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
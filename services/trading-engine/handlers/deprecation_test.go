@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDeprecatedAliasPointsAtSuccessor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/v1/orders/:order_id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/:order_id", Deprecated("/v1/orders/:order_id"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/abc-123", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected alias to still serve the request, got %d", w.Code)
+	}
+	if got := w.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("expected Deprecation header, got %q", got)
+	}
+	if got, want := w.Header().Get("Link"), `</v1/orders/abc-123>; rel="successor-version"`; got != want {
+		t.Errorf("expected Link %q, got %q", want, got)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/orders/abc-123", nil))
+	if w.Header().Get("Deprecation") != "" {
+		t.Errorf("versioned route should not be marked deprecated")
+	}
+}
@@ -1,47 +1,60 @@
 package handlers
 
 import (
-	"encoding/base64"
-	"fmt"
-	"io"
+	"encoding/json"
 	"net/http"
-	"os"
-	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
+	"github.com/seunghoon34/trading-app/services/trading-engine/internal/idempotency"
 	"github.com/seunghoon34/trading-app/services/trading-engine/internal/logger"
+	"github.com/seunghoon34/trading-app/services/trading-engine/internal/risk"
 )
 
-func makeAlpacaRequest(method, url string, payload io.Reader) (*http.Response, error) {
-	req, err := http.NewRequest(method, url, payload)
-	if err != nil {
-		return nil, err
-	}
-
-	// Add authentication headers
-	auth := os.Getenv("ALPACA_API_KEY") + ":" + os.Getenv("ALPACA_SECRET_KEY")
-	basicAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte(auth))
-	req.Header.Add("Authorization", basicAuth)
-	req.Header.Add("Accept", "application/json")
-
-	if method == "POST" {
-		req.Header.Add("Content-Type", "application/json")
+// sendOrderWithLegsResponse returns the parent order ID together with the IDs of its legs
+// so that clients can track each leg of a bracket, OCO or OTO order individually
+func sendOrderWithLegsResponse(c *gin.Context, order *broker.Order) {
+	legIDs := make([]string, 0, len(order.Legs))
+	legs := make([]OrderLeg, 0, len(order.Legs))
+	for _, leg := range order.Legs {
+		legIDs = append(legIDs, leg.ID)
+		legs = append(legs, OrderLeg{
+			ID:         leg.ID,
+			Side:       leg.Side,
+			Type:       leg.Type,
+			Status:     leg.Status,
+			LimitPrice: leg.LimitPrice,
+			StopPrice:  leg.StopPrice,
+		})
 	}
 
-	return http.DefaultClient.Do(req)
+	c.JSON(http.StatusOK, gin.H{
+		"id":          order.ID,
+		"symbol":      order.Symbol,
+		"side":        order.Side,
+		"type":        order.Type,
+		"status":      order.Status,
+		"order_class": order.OrderClass,
+		"leg_ids":     legIDs,
+		"legs":        legs,
+		"order":       order,
+	})
 }
 
-func sendAlpacaResponse(c *gin.Context, res *http.Response) {
-	defer res.Body.Close()
-
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read response"})
-		return
-	}
-
-	c.Header("Content-Type", "application/json")
-	c.String(res.StatusCode, string(body))
+// rejectOrder responds to an order refused before it reached the broker
+func rejectOrder(c *gin.Context, accountID, symbol string, rejection *risk.Rejection) {
+	logger.WithFields(map[string]interface{}{
+		"account_id":  accountID,
+		"symbol":      symbol,
+		"check":       rejection.Check,
+		"reason_code": rejection.ReasonCode,
+		"action":      "order_risk_rejected",
+	}).Warn(rejection.Message)
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":       rejection.Message,
+		"reason_code": rejection.ReasonCode,
+		"check":       rejection.Check,
+	})
 }
 
 func CreateOrder(c *gin.Context) {
@@ -52,12 +65,7 @@ func CreateOrder(c *gin.Context) {
 		return
 	}
 
-	var OrderData struct {
-		Side     string `json:"side"`
-		Symbol   string `json:"symbol"`
-		Qty      string `json:"qty"`
-		Notional string `json:"notional"`
-	}
+	var OrderData OrderRequest
 
 	if err := c.ShouldBindJSON(&OrderData); err != nil {
 		logger.WithFields(map[string]interface{}{
@@ -70,32 +78,77 @@ func CreateOrder(c *gin.Context) {
 		return
 	}
 
-	if OrderData.Side != "buy" && OrderData.Side != "sell" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Side must be 'buy' or 'sell'"})
+	OrderData.Normalize()
+	if err := OrderData.Validate(); err != nil {
+		logger.WithFields(map[string]interface{}{
+			"account_id": accountID,
+			"symbol":     OrderData.Symbol,
+			"type":       OrderData.Type,
+			"error":      err.Error(),
+			"action":     "order_validation_failed",
+		}).Warn("Order validation failed")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	url := fmt.Sprintf("https://broker-api.sandbox.alpaca.markets/v1/trading/accounts/%s/orders", accountID)
+	// Retries carrying the same key replay the first response instead of placing a second order
+	idempotencyKey := idempotencyKeyFor(c, accountID, OrderData.ClientOrderID)
+	if headerKey := c.GetHeader("Idempotency-Key"); headerKey != "" && OrderData.ClientOrderID == "" && len(headerKey) <= 128 {
+		// Let the broker deduplicate as well
+		OrderData.ClientOrderID = headerKey
+	}
 
-	var payload *strings.Reader
-	if OrderData.Qty != "" {
-		payload = strings.NewReader(fmt.Sprintf(`{"type":"market","time_in_force":"day","side":"%s","symbol":"%s","qty":"%s"}`, OrderData.Side, OrderData.Symbol, OrderData.Qty))
-	} else {
-		payload = strings.NewReader(fmt.Sprintf(`{"type":"market","time_in_force":"day","side":"%s","symbol":"%s","notional":"%s"}`, OrderData.Side, OrderData.Symbol, OrderData.Notional))
+	if idempotencyKey != "" {
+		orderJSON, err := json.Marshal(OrderData)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode order"})
+			return
+		}
+		requestHash := idempotency.HashRequest(orderJSON)
+		if !beginIdempotentRequest(c, idempotencyKey, requestHash) {
+			return
+		}
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		defer finishIdempotentRequest(idempotencyKey, requestHash, recorder)
+	}
+
+	if rejection := applyMarketHours(accountID, &OrderData); rejection != nil {
+		rejectOrder(c, accountID, OrderData.Symbol, rejection)
+		return
+	}
+
+	rejection, err := checkOrderRisk(c.Request.Context(), accountID, OrderData)
+	if err != nil {
+		logger.WithFields(map[string]interface{}{
+			"account_id": accountID,
+			"symbol":     OrderData.Symbol,
+			"error":      err.Error(),
+			"action":     "risk_check_failed",
+		}).Error("Pre-trade risk check failed")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to run pre-trade risk checks"})
+		return
+	}
+	if rejection != nil {
+		rejectOrder(c, accountID, OrderData.Symbol, rejection)
+		return
 	}
 
 	logger.WithFields(map[string]interface{}{
-		"account_id": accountID,
-		"symbol":     OrderData.Symbol,
-		"side":       OrderData.Side,
-		"qty":        OrderData.Qty,
-		"action":     "order_create_attempt",
+		"account_id":    accountID,
+		"symbol":        OrderData.Symbol,
+		"side":          OrderData.Side,
+		"qty":           OrderData.Qty,
+		"notional":      OrderData.Notional,
+		"type":          OrderData.Type,
+		"time_in_force": OrderData.TimeInForce,
+		"action":        "order_create_attempt",
 	}).Info("Order creation started")
 
-	res, err := makeAlpacaRequest("POST", url, payload)
-
+	markSubmitted(c)
+	order, err := brokerClient.SubmitOrder(c.Request.Context(), accountID, toBrokerOrder(OrderData))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute order"})
+		respondBrokerError(c, err, "Failed to execute order")
 		logger.WithFields(map[string]interface{}{
 			"account_id": accountID,
 			"symbol":     OrderData.Symbol,
@@ -105,7 +158,12 @@ func CreateOrder(c *gin.Context) {
 		return
 	}
 
-	sendAlpacaResponse(c, res)
+	if OrderData.IsAdvanced() {
+		sendOrderWithLegsResponse(c, order)
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
 }
 
 func GetOrder(c *gin.Context) {
@@ -116,15 +174,13 @@ func GetOrder(c *gin.Context) {
 		return
 	}
 
-	url := fmt.Sprintf("https://broker-api.sandbox.alpaca.markets/v1/trading/accounts/%s/orders/%s", accountID, orderID)
-
 	logger.WithFields(map[string]interface{}{
 		"account_id": accountID,
 		"order_id":   orderID,
 		"action":     "get_order_attempt",
 	}).Info("Get order started")
 
-	res, err := makeAlpacaRequest("GET", url, nil)
+	order, err := brokerClient.GetOrder(c.Request.Context(), accountID, orderID)
 	if err != nil {
 
 		logger.WithFields(map[string]interface{}{
@@ -134,13 +190,15 @@ func GetOrder(c *gin.Context) {
 			"action":     "get_order_failed",
 		}).Error("Get order failed")
 
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute request"})
+		respondBrokerError(c, err, "Failed to execute request")
 		return
 	}
 
-	sendAlpacaResponse(c, res)
+	c.JSON(http.StatusOK, order)
 }
 
+// GetOrders lists the account's orders with filters and cursor pagination. Pass the
+// returned next_cursor back as cursor, with the same filters, to fetch the next page.
 func GetOrders(c *gin.Context) {
 	accountID := c.GetHeader("X-Account-ID") // ← Get from header
 	if accountID == "" {
@@ -148,25 +206,124 @@ func GetOrders(c *gin.Context) {
 		return
 	}
 
-	url := fmt.Sprintf("https://broker-api.sandbox.alpaca.markets/v1/trading/accounts/%s/orders", accountID)
+	query, err := parseOrderListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	logger.WithFields(map[string]interface{}{
 		"account_id": accountID,
+		"status":     query.Status,
+		"symbols":    query.Symbols,
+		"limit":      query.Limit,
+		"paged":      query.Cursor != nil,
 		"action":     "get_all_orders_attempt",
 	}).Info("Get all orders started")
 
-	res, err := makeAlpacaRequest("GET", url, nil)
+	response, err := listOrdersPage(c.Request.Context(), accountID, query)
 	if err != nil {
 		logger.WithFields(map[string]interface{}{
 			"account_id": accountID,
 			"error":      err.Error(),
 			"action":     "get_all_orders_failed",
 		}).Error("Get all orders failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute request"})
+		respondBrokerError(c, err, "Failed to execute request")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ReplaceOrder amends a working order in place of a cancel and re-submit. The broker
+// cancels the original and returns the order that replaces it.
+func ReplaceOrder(c *gin.Context) {
+	orderID := c.Param("order_id")
+	accountID := c.GetHeader("X-Account-ID")
+	if accountID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "X-Account-ID header is required"})
+		return
+	}
+
+	var change ReplaceOrderRequest
+	if err := c.ShouldBindJSON(&change); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	change.Normalize()
+
+	original, err := brokerClient.GetOrder(c.Request.Context(), accountID, orderID)
+	if err != nil {
+		logger.WithFields(map[string]interface{}{
+			"account_id": accountID,
+			"order_id":   orderID,
+			"error":      err.Error(),
+			"action":     "replace_order_failed",
+		}).Error("Replace order failed")
+		respondBrokerError(c, err, "Failed to fetch order")
+		return
+	}
+
+	if err := change.Validate(original); err != nil {
+		logger.WithFields(map[string]interface{}{
+			"account_id": accountID,
+			"order_id":   orderID,
+			"status":     original.Status,
+			"type":       original.Type,
+			"error":      err.Error(),
+			"action":     "replace_validation_failed",
+		}).Warn("Replace validation failed")
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
 		return
 	}
 
-	sendAlpacaResponse(c, res)
+	// The replacement is checked in full, with exposure measured against the working order
+	working := workingOrder(original)
+	rejection, err := checkReplaceRisk(c.Request.Context(), accountID, change.ReplacedOrder(original), &working)
+	if err != nil {
+		logger.WithFields(map[string]interface{}{
+			"account_id": accountID,
+			"order_id":   orderID,
+			"error":      err.Error(),
+			"action":     "risk_check_failed",
+		}).Error("Pre-trade risk check failed")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to run pre-trade risk checks"})
+		return
+	}
+	if rejection != nil {
+		rejectOrder(c, accountID, original.Symbol, rejection)
+		return
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"account_id":    accountID,
+		"order_id":      orderID,
+		"qty":           change.Qty,
+		"limit_price":   change.LimitPrice,
+		"stop_price":    change.StopPrice,
+		"trail":         change.Trail,
+		"time_in_force": change.TimeInForce,
+		"action":        "replace_order_attempt",
+	}).Info("Replace order started")
+
+	order, err := brokerClient.ReplaceOrder(c.Request.Context(), accountID, orderID, toBrokerReplace(change))
+	if err != nil {
+		logger.WithFields(map[string]interface{}{
+			"account_id": accountID,
+			"order_id":   orderID,
+			"error":      err.Error(),
+			"action":     "replace_order_failed",
+		}).Error("Replace order failed")
+		respondBrokerError(c, err, "Failed to replace order")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":       order.ID,
+		"replaces": orderID,
+		"status":   order.Status,
+		"order":    order,
+	})
 }
 
 func DeleteOrder(c *gin.Context) {
@@ -177,27 +334,24 @@ func DeleteOrder(c *gin.Context) {
 		return
 	}
 
-	url := fmt.Sprintf("https://broker-api.sandbox.alpaca.markets/v1/trading/accounts/%s/orders/%s", accountID, orderID)
-
 	logger.WithFields(map[string]interface{}{
 		"account_id": accountID,
 		"order_id":   orderID,
 		"action":     "delete_order_attempt",
 	}).Info("Delete order started")
 
-	res, err := makeAlpacaRequest("DELETE", url, nil)
-	if err != nil {
+	if err := brokerClient.CancelOrder(c.Request.Context(), accountID, orderID); err != nil {
 		logger.WithFields(map[string]interface{}{
 			"account_id": accountID,
 			"order_id":   orderID,
 			"error":      err.Error(),
 			"action":     "delete_order_failed",
 		}).Error("Delete order failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute request"})
+		respondBrokerError(c, err, "Failed to execute request")
 		return
 	}
 
-	sendAlpacaResponse(c, res)
+	c.Status(http.StatusNoContent)
 }
 
 func DeleteAllOrders(c *gin.Context) {
@@ -207,22 +361,20 @@ func DeleteAllOrders(c *gin.Context) {
 		return
 	}
 
-	url := fmt.Sprintf("https://broker-api.sandbox.alpaca.markets/v1/trading/accounts/%s/orders", accountID)
-
 	logger.WithFields(map[string]interface{}{
 		"account_id": accountID,
 		"action":     "delete_all_orders_attempt",
 	}).Info("Delete all orders started")
-	res, err := makeAlpacaRequest("DELETE", url, nil)
+	statuses, err := brokerClient.CancelAllOrders(c.Request.Context(), accountID)
 	if err != nil {
 		logger.WithFields(map[string]interface{}{
 			"account_id": accountID,
 			"error":      err.Error(),
 			"action":     "delete_all_orders_failed",
 		}).Error("Delete all orders failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute request"})
+		respondBrokerError(c, err, "Failed to execute request")
 		return
 	}
 
-	sendAlpacaResponse(c, res)
+	c.JSON(http.StatusMultiStatus, statuses)
 }
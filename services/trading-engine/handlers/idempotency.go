@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/services/trading-engine/internal/idempotency"
+	"github.com/seunghoon34/trading-app/services/trading-engine/internal/logger"
+)
+
+var (
+	idempotencyStore  idempotency.Store = idempotency.NewMemoryStore()
+	idempotencyConfig                   = idempotency.LoadConfig()
+)
+
+// InitIdempotency selects the idempotency key store used by CreateOrder
+func InitIdempotency() {
+	idempotencyStore = idempotency.NewStore()
+	idempotencyConfig = idempotency.LoadConfig()
+}
+
+// responseRecorder captures the response written by a handler so it can be replayed
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+	// submitted is set once the order has been sent to the broker
+	submitted bool
+}
+
+// markSubmitted records that the request reached the broker, after which a failure no
+// longer means the order was not placed
+func markSubmitted(c *gin.Context) {
+	if recorder, ok := c.Writer.(*responseRecorder); ok {
+		recorder.submitted = true
+	}
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// idempotencyKeyFor returns the account scoped key for the request. The Idempotency-Key
+// header wins; otherwise the client_order_id from the body is used.
+func idempotencyKeyFor(c *gin.Context, accountID, clientOrderID string) string {
+	key := c.GetHeader("Idempotency-Key")
+	if key == "" {
+		key = clientOrderID
+	}
+	if key == "" {
+		return ""
+	}
+	return accountID + ":" + key
+}
+
+// beginIdempotentRequest reserves the key or replays the stored response.
+// It returns false when the response has already been written.
+func beginIdempotentRequest(c *gin.Context, key, requestHash string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	existing, reserved, err := idempotencyStore.Reserve(ctx, key, requestHash, idempotencyConfig.LockTimeout)
+	if err != nil {
+		logger.WithFields(map[string]interface{}{
+			"idempotency_key": key,
+			"error":           err.Error(),
+			"action":          "idempotency_reserve_failed",
+		}).Error("Idempotency key reservation failed")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to check idempotency key"})
+		return false
+	}
+	if reserved {
+		return true
+	}
+
+	if existing.RequestHash != requestHash {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "Idempotency key has already been used with a different request body",
+		})
+		return false
+	}
+
+	if !existing.Completed {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "A request with this idempotency key is still being processed",
+		})
+		return false
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"idempotency_key": key,
+		"status_code":     existing.StatusCode,
+		"action":          "idempotent_replay",
+	}).Info("Replaying stored order response")
+
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(existing.StatusCode, existing.ContentType, existing.Body)
+	return false
+}
+
+// finishIdempotentRequest stores the recorded response for the key. Server side
+// failures before the order was submitted release the key so the client can safely
+// retry. After submission a failure is ambiguous, as the broker may have accepted the
+// order, so the in-flight reservation is kept until it times out and retries get a 409
+// in the meantime.
+func finishIdempotentRequest(key, requestHash string, recorder *responseRecorder) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	status := recorder.Status()
+	if status >= http.StatusInternalServerError && recorder.submitted {
+		logger.WithFields(map[string]interface{}{
+			"idempotency_key": key,
+			"status_code":     status,
+			"action":          "idempotency_outcome_unknown",
+		}).Warn("Order submission failed ambiguously, keeping idempotency key reserved")
+		return
+	}
+	if status >= http.StatusInternalServerError {
+		if err := idempotencyStore.Release(ctx, key); err != nil {
+			logger.WithFields(map[string]interface{}{
+				"idempotency_key": key,
+				"error":           err.Error(),
+				"action":          "idempotency_release_failed",
+			}).Error("Failed to release idempotency key")
+		}
+		return
+	}
+
+	record := idempotency.Record{
+		RequestHash: requestHash,
+		StatusCode:  status,
+		ContentType: recorder.Header().Get("Content-Type"),
+		Body:        recorder.body.Bytes(),
+		CreatedAt:   time.Now(),
+	}
+	if err := idempotencyStore.Complete(ctx, key, record, idempotencyConfig.Retention); err != nil {
+		logger.WithFields(map[string]interface{}{
+			"idempotency_key": key,
+			"error":           err.Error(),
+			"action":          "idempotency_store_failed",
+		}).Error("Failed to store idempotent response")
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
+	"github.com/seunghoon34/trading-app/services/trading-engine/internal/idempotency"
+)
+
+// orderBroker accepts orders for an account with ample buying power; every other
+// method comes from the embedded nil interface
+type orderBroker struct {
+	broker.Broker
+	accountErr error
+	submitErr  error
+	submitted  int
+}
+
+func (b *orderBroker) GetAccount(ctx context.Context, accountID string) (*broker.Account, error) {
+	if b.accountErr != nil {
+		return nil, b.accountErr
+	}
+	return &broker.Account{BuyingPower: "100000", Equity: "100000", LastEquity: "100000"}, nil
+}
+
+func (b *orderBroker) ListPositions(ctx context.Context, accountID string) ([]broker.Position, error) {
+	return nil, nil
+}
+
+func (b *orderBroker) SubmitOrder(ctx context.Context, accountID string, req broker.OrderRequest) (*broker.Order, error) {
+	b.submitted++
+	if b.submitErr != nil {
+		return nil, b.submitErr
+	}
+	return &broker.Order{ID: "order-1", Symbol: req.Symbol, Side: req.Side, Type: req.Type, Status: "accepted"}, nil
+}
+
+// useIdempotencyStore gives the test an empty key store and keeps the risk checks
+// off the network: limit orders skip the market clock and quotes, and asset lookups
+// fail fast so those checks are skipped
+func useIdempotencyStore(t *testing.T) {
+	t.Helper()
+	previous := idempotencyStore
+	idempotencyStore = idempotency.NewMemoryStore()
+	t.Cleanup(func() { idempotencyStore = previous })
+
+	marketData := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(marketData.Close)
+	t.Setenv("MARKET_DATA_SERVICE_URL", marketData.URL)
+}
+
+func postOrder(t *testing.T, key, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/v1/orders", CreateOrder)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Account-ID", "acct-1")
+	req.Header.Set("Idempotency-Key", key)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+const limitOrder = `{"symbol":"AAPL","side":"buy","qty":"1","type":"limit","limit_price":"190"}`
+
+func TestCreateOrderReplaysCompletedKey(t *testing.T) {
+	useIdempotencyStore(t)
+	b := &orderBroker{}
+	useBroker(t, b)
+
+	first := postOrder(t, "key-1", limitOrder)
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", first.Code, first.Body)
+	}
+	second := postOrder(t, "key-1", limitOrder)
+	if second.Code != http.StatusOK || second.Body.String() != first.Body.String() {
+		t.Fatalf("expected the first response replayed, got %d: %s", second.Code, second.Body)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("expected the replay to be marked with Idempotent-Replayed")
+	}
+	if b.submitted != 1 {
+		t.Errorf("expected one order submitted, got %d", b.submitted)
+	}
+}
+
+func TestCreateOrderRejectsReusedKeyWithDifferentBody(t *testing.T) {
+	useIdempotencyStore(t)
+	b := &orderBroker{}
+	useBroker(t, b)
+
+	if w := postOrder(t, "key-1", limitOrder); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	w := postOrder(t, "key-1", strings.Replace(limitOrder, `"qty":"1"`, `"qty":"2"`, 1))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", w.Code, w.Body)
+	}
+	if b.submitted != 1 {
+		t.Errorf("expected one order submitted, got %d", b.submitted)
+	}
+}
+
+func TestCreateOrderKeepsKeyAfterAmbiguousFailure(t *testing.T) {
+	useIdempotencyStore(t)
+	b := &orderBroker{submitErr: &broker.APIError{StatusCode: http.StatusBadGateway, Message: "upstream timeout"}}
+	useBroker(t, b)
+
+	if w := postOrder(t, "key-1", limitOrder); w.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502, got %d: %s", w.Code, w.Body)
+	}
+	// The broker may have taken the order, so a retry must not submit it again
+	b.submitErr = nil
+	w := postOrder(t, "key-1", limitOrder)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 while the key is in flight, got %d: %s", w.Code, w.Body)
+	}
+	if b.submitted != 1 {
+		t.Errorf("expected one order submitted, got %d", b.submitted)
+	}
+}
+
+func TestCreateOrderReleasesKeyAfterFailureBeforeSubmission(t *testing.T) {
+	useIdempotencyStore(t)
+	b := &orderBroker{accountErr: errors.New("connection refused")}
+	useBroker(t, b)
+
+	if w := postOrder(t, "key-1", limitOrder); w.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502 from the risk checks, got %d: %s", w.Code, w.Body)
+	}
+	b.accountErr = nil
+	if w := postOrder(t, "key-1", limitOrder); w.Code != http.StatusOK {
+		t.Fatalf("expected the retry to go through, got %d: %s", w.Code, w.Body)
+	}
+	if b.submitted != 1 {
+		t.Errorf("expected one order submitted, got %d", b.submitted)
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/seunghoon34/trading-app/pkg/broker"
+	"github.com/seunghoon34/trading-app/services/trading-engine/internal/logger"
+	"github.com/seunghoon34/trading-app/services/trading-engine/internal/marketdata"
+	"github.com/seunghoon34/trading-app/services/trading-engine/internal/risk"
+)
+
+// marketClock is replaced in tests
+var marketClock = marketdata.MarketClock
+
+// applyMarketHours guards day market orders placed while the market is closed. Under
+// the reject policy the order is refused; under the queue policy it becomes an OPG
+// order for the next opening auction, once the broker is taking those. If the clock
+// cannot be read the order goes through unchanged and the broker has the final word.
+func applyMarketHours(accountID string, order *OrderRequest) *risk.Rejection {
+	if order.Type != OrderTypeMarket || order.TimeInForce != "day" {
+		return nil
+	}
+
+	clock, err := marketClock()
+	if err != nil {
+		logger.WithFields(map[string]interface{}{
+			"account_id": accountID,
+			"symbol":     order.Symbol,
+			"error":      err.Error(),
+			"action":     "market_clock_lookup_failed",
+		}).Warn("Market clock lookup failed")
+		return nil
+	}
+	if clock.IsOpen {
+		return nil
+	}
+
+	nextOpen := clock.NextOpen.Format(time.RFC3339)
+	if order.MarketHoursPolicy != MarketHoursQueue {
+		return &risk.Rejection{
+			Check:      "market_hours",
+			ReasonCode: risk.ReasonMarketClosed,
+			Message:    fmt.Sprintf("Market is closed until %s; use market_hours_policy 'queue' to trade at the open", nextOpen),
+		}
+	}
+
+	// OPG orders must be simple whole-share orders
+	kind := ""
+	if order.Notional != "" {
+		kind = "notional"
+	} else if qty := parseFloat(order.Qty); qty != math.Trunc(qty) {
+		kind = "fractional"
+	} else if order.IsAdvanced() {
+		kind = order.OrderClass
+	}
+	if kind != "" {
+		return &risk.Rejection{
+			Check:      "market_hours",
+			ReasonCode: risk.ReasonMarketClosed,
+			Message:    fmt.Sprintf("Market is closed until %s and %s orders cannot be queued for the open", nextOpen, kind),
+		}
+	}
+
+	// The broker only takes OPG orders overnight, so between the close and 19:00 ET
+	// the order cannot be queued yet
+	at := clock.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	if accepted, from := broker.AcceptsOpeningAuctionOrders(at); !accepted {
+		return &risk.Rejection{
+			Check:      "market_hours",
+			ReasonCode: risk.ReasonMarketClosed,
+			Message: fmt.Sprintf("Market is closed until %s and orders can be queued for the open from %s",
+				nextOpen, from.Format(time.RFC3339)),
+		}
+	}
+
+	order.TimeInForce = "opg"
+	logger.WithFields(map[string]interface{}{
+		"account_id": accountID,
+		"symbol":     order.Symbol,
+		"next_open":  nextOpen,
+		"action":     "order_queued_for_open",
+	}).Info("Market closed, queuing order for the opening auction")
+	return nil
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seunghoon34/trading-app/services/trading-engine/internal/marketdata"
+	"github.com/seunghoon34/trading-app/services/trading-engine/internal/risk"
+)
+
+func TestApplyMarketHours(t *testing.T) {
+	nextOpen := time.Date(2024, 4, 1, 13, 30, 0, 0, time.UTC)
+	// 21:00 and 16:30 ET the evening before
+	closed := func() (*marketdata.Clock, error) {
+		return &marketdata.Clock{Timestamp: time.Date(2024, 4, 1, 1, 0, 0, 0, time.UTC), NextOpen: nextOpen}, nil
+	}
+	afterClose := func() (*marketdata.Clock, error) {
+		return &marketdata.Clock{Timestamp: time.Date(2024, 3, 28, 20, 30, 0, 0, time.UTC), NextOpen: nextOpen}, nil
+	}
+	evening := func() (*marketdata.Clock, error) {
+		return &marketdata.Clock{Timestamp: time.Date(2024, 3, 28, 23, 30, 0, 0, time.UTC), NextOpen: nextOpen}, nil
+	}
+	open := func() (*marketdata.Clock, error) { return &marketdata.Clock{IsOpen: true}, nil }
+	unavailable := func() (*marketdata.Clock, error) { return nil, errors.New("market-data is down") }
+
+	tests := []struct {
+		name    string
+		clock   func() (*marketdata.Clock, error)
+		order   OrderRequest
+		wantTIF string
+		wantErr string
+	}{
+		{"open market", open, OrderRequest{Type: "market", TimeInForce: "day", Qty: "1"}, "day", ""},
+		{"closed rejects by default", closed, OrderRequest{Type: "market", TimeInForce: "day", Qty: "1"}, "day", "Market is closed until 2024-04-01T13:30:00Z"},
+		{"closed rejects", closed, OrderRequest{Type: "market", TimeInForce: "day", Qty: "1", MarketHoursPolicy: MarketHoursReject}, "day", "Market is closed"},
+		{"closed queues as opg", closed, OrderRequest{Type: "market", TimeInForce: "day", Qty: "1", MarketHoursPolicy: MarketHoursQueue}, "opg", ""},
+		{"queues from 19:00 ET", evening, OrderRequest{Type: "market", TimeInForce: "day", Qty: "1", MarketHoursPolicy: MarketHoursQueue}, "opg", ""},
+		{"cannot queue before 19:00 ET", afterClose, OrderRequest{Type: "market", TimeInForce: "day", Qty: "1", MarketHoursPolicy: MarketHoursQueue}, "day", "can be queued for the open from 2024-03-28T19:00:00-04:00"},
+		{"notional cannot queue", closed, OrderRequest{Type: "market", TimeInForce: "day", Notional: "100", MarketHoursPolicy: MarketHoursQueue}, "day", "notional orders cannot be queued"},
+		{"fractional cannot queue", closed, OrderRequest{Type: "market", TimeInForce: "day", Qty: "0.5", MarketHoursPolicy: MarketHoursQueue}, "day", "fractional orders cannot be queued"},
+		{"bracket cannot queue", closed, OrderRequest{Type: "market", TimeInForce: "day", Qty: "1", OrderClass: OrderClassBracket, MarketHoursPolicy: MarketHoursQueue}, "day", "bracket orders cannot be queued"},
+		{"limit orders rest at the broker", closed, OrderRequest{Type: "limit", TimeInForce: "day", Qty: "1", LimitPrice: "10"}, "day", ""},
+		{"gtc market orders are left alone", closed, OrderRequest{Type: "market", TimeInForce: "gtc", Qty: "1"}, "gtc", ""},
+		{"clock unavailable", unavailable, OrderRequest{Type: "market", TimeInForce: "day", Qty: "1"}, "day", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			marketClock = tt.clock
+			defer func() { marketClock = marketdata.MarketClock }()
+
+			order := tt.order
+			order.Symbol = "AAPL"
+			rejection := applyMarketHours("acct", &order)
+
+			if tt.wantErr == "" && rejection != nil {
+				t.Fatalf("unexpected rejection: %v", rejection)
+			}
+			if tt.wantErr != "" {
+				if rejection == nil || !strings.Contains(rejection.Message, tt.wantErr) {
+					t.Fatalf("expected rejection containing %q, got %v", tt.wantErr, rejection)
+				}
+				if rejection.ReasonCode != risk.ReasonMarketClosed {
+					t.Errorf("expected %s, got %s", risk.ReasonMarketClosed, rejection.ReasonCode)
+				}
+			}
+			if order.TimeInForce != tt.wantTIF {
+				t.Errorf("expected time_in_force %s, got %s", tt.wantTIF, order.TimeInForce)
+			}
+		})
+	}
+}
@@ -0,0 +1,482 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/seunghoon34/trading-app/pkg/broker"
+)
+
+// Supported order types
+const (
+	OrderTypeMarket       = "market"
+	OrderTypeLimit        = "limit"
+	OrderTypeStop         = "stop"
+	OrderTypeStopLimit    = "stop_limit"
+	OrderTypeTrailingStop = "trailing_stop"
+)
+
+// Supported order classes
+const (
+	OrderClassSimple  = "simple"
+	OrderClassBracket = "bracket"
+	OrderClassOCO     = "oco"
+	OrderClassOTO     = "oto"
+)
+
+// validTimeInForce lists the time_in_force values accepted by the broker
+var validTimeInForce = map[string]bool{
+	"day": true,
+	"gtc": true,
+	"opg": true,
+	"cls": true,
+	"ioc": true,
+	"fok": true,
+}
+
+// Market hours policies for day market orders placed while the market is closed
+const (
+	// MarketHoursReject refuses the order; it is the default
+	MarketHoursReject = "reject"
+	// MarketHoursQueue resubmits the order as OPG to trade in the opening auction
+	MarketHoursQueue = "queue"
+)
+
+// OrderRequest represents the order body accepted by CreateOrder and sent to Alpaca
+type OrderRequest struct {
+	Side          string `json:"side"`
+	Symbol        string `json:"symbol"`
+	Qty           string `json:"qty,omitempty"`
+	Notional      string `json:"notional,omitempty"`
+	Type          string `json:"type"`
+	TimeInForce   string `json:"time_in_force"`
+	LimitPrice    string `json:"limit_price,omitempty"`
+	StopPrice     string `json:"stop_price,omitempty"`
+	TrailPrice    string `json:"trail_price,omitempty"`
+	TrailPercent  string `json:"trail_percent,omitempty"`
+	ExtendedHours bool   `json:"extended_hours,omitempty"`
+	ClientOrderID string `json:"client_order_id,omitempty"`
+
+	OrderClass string         `json:"order_class,omitempty"`
+	TakeProfit *TakeProfitLeg `json:"take_profit,omitempty"`
+	StopLoss   *StopLossLeg   `json:"stop_loss,omitempty"`
+
+	// MarketHoursPolicy is handled here and not sent to the broker
+	MarketHoursPolicy string `json:"market_hours_policy,omitempty"`
+}
+
+// replaceableStatuses lists the order states in which the broker accepts a replace
+var replaceableStatuses = map[string]bool{
+	"new":              true,
+	"accepted":         true,
+	"partially_filled": true,
+	"held":             true,
+}
+
+// ReplaceOrderRequest is the body accepted by ReplaceOrder. Omitted fields keep the
+// working order's values; trail is the new trail_price or trail_percent, whichever
+// the order was placed with.
+type ReplaceOrderRequest struct {
+	Qty           string `json:"qty,omitempty"`
+	TimeInForce   string `json:"time_in_force,omitempty"`
+	LimitPrice    string `json:"limit_price,omitempty"`
+	StopPrice     string `json:"stop_price,omitempty"`
+	Trail         string `json:"trail,omitempty"`
+	ClientOrderID string `json:"client_order_id,omitempty"`
+}
+
+// TakeProfitLeg is the limit leg of a bracket, OCO or OTO order
+type TakeProfitLeg struct {
+	LimitPrice string `json:"limit_price"`
+}
+
+// StopLossLeg is the stop leg of a bracket, OCO or OTO order
+type StopLossLeg struct {
+	StopPrice  string `json:"stop_price"`
+	LimitPrice string `json:"limit_price,omitempty"`
+}
+
+// OrderLeg summarizes a child order returned by the broker for advanced order classes
+type OrderLeg struct {
+	ID         string  `json:"id"`
+	Side       string  `json:"side"`
+	Type       string  `json:"type"`
+	Status     string  `json:"status"`
+	LimitPrice *string `json:"limit_price"`
+	StopPrice  *string `json:"stop_price"`
+}
+
+// Normalize fills in defaults and canonicalizes casing before validation
+func (o *OrderRequest) Normalize() {
+	o.Side = strings.ToLower(strings.TrimSpace(o.Side))
+	o.Symbol = strings.ToUpper(strings.TrimSpace(o.Symbol))
+	o.Type = strings.ToLower(strings.TrimSpace(o.Type))
+	o.TimeInForce = strings.ToLower(strings.TrimSpace(o.TimeInForce))
+	o.OrderClass = strings.ToLower(strings.TrimSpace(o.OrderClass))
+	o.MarketHoursPolicy = strings.ToLower(strings.TrimSpace(o.MarketHoursPolicy))
+
+	// Keep the previous behaviour of a day market order when nothing is specified
+	if o.Type == "" {
+		o.Type = OrderTypeMarket
+	}
+	if o.TimeInForce == "" {
+		o.TimeInForce = "day"
+	}
+}
+
+// Validate checks that the fields supplied make sense for the order type
+func (o *OrderRequest) Validate() error {
+	if o.Side != "buy" && o.Side != "sell" {
+		return errors.New("Side must be 'buy' or 'sell'")
+	}
+	if o.Symbol == "" {
+		return errors.New("symbol is required")
+	}
+	if len(o.ClientOrderID) > 128 {
+		return errors.New("client_order_id must be at most 128 characters")
+	}
+	if !validTimeInForce[o.TimeInForce] {
+		return fmt.Errorf("invalid time_in_force '%s'", o.TimeInForce)
+	}
+	if o.MarketHoursPolicy != "" && o.MarketHoursPolicy != MarketHoursReject && o.MarketHoursPolicy != MarketHoursQueue {
+		return errors.New("market_hours_policy must be 'reject' or 'queue'")
+	}
+
+	// Exactly one of qty or notional
+	if (o.Qty == "") == (o.Notional == "") {
+		return errors.New("exactly one of qty or notional is required")
+	}
+	if err := validatePositive("qty", o.Qty); err != nil {
+		return err
+	}
+	if err := validatePositive("notional", o.Notional); err != nil {
+		return err
+	}
+
+	prices := []struct{ name, value string }{
+		{"limit_price", o.LimitPrice},
+		{"stop_price", o.StopPrice},
+		{"trail_price", o.TrailPrice},
+		{"trail_percent", o.TrailPercent},
+	}
+	for _, p := range prices {
+		if err := validatePositive(p.name, p.value); err != nil {
+			return err
+		}
+	}
+
+	hasTrail := o.TrailPrice != "" || o.TrailPercent != ""
+
+	switch o.Type {
+	case OrderTypeMarket:
+		if o.LimitPrice != "" || o.StopPrice != "" || hasTrail {
+			return errors.New("market orders do not accept limit_price, stop_price or trail fields")
+		}
+	case OrderTypeLimit:
+		if o.LimitPrice == "" {
+			return errors.New("limit orders require limit_price")
+		}
+		if o.StopPrice != "" || hasTrail {
+			return errors.New("limit orders do not accept stop_price or trail fields")
+		}
+	case OrderTypeStop:
+		if o.StopPrice == "" {
+			return errors.New("stop orders require stop_price")
+		}
+		if o.LimitPrice != "" || hasTrail {
+			return errors.New("stop orders do not accept limit_price or trail fields")
+		}
+	case OrderTypeStopLimit:
+		if o.StopPrice == "" || o.LimitPrice == "" {
+			return errors.New("stop_limit orders require both stop_price and limit_price")
+		}
+		if hasTrail {
+			return errors.New("stop_limit orders do not accept trail fields")
+		}
+	case OrderTypeTrailingStop:
+		if (o.TrailPrice == "") == (o.TrailPercent == "") {
+			return errors.New("trailing_stop orders require exactly one of trail_price or trail_percent")
+		}
+		if o.LimitPrice != "" || o.StopPrice != "" {
+			return errors.New("trailing_stop orders do not accept limit_price or stop_price")
+		}
+		if o.TimeInForce != "day" && o.TimeInForce != "gtc" {
+			return errors.New("trailing_stop orders only support day or gtc time_in_force")
+		}
+	default:
+		return fmt.Errorf("invalid order type '%s'", o.Type)
+	}
+
+	// Notional (fractional dollar) orders are only supported as day orders
+	if o.Notional != "" {
+		if o.TimeInForce != "day" {
+			return errors.New("notional orders only support day time_in_force")
+		}
+		if o.Type == OrderTypeTrailingStop {
+			return errors.New("notional orders cannot be trailing_stop orders")
+		}
+	}
+
+	// Extended hours trading is only available for day limit orders
+	if o.ExtendedHours && (o.Type != OrderTypeLimit || o.TimeInForce != "day") {
+		return errors.New("extended_hours is only supported for limit orders with day time_in_force")
+	}
+
+	return o.validateOrderClass()
+}
+
+// IsAdvanced reports whether the order spawns legs at the broker
+func (o *OrderRequest) IsAdvanced() bool {
+	return o.OrderClass != "" && o.OrderClass != OrderClassSimple
+}
+
+// validateOrderClass checks the take_profit and stop_loss legs for bracket, OCO and OTO orders
+func (o *OrderRequest) validateOrderClass() error {
+	if !o.IsAdvanced() {
+		if o.TakeProfit != nil || o.StopLoss != nil {
+			return errors.New("take_profit and stop_loss require an order_class of bracket, oco or oto")
+		}
+		return nil
+	}
+
+	if o.Notional != "" {
+		return errors.New("notional orders cannot use bracket, oco or oto order classes")
+	}
+	if o.TimeInForce != "day" && o.TimeInForce != "gtc" {
+		return fmt.Errorf("%s orders only support day or gtc time_in_force", o.OrderClass)
+	}
+	if o.ExtendedHours {
+		return fmt.Errorf("%s orders cannot trade in extended hours", o.OrderClass)
+	}
+
+	if o.TakeProfit != nil {
+		if o.TakeProfit.LimitPrice == "" {
+			return errors.New("take_profit requires limit_price")
+		}
+		if err := validatePositive("take_profit.limit_price", o.TakeProfit.LimitPrice); err != nil {
+			return err
+		}
+	}
+	if o.StopLoss != nil {
+		if o.StopLoss.StopPrice == "" {
+			return errors.New("stop_loss requires stop_price")
+		}
+		if err := validatePositive("stop_loss.stop_price", o.StopLoss.StopPrice); err != nil {
+			return err
+		}
+		if err := validatePositive("stop_loss.limit_price", o.StopLoss.LimitPrice); err != nil {
+			return err
+		}
+	}
+
+	switch o.OrderClass {
+	case OrderClassBracket:
+		if o.Type != OrderTypeMarket && o.Type != OrderTypeLimit {
+			return errors.New("bracket orders must be market or limit orders")
+		}
+		if o.TakeProfit == nil || o.StopLoss == nil {
+			return errors.New("bracket orders require both take_profit and stop_loss")
+		}
+	case OrderClassOCO:
+		if o.Type != OrderTypeLimit {
+			return errors.New("oco orders must be limit orders")
+		}
+		if o.TakeProfit == nil || o.StopLoss == nil {
+			return errors.New("oco orders require both take_profit and stop_loss")
+		}
+	case OrderClassOTO:
+		if o.Type != OrderTypeMarket && o.Type != OrderTypeLimit {
+			return errors.New("oto orders must be market or limit orders")
+		}
+		if (o.TakeProfit == nil) == (o.StopLoss == nil) {
+			return errors.New("oto orders require exactly one of take_profit or stop_loss")
+		}
+	default:
+		return fmt.Errorf("invalid order_class '%s'", o.OrderClass)
+	}
+
+	return o.validateLegPrices()
+}
+
+// validateLegPrices checks that the exit legs sit on the correct side of each other and of the entry.
+// A bracket or OTO protects the position opened by the parent, so a buy exits with legs that sell:
+// take-profit above, stop-loss below. An OCO is itself the exit, so a sell OCO protects a long.
+func (o *OrderRequest) validateLegPrices() error {
+	protectsLong := o.Side == "buy"
+	if o.OrderClass == OrderClassOCO {
+		protectsLong = o.Side == "sell"
+	}
+
+	var takeProfit, stopLoss, stopLimit, entry float64
+	if o.TakeProfit != nil {
+		takeProfit, _ = strconv.ParseFloat(o.TakeProfit.LimitPrice, 64)
+	}
+	if o.StopLoss != nil {
+		stopLoss, _ = strconv.ParseFloat(o.StopLoss.StopPrice, 64)
+		stopLimit, _ = strconv.ParseFloat(o.StopLoss.LimitPrice, 64)
+	}
+	if o.Type == OrderTypeLimit && o.OrderClass != OrderClassOCO {
+		entry, _ = strconv.ParseFloat(o.LimitPrice, 64)
+	}
+
+	if protectsLong {
+		if o.TakeProfit != nil && o.StopLoss != nil && stopLoss >= takeProfit {
+			return errors.New("stop_loss.stop_price must be below take_profit.limit_price")
+		}
+		if stopLimit > 0 && stopLimit > stopLoss {
+			return errors.New("stop_loss.limit_price must not be above stop_loss.stop_price")
+		}
+		if entry > 0 && o.TakeProfit != nil && takeProfit <= entry {
+			return errors.New("take_profit.limit_price must be above the entry limit_price")
+		}
+		if entry > 0 && o.StopLoss != nil && stopLoss >= entry {
+			return errors.New("stop_loss.stop_price must be below the entry limit_price")
+		}
+		return nil
+	}
+
+	if o.TakeProfit != nil && o.StopLoss != nil && stopLoss <= takeProfit {
+		return errors.New("stop_loss.stop_price must be above take_profit.limit_price")
+	}
+	if stopLimit > 0 && stopLimit < stopLoss {
+		return errors.New("stop_loss.limit_price must not be below stop_loss.stop_price")
+	}
+	if entry > 0 && o.TakeProfit != nil && takeProfit >= entry {
+		return errors.New("take_profit.limit_price must be below the entry limit_price")
+	}
+	if entry > 0 && o.StopLoss != nil && stopLoss <= entry {
+		return errors.New("stop_loss.stop_price must be above the entry limit_price")
+	}
+	return nil
+}
+
+// Normalize canonicalizes casing before validation
+func (r *ReplaceOrderRequest) Normalize() {
+	r.TimeInForce = strings.ToLower(strings.TrimSpace(r.TimeInForce))
+}
+
+// Validate checks the change against the working order's type and status
+func (r *ReplaceOrderRequest) Validate(original *broker.Order) error {
+	if r.Qty == "" && r.TimeInForce == "" && r.LimitPrice == "" && r.StopPrice == "" && r.Trail == "" {
+		return errors.New("at least one of qty, limit_price, stop_price, trail or time_in_force is required")
+	}
+	if !replaceableStatuses[original.Status] {
+		return fmt.Errorf("orders in '%s' state cannot be replaced", original.Status)
+	}
+	if len(r.ClientOrderID) > 128 {
+		return errors.New("client_order_id must be at most 128 characters")
+	}
+
+	fields := []struct{ name, value string }{
+		{"qty", r.Qty},
+		{"limit_price", r.LimitPrice},
+		{"stop_price", r.StopPrice},
+		{"trail", r.Trail},
+	}
+	for _, f := range fields {
+		if err := validatePositive(f.name, f.value); err != nil {
+			return err
+		}
+	}
+
+	orderType := original.Type
+	if r.LimitPrice != "" && orderType != OrderTypeLimit && orderType != OrderTypeStopLimit {
+		return fmt.Errorf("limit_price cannot be changed on %s orders", orderType)
+	}
+	if r.StopPrice != "" && orderType != OrderTypeStop && orderType != OrderTypeStopLimit {
+		return fmt.Errorf("stop_price cannot be changed on %s orders", orderType)
+	}
+	if r.Trail != "" && orderType != OrderTypeTrailingStop {
+		return fmt.Errorf("trail cannot be changed on %s orders", orderType)
+	}
+
+	if r.Qty != "" {
+		if original.Qty == nil {
+			return errors.New("qty cannot be changed on notional orders")
+		}
+		// The replacement cannot take back shares that have already traded
+		if filled := parseFloat(original.FilledQty); parseFloat(r.Qty) <= filled {
+			return fmt.Errorf("qty must be greater than the filled quantity %s", original.FilledQty)
+		}
+	}
+
+	if r.TimeInForce != "" {
+		if !validTimeInForce[r.TimeInForce] {
+			return fmt.Errorf("invalid time_in_force '%s'", r.TimeInForce)
+		}
+		dayOrGTC := r.TimeInForce == "day" || r.TimeInForce == "gtc"
+		if orderType == OrderTypeTrailingStop && !dayOrGTC {
+			return errors.New("trailing_stop orders only support day or gtc time_in_force")
+		}
+		if original.OrderClass != "" && original.OrderClass != OrderClassSimple && !dayOrGTC {
+			return fmt.Errorf("%s orders only support day or gtc time_in_force", original.OrderClass)
+		}
+		if original.Notional != nil && r.TimeInForce != "day" {
+			return errors.New("notional orders only support day time_in_force")
+		}
+	}
+
+	return nil
+}
+
+// workingOrder describes a broker order as the request that would place it
+func workingOrder(order *broker.Order) OrderRequest {
+	working := OrderRequest{
+		Symbol:      order.Symbol,
+		Side:        order.Side,
+		Type:        order.Type,
+		TimeInForce: order.TimeInForce,
+	}
+	if order.Qty != nil {
+		working.Qty = *order.Qty
+	}
+	if order.Notional != nil {
+		working.Notional = *order.Notional
+	}
+	if order.LimitPrice != nil {
+		working.LimitPrice = *order.LimitPrice
+	}
+	if order.StopPrice != nil {
+		working.StopPrice = *order.StopPrice
+	}
+	return working
+}
+
+// ReplacedOrder is the order as it will stand once the replace is accepted: the
+// original with the changed fields applied. The pre-trade risk checks evaluate it in
+// full, and measure the exposure it adds against the working order.
+func (r *ReplaceOrderRequest) ReplacedOrder(original *broker.Order) OrderRequest {
+	order := workingOrder(original)
+	if r.Qty != "" {
+		order.Qty = r.Qty
+	}
+	if r.LimitPrice != "" {
+		order.LimitPrice = r.LimitPrice
+	}
+	if r.StopPrice != "" {
+		order.StopPrice = r.StopPrice
+	}
+	if r.TimeInForce != "" {
+		order.TimeInForce = r.TimeInForce
+	}
+	return order
+}
+
+// validatePositive checks that an optional decimal field is a positive number.
+// ParseFloat accepts NaN and Inf, which are never a valid quantity or price.
+func validatePositive(name, value string) error {
+	if value == "" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Errorf("%s must be a number", name)
+	}
+	if v <= 0 {
+		return fmt.Errorf("%s must be greater than zero", name)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
+)
+
+const (
+	// defaultOrderListLimit is the page size when the client does not ask for one
+	defaultOrderListLimit = 50
+	// maxOrderListLimit caps a single page; larger pages span several broker requests
+	maxOrderListLimit = 1000
+	// brokerOrderPageSize is the most orders the broker returns per request
+	brokerOrderPageSize = 500
+	// maxBrokerPagesPerList bounds the broker requests made for one page of results
+	maxBrokerPagesPerList = 10
+)
+
+// OrderListResponse is the body returned by GetOrders. NextCursor is null on the last page.
+type OrderListResponse struct {
+	Orders     []broker.Order `json:"orders"`
+	NextCursor *string        `json:"next_cursor"`
+	Count      int            `json:"count"`
+}
+
+// OrderListQuery holds the filters accepted by GetOrders
+type OrderListQuery struct {
+	Status    string
+	Symbols   []string
+	Side      string
+	After     time.Time
+	Until     time.Time
+	Direction string
+	Limit     int
+	Nested    bool
+	Cursor    *orderCursor
+}
+
+// orderCursor marks where the previous page stopped: the creation time of its last
+// order and the IDs already returned at that instant, so orders sharing a timestamp
+// are neither skipped nor repeated.
+type orderCursor struct {
+	Direction string    `json:"d"`
+	CreatedAt time.Time `json:"t"`
+	IDs       []string  `json:"ids"`
+}
+
+func (c *orderCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeOrderCursor(value string) (*orderCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var cursor orderCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.CreatedAt.IsZero() {
+		return nil, errors.New("invalid cursor")
+	}
+	return &cursor, nil
+}
+
+// parseOrderListQuery reads and validates the GetOrders query string
+func parseOrderListQuery(c *gin.Context) (OrderListQuery, error) {
+	q := OrderListQuery{
+		Status:    strings.ToLower(c.DefaultQuery("status", "open")),
+		Side:      strings.ToLower(c.Query("side")),
+		Direction: strings.ToLower(c.DefaultQuery("direction", "desc")),
+		Limit:     defaultOrderListLimit,
+	}
+
+	if q.Status != "open" && q.Status != "closed" && q.Status != "all" {
+		return q, errors.New("status must be 'open', 'closed' or 'all'")
+	}
+	if q.Side != "" && q.Side != "buy" && q.Side != "sell" {
+		return q, errors.New("side must be 'buy' or 'sell'")
+	}
+	if q.Direction != "asc" && q.Direction != "desc" {
+		return q, errors.New("direction must be 'asc' or 'desc'")
+	}
+
+	if symbols := c.Query("symbols"); symbols != "" {
+		for _, symbol := range strings.Split(symbols, ",") {
+			if symbol = strings.ToUpper(strings.TrimSpace(symbol)); symbol != "" {
+				q.Symbols = append(q.Symbols, symbol)
+			}
+		}
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxOrderListLimit {
+			return q, fmt.Errorf("limit must be between 1 and %d", maxOrderListLimit)
+		}
+		q.Limit = n
+	}
+
+	if nested := c.Query("nested"); nested != "" {
+		b, err := strconv.ParseBool(nested)
+		if err != nil {
+			return q, errors.New("nested must be true or false")
+		}
+		q.Nested = b
+	}
+
+	var err error
+	if q.After, err = parseTimestamp(c.Query("after")); err != nil {
+		return q, errors.New("after must be an RFC3339 timestamp or a YYYY-MM-DD date")
+	}
+	if q.Until, err = parseTimestamp(c.Query("until")); err != nil {
+		return q, errors.New("until must be an RFC3339 timestamp or a YYYY-MM-DD date")
+	}
+	if !q.After.IsZero() && !q.Until.IsZero() && !q.After.Before(q.Until) {
+		return q, errors.New("after must be before until")
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		if q.Cursor, err = decodeOrderCursor(cursor); err != nil {
+			return q, err
+		}
+		if q.Cursor.Direction != q.Direction {
+			return q, errors.New("cursor was issued for a different direction")
+		}
+	}
+
+	return q, nil
+}
+
+// parseTimestamp accepts RFC3339 timestamps or plain dates; empty means unset
+func parseTimestamp(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// listOrdersPage collects one page of orders, walking the broker's created_at window
+// across as many broker requests as the page needs
+func listOrdersPage(ctx context.Context, accountID string, q OrderListQuery) (*OrderListResponse, error) {
+	params := broker.ListOrdersParams{
+		Status:    q.Status,
+		Limit:     brokerOrderPageSize,
+		After:     q.After,
+		Until:     q.Until,
+		Direction: q.Direction,
+		Nested:    q.Nested,
+		Symbols:   q.Symbols,
+		Side:      q.Side,
+	}
+
+	// The broker's bounds are exclusive, so each window is widened by a nanosecond to
+	// include the boundary instant and the orders already seen there are skipped
+	seen := map[string]bool{}
+	if q.Cursor != nil {
+		params = advanceOrderWindow(params, q.Cursor.CreatedAt)
+		for _, id := range q.Cursor.IDs {
+			seen[id] = true
+		}
+	}
+
+	orders := make([]broker.Order, 0, q.Limit)
+	exhausted := false
+	for page := 0; page < maxBrokerPagesPerList && len(orders) <= q.Limit; page++ {
+		batch, err := brokerClient.ListOrders(ctx, accountID, params)
+		if err != nil {
+			return nil, err
+		}
+		added := 0
+		for _, order := range batch {
+			if !seen[order.ID] {
+				orders = append(orders, order)
+				added++
+			}
+		}
+		// A short page is the end; so is a full page of orders already returned,
+		// which only happens when more than a page shares one instant
+		if len(batch) < brokerOrderPageSize || added == 0 {
+			exhausted = true
+			break
+		}
+
+		boundary := batch[len(batch)-1].CreatedAt
+		next := map[string]bool{}
+		for _, order := range batch {
+			if order.CreatedAt.Equal(boundary) {
+				next[order.ID] = true
+			}
+		}
+		seen = next
+		params = advanceOrderWindow(params, boundary)
+	}
+
+	hasMore := len(orders) > q.Limit || !exhausted && len(orders) > 0
+	if len(orders) > q.Limit {
+		orders = orders[:q.Limit]
+	}
+	response := &OrderListResponse{Orders: orders, Count: len(orders)}
+	if !hasMore {
+		return response, nil
+	}
+
+	last := response.Orders[len(response.Orders)-1]
+	cursor := &orderCursor{Direction: q.Direction, CreatedAt: last.CreatedAt}
+	for _, order := range response.Orders {
+		if order.CreatedAt.Equal(last.CreatedAt) {
+			cursor.IDs = append(cursor.IDs, order.ID)
+		}
+	}
+	if q.Cursor != nil && q.Cursor.CreatedAt.Equal(last.CreatedAt) {
+		cursor.IDs = append(cursor.IDs, q.Cursor.IDs...)
+	}
+	next := cursor.encode()
+	response.NextCursor = &next
+	return response, nil
+}
+
+// advanceOrderWindow moves the listing window past boundary in the listing direction,
+// keeping the boundary instant itself in range
+func advanceOrderWindow(params broker.ListOrdersParams, boundary time.Time) broker.ListOrdersParams {
+	if params.Direction == "asc" {
+		params.After = boundary.Add(-time.Nanosecond)
+	} else {
+		params.Until = boundary.Add(time.Nanosecond)
+	}
+	return params
+}
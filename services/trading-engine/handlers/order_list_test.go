@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seunghoon34/trading-app/pkg/broker"
+)
+
+// pagedBroker serves ListOrders from memory with the broker's exclusive after/until
+// bounds and page cap; every other method comes from the embedded nil interface
+type pagedBroker struct {
+	broker.Broker
+	orders []broker.Order
+	calls  int
+}
+
+func (b *pagedBroker) ListOrders(ctx context.Context, accountID string, params broker.ListOrdersParams) ([]broker.Order, error) {
+	b.calls++
+	var matches []broker.Order
+	for _, o := range b.orders {
+		if !params.After.IsZero() && !o.CreatedAt.After(params.After) {
+			continue
+		}
+		if !params.Until.IsZero() && !o.CreatedAt.Before(params.Until) {
+			continue
+		}
+		if params.Side != "" && o.Side != params.Side {
+			continue
+		}
+		matches = append(matches, o)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if params.Direction == "asc" {
+			return matches[i].CreatedAt.Before(matches[j].CreatedAt)
+		}
+		return matches[i].CreatedAt.After(matches[j].CreatedAt)
+	})
+	if len(matches) > params.Limit {
+		matches = matches[:params.Limit]
+	}
+	return matches, nil
+}
+
+// newPagedBroker creates n orders, three per second, so page boundaries land on ties
+func newPagedBroker(n int) *pagedBroker {
+	start := time.Date(2024, 1, 2, 14, 30, 0, 0, time.UTC)
+	b := &pagedBroker{}
+	for i := 0; i < n; i++ {
+		side := "buy"
+		if i%2 == 1 {
+			side = "sell"
+		}
+		b.orders = append(b.orders, broker.Order{
+			ID:        fmt.Sprintf("order-%04d", i),
+			Side:      side,
+			CreatedAt: start.Add(time.Duration(i/3) * time.Second),
+		})
+	}
+	return b
+}
+
+func useBroker(t *testing.T, b broker.Broker) {
+	t.Helper()
+	previous := brokerClient
+	SetBroker(b)
+	t.Cleanup(func() { SetBroker(previous) })
+}
+
+func TestListOrdersPageWalksEveryOrderOnce(t *testing.T) {
+	for _, direction := range []string{"asc", "desc"} {
+		t.Run(direction, func(t *testing.T) {
+			b := newPagedBroker(1300)
+			useBroker(t, b)
+
+			seen := map[string]bool{}
+			var cursor *orderCursor
+			var last time.Time
+			for pages := 0; ; pages++ {
+				if pages > 10 {
+					t.Fatalf("pagination did not terminate")
+				}
+				page, err := listOrdersPage(context.Background(), "acct", OrderListQuery{Status: "all", Direction: direction, Limit: 400, Cursor: cursor})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if page.Count != len(page.Orders) {
+					t.Fatalf("count %d does not match %d orders", page.Count, len(page.Orders))
+				}
+				for _, o := range page.Orders {
+					if seen[o.ID] {
+						t.Fatalf("order %s returned twice", o.ID)
+					}
+					seen[o.ID] = true
+					if !last.IsZero() && (direction == "asc" && o.CreatedAt.Before(last) || direction == "desc" && o.CreatedAt.After(last)) {
+						t.Fatalf("order %s out of order", o.ID)
+					}
+					last = o.CreatedAt
+				}
+				if page.NextCursor == nil {
+					break
+				}
+				if cursor, err = decodeOrderCursor(*page.NextCursor); err != nil {
+					t.Fatalf("invalid cursor: %v", err)
+				}
+			}
+
+			if len(seen) != 1300 {
+				t.Errorf("expected all 1300 orders, got %d", len(seen))
+			}
+		})
+	}
+}
+
+func TestListOrdersPageSpansBrokerPages(t *testing.T) {
+	b := newPagedBroker(1200)
+	useBroker(t, b)
+
+	page, err := listOrdersPage(context.Background(), "acct", OrderListQuery{Status: "all", Direction: "desc", Limit: maxOrderListLimit})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.Count != maxOrderListLimit || page.NextCursor == nil {
+		t.Errorf("expected a full page with a cursor, got %d orders", page.Count)
+	}
+	if b.calls != 3 {
+		t.Errorf("expected 3 broker requests, got %d", b.calls)
+	}
+}
+
+func TestListOrdersPageLastPage(t *testing.T) {
+	useBroker(t, newPagedBroker(20))
+
+	page, err := listOrdersPage(context.Background(), "acct", OrderListQuery{Status: "all", Direction: "desc", Limit: 50, Side: "sell"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.Count != 10 || page.NextCursor != nil {
+		t.Errorf("expected 10 sell orders and no cursor, got %d (cursor %v)", page.Count, page.NextCursor)
+	}
+}
+
+func TestParseOrderListQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ascCursor := (&orderCursor{Direction: "asc", CreatedAt: time.Now()}).encode()
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+		check   func(t *testing.T, q OrderListQuery)
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			check: func(t *testing.T, q OrderListQuery) {
+				if q.Status != "open" || q.Direction != "desc" || q.Limit != defaultOrderListLimit || q.Nested {
+					t.Errorf("unexpected defaults: %+v", q)
+				}
+			},
+		},
+		{
+			name:  "all filters",
+			query: "status=closed&symbols=aapl,%20msft&side=BUY&after=2024-01-02&until=2024-02-01T00:00:00Z&direction=asc&limit=200&nested=true",
+			check: func(t *testing.T, q OrderListQuery) {
+				if q.Status != "closed" || q.Side != "buy" || q.Direction != "asc" || q.Limit != 200 || !q.Nested {
+					t.Errorf("unexpected query: %+v", q)
+				}
+				if len(q.Symbols) != 2 || q.Symbols[1] != "MSFT" {
+					t.Errorf("unexpected symbols: %v", q.Symbols)
+				}
+				if !q.After.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+					t.Errorf("unexpected after: %v", q.After)
+				}
+			},
+		},
+		{name: "bad status", query: "status=pending", wantErr: "status must be"},
+		{name: "limit too large", query: "limit=5000", wantErr: "limit must be between"},
+		{name: "bad timestamp", query: "after=yesterday", wantErr: "after must be"},
+		{name: "inverted window", query: "after=2024-02-01&until=2024-01-01", wantErr: "after must be before until"},
+		{name: "garbage cursor", query: "cursor=!!!", wantErr: "invalid cursor"},
+		{name: "cursor direction mismatch", query: "cursor=" + ascCursor, wantErr: "different direction"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/orders?"+tt.query, nil)
+
+			q, err := parseOrderListQuery(c)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.check(t, q)
+		})
+	}
+}
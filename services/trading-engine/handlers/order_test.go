@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/seunghoon34/trading-app/pkg/broker"
+)
+
+func TestOrderRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		order   OrderRequest
+		wantErr string
+	}{
+		{
+			name:  "default market order",
+			order: OrderRequest{Side: "buy", Symbol: "aapl", Qty: "1"},
+		},
+		{
+			name:  "queue when closed",
+			order: OrderRequest{Side: "buy", Symbol: "AAPL", Qty: "1", MarketHoursPolicy: "Queue"},
+		},
+		{
+			name:    "unknown market hours policy",
+			order:   OrderRequest{Side: "buy", Symbol: "AAPL", Qty: "1", MarketHoursPolicy: "wait"},
+			wantErr: "market_hours_policy must be 'reject' or 'queue'",
+		},
+		{
+			name:  "limit gtc",
+			order: OrderRequest{Side: "sell", Symbol: "AAPL", Qty: "2", Type: "limit", TimeInForce: "gtc", LimitPrice: "190.50"},
+		},
+		{
+			name:    "limit without price",
+			order:   OrderRequest{Side: "buy", Symbol: "AAPL", Qty: "1", Type: "limit"},
+			wantErr: "limit orders require limit_price",
+		},
+		{
+			name:  "stop limit",
+			order: OrderRequest{Side: "sell", Symbol: "AAPL", Qty: "1", Type: "stop_limit", StopPrice: "180", LimitPrice: "179"},
+		},
+		{
+			name:    "stop limit missing limit",
+			order:   OrderRequest{Side: "sell", Symbol: "AAPL", Qty: "1", Type: "stop_limit", StopPrice: "180"},
+			wantErr: "require both stop_price and limit_price",
+		},
+		{
+			name:  "trailing percent",
+			order: OrderRequest{Side: "sell", Symbol: "AAPL", Qty: "1", Type: "trailing_stop", TrailPercent: "5"},
+		},
+		{
+			name:    "trailing with both trail fields",
+			order:   OrderRequest{Side: "sell", Symbol: "AAPL", Qty: "1", Type: "trailing_stop", TrailPercent: "5", TrailPrice: "2"},
+			wantErr: "exactly one of trail_price or trail_percent",
+		},
+		{
+			name:    "market with limit price",
+			order:   OrderRequest{Side: "buy", Symbol: "AAPL", Qty: "1", LimitPrice: "10"},
+			wantErr: "market orders do not accept",
+		},
+		{
+			name:    "qty and notional",
+			order:   OrderRequest{Side: "buy", Symbol: "AAPL", Qty: "1", Notional: "100"},
+			wantErr: "exactly one of qty or notional",
+		},
+		{
+			name:    "notional gtc",
+			order:   OrderRequest{Side: "buy", Symbol: "AAPL", Notional: "100", TimeInForce: "gtc"},
+			wantErr: "notional orders only support day",
+		},
+		{
+			name:    "bad time in force",
+			order:   OrderRequest{Side: "buy", Symbol: "AAPL", Qty: "1", TimeInForce: "week"},
+			wantErr: "invalid time_in_force",
+		},
+		{
+			name:    "extended hours market",
+			order:   OrderRequest{Side: "buy", Symbol: "AAPL", Qty: "1", ExtendedHours: true},
+			wantErr: "extended_hours is only supported",
+		},
+		{
+			name:    "negative price",
+			order:   OrderRequest{Side: "buy", Symbol: "AAPL", Qty: "1", Type: "limit", LimitPrice: "-1"},
+			wantErr: "limit_price must be greater than zero",
+		},
+		{
+			name:    "NaN qty",
+			order:   OrderRequest{Side: "buy", Symbol: "AAPL", Qty: "NaN"},
+			wantErr: "qty must be a number",
+		},
+		{
+			name:    "infinite notional",
+			order:   OrderRequest{Side: "buy", Symbol: "AAPL", Notional: "Inf"},
+			wantErr: "notional must be a number",
+		},
+		{
+			name:    "infinite limit price",
+			order:   OrderRequest{Side: "buy", Symbol: "AAPL", Qty: "1", Type: "limit", LimitPrice: "+Inf"},
+			wantErr: "limit_price must be a number",
+		},
+		{
+			name:    "NaN stop price",
+			order:   OrderRequest{Side: "sell", Symbol: "AAPL", Qty: "1", Type: "stop", StopPrice: "nan"},
+			wantErr: "stop_price must be a number",
+		},
+		{
+			name:    "infinite trail price",
+			order:   OrderRequest{Side: "sell", Symbol: "AAPL", Qty: "1", Type: "trailing_stop", TrailPrice: "infinity"},
+			wantErr: "trail_price must be a number",
+		},
+		{
+			name:    "NaN trail percent",
+			order:   OrderRequest{Side: "sell", Symbol: "AAPL", Qty: "1", Type: "trailing_stop", TrailPercent: "NaN"},
+			wantErr: "trail_percent must be a number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.order.Normalize()
+			err := tt.order.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestOrderRequestMarshalOmitsEmptyFields(t *testing.T) {
+	order := OrderRequest{Side: "buy", Symbol: "AAPL", Qty: "1"}
+	order.Normalize()
+
+	data, err := json.Marshal(order)
+	if err != nil {
+		t.Fatalf("failed to marshal order: %v", err)
+	}
+
+	want := `{"side":"buy","symbol":"AAPL","qty":"1","type":"market","time_in_force":"day"}`
+	if string(data) != want {
+		t.Errorf("expected %s, got %s", want, string(data))
+	}
+}
+
+func TestOrderRequestValidateOrderClass(t *testing.T) {
+	tests := []struct {
+		name    string
+		order   OrderRequest
+		wantErr string
+	}{
+		{
+			name: "buy bracket",
+			order: OrderRequest{Side: "buy", Symbol: "AAPL", Qty: "1", OrderClass: "bracket",
+				TakeProfit: &TakeProfitLeg{LimitPrice: "210"}, StopLoss: &StopLossLeg{StopPrice: "180", LimitPrice: "179"}},
+		},
+		{
+			name: "buy bracket with stop above take profit",
+			order: OrderRequest{Side: "buy", Symbol: "AAPL", Qty: "1", OrderClass: "bracket",
+				TakeProfit: &TakeProfitLeg{LimitPrice: "180"}, StopLoss: &StopLossLeg{StopPrice: "210"}},
+			wantErr: "stop_loss.stop_price must be below take_profit.limit_price",
+		},
+		{
+			name: "sell bracket",
+			order: OrderRequest{Side: "sell", Symbol: "AAPL", Qty: "1", OrderClass: "bracket",
+				TakeProfit: &TakeProfitLeg{LimitPrice: "180"}, StopLoss: &StopLossLeg{StopPrice: "210"}},
+		},
+		{
+			name: "limit bracket with take profit below entry",
+			order: OrderRequest{Side: "buy", Symbol: "AAPL", Qty: "1", Type: "limit", LimitPrice: "200", OrderClass: "bracket",
+				TakeProfit: &TakeProfitLeg{LimitPrice: "195"}, StopLoss: &StopLossLeg{StopPrice: "180"}},
+			wantErr: "take_profit.limit_price must be above the entry",
+		},
+		{
+			name: "bracket missing stop loss",
+			order: OrderRequest{Side: "buy", Symbol: "AAPL", Qty: "1", OrderClass: "bracket",
+				TakeProfit: &TakeProfitLeg{LimitPrice: "210"}},
+			wantErr: "require both take_profit and stop_loss",
+		},
+		{
+			name: "sell oco protecting a long",
+			order: OrderRequest{Side: "sell", Symbol: "AAPL", Qty: "1", Type: "limit", LimitPrice: "210", OrderClass: "oco",
+				TakeProfit: &TakeProfitLeg{LimitPrice: "210"}, StopLoss: &StopLossLeg{StopPrice: "180"}},
+		},
+		{
+			name: "oco market order",
+			order: OrderRequest{Side: "sell", Symbol: "AAPL", Qty: "1", OrderClass: "oco",
+				TakeProfit: &TakeProfitLeg{LimitPrice: "210"}, StopLoss: &StopLossLeg{StopPrice: "180"}},
+			wantErr: "oco orders must be limit orders",
+		},
+		{
+			name: "oto with stop loss only",
+			order: OrderRequest{Side: "buy", Symbol: "AAPL", Qty: "1", OrderClass: "oto",
+				StopLoss: &StopLossLeg{StopPrice: "180"}},
+		},
+		{
+			name: "oto with both legs",
+			order: OrderRequest{Side: "buy", Symbol: "AAPL", Qty: "1", OrderClass: "oto",
+				TakeProfit: &TakeProfitLeg{LimitPrice: "210"}, StopLoss: &StopLossLeg{StopPrice: "180"}},
+			wantErr: "exactly one of take_profit or stop_loss",
+		},
+		{
+			name: "legs without order class",
+			order: OrderRequest{Side: "buy", Symbol: "AAPL", Qty: "1",
+				TakeProfit: &TakeProfitLeg{LimitPrice: "210"}},
+			wantErr: "require an order_class",
+		},
+		{
+			name: "notional bracket",
+			order: OrderRequest{Side: "buy", Symbol: "AAPL", Notional: "100", OrderClass: "bracket",
+				TakeProfit: &TakeProfitLeg{LimitPrice: "210"}, StopLoss: &StopLossLeg{StopPrice: "180"}},
+			wantErr: "notional orders cannot use",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.order.Normalize()
+			err := tt.order.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReplaceOrderRequestValidate(t *testing.T) {
+	qty := "10"
+	limitOrder := &broker.Order{Type: "limit", Status: "new", Qty: &qty, FilledQty: "4", TimeInForce: "day"}
+	trailingOrder := &broker.Order{Type: "trailing_stop", Status: "new", Qty: &qty, FilledQty: "0"}
+	notional := "500"
+	notionalOrder := &broker.Order{Type: "market", Status: "accepted", Notional: &notional, FilledQty: "0"}
+	bracketOrder := &broker.Order{Type: "limit", Status: "new", Qty: &qty, FilledQty: "0", OrderClass: "bracket"}
+
+	tests := []struct {
+		name     string
+		original *broker.Order
+		change   ReplaceOrderRequest
+		wantErr  string
+	}{
+		{
+			name:     "move limit",
+			original: limitOrder,
+			change:   ReplaceOrderRequest{LimitPrice: "191.25"},
+		},
+		{
+			name:     "grow qty and change tif",
+			original: limitOrder,
+			change:   ReplaceOrderRequest{Qty: "12", TimeInForce: "GTC"},
+		},
+		{
+			name:     "nothing to change",
+			original: limitOrder,
+			change:   ReplaceOrderRequest{ClientOrderID: "abc"},
+			wantErr:  "at least one of",
+		},
+		{
+			name:     "filled order",
+			original: &broker.Order{Type: "limit", Status: "filled", Qty: &qty},
+			change:   ReplaceOrderRequest{LimitPrice: "190"},
+			wantErr:  "orders in 'filled' state cannot be replaced",
+		},
+		{
+			name:     "stop price on limit order",
+			original: limitOrder,
+			change:   ReplaceOrderRequest{StopPrice: "180"},
+			wantErr:  "stop_price cannot be changed on limit orders",
+		},
+		{
+			name:     "trail on limit order",
+			original: limitOrder,
+			change:   ReplaceOrderRequest{Trail: "2"},
+			wantErr:  "trail cannot be changed on limit orders",
+		},
+		{
+			name:     "qty below filled",
+			original: limitOrder,
+			change:   ReplaceOrderRequest{Qty: "4"},
+			wantErr:  "greater than the filled quantity",
+		},
+		{
+			name:     "negative limit",
+			original: limitOrder,
+			change:   ReplaceOrderRequest{LimitPrice: "-1"},
+			wantErr:  "limit_price must be greater than zero",
+		},
+		{
+			name:     "trail on trailing stop",
+			original: trailingOrder,
+			change:   ReplaceOrderRequest{Trail: "1.5"},
+		},
+		{
+			name:     "trailing stop ioc",
+			original: trailingOrder,
+			change:   ReplaceOrderRequest{TimeInForce: "ioc"},
+			wantErr:  "trailing_stop orders only support day or gtc",
+		},
+		{
+			name:     "qty on notional order",
+			original: notionalOrder,
+			change:   ReplaceOrderRequest{Qty: "2"},
+			wantErr:  "qty cannot be changed on notional orders",
+		},
+		{
+			name:     "bracket fok",
+			original: bracketOrder,
+			change:   ReplaceOrderRequest{TimeInForce: "fok"},
+			wantErr:  "bracket orders only support day or gtc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.change.Normalize()
+			err := tt.change.Validate(tt.original)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReplaceOrderRequestReplacedOrder(t *testing.T) {
+	qty, limit := "10", "190"
+	original := &broker.Order{Symbol: "AAPL", Side: "buy", Type: "limit", TimeInForce: "gtc", Qty: &qty, LimitPrice: &limit}
+
+	replaced := (&ReplaceOrderRequest{Qty: "15", LimitPrice: "195"}).ReplacedOrder(original)
+	if replaced.Qty != "15" || replaced.LimitPrice != "195" || replaced.Symbol != "AAPL" || replaced.Side != "buy" || replaced.TimeInForce != "gtc" {
+		t.Errorf("unexpected replaced order: %+v", replaced)
+	}
+
+	// A price change alone keeps the working quantity, so it is still checked in full
+	replaced = (&ReplaceOrderRequest{LimitPrice: "200"}).ReplacedOrder(original)
+	if replaced.Qty != "10" || replaced.LimitPrice != "200" {
+		t.Errorf("unexpected replaced order: %+v", replaced)
+	}
+
+	if working := workingOrder(original); working.Qty != "10" || working.LimitPrice != "190" || working.Notional != "" {
+		t.Errorf("unexpected working order: %+v", working)
+	}
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/seunghoon34/trading-app/services/trading-engine/internal/logger"
+	"github.com/seunghoon34/trading-app/services/trading-engine/internal/marketdata"
+	"github.com/seunghoon34/trading-app/services/trading-engine/internal/risk"
+)
+
+var (
+	riskConfig = &risk.Config{}
+	riskChain  = risk.DefaultChain()
+)
+
+// InitRiskChecks loads the pre-trade risk rules used by CreateOrder
+func InitRiskChecks() error {
+	config, err := risk.LoadConfig()
+	if err != nil {
+		return err
+	}
+	riskConfig = config
+	return nil
+}
+
+func parseFloat(value string) float64 {
+	f, _ := strconv.ParseFloat(value, 64)
+	return f
+}
+
+// riskOrder parses the order's decimal fields for the risk checks
+func riskOrder(order OrderRequest) risk.Order {
+	return risk.Order{
+		Symbol:     order.Symbol,
+		Side:       order.Side,
+		Type:       order.Type,
+		Qty:        parseFloat(order.Qty),
+		Notional:   parseFloat(order.Notional),
+		LimitPrice: parseFloat(order.LimitPrice),
+		StopPrice:  parseFloat(order.StopPrice),
+	}
+}
+
+// buildRiskContext pulls the account balances and positions and estimates a price for the order
+func buildRiskContext(ctx context.Context, accountID string, order OrderRequest) (*risk.Context, error) {
+	account, err := brokerClient.GetAccount(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch account: %w", err)
+	}
+
+	positions, err := brokerClient.ListPositions(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch positions: %w", err)
+	}
+
+	riskCtx := &risk.Context{
+		AccountID: accountID,
+		Order:     riskOrder(order),
+		Account: risk.Account{
+			BuyingPower: parseFloat(account.BuyingPower),
+			Equity:      parseFloat(account.Equity),
+			LastEquity:  parseFloat(account.LastEquity),
+		},
+		Positions: make(map[string]risk.Position, len(positions)),
+	}
+
+	for _, p := range positions {
+		riskCtx.Positions[p.Symbol] = risk.Position{
+			Symbol:       p.Symbol,
+			Qty:          parseFloat(p.Quantity),
+			MarketValue:  parseFloat(p.MarketValue),
+			CurrentPrice: parseFloat(p.CurrentPrice),
+		}
+	}
+
+	riskCtx.Asset = lookupAsset(accountID, order.Symbol)
+	riskCtx.Price = estimatePrice(riskCtx)
+	return riskCtx, nil
+}
+
+// lookupAsset asks the asset master about the symbol. When it cannot be reached the
+// asset checks are skipped and the broker remains the final word.
+func lookupAsset(accountID, symbol string) *risk.Asset {
+	asset, err := marketdata.GetAsset(symbol)
+	if errors.Is(err, marketdata.ErrNotFound) {
+		return &risk.Asset{Listed: false}
+	}
+	if err != nil {
+		logger.WithFields(map[string]interface{}{
+			"account_id": accountID,
+			"symbol":     symbol,
+			"error":      err.Error(),
+			"action":     "asset_lookup_failed",
+		}).Warn("Asset lookup for risk checks failed")
+		return nil
+	}
+	return &risk.Asset{
+		Listed:       true,
+		Tradable:     asset.Tradable && asset.Status == "active",
+		Fractionable: asset.Fractionable,
+		Shortable:    asset.Shortable,
+	}
+}
+
+// estimatePrice prefers the order's own limit or stop price, then the held position's
+// mark, and finally the latest quote from the market-data service
+func estimatePrice(ctx *risk.Context) float64 {
+	if ctx.Order.LimitPrice > 0 {
+		return ctx.Order.LimitPrice
+	}
+	if ctx.Order.StopPrice > 0 {
+		return ctx.Order.StopPrice
+	}
+	if position, ok := ctx.Positions[ctx.Order.Symbol]; ok && position.CurrentPrice > 0 {
+		return position.CurrentPrice
+	}
+
+	price, err := marketdata.LatestPrice(ctx.Order.Symbol, ctx.Order.Side)
+	if err != nil {
+		logger.WithFields(map[string]interface{}{
+			"account_id": ctx.AccountID,
+			"symbol":     ctx.Order.Symbol,
+			"error":      err.Error(),
+			"action":     "risk_price_lookup_failed",
+		}).Warn("Price lookup for risk checks failed")
+		return 0
+	}
+	return price
+}
+
+// checkOrderRisk runs the pre-trade risk chain for the account
+func checkOrderRisk(ctx context.Context, accountID string, order OrderRequest) (*risk.Rejection, error) {
+	return checkReplaceRisk(ctx, accountID, order, nil)
+}
+
+// checkReplaceRisk runs the pre-trade risk chain for an order replacing the working
+// order replaces, or for a new order when replaces is nil
+func checkReplaceRisk(ctx context.Context, accountID string, order OrderRequest, replaces *OrderRequest) (*risk.Rejection, error) {
+	riskCtx, err := buildRiskContext(ctx, accountID, order)
+	if err != nil {
+		return nil, err
+	}
+	if replaces != nil {
+		working := riskOrder(*replaces)
+		riskCtx.Replaces = &working
+	}
+	return riskChain.Evaluate(riskCtx, riskConfig.RulesFor(accountID)), nil
+}
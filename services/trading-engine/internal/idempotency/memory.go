@@ -0,0 +1,74 @@
+// file: trading-engine/internal/idempotency/memory.go
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	record    Record
+	expiresAt time.Time
+}
+
+// MemoryStore keeps idempotency keys in process memory. It is only suitable
+// for a single trading-engine instance; use RedisStore when running replicas.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	now     func() time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]memoryEntry),
+		now:     time.Now,
+	}
+}
+
+func (m *MemoryStore) Reserve(ctx context.Context, key, requestHash string, ttl time.Duration) (*Record, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.evictExpired(now)
+
+	if entry, ok := m.entries[key]; ok {
+		record := entry.record
+		return &record, false, nil
+	}
+
+	m.entries[key] = memoryEntry{
+		record:    Record{RequestHash: requestHash, CreatedAt: now},
+		expiresAt: now.Add(ttl),
+	}
+	return nil, true, nil
+}
+
+func (m *MemoryStore) Complete(ctx context.Context, key string, record Record, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record.Completed = true
+	m.entries[key] = memoryEntry{record: record, expiresAt: m.now().Add(ttl)}
+	return nil
+}
+
+func (m *MemoryStore) Release(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, key)
+	return nil
+}
+
+// evictExpired drops expired keys; callers must hold the lock
+func (m *MemoryStore) evictExpired(now time.Time) {
+	for key, entry := range m.entries {
+		if now.After(entry.expiresAt) {
+			delete(m.entries, key)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreLifecycle(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	existing, reserved, err := store.Reserve(ctx, "acct:key-1", "hash-a", time.Minute)
+	if err != nil || !reserved || existing != nil {
+		t.Fatalf("expected first reservation to succeed, got reserved=%v existing=%v err=%v", reserved, existing, err)
+	}
+
+	// A concurrent retry sees the in-flight reservation
+	existing, reserved, _ = store.Reserve(ctx, "acct:key-1", "hash-a", time.Minute)
+	if reserved || existing == nil || existing.Completed {
+		t.Fatalf("expected in-flight record, got reserved=%v existing=%+v", reserved, existing)
+	}
+
+	err = store.Complete(ctx, "acct:key-1", Record{RequestHash: "hash-a", StatusCode: 200, Body: []byte(`{"id":"1"}`)}, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to complete: %v", err)
+	}
+
+	existing, reserved, _ = store.Reserve(ctx, "acct:key-1", "hash-b", time.Minute)
+	if reserved || existing == nil || !existing.Completed {
+		t.Fatalf("expected completed record, got reserved=%v existing=%+v", reserved, existing)
+	}
+	if existing.RequestHash != "hash-a" || string(existing.Body) != `{"id":"1"}` {
+		t.Errorf("unexpected stored record: %+v", existing)
+	}
+
+	if err := store.Release(ctx, "acct:key-1"); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+	if _, reserved, _ = store.Reserve(ctx, "acct:key-1", "hash-b", time.Minute); !reserved {
+		t.Errorf("expected key to be reusable after release")
+	}
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	store.Complete(ctx, "acct:key-1", Record{RequestHash: "hash-a", StatusCode: 200}, time.Hour)
+
+	now = now.Add(2 * time.Hour)
+	if _, reserved, _ := store.Reserve(ctx, "acct:key-1", "hash-b", time.Minute); !reserved {
+		t.Errorf("expected expired key to be reserved again")
+	}
+}
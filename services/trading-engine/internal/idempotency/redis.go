@@ -0,0 +1,77 @@
+// file: trading-engine/internal/idempotency/redis.go
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	redisKeyPrefix = "idempotency:"
+	// reserveAttempts bounds the SETNX/GET retries in Reserve
+	reserveAttempts = 5
+)
+
+// RedisStore keeps idempotency keys in Redis so that every trading-engine replica sees them
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore connects to the Redis server at addr
+func NewRedisStore(addr string) *RedisStore {
+	return &RedisStore{
+		client: redis.NewClient(&redis.Options{Addr: addr}),
+	}
+}
+
+func (r *RedisStore) Reserve(ctx context.Context, key, requestHash string, ttl time.Duration) (*Record, bool, error) {
+	data, err := json.Marshal(Record{RequestHash: requestHash, CreatedAt: time.Now()})
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Another client may take or release the key between SETNX and GET, so retry
+	// until we either hold the key or can read whoever does
+	for attempt := 0; attempt < reserveAttempts; attempt++ {
+		reserved, err := r.client.SetNX(ctx, redisKeyPrefix+key, data, ttl).Result()
+		if err != nil {
+			return nil, false, err
+		}
+		if reserved {
+			return nil, true, nil
+		}
+
+		existing, err := r.client.Get(ctx, redisKeyPrefix+key).Bytes()
+		if err == redis.Nil {
+			// The key expired between SETNX and GET
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		var record Record
+		if err := json.Unmarshal(existing, &record); err != nil {
+			return nil, false, err
+		}
+		return &record, false, nil
+	}
+	return nil, false, fmt.Errorf("idempotency key %q changed hands %d times while reserving it", key, reserveAttempts)
+}
+
+func (r *RedisStore) Complete(ctx context.Context, key string, record Record, ttl time.Duration) error {
+	record.Completed = true
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, redisKeyPrefix+key, data, ttl).Err()
+}
+
+func (r *RedisStore) Release(ctx context.Context, key string) error {
+	return r.client.Del(ctx, redisKeyPrefix+key).Err()
+}
@@ -0,0 +1,132 @@
+package idempotency
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedRedis answers SET and GET from queued replies, so a test can stage the key
+// changing hands between the two calls. Replies are raw RESP; an empty queue answers nil.
+type scriptedRedis struct {
+	mu      sync.Mutex
+	replies map[string][]string
+	calls   []string
+}
+
+func (s *scriptedRedis) reply(command string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, command)
+	queue := s.replies[command]
+	if len(queue) == 0 {
+		return "$-1\r\n"
+	}
+	s.replies[command] = queue[1:]
+	return queue[0]
+}
+
+func (s *scriptedRedis) serve(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func (s *scriptedRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		if _, err := conn.Write([]byte(s.reply(strings.ToUpper(args[0])))); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand parses one RESP array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func bulk(value string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+}
+
+func TestRedisStoreReserveRetriesWhenKeyExpiresBeforeGet(t *testing.T) {
+	ctx := context.Background()
+
+	// SETNX loses, the key expires before GET, SETNX loses again to a new holder
+	// and GET finally reads that holder's record
+	server := &scriptedRedis{replies: map[string][]string{
+		"SET": {"$-1\r\n", "$-1\r\n"},
+		"GET": {"$-1\r\n", bulk(`{"request_hash":"hash-b"}`)},
+	}}
+	store := NewRedisStore(server.serve(t))
+
+	existing, reserved, err := store.Reserve(ctx, "acct:key-1", "hash-a", time.Minute)
+	if err != nil || reserved || existing == nil {
+		t.Fatalf("expected the new holder's record, got reserved=%v existing=%v err=%v", reserved, existing, err)
+	}
+	if existing.RequestHash != "hash-b" {
+		t.Errorf("unexpected record %+v", existing)
+	}
+	if got := strings.Join(server.calls, " "); got != "SET GET SET GET" {
+		t.Errorf("unexpected command sequence %s", got)
+	}
+
+	// The retried SETNX wins once the key has expired
+	server = &scriptedRedis{replies: map[string][]string{"SET": {"$-1\r\n", "+OK\r\n"}}}
+	store = NewRedisStore(server.serve(t))
+	if existing, reserved, err := store.Reserve(ctx, "acct:key-1", "hash-a", time.Minute); err != nil || !reserved || existing != nil {
+		t.Errorf("expected the retry to reserve, got reserved=%v existing=%v err=%v", reserved, existing, err)
+	}
+
+	// A key that never settles is an error rather than a nil record
+	server = &scriptedRedis{replies: map[string][]string{}}
+	store = NewRedisStore(server.serve(t))
+	if existing, reserved, err := store.Reserve(ctx, "acct:key-1", "hash-a", time.Minute); err == nil || reserved || existing != nil {
+		t.Errorf("expected an error, got reserved=%v existing=%v err=%v", reserved, existing, err)
+	}
+}
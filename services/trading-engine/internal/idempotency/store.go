@@ -0,0 +1,68 @@
+// file: trading-engine/internal/idempotency/store.go
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"time"
+)
+
+// Record is what gets stored for an idempotency key
+type Record struct {
+	RequestHash string    `json:"request_hash"`
+	Completed   bool      `json:"completed"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	Body        []byte    `json:"body,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Store persists idempotency keys and the responses they produced
+type Store interface {
+	// Reserve claims key for a new request. If the key already exists, the
+	// existing record is returned and reserved is false.
+	Reserve(ctx context.Context, key, requestHash string, ttl time.Duration) (existing *Record, reserved bool, err error)
+	// Complete stores the final response for a reserved key
+	Complete(ctx context.Context, key string, record Record, ttl time.Duration) error
+	// Release drops a reservation so the request can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// Config controls how long keys are kept
+type Config struct {
+	// Retention is how long a completed response is replayed for
+	Retention time.Duration
+	// LockTimeout is how long an in-flight reservation blocks other requests with the same key
+	LockTimeout time.Duration
+}
+
+// LoadConfig reads IDEMPOTENCY_RETENTION and IDEMPOTENCY_LOCK_TIMEOUT, defaulting to 24h and 1m
+func LoadConfig() Config {
+	config := Config{
+		Retention:   24 * time.Hour,
+		LockTimeout: time.Minute,
+	}
+	if d, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_RETENTION")); err == nil && d > 0 {
+		config.Retention = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_LOCK_TIMEOUT")); err == nil && d > 0 {
+		config.LockTimeout = d
+	}
+	return config
+}
+
+// NewStore returns a Redis backed store when REDIS_ADDR is set, otherwise an in-memory store
+func NewStore() Store {
+	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
+		return NewRedisStore(addr)
+	}
+	return NewMemoryStore()
+}
+
+// HashRequest returns a stable fingerprint of a request body
+func HashRequest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
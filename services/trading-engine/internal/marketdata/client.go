@@ -0,0 +1,125 @@
+// file: trading-engine/internal/marketdata/client.go
+package marketdata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Quote is the latest NBBO quote for a symbol as returned by the market-data service
+type Quote struct {
+	AskPrice float64 `json:"ap"`
+	BidPrice float64 `json:"bp"`
+}
+
+type quoteResponse struct {
+	Symbol string `json:"symbol"`
+	Quote  Quote  `json:"quote"`
+}
+
+// ErrNotFound is returned when market-data answers 404, e.g. for an unknown symbol
+var ErrNotFound = errors.New("not found")
+
+var httpClient = &http.Client{Timeout: 5 * time.Second}
+
+func baseURL() string {
+	if u := os.Getenv("MARKET_DATA_SERVICE_URL"); u != "" {
+		return u
+	}
+	return "http://market-data:8082" // Default for local development
+}
+
+// get fetches a market-data endpoint and decodes the JSON body into out
+func get(path string, out interface{}) error {
+	res, err := httpClient.Get(baseURL() + path)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrNotFound, string(body))
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("market-data returned status %d: %s", res.StatusCode, string(body))
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+// LatestQuote fetches the latest quote for a symbol
+func LatestQuote(symbol string) (*Quote, error) {
+	var res quoteResponse
+	if err := get("/quotes/"+url.PathEscape(symbol), &res); err != nil {
+		return nil, err
+	}
+	return &res.Quote, nil
+}
+
+// LatestPrice returns the price a marketable order on the given side would likely trade at:
+// the ask for buys and the bid for sells, falling back to the other side when one is empty.
+func LatestPrice(symbol, side string) (float64, error) {
+	quote, err := LatestQuote(symbol)
+	if err != nil {
+		return 0, err
+	}
+
+	price := quote.BidPrice
+	if side == "buy" {
+		price = quote.AskPrice
+	}
+	if price == 0 {
+		price = quote.AskPrice + quote.BidPrice
+	}
+	if price == 0 {
+		return 0, fmt.Errorf("no quote available for %s", symbol)
+	}
+	return price, nil
+}
+
+// Clock reports whether the regular session is open and when it next opens and closes
+type Clock struct {
+	Timestamp time.Time `json:"timestamp"`
+	IsOpen    bool      `json:"is_open"`
+	NextOpen  time.Time `json:"next_open"`
+	NextClose time.Time `json:"next_close"`
+}
+
+// MarketClock fetches the market clock
+func MarketClock() (*Clock, error) {
+	var clock Clock
+	if err := get("/clock", &clock); err != nil {
+		return nil, err
+	}
+	return &clock, nil
+}
+
+// Asset is a symbol's entry in the asset master
+type Asset struct {
+	Symbol       string `json:"symbol"`
+	Name         string `json:"name"`
+	Status       string `json:"status"`
+	Tradable     bool   `json:"tradable"`
+	Shortable    bool   `json:"shortable"`
+	Fractionable bool   `json:"fractionable"`
+}
+
+// GetAsset looks up a symbol in the asset master; unknown symbols return ErrNotFound
+func GetAsset(symbol string) (*Asset, error) {
+	var asset Asset
+	if err := get("/assets/"+url.PathEscape(symbol), &asset); err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
@@ -0,0 +1,203 @@
+// file: trading-engine/internal/risk/checks.go
+package risk
+
+import (
+	"fmt"
+	"math"
+)
+
+// RiskCheck is a single pre-trade rule. Check returns nil when the order passes.
+type RiskCheck interface {
+	Name() string
+	Check(ctx *Context, rules Rules) *Rejection
+}
+
+// Chain runs checks in order and stops at the first rejection
+type Chain struct {
+	checks []RiskCheck
+}
+
+// NewChain creates a chain that evaluates the given checks in order
+func NewChain(checks ...RiskCheck) *Chain {
+	return &Chain{checks: checks}
+}
+
+// DefaultChain returns the checks used by CreateOrder, cheapest first
+func DefaultChain() *Chain {
+	return NewChain(
+		AssetCheck{},
+		RestrictedSymbolCheck{},
+		DailyLossCheck{},
+		MaxOrderNotionalCheck{},
+		BuyingPowerCheck{},
+		PositionLimitCheck{},
+	)
+}
+
+// Evaluate returns the first rejection in the chain, or nil if every check passes
+func (c *Chain) Evaluate(ctx *Context, rules Rules) *Rejection {
+	for _, check := range c.checks {
+		if rejection := check.Check(ctx, rules); rejection != nil {
+			rejection.Check = check.Name()
+			return rejection
+		}
+	}
+	return nil
+}
+
+// AssetCheck rejects symbols the broker does not list or trade, fractional orders in
+// assets that cannot be split, and sells that open a short the broker cannot borrow for
+type AssetCheck struct{}
+
+func (AssetCheck) Name() string { return "asset" }
+
+func (AssetCheck) Check(ctx *Context, rules Rules) *Rejection {
+	asset := ctx.Asset
+	if asset == nil {
+		return nil
+	}
+	symbol := ctx.Order.Symbol
+	switch {
+	case !asset.Listed:
+		return &Rejection{ReasonCode: ReasonUnknownSymbol, Message: fmt.Sprintf("%s is not a known symbol", symbol)}
+	case !asset.Tradable:
+		return &Rejection{ReasonCode: ReasonNotTradable, Message: fmt.Sprintf("%s is not tradable", symbol)}
+	}
+
+	fractional := ctx.Order.Notional > 0 || ctx.Order.Qty != math.Trunc(ctx.Order.Qty)
+	if fractional && !asset.Fractionable {
+		return &Rejection{
+			ReasonCode: ReasonNotFractionable,
+			Message:    fmt.Sprintf("%s does not support fractional or notional orders", symbol),
+		}
+	}
+
+	if ctx.Order.Side == "sell" && !asset.Shortable {
+		qty := ctx.EstimatedQty()
+		if qty > 0 && qty > ctx.Positions[symbol].Qty {
+			return &Rejection{
+				ReasonCode: ReasonNotShortable,
+				Message:    fmt.Sprintf("%s cannot be sold short", symbol),
+			}
+		}
+	}
+	return nil
+}
+
+// RestrictedSymbolCheck blocks any order in a restricted symbol
+type RestrictedSymbolCheck struct{}
+
+func (RestrictedSymbolCheck) Name() string { return "restricted_symbol" }
+
+func (RestrictedSymbolCheck) Check(ctx *Context, rules Rules) *Rejection {
+	if rules.IsRestricted(ctx.Order.Symbol) {
+		return &Rejection{
+			ReasonCode: ReasonRestrictedSymbol,
+			Message:    fmt.Sprintf("%s is on the restricted symbol list", ctx.Order.Symbol),
+		}
+	}
+	return nil
+}
+
+// DailyLossCheck stops new exposure once the account has lost more than the limit today.
+// Orders that only reduce existing positions are still allowed.
+type DailyLossCheck struct{}
+
+func (DailyLossCheck) Name() string { return "daily_loss_limit" }
+
+func (DailyLossCheck) Check(ctx *Context, rules Rules) *Rejection {
+	if rules.DailyLossLimit <= 0 || !ctx.IncreasesExposure() {
+		return nil
+	}
+	loss := ctx.Account.LastEquity - ctx.Account.Equity
+	if loss >= rules.DailyLossLimit {
+		return &Rejection{
+			ReasonCode: ReasonDailyLossLimit,
+			Message:    fmt.Sprintf("daily loss of %.2f has reached the limit of %.2f", loss, rules.DailyLossLimit),
+		}
+	}
+	return nil
+}
+
+// MaxOrderNotionalCheck caps the dollar value of a single order
+type MaxOrderNotionalCheck struct{}
+
+func (MaxOrderNotionalCheck) Name() string { return "max_order_notional" }
+
+func (MaxOrderNotionalCheck) Check(ctx *Context, rules Rules) *Rejection {
+	if rules.MaxOrderNotional <= 0 {
+		return nil
+	}
+	notional := ctx.EstimatedNotional()
+	if notional == 0 {
+		return priceUnavailable(ctx)
+	}
+	if notional > rules.MaxOrderNotional {
+		return &Rejection{
+			ReasonCode: ReasonMaxOrderNotional,
+			Message:    fmt.Sprintf("order value %.2f exceeds the maximum of %.2f", notional, rules.MaxOrderNotional),
+		}
+	}
+	return nil
+}
+
+// BuyingPowerCheck rejects buys the account cannot pay for
+type BuyingPowerCheck struct{}
+
+func (BuyingPowerCheck) Name() string { return "buying_power" }
+
+func (BuyingPowerCheck) Check(ctx *Context, rules Rules) *Rejection {
+	if ctx.Order.Side != "buy" {
+		return nil
+	}
+	// Without a price we cannot size a qty order; leave it to the broker
+	if ctx.EstimatedNotional() == 0 {
+		return nil
+	}
+	// A replace releases the buying power held for the order it replaces
+	if notional := ctx.AddedNotional(); notional > ctx.Account.BuyingPower {
+		return &Rejection{
+			ReasonCode: ReasonInsufficientBuyingPower,
+			Message:    fmt.Sprintf("order value %.2f exceeds buying power of %.2f", notional, ctx.Account.BuyingPower),
+		}
+	}
+	return nil
+}
+
+// PositionLimitCheck caps the absolute dollar value held in one symbol after the order
+// fills, counting only the added value of a replace
+type PositionLimitCheck struct{}
+
+func (PositionLimitCheck) Name() string { return "position_limit" }
+
+func (PositionLimitCheck) Check(ctx *Context, rules Rules) *Rejection {
+	if rules.MaxPositionNotional <= 0 || !ctx.IncreasesExposure() {
+		return nil
+	}
+	if ctx.Price == 0 {
+		return priceUnavailable(ctx)
+	}
+
+	held := ctx.Positions[ctx.Order.Symbol].Qty * ctx.Price
+	added := ctx.AddedNotional()
+	if ctx.Order.Side == "sell" {
+		added = -added
+	}
+	resulting := math.Abs(held + added)
+
+	if resulting > rules.MaxPositionNotional {
+		return &Rejection{
+			ReasonCode: ReasonPositionLimit,
+			Message: fmt.Sprintf("resulting %s position of %.2f exceeds the limit of %.2f",
+				ctx.Order.Symbol, resulting, rules.MaxPositionNotional),
+		}
+	}
+	return nil
+}
+
+func priceUnavailable(ctx *Context) *Rejection {
+	return &Rejection{
+		ReasonCode: ReasonPriceUnavailable,
+		Message:    fmt.Sprintf("could not determine a price for %s to evaluate order limits", ctx.Order.Symbol),
+	}
+}
@@ -0,0 +1,213 @@
+package risk
+
+import "testing"
+
+func TestChainEvaluate(t *testing.T) {
+	baseAccount := Account{BuyingPower: 10000, Equity: 10000, LastEquity: 10000}
+	positions := map[string]Position{
+		"AAPL": {Symbol: "AAPL", Qty: 10, MarketValue: 2000, CurrentPrice: 200},
+	}
+
+	tests := []struct {
+		name     string
+		order    Order
+		account  Account
+		price    float64
+		asset    *Asset
+		replaces *Order
+		rules    Rules
+		wantCode string
+	}{
+		{
+			name:  "no rules configured",
+			order: Order{Symbol: "TSLA", Side: "buy", Qty: 1},
+			price: 250,
+		},
+		{
+			name:     "restricted symbol",
+			order:    Order{Symbol: "GME", Side: "buy", Qty: 1},
+			price:    20,
+			rules:    Rules{RestrictedSymbols: []string{"gme"}},
+			wantCode: ReasonRestrictedSymbol,
+		},
+		{
+			name:     "order notional over limit",
+			order:    Order{Symbol: "TSLA", Side: "buy", Qty: 10},
+			price:    250,
+			rules:    Rules{MaxOrderNotional: 1000},
+			wantCode: ReasonMaxOrderNotional,
+		},
+		{
+			name:     "insufficient buying power",
+			order:    Order{Symbol: "TSLA", Side: "buy", Notional: 20000},
+			rules:    Rules{},
+			wantCode: ReasonInsufficientBuyingPower,
+		},
+		{
+			name:     "position cap exceeded by add",
+			order:    Order{Symbol: "AAPL", Side: "buy", Qty: 10},
+			price:    200,
+			rules:    Rules{MaxPositionNotional: 3000},
+			wantCode: ReasonPositionLimit,
+		},
+		{
+			name:  "reducing sell ignores position cap",
+			order: Order{Symbol: "AAPL", Side: "sell", Qty: 5},
+			price: 200,
+			rules: Rules{MaxPositionNotional: 100},
+		},
+		{
+			name:     "daily loss limit blocks buys",
+			order:    Order{Symbol: "TSLA", Side: "buy", Qty: 1},
+			account:  Account{BuyingPower: 10000, Equity: 9000, LastEquity: 10000},
+			price:    250,
+			rules:    Rules{DailyLossLimit: 500},
+			wantCode: ReasonDailyLossLimit,
+		},
+		{
+			name:    "daily loss limit allows reducing sells",
+			order:   Order{Symbol: "AAPL", Side: "sell", Qty: 10},
+			account: Account{BuyingPower: 10000, Equity: 9000, LastEquity: 10000},
+			price:   200,
+			rules:   Rules{DailyLossLimit: 500},
+		},
+		{
+			name:     "unknown price with notional rule",
+			order:    Order{Symbol: "TSLA", Side: "buy", Qty: 1},
+			rules:    Rules{MaxOrderNotional: 1000},
+			wantCode: ReasonPriceUnavailable,
+		},
+		{
+			name:     "unknown symbol",
+			order:    Order{Symbol: "NOPE", Side: "buy", Qty: 1},
+			price:    10,
+			asset:    &Asset{},
+			wantCode: ReasonUnknownSymbol,
+		},
+		{
+			name:     "halted asset",
+			order:    Order{Symbol: "TSLA", Side: "buy", Qty: 1},
+			price:    250,
+			asset:    &Asset{Listed: true},
+			wantCode: ReasonNotTradable,
+		},
+		{
+			name:     "notional order in a non-fractionable asset",
+			order:    Order{Symbol: "TSLA", Side: "buy", Notional: 100},
+			asset:    &Asset{Listed: true, Tradable: true},
+			wantCode: ReasonNotFractionable,
+		},
+		{
+			name:  "whole shares in a non-fractionable asset",
+			order: Order{Symbol: "TSLA", Side: "buy", Qty: 2},
+			price: 250,
+			asset: &Asset{Listed: true, Tradable: true},
+		},
+		{
+			name:     "short sale of a non-shortable asset",
+			order:    Order{Symbol: "AAPL", Side: "sell", Qty: 15},
+			price:    200,
+			asset:    &Asset{Listed: true, Tradable: true},
+			wantCode: ReasonNotShortable,
+		},
+		{
+			name:  "closing sale of a non-shortable asset",
+			order: Order{Symbol: "AAPL", Side: "sell", Qty: 10},
+			price: 200,
+			asset: &Asset{Listed: true, Tradable: true},
+		},
+		{
+			name:     "replace raising the limit price over the notional cap",
+			order:    Order{Symbol: "TSLA", Side: "buy", Type: "limit", Qty: 10, LimitPrice: 150},
+			price:    150,
+			replaces: &Order{Symbol: "TSLA", Side: "buy", Type: "limit", Qty: 10, LimitPrice: 90},
+			rules:    Rules{MaxOrderNotional: 1000},
+			wantCode: ReasonMaxOrderNotional,
+		},
+		{
+			name:     "replace counts only the added value against the position cap",
+			order:    Order{Symbol: "AAPL", Side: "buy", Type: "limit", Qty: 12, LimitPrice: 200},
+			price:    200,
+			replaces: &Order{Symbol: "AAPL", Side: "buy", Type: "limit", Qty: 10, LimitPrice: 200},
+			rules:    Rules{MaxPositionNotional: 2500},
+		},
+		{
+			name:     "replace raising the limit price past the position cap",
+			order:    Order{Symbol: "AAPL", Side: "buy", Type: "limit", Qty: 10, LimitPrice: 260},
+			price:    260,
+			replaces: &Order{Symbol: "AAPL", Side: "buy", Type: "limit", Qty: 10, LimitPrice: 200},
+			rules:    Rules{MaxPositionNotional: 2500},
+			wantCode: ReasonPositionLimit,
+		},
+		{
+			name:     "smaller replace ignores the daily loss limit",
+			order:    Order{Symbol: "TSLA", Side: "buy", Type: "limit", Qty: 1, LimitPrice: 250},
+			account:  Account{BuyingPower: 10000, Equity: 9000, LastEquity: 10000},
+			price:    250,
+			replaces: &Order{Symbol: "TSLA", Side: "buy", Type: "limit", Qty: 2, LimitPrice: 250},
+			rules:    Rules{DailyLossLimit: 500},
+		},
+		{
+			name:     "replace checks buying power for the added value",
+			order:    Order{Symbol: "TSLA", Side: "buy", Type: "limit", Qty: 50, LimitPrice: 250},
+			price:    250,
+			replaces: &Order{Symbol: "TSLA", Side: "buy", Type: "limit", Qty: 40, LimitPrice: 250},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := tt.account
+			if account == (Account{}) {
+				account = baseAccount
+			}
+			ctx := &Context{
+				AccountID: "test-account",
+				Order:     tt.order,
+				Account:   account,
+				Positions: positions,
+				Price:     tt.price,
+				Asset:     tt.asset,
+				Replaces:  tt.replaces,
+			}
+
+			rejection := DefaultChain().Evaluate(ctx, tt.rules)
+			if tt.wantCode == "" {
+				if rejection != nil {
+					t.Fatalf("expected order to pass, got %v", rejection)
+				}
+				return
+			}
+			if rejection == nil {
+				t.Fatalf("expected rejection %s, got none", tt.wantCode)
+			}
+			if rejection.ReasonCode != tt.wantCode {
+				t.Errorf("expected reason code %s, got %s", tt.wantCode, rejection.ReasonCode)
+			}
+			if rejection.Check == "" {
+				t.Errorf("expected rejection to name the failing check")
+			}
+		})
+	}
+}
+
+func TestConfigRulesFor(t *testing.T) {
+	config := &Config{
+		Default: Rules{MaxOrderNotional: 5000, DailyLossLimit: 1000},
+		Accounts: map[string]Rules{
+			"vip": {MaxOrderNotional: 50000},
+		},
+	}
+
+	rules := config.RulesFor("vip")
+	if rules.MaxOrderNotional != 50000 {
+		t.Errorf("expected account override of 50000, got %v", rules.MaxOrderNotional)
+	}
+	if rules.DailyLossLimit != 1000 {
+		t.Errorf("expected default daily loss limit to carry over, got %v", rules.DailyLossLimit)
+	}
+
+	if got := config.RulesFor("other").MaxOrderNotional; got != 5000 {
+		t.Errorf("expected default of 5000, got %v", got)
+	}
+}
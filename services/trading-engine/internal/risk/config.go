@@ -0,0 +1,73 @@
+// file: trading-engine/internal/risk/config.go
+package risk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Config holds the default rules and any per-account overrides
+type Config struct {
+	Default  Rules            `json:"default"`
+	Accounts map[string]Rules `json:"accounts"`
+}
+
+// RulesFor returns the default rules with the account's overrides applied
+func (c *Config) RulesFor(accountID string) Rules {
+	rules := c.Default
+	if override, ok := c.Accounts[accountID]; ok {
+		rules = rules.merge(override)
+	}
+	return rules
+}
+
+// LoadConfig reads the rules from the JSON file at RISK_CONFIG_PATH when set,
+// otherwise it builds the default rules from RISK_* environment variables.
+func LoadConfig() (*Config, error) {
+	if path := os.Getenv("RISK_CONFIG_PATH"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read risk config %s: %w", path, err)
+		}
+		var config Config
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse risk config %s: %w", path, err)
+		}
+		return &config, nil
+	}
+
+	config := &Config{}
+	var err error
+	if config.Default.MaxOrderNotional, err = envFloat("RISK_MAX_ORDER_NOTIONAL"); err != nil {
+		return nil, err
+	}
+	if config.Default.MaxPositionNotional, err = envFloat("RISK_MAX_POSITION_NOTIONAL"); err != nil {
+		return nil, err
+	}
+	if config.Default.DailyLossLimit, err = envFloat("RISK_DAILY_LOSS_LIMIT"); err != nil {
+		return nil, err
+	}
+	if symbols := os.Getenv("RISK_RESTRICTED_SYMBOLS"); symbols != "" {
+		for _, symbol := range strings.Split(symbols, ",") {
+			if symbol = strings.TrimSpace(symbol); symbol != "" {
+				config.Default.RestrictedSymbols = append(config.Default.RestrictedSymbols, strings.ToUpper(symbol))
+			}
+		}
+	}
+	return config, nil
+}
+
+func envFloat(name string) (float64, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return f, nil
+}
@@ -0,0 +1,182 @@
+// file: trading-engine/internal/risk/risk.go
+package risk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reason codes returned to clients when an order is rejected before reaching the broker
+const (
+	ReasonRestrictedSymbol        = "RESTRICTED_SYMBOL"
+	ReasonInsufficientBuyingPower = "INSUFFICIENT_BUYING_POWER"
+	ReasonMaxOrderNotional        = "MAX_ORDER_NOTIONAL_EXCEEDED"
+	ReasonPositionLimit           = "POSITION_LIMIT_EXCEEDED"
+	ReasonDailyLossLimit          = "DAILY_LOSS_LIMIT_EXCEEDED"
+	ReasonPriceUnavailable        = "PRICE_UNAVAILABLE"
+	ReasonMarketClosed            = "MARKET_CLOSED"
+	ReasonUnknownSymbol           = "UNKNOWN_SYMBOL"
+	ReasonNotTradable             = "NOT_TRADABLE"
+	ReasonNotFractionable         = "NOT_FRACTIONABLE"
+	ReasonNotShortable            = "NOT_SHORTABLE"
+)
+
+// Order is the order being evaluated, with decimal fields already parsed
+type Order struct {
+	Symbol     string
+	Side       string
+	Type       string
+	Qty        float64
+	Notional   float64
+	LimitPrice float64
+	StopPrice  float64
+}
+
+// Account holds the account balances used by the checks
+type Account struct {
+	BuyingPower float64
+	Equity      float64
+	LastEquity  float64
+}
+
+// Position is the current holding of a single symbol
+type Position struct {
+	Symbol       string
+	Qty          float64
+	MarketValue  float64
+	CurrentPrice float64
+}
+
+// Asset is what the asset master says about the order's symbol
+type Asset struct {
+	// Listed is false when the broker does not know the symbol
+	Listed       bool
+	Tradable     bool
+	Fractionable bool
+	Shortable    bool
+}
+
+// Context carries everything a check needs to evaluate a single order
+type Context struct {
+	AccountID string
+	Order     Order
+	Account   Account
+	Positions map[string]Position
+	// Asset is nil when the asset master could not be consulted
+	Asset *Asset
+	// Price is the estimated execution price, zero when it could not be determined
+	Price float64
+	// Replaces is the working order a replace amends, nil for a new order. The
+	// exposure checks then count only what the replacement adds on top of it.
+	Replaces *Order
+}
+
+// EstimatedNotional returns the dollar value of the order, or zero if it cannot be estimated
+func (c *Context) EstimatedNotional() float64 {
+	if c.Order.Notional > 0 {
+		return c.Order.Notional
+	}
+	return c.Order.Qty * c.Price
+}
+
+// EstimatedQty returns the share quantity of the order, or zero if it cannot be estimated
+func (c *Context) EstimatedQty() float64 {
+	if c.Order.Qty > 0 {
+		return c.Order.Qty
+	}
+	if c.Price > 0 {
+		return c.Order.Notional / c.Price
+	}
+	return 0
+}
+
+// notionalAt values the order at its own limit or stop price, falling back to price
+func (o *Order) notionalAt(price float64) float64 {
+	if o.Notional > 0 {
+		return o.Notional
+	}
+	if o.LimitPrice > 0 {
+		price = o.LimitPrice
+	} else if o.StopPrice > 0 {
+		price = o.StopPrice
+	}
+	return o.Qty * price
+}
+
+// AddedNotional returns the dollar value the order adds: all of it for a new order,
+// and the new value less the replaced order's value for a replace
+func (c *Context) AddedNotional() float64 {
+	notional := c.EstimatedNotional()
+	if c.Replaces == nil {
+		return notional
+	}
+	return notional - c.Replaces.notionalAt(c.Price)
+}
+
+// IncreasesExposure reports whether the order opens or adds to a position.
+// Sells only count when they go beyond the held quantity and open a short. A replace
+// only counts when it is worth more than the order it replaces, or, without a price,
+// when it is larger.
+func (c *Context) IncreasesExposure() bool {
+	if c.Replaces != nil {
+		added := c.AddedNotional()
+		if c.Price == 0 {
+			added = c.Order.Qty - c.Replaces.Qty
+		}
+		if added <= 0 {
+			return false
+		}
+	}
+	if c.Order.Side == "buy" {
+		return true
+	}
+	held := c.Positions[c.Order.Symbol].Qty
+	qty := c.EstimatedQty()
+	return qty == 0 || qty > held
+}
+
+// Rejection describes why an order failed a risk check
+type Rejection struct {
+	Check      string `json:"check"`
+	ReasonCode string `json:"reason_code"`
+	Message    string `json:"message"`
+}
+
+func (r *Rejection) Error() string {
+	return fmt.Sprintf("%s: %s", r.ReasonCode, r.Message)
+}
+
+// Rules are the configurable per-account limits. A zero value disables the limit.
+type Rules struct {
+	MaxOrderNotional    float64  `json:"max_order_notional"`
+	MaxPositionNotional float64  `json:"max_position_notional"`
+	DailyLossLimit      float64  `json:"daily_loss_limit"`
+	RestrictedSymbols   []string `json:"restricted_symbols"`
+}
+
+// IsRestricted reports whether trading the symbol is blocked
+func (r Rules) IsRestricted(symbol string) bool {
+	for _, restricted := range r.RestrictedSymbols {
+		if strings.EqualFold(restricted, symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// merge overlays the non-zero fields of override onto r
+func (r Rules) merge(override Rules) Rules {
+	if override.MaxOrderNotional != 0 {
+		r.MaxOrderNotional = override.MaxOrderNotional
+	}
+	if override.MaxPositionNotional != 0 {
+		r.MaxPositionNotional = override.MaxPositionNotional
+	}
+	if override.DailyLossLimit != 0 {
+		r.DailyLossLimit = override.DailyLossLimit
+	}
+	if override.RestrictedSymbols != nil {
+		r.RestrictedSymbols = override.RestrictedSymbols
+	}
+	return r
+}
@@ -2,6 +2,7 @@
 package main
 
 import (
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -10,21 +11,34 @@ import (
 
 func main() {
 
+	if err := handlers.InitRiskChecks(); err != nil {
+		log.Fatalf("Failed to load risk configuration: %v", err)
+	}
+	handlers.InitIdempotency()
+
 	r := gin.Default()
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"message": "health endpoint",
 		})
 	})
-	r.POST("/orders", handlers.CreateOrder)
-
-	r.DELETE("/orders", handlers.DeleteAllOrders)
-
-	r.GET("/:order_id", handlers.GetOrder)
-
-	r.GET("/orders", handlers.GetOrders)
 
-	r.DELETE("/:order_id", handlers.DeleteOrder)
+	v1 := r.Group("/v1")
+	{
+		v1.POST("/orders", handlers.CreateOrder)
+		v1.GET("/orders", handlers.GetOrders)
+		v1.DELETE("/orders", handlers.DeleteAllOrders)
+		v1.GET("/orders/:order_id", handlers.GetOrder)
+		v1.PATCH("/orders/:order_id", handlers.ReplaceOrder)
+		v1.DELETE("/orders/:order_id", handlers.DeleteOrder)
+	}
+
+	// Deprecated unversioned aliases, kept until clients move to /v1
+	r.POST("/orders", handlers.Deprecated("/v1/orders"), handlers.CreateOrder)
+	r.GET("/orders", handlers.Deprecated("/v1/orders"), handlers.GetOrders)
+	r.DELETE("/orders", handlers.Deprecated("/v1/orders"), handlers.DeleteAllOrders)
+	r.GET("/:order_id", handlers.Deprecated("/v1/orders/:order_id"), handlers.GetOrder)
+	r.DELETE("/:order_id", handlers.Deprecated("/v1/orders/:order_id"), handlers.DeleteOrder)
 
 	r.Run(":8083") // listen and serve on 0.0.0.0:8080 (for windows "localhost:8080")
 
@@ -6,6 +6,7 @@ import (
 	"io"
 	"math/rand"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 )
@@ -32,8 +33,16 @@ func generateUniquePhone() string {
 	return fmt.Sprintf("+1555%07d", rand.Intn(10000000))
 }
 
+// brokerURL is the Broker API base URL; ALPACA_BROKER_URL overrides the sandbox
+func brokerURL() string {
+	if url := strings.TrimRight(os.Getenv("ALPACA_BROKER_URL"), "/"); url != "" {
+		return url
+	}
+	return "https://broker-api.sandbox.alpaca.markets"
+}
+
 func CreateAlpacaAccount(email string, firstName string, lastName string) (*AlpacaAccount, error) {
-	url := "https://broker-api.sandbox.alpaca.markets/v1/accounts"
+	url := brokerURL() + "/v1/accounts"
 
 	// Generate unique identifiers for sandbox
 	taxId := generateUniqueTaxId()